@@ -11,10 +11,19 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"strings"
 )
 
 // Authenticate sends the credentials to MySQL.
 func (bl *Remote) Authenticate(username, password string) error {
+	span := startSpan(bl.spanCtx, bl.spans, SpanAuthenticate)
+	span.SetAttribute(AttrAddr, bl.addr)
+	err := bl.authenticate(username, password)
+	endSpan(span, err)
+	return err
+}
+
+func (bl *Remote) authenticate(username, password string) error {
 	bl.authFlow = nil
 	var plugin string
 	switch bl.hs.authPluginName {
@@ -32,7 +41,7 @@ func (bl *Remote) Authenticate(username, password string) error {
 		return err
 	}
 
-	err = bl.write(handshakeResponse41{
+	resp := handshakeResponse41{
 		capabilityFlags: capLongFlag | capSecureConnection,
 		maxPacketSize:   maxPacketSize,
 		characterSet:    bl.hs.characterSet,
@@ -41,14 +50,15 @@ func (bl *Remote) Authenticate(username, password string) error {
 		database:        "",
 		authPluginName:  plugin,
 		connectAttrs:    nil,
-	})
-	if err != nil {
+	}
+	if err = bl.write(resp); err != nil {
 		return err
 	}
+	bl.capabilities = bl.hs.capabilityFlags & resp.capabilities()
 	var numAuthSwitches = 0
 AuthSuccess:
 	for {
-		r := newReader(bl.conn, &bl.seq)
+		r := newReader(bl.rw(), &bl.seq)
 		marker, err := r.peek()
 		if err != nil {
 			return err
@@ -85,7 +95,7 @@ AuthSuccess:
 						break AuthSuccess
 					case 4:
 						bl.authFlow = append(bl.authFlow, "performFullAuthentication")
-						switch bl.conn.(type) {
+						switch bl.raw.(type) {
 						case *tls.Conn, *net.UnixConn:
 							authResponse = append([]byte(password), 0)
 						default:
@@ -94,7 +104,7 @@ AuthSuccess:
 								if err := bl.write(requestPublicKey{}); err != nil {
 									return err
 								}
-								r := newReader(bl.conn, &bl.seq)
+								r := newReader(bl.rw(), &bl.seq)
 								amd := authMoreData{}
 								if err := amd.decode(r); err != nil {
 									return err
@@ -162,15 +172,42 @@ AuthSuccess:
 		}
 	}
 	// authentication succeeded
+	if bl.controlEnabled {
+		bl.mu.Lock()
+		bl.credentials = StaticCredentials(username, password)
+		bl.mu.Unlock()
+	}
 
 	// query serverVersion. seems azure reports wrong serverVersion in handshake
 	// Azure Database for MySQL service that is created with version 5.7
 	// reports its version as "5.6.26.0" in initial handshake packet.
 	rows, err := bl.queryRows(`select version()`)
 	if err != nil {
+		if bl.azureCompat || bl.minimalPrivileges {
+			return bl.checkServerUUID()
+		}
 		return err
 	}
 	bl.hs.serverVersion = rows[0][0].(string)
+	return bl.checkServerUUID()
+}
+
+// checkServerUUID reads server_uuid, and validates it against
+// expected server uuid if any.
+func (bl *Remote) checkServerUUID() error {
+	rows, err := bl.queryRows(`select @@server_uuid`)
+	if err == nil && len(rows) == 1 {
+		bl.serverUUID, _ = rows[0][0].(string)
+	}
+	if bl.expectedServerUUID == "" {
+		return nil // mariadb has no server_uuid
+	}
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(bl.serverUUID, bl.expectedServerUUID) {
+		return &ServerUUIDError{Expected: bl.expectedServerUUID, Actual: bl.serverUUID}
+	}
 	return nil
 }
 
@@ -182,7 +219,7 @@ func (bl *Remote) encryptPassword(plugin string, password, scramble []byte) ([]b
 		if len(password) == 0 {
 			return []byte{0}, nil
 		}
-		switch bl.conn.(type) {
+		switch bl.raw.(type) {
 		case *tls.Conn:
 			// unlike caching_sha2_password, sha256_password does not accept
 			// cleartext password on unix transport
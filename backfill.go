@@ -0,0 +1,400 @@
+package binlog
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Backfiller streams existing rows of a table in chunks, ordered by
+// primary key, interleaved with live events from Src, without locking
+// the table. It implements the watermark algorithm of DBLog:
+//
+//	https://arxiv.org/abs/2010.12597
+//
+// For each chunk, it writes low watermark to WatermarkTable, using
+// WatermarkWriter, selects
+// the chunk, and writes high watermark. Live changes to rows of the
+// chunk seen between the watermarks, supersede the selected rows. The
+// remaining rows are returned as BackfillEvent, in place of the high
+// watermark, so that the result is consistent with the stream.
+//
+// The WatermarkTable must be created as described in WatermarkWriter.
+// Its row changes are consumed by Backfiller and not returned.
+// Requires binlog_row_image=FULL.
+//
+// Primary keys of selected rows are converted to types decoded from
+// binlog, using column types of live RowsEvent, to compare them. Text of
+// TIMESTAMP values is taken as UTC, so sessions of DB must use UTC time
+// zone if primary key has TIMESTAMP column. ENUM and SET primary key
+// columns are not supported.
+type Backfiller struct {
+	Src            EventSource
+	DB             *sql.DB  // connection to the source
+	Table          string   // table to backfill, as "db.table"
+	PK             []string // primary key columns
+	ChunkSize      int      // rows per chunk. defaults to 1000
+	WatermarkTable string   // as "db.table"
+	WatermarkID    int      // id of watermark row. see WatermarkWriter
+
+	state   backfillState
+	low     string
+	high    string
+	cols    []string        // columns of table
+	pkIdx   []int           // index of PK in cols
+	chunk   [][]interface{} // selected rows. nil if superseded
+	keys    map[string]int  // key of row in chunk to its index. nil until indexed
+	live    []Column        // columns of current live RowsEvent of Table
+	after   []interface{}   // primary key of last row selected
+	last    bool            // whether chunk is the last
+	nchunk  int
+	curr    int  // 0: none, 1: live rows of Table, 2: BackfillEvent
+	emitted int  // rows of chunk returned by NextRow
+	window  bool // whether current live rows are between watermarks
+}
+
+type backfillState int
+
+const (
+	backfillIdle   backfillState = iota // chunk not started
+	backfillLow                         // waiting for low watermark
+	backfillWindow                      // between watermarks
+	backfillDone
+)
+
+// BackfillEvent is a synthetic event, returned by Backfiller with
+// chunk of existing rows of the table, to be treated as inserts.
+// Rows are read using NextRow, as with RowsEvent. The values are as
+// returned by database/sql, such as []byte, int64, float64, nil.
+type BackfillEvent struct {
+	Schema, Table string
+	Columns       []string // column names
+	Chunk         int      // sequence number of chunk, starting from 1
+	Last          bool     // whether it is the last chunk
+}
+
+// Done tells whether all chunks are returned.
+func (b *Backfiller) Done() bool {
+	return b.state == backfillDone
+}
+
+// NextEvent returns next event from Src, or BackfillEvent.
+func (b *Backfiller) NextEvent() (Event, error) {
+	if b.curr == 1 {
+		// reconcile unread rows
+		for {
+			if _, _, err := b.NextRow(); err == io.EOF {
+				break
+			} else if err != nil {
+				return Event{}, err
+			}
+		}
+	}
+	b.curr = 0
+	if b.state == backfillIdle {
+		if err := b.startChunk(); err != nil {
+			return Event{}, err
+		}
+	}
+	for {
+		e, err := b.Src.NextEvent()
+		if err != nil {
+			return e, err
+		}
+		d := WatermarkDetector{Table: b.WatermarkTable}
+		marks, ok, err := d.Detect(e, b.Src)
+		if err != nil {
+			return Event{}, err
+		}
+		if ok {
+			if b.watermark(marks) {
+				b.curr, b.emitted = 2, 0
+				schema, table := splitTable(b.Table)
+				be := BackfillEvent{schema, table, b.cols, b.nchunk, b.last}
+				return synthetic(e, be), nil
+			}
+			continue
+		}
+		if re, ok := e.Data.(RowsEvent); ok && re.TableMap != nil && re.TableMap.SchemaName+"."+re.TableMap.TableName == b.Table {
+			b.curr, b.window = 1, b.state == backfillWindow
+			b.live = re.Columns()
+		}
+		return e, nil
+	}
+}
+
+// NextRow returns next row of current RowsEvent or BackfillEvent.
+func (b *Backfiller) NextRow() (values []interface{}, valuesBeforeUpdate []interface{}, err error) {
+	if b.curr == 2 {
+		for b.emitted < len(b.chunk) {
+			row := b.chunk[b.emitted]
+			b.emitted++
+			if row != nil {
+				return row, nil, nil
+			}
+		}
+		return nil, nil, io.EOF
+	}
+	values, valuesBeforeUpdate, err = b.Src.NextRow()
+	if err == nil && b.curr == 1 && b.window {
+		if b.keys == nil {
+			b.indexChunk()
+		}
+		for _, row := range [][]interface{}{values, valuesBeforeUpdate} {
+			if row == nil {
+				continue
+			}
+			if i, ok := b.keys[b.key(row, false)]; ok {
+				b.chunk[i] = nil // superseded by live change
+			}
+		}
+	}
+	if err == io.EOF {
+		b.curr = 0
+	}
+	return
+}
+
+// startChunk writes low watermark, selects next chunk and writes
+// high watermark.
+func (b *Backfiller) startChunk() error {
+	if b.ChunkSize <= 0 {
+		b.ChunkSize = 1000
+	}
+	w := &WatermarkWriter{DB: b.DB, Table: b.WatermarkTable, ID: b.WatermarkID}
+	var err error
+	if b.low, err = w.Write(); err != nil {
+		return err
+	}
+	if err := b.selectChunk(); err != nil {
+		return err
+	}
+	if b.high, err = w.Write(); err != nil {
+		return err
+	}
+	b.state = backfillLow
+	return nil
+}
+
+func (b *Backfiller) selectChunk() error {
+	pk := make([]string, len(b.PK))
+	for i, col := range b.PK {
+		pk[i] = quoteIdent(col)
+	}
+	schema, table := splitTable(b.Table)
+	q := fmt.Sprintf("select * from %s.%s", quoteIdent(schema), quoteIdent(table))
+	if b.after != nil {
+		marks := strings.TrimSuffix(strings.Repeat("?,", len(pk)), ",")
+		q += fmt.Sprintf(" where (%s) > (%s)", strings.Join(pk, ","), marks)
+	}
+	q += fmt.Sprintf(" order by %s limit %d", strings.Join(pk, ","), b.ChunkSize)
+	rows, err := b.DB.Query(q, b.after...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if b.cols, err = rows.Columns(); err != nil {
+		return err
+	}
+	b.pkIdx = b.pkIdx[:0]
+	for _, col := range b.PK {
+		i := indexFold(b.cols, col)
+		if i == -1 {
+			return fmt.Errorf("binlog: primary key column %s not found in %s", col, b.Table)
+		}
+		b.pkIdx = append(b.pkIdx, i)
+	}
+	b.chunk, b.keys = nil, nil
+	for rows.Next() {
+		row := make([]interface{}, len(b.cols))
+		dest := make([]interface{}, len(row))
+		for i := range row {
+			dest[i] = &row[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		b.chunk = append(b.chunk, row)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	b.nchunk++
+	b.last = len(b.chunk) < b.ChunkSize
+	if n := len(b.chunk); n > 0 {
+		b.after = b.after[:0]
+		for _, i := range b.pkIdx {
+			b.after = append(b.after, b.chunk[n-1][i])
+		}
+	}
+	return nil
+}
+
+// watermark handles watermarks detected. returns true, if the chunk
+// is to be emitted.
+func (b *Backfiller) watermark(marks []Watermark) (emit bool) {
+	for _, m := range marks {
+		switch {
+		case m.ID != int64(b.WatermarkID):
+		case b.state == backfillLow && m.Value == b.low:
+			b.state = backfillWindow
+		case b.state == backfillWindow && m.Value == b.high:
+			b.state = backfillIdle
+			if b.last {
+				b.state = backfillDone
+			}
+			emit = true
+		}
+	}
+	return emit
+}
+
+// indexChunk indexes rows of chunk by key, once column types are known
+// from live RowsEvent.
+func (b *Backfiller) indexChunk() {
+	b.keys = make(map[string]int)
+	for i, row := range b.chunk {
+		if row != nil {
+			b.keys[b.key(row, true)] = i
+		}
+	}
+}
+
+// key returns canonical encoding of primary key of row. Values of
+// selected rows are converted to types decoded from binlog, so that
+// they can be compared with live rows.
+func (b *Backfiller) key(row []interface{}, selected bool) string {
+	var buf []byte
+	for _, i := range b.pkIdx {
+		var col Column
+		if i < len(b.live) {
+			col = b.live[i]
+		}
+		var v interface{}
+		if i < len(row) {
+			v = row[i]
+		}
+		if selected {
+			v = sqlValue(col, v)
+		}
+		buf = appendCanonical(buf, col, v)
+	}
+	return string(buf)
+}
+
+// sqlValue converts value v of column col, as returned by database/sql,
+// to type decoded from binlog. Values not convertible are returned as is.
+func sqlValue(col Column, v interface{}) interface{} {
+	var text string
+	switch t := v.(type) {
+	case nil:
+		return nil
+	case []byte:
+		text = string(t)
+	case string:
+		text = t
+	case time.Time:
+		switch col.Type {
+		case TypeTimestamp, TypeTimestamp2:
+			return t
+		}
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	default:
+		text = fmt.Sprint(t)
+	}
+	switch col.Type {
+	case TypeTiny, TypeShort, TypeInt24, TypeLong, TypeLongLong, TypeYear:
+		n, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			u, err := strconv.ParseUint(text, 10, 64)
+			if err != nil {
+				return v
+			}
+			n = int64(u)
+		}
+		switch col.Type {
+		case TypeTiny:
+			return int8(n)
+		case TypeShort:
+			return int16(n)
+		case TypeInt24, TypeLong:
+			return int32(n)
+		case TypeYear:
+			return int(n)
+		}
+		return n
+	case TypeFloat, TypeDouble:
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return v
+		}
+		if col.Type == TypeFloat {
+			return float32(f)
+		}
+		return f
+	case TypeDecimal, TypeNewDecimal:
+		return Decimal(text)
+	case TypeDate, TypeNewDate, TypeDateTime, TypeDateTime2, TypeTimestamp, TypeTimestamp2:
+		t, err := time.ParseInLocation("2006-01-02 15:04:05.999999999", text, time.UTC)
+		if err != nil {
+			if t, err = time.ParseInLocation("2006-01-02", text, time.UTC); err != nil {
+				return v
+			}
+		}
+		return t
+	case TypeTime, TypeTime2:
+		if d, ok := parseTimeValue(text); ok {
+			return d
+		}
+	}
+	return v
+}
+
+// parseTimeValue parses value of TIME column, such as "-838:59:59.000000".
+func parseTimeValue(s string) (time.Duration, bool) {
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	var d time.Duration
+	if i := strings.IndexByte(s, '.'); i != -1 {
+		n, err := strconv.Atoi((s[i+1:] + "000000000")[:9])
+		if err != nil {
+			return 0, false
+		}
+		d, s = time.Duration(n), s[:i]
+	}
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return 0, false
+	}
+	for i, unit := range []time.Duration{time.Hour, time.Minute, time.Second} {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return 0, false
+		}
+		d += time.Duration(n) * unit
+	}
+	if neg {
+		d = -d
+	}
+	return d, true
+}
+
+// splitTable splits "db.table" into schema and table.
+func splitTable(s string) (schema, table string) {
+	if i := strings.IndexByte(s, '.'); i != -1 {
+		return s[:i], s[i+1:]
+	}
+	return "", s
+}
+
+func indexFold(list []string, s string) int {
+	for i, v := range list {
+		if strings.EqualFold(v, s) {
+			return i
+		}
+	}
+	return -1
+}
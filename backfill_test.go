@@ -0,0 +1,231 @@
+package binlog
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSource is EventSource returning queued events.
+type fakeSource struct {
+	events []fakeSourceEvent
+	rows   [][2][]interface{}
+}
+
+type fakeSourceEvent struct {
+	e    Event
+	rows [][2][]interface{} // values, valuesBeforeUpdate
+}
+
+func (s *fakeSource) push(e Event, rows ...[2][]interface{}) {
+	s.events = append(s.events, fakeSourceEvent{e, rows})
+}
+
+// pushRows queues RowsEvent of typ on table with columns id and name,
+// or columns of watermark table.
+func (s *fakeSource) pushRows(typ EventType, schema, table string, rows ...[2][]interface{}) {
+	cols := []Column{{Name: "id", Ordinal: 0, Type: TypeLong}, {Name: "name", Ordinal: 1, Type: TypeVarchar}}
+	if table == "watermark" {
+		cols = []Column{{Name: "id", Ordinal: 0, Type: TypeLong}, {Name: "value", Ordinal: 1, Type: TypeVarchar}, {Name: "ts", Ordinal: 2, Type: TypeLongLong}}
+	}
+	re := RowsEvent{
+		eventType: typ,
+		TableMap:  &TableMapEvent{SchemaName: schema, TableName: table, Columns: cols},
+		columns:   [][]Column{cols, cols},
+	}
+	s.push(Event{Header: EventHeader{EventType: typ}, Data: re}, rows...)
+}
+
+func (s *fakeSource) NextEvent() (Event, error) {
+	if len(s.events) == 0 {
+		return Event{}, io.EOF
+	}
+	e := s.events[0]
+	s.events, s.rows = s.events[1:], e.rows
+	return e.e, nil
+}
+
+func (s *fakeSource) NextRow() (values []interface{}, valuesBeforeUpdate []interface{}, err error) {
+	if len(s.rows) == 0 {
+		return nil, nil, io.EOF
+	}
+	row := s.rows[0]
+	s.rows = s.rows[1:]
+	return row[0], row[1], nil
+}
+
+// fakeDriver is database/sql driver, calling exec and query funcs.
+type fakeDriver struct {
+	exec  func(q string, args []driver.Value) error
+	query func(q string, args []driver.Value) (cols []string, rows [][]driver.Value)
+}
+
+func (d *fakeDriver) Connect(context.Context) (driver.Conn, error) { return fakeDriverConn{d}, nil }
+func (d *fakeDriver) Driver() driver.Driver                        { return nil }
+
+type fakeDriverConn struct{ d *fakeDriver }
+
+func (c fakeDriverConn) Prepare(q string) (driver.Stmt, error) { return fakeStmt{c.d, q}, nil }
+func (c fakeDriverConn) Close() error                          { return nil }
+func (c fakeDriverConn) Begin() (driver.Tx, error)             { return nil, fmt.Errorf("not supported") }
+
+type fakeStmt struct {
+	d *fakeDriver
+	q string
+}
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(1), s.d.exec(s.q, args)
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	cols, rows := s.d.query(s.q, args)
+	return &fakeRows{cols, rows}, nil
+}
+
+type fakeRows struct {
+	cols []string
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.cols }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestBackfiller(t *testing.T) {
+	src := &fakeSource{}
+	src.pushRows(WRITE_ROWS_EVENTv2, "test", "users", [2][]interface{}{{int32(9), "i"}})
+	table := [][]driver.Value{{int64(1), "a"}, {int64(2), "b"}, {int64(3), "c"}, {int64(4), "d"}, {int64(5), "e"}}
+	var queries []string
+	marks := 0
+	d := &fakeDriver{
+		exec: func(q string, args []driver.Value) error {
+			if !strings.Contains(q, "`test`.`watermark`") {
+				return fmt.Errorf("unexpected %s", q)
+			}
+			v := args[1].(string)
+			src.pushRows(UPDATE_ROWS_EVENTv2, "test", "watermark", [2][]interface{}{{int32(0), v, args[2]}, {int32(0), "old", int64(0)}})
+			marks++
+			if marks == 1 {
+				// live update of row 2 between watermarks of first chunk
+				src.pushRows(UPDATE_ROWS_EVENTv2, "test", "users", [2][]interface{}{{int32(2), "b2"}, {int32(2), "b"}})
+				table[1][1] = "b2"
+			}
+			return nil
+		},
+		query: func(q string, args []driver.Value) ([]string, [][]driver.Value) {
+			queries = append(queries, q)
+			var rows [][]driver.Value
+			for _, row := range table {
+				if len(args) == 0 || row[0].(int64) > args[0].(int64) {
+					rows = append(rows, row)
+				}
+			}
+			if len(rows) > 2 {
+				rows = rows[:2]
+			}
+			return []string{"id", "name"}, rows
+		},
+	}
+	b := &Backfiller{
+		Src:            src,
+		DB:             sql.OpenDB(d),
+		Table:          "test.users",
+		PK:             []string{"id"},
+		ChunkSize:      2,
+		WatermarkTable: "test.watermark",
+	}
+	var got []string
+	for {
+		e, err := b.NextEvent()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		var prefix string
+		switch d := e.Data.(type) {
+		case BackfillEvent:
+			prefix = fmt.Sprint("chunk", d.Chunk)
+			if d.Last {
+				prefix += "-last"
+			}
+		case RowsEvent:
+			prefix = e.Header.EventType.String()
+		}
+		for {
+			values, _, err := b.NextRow()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, fmt.Sprintf("%s:%v:%s", prefix, values[0], values[1]))
+		}
+	}
+	want := []string{
+		"writeRowsV2:9:i",
+		"updateRowsV2:2:b2",
+		"chunk1:1:a", // row 2 superseded by live update
+		"chunk2:3:c", "chunk2:4:d",
+		"chunk3-last:5:e",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if !b.Done() {
+		t.Fatal("not done")
+	}
+	if want := "select * from `test`.`users` where (`id`) > (?) order by `id` limit 2"; queries[1] != want {
+		t.Fatalf("got %q, want %q", queries[1], want)
+	}
+}
+
+func TestBackfiller_key(t *testing.T) {
+	utc := time.Date(2021, 1, 2, 3, 4, 5, 500000000, time.UTC)
+	tests := []struct {
+		typ      ColumnType
+		selected interface{} // as returned by database/sql
+		live     interface{} // as decoded from binlog
+	}{
+		{TypeLong, int64(-2), int32(-2)},
+		{TypeLong, []byte("-2"), int32(-2)},
+		{TypeTiny, []byte("200"), int8(-56)}, // unsigned without metadata
+		{TypeLongLong, uint64(1 << 63), int64(-1 << 63)},
+		{TypeNewDecimal, []byte("1.50"), Decimal("1.50")},
+		{TypeDateTime2, []byte("2021-01-02 03:04:05.5"), utc},
+		{TypeDateTime2, time.Date(2021, 1, 2, 3, 4, 5, 500000000, time.FixedZone("x", 3600)), utc}, // parseTime with loc
+		{TypeDate, []byte("2021-01-02"), time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{TypeTimestamp2, []byte("2021-01-02 03:04:05.500000"), utc.Local()},
+		{TypeTime2, []byte("-12:34:56.5"), -(12*time.Hour + 34*time.Minute + 56*time.Second + 500*time.Millisecond)},
+		{TypeYear, []byte("2021"), 2021},
+		{TypeVarchar, []byte("a"), "a"},
+	}
+	for _, tc := range tests {
+		b := &Backfiller{pkIdx: []int{1}, live: []Column{{Type: TypeLong}, {Ordinal: 1, Type: tc.typ}}}
+		selected := b.key([]interface{}{int64(1), tc.selected}, true)
+		live := b.key([]interface{}{int32(1), tc.live}, false)
+		if selected != live {
+			t.Errorf("%s: key of %#v does not match %#v", tc.typ, tc.selected, tc.live)
+		}
+	}
+}
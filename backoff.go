@@ -0,0 +1,94 @@
+package binlog
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Backoff decides delays between attempts of an operation that is
+// retried, such as dial or waiting for new binlog file.
+type Backoff interface {
+	// Next returns delay before retry, after given number of failed
+	// attempts, starting from 1. Returns false to give up.
+	Next(attempt int) (time.Duration, bool)
+}
+
+// ExponentialBackoff is Backoff, whose delay starts at Initial and is
+// multiplied by Multiplier after each attempt, up to Max. Zero value
+// of a field means its value in DefaultBackoff.
+type ExponentialBackoff struct {
+	Initial    time.Duration
+	Max        time.Duration // cap on delay
+	Multiplier float64
+
+	// Jitter is fraction of delay, that is randomly reduced from it,
+	// so that clients do not retry in lockstep. Negative disables it.
+	Jitter float64
+
+	// MaxAttempts is maximum number of retries. negative means retry
+	// forever.
+	MaxAttempts int
+}
+
+// DefaultBackoff is used for operations retried, unless overridden.
+var DefaultBackoff = ExponentialBackoff{
+	Initial:     100 * time.Millisecond,
+	Max:         5 * time.Second,
+	Multiplier:  2,
+	Jitter:      0.2,
+	MaxAttempts: 10,
+}
+
+// Next implements Backoff.
+func (b ExponentialBackoff) Next(attempt int) (time.Duration, bool) {
+	def := DefaultBackoff
+	if b.Initial == 0 {
+		b.Initial = def.Initial
+	}
+	if b.Max == 0 {
+		b.Max = def.Max
+	}
+	if b.Multiplier == 0 {
+		b.Multiplier = def.Multiplier
+	}
+	if b.Jitter == 0 {
+		b.Jitter = def.Jitter
+	}
+	if b.MaxAttempts == 0 {
+		b.MaxAttempts = def.MaxAttempts
+	}
+	if b.MaxAttempts > 0 && attempt > b.MaxAttempts {
+		return 0, false
+	}
+	d := float64(b.Initial) * math.Pow(b.Multiplier, float64(attempt-1))
+	if d > float64(b.Max) {
+		d = float64(b.Max)
+	}
+	if b.Jitter > 0 {
+		d -= d * b.Jitter * rand.Float64()
+	}
+	return time.Duration(d), true
+}
+
+// pollBackoff is default Backoff of Local, waiting for new events.
+var pollBackoff = ExponentialBackoff{
+	Initial:     50 * time.Millisecond,
+	Max:         time.Second,
+	MaxAttempts: -1,
+}
+
+// retry calls f until it succeeds, or b gives up. nil b means
+// no retry. The last error is returned.
+func retry(b Backoff, clock Clock, f func() error) error {
+	err := f()
+	for attempt := 1; err != nil && b != nil; attempt++ {
+		d, ok := b.Next(attempt)
+		if !ok {
+			break
+		}
+		clockOrSystem(clock).Sleep(d)
+		err = f()
+	}
+	return err
+}
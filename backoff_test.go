@@ -0,0 +1,46 @@
+package binlog
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	b := ExponentialBackoff{Initial: time.Second, Max: 5 * time.Second, Jitter: -1, MaxAttempts: 4}
+	for attempt, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second} {
+		d, ok := b.Next(attempt + 1)
+		if !ok || d != want {
+			t.Fatalf("attempt %d: got %v %v, want %v", attempt+1, d, ok, want)
+		}
+	}
+	if _, ok := b.Next(5); ok {
+		t.Fatal("must give up after MaxAttempts")
+	}
+	b.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if d, _ := b.Next(1); d <= time.Second/2 || d > time.Second {
+			t.Fatalf("got %v, want in (500ms, 1s]", d)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	clock := &fakeClock{}
+	calls := 0
+	err := retry(ExponentialBackoff{Initial: time.Second, Jitter: -1, MaxAttempts: 2}, clock, func() error {
+		calls++
+		return errors.New("failed")
+	})
+	if err == nil || calls != 3 || len(clock.sleeps) != 2 {
+		t.Fatal(err, calls, clock.sleeps)
+	}
+	calls = 0
+	err = retry(nil, clock, func() error {
+		calls++
+		return errors.New("failed")
+	})
+	if err == nil || calls != 1 {
+		t.Fatal(err, calls)
+	}
+}
@@ -1,6 +1,35 @@
 package binlog
 
+import "time"
+
 func nextEvent(r *reader, rotateChecksum int) (Event, error) {
+	r.received = time.Time{}
+	e, err := decodeEvent(r, rotateChecksum)
+	now := clockOrSystem(r.opts.clock).Now()
+	if r.received.IsZero() { // header not received
+		r.received = now
+	}
+	e.meta = EventMeta{
+		ReceivedAt: r.received,
+		Size:       int(e.Header.EventSize),
+		DecodeTime: now.Sub(r.received),
+		Source:     r.source,
+	}
+	if err != nil {
+		return e, err
+	}
+	if err := r.tx.track(r, e); err != nil {
+		return Event{}, err
+	}
+	if r.opts.statementMode {
+		if err := r.stmt.track(r, e); err != nil {
+			return Event{}, err
+		}
+	}
+	return e, nil
+}
+
+func decodeEvent(r *reader, rotateChecksum int) (Event, error) {
 	if r.hash != nil {
 		r.hash.Reset()
 	}
@@ -8,17 +37,25 @@ func nextEvent(r *reader, rotateChecksum int) (Event, error) {
 	if err := h.decode(r); err != nil {
 		return Event{}, err
 	}
+	r.received = clockOrSystem(r.opts.clock).Now()
 	switch h.EventType {
 	case FORMAT_DESCRIPTION_EVENT:
 		r.checksum = 0 // computed in decode
 	case ROTATE_EVENT:
 		r.checksum = rotateChecksum
+		if rotateChecksum < 0 { // unknown, detected in decode
+			r.checksum = 0
+		}
 	}
 	headerSize := uint32(13)
 	if r.fde.BinlogVersion > 1 {
 		headerSize = 19
 	}
 	r.limit = int(h.EventSize-headerSize) - r.checksum
+	r.verified, r.corrupt, r.skipAfter = false, nil, 0
+	if err := r.checkEvent(); err != nil {
+		return Event{}, err
+	}
 
 	if h.NextPos != 0 {
 		r.binlogPos = h.NextPos
@@ -29,84 +66,109 @@ func nextEvent(r *reader, rotateChecksum int) (Event, error) {
 	case FORMAT_DESCRIPTION_EVENT:
 		r.fde = FormatDescriptionEvent{}
 		err := r.fde.decode(r, h.EventSize)
-		return Event{h, r.fde}, err
+		return Event{Header: h, Data: r.fde}, err
 	case STOP_EVENT:
-		return Event{h, StopEvent{}}, nil
+		return Event{Header: h, Data: StopEvent{}}, nil
 	case ROTATE_EVENT:
 		re := RotateEvent{}
+		var headerCRC uint32
+		if r.hash != nil {
+			headerCRC = r.hash.Sum32()
+		}
 		err := re.decode(r)
+		if err == nil && rotateChecksum < 0 && r.hash != nil {
+			re.trimChecksum(headerCRC)
+		}
 		if err == nil {
 			r.binlogFile, r.binlogPos = re.NextBinlog, uint32(re.Position)
 			h.LogFile, h.NextPos = r.binlogFile, r.binlogPos
 		}
 		r.tmeCache = make(map[uint64]*TableMapEvent)
-		return Event{h, re}, err
+		return Event{Header: h, Data: re}, err
 	case TABLE_MAP_EVENT:
 		tme := TableMapEvent{}
 		err := tme.decode(r)
 		r.tmeCache[tme.tableID] = &tme
-		return Event{h, tme}, err
+		return Event{Header: h, Data: tme}, err
 	case WRITE_ROWS_EVENTv0, WRITE_ROWS_EVENTv1, WRITE_ROWS_EVENTv2,
 		UPDATE_ROWS_EVENTv0, UPDATE_ROWS_EVENTv1, UPDATE_ROWS_EVENTv2,
 		DELETE_ROWS_EVENTv0, DELETE_ROWS_EVENTv1, DELETE_ROWS_EVENTv2:
 		r.re = RowsEvent{}
 		err := r.re.decode(r, h.EventType)
-		return Event{h, r.re}, err
+		return Event{Header: h, Data: r.re}, err
 	case PREVIOUS_GTIDS_EVENT:
-		return Event{h, previousGTIDsEvent{}}, nil
+		return unknownEvent(r, h)
 	case ANONYMOUS_GTID_EVENT:
-		return Event{h, anonymousGTIDEvent{}}, nil
+		return unknownEvent(r, h)
 	case QUERY_EVENT:
 		qe := QueryEvent{}
 		err := qe.decode(r)
-		return Event{h, qe}, err
+		return Event{Header: h, Data: qe}, err
 	case XID_EVENT:
-		return Event{h, xidEvent{}}, nil
+		xe := XIDEvent{}
+		err := xe.decode(r)
+		return Event{Header: h, Data: xe}, err
 	case GTID_EVENT:
-		return Event{h, gtidEvent{}}, nil
+		ge := GTIDEvent{}
+		err := ge.decode(r)
+		return Event{Header: h, Data: ge}, err
 	case INTVAR_EVENT:
 		ive := IntVarEvent{}
 		err := ive.decode(r)
-		return Event{h, ive}, err
+		return Event{Header: h, Data: ive}, err
 	case LOAD_EVENT:
-		return Event{h, loadEvent{}}, nil
+		return unknownEvent(r, h)
 	case SLAVE_EVENT:
-		return Event{h, slaveEvent{}}, nil
+		return unknownEvent(r, h)
 	case CREATE_FILE_EVENT:
-		return Event{h, createFileEvent{}}, nil
+		return unknownEvent(r, h)
 	case DELETE_FILE_EVENT:
-		return Event{h, deleteFileEvent{}}, nil
+		return unknownEvent(r, h)
 	case BEGIN_LOAD_QUERY_EVENT:
-		return Event{h, beginLoadQueryEvent{}}, nil
+		return unknownEvent(r, h)
 	case EXECUTE_LOAD_QUERY_EVENT:
-		return Event{h, executeLoadQueryEvent{}}, nil
+		return unknownEvent(r, h)
 	case RAND_EVENT:
 		re := RandEvent{}
 		err := re.decode(r)
-		return Event{h, re}, err
+		return Event{Header: h, Data: re}, err
 	case USER_VAR_EVENT:
 		uve := UserVarEvent{}
 		err := uve.decode(r)
-		return Event{h, uve}, err
+		return Event{Header: h, Data: uve}, err
 	case NEW_LOAD_EVENT:
-		return Event{h, newLoadEvent{}}, nil
+		return unknownEvent(r, h)
 	case EXEC_LOAD_EVENT:
-		return Event{h, execLoadEvent{}}, nil
+		return unknownEvent(r, h)
 	case APPEND_BLOCK_EVENT:
-		return Event{h, appendBlockEvent{}}, nil
+		return unknownEvent(r, h)
 	case INCIDENT_EVENT:
 		ie := IncidentEvent{}
 		err := ie.decode(r)
-		return Event{h, ie}, err
+		return Event{Header: h, Data: ie}, err
 	case HEARTBEAT_EVENT:
-		return Event{h, HeartbeatEvent{}}, nil
+		return Event{Header: h, Data: HeartbeatEvent{}}, nil
 	case IGNORABLE_EVENT:
-		return Event{h, ignorableEvent{}}, nil
+		return unknownEvent(r, h)
 	case ROWS_QUERY_EVENT:
 		rqe := RowsQueryEvent{}
 		err := rqe.decode(r)
-		return Event{h, rqe}, err
+		return Event{Header: h, Data: rqe}, err
+	case VIEW_CHANGE_EVENT:
+		vce := ViewChangeEvent{}
+		err := vce.decode(r)
+		return Event{Header: h, Data: vce}, err
+	case XA_PREPARE_LOG_EVENT:
+		xpe := XAPrepareEvent{}
+		err := xpe.decode(r)
+		return Event{Header: h, Data: xpe}, err
 	default:
-		return Event{h, UnknownEvent{}}, nil
+		return unknownEvent(r, h)
 	}
 }
+
+// unknownEvent returns event with raw body, for event types not decoded.
+func unknownEvent(r *reader, h EventHeader) (Event, error) {
+	body := r.bytesEOF()
+	return Event{Header: h, Data: UnknownEvent{Type: h.EventType, Body: body}}, r.err
+}
@@ -0,0 +1,261 @@
+package binlog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// collation captures name and character set of a collation id.
+type collation struct {
+	name    string
+	charset string
+}
+
+// collations maps collation id to collation, as found in
+// information_schema.COLLATIONS of MySQL 8.0.
+//
+// https://dev.mysql.com/doc/refman/8.0/en/information-schema-collations-table.html
+var collations = map[uint64]collation{
+	1:   {"big5_chinese_ci", "big5"},
+	2:   {"latin2_czech_cs", "latin2"},
+	3:   {"dec8_swedish_ci", "dec8"},
+	4:   {"cp850_general_ci", "cp850"},
+	5:   {"latin1_german1_ci", "latin1"},
+	6:   {"hp8_english_ci", "hp8"},
+	7:   {"koi8r_general_ci", "koi8r"},
+	8:   {"latin1_swedish_ci", "latin1"},
+	9:   {"latin2_general_ci", "latin2"},
+	10:  {"swe7_swedish_ci", "swe7"},
+	11:  {"ascii_general_ci", "ascii"},
+	12:  {"ujis_japanese_ci", "ujis"},
+	13:  {"sjis_japanese_ci", "sjis"},
+	14:  {"cp1251_bulgarian_ci", "cp1251"},
+	15:  {"latin1_danish_ci", "latin1"},
+	16:  {"hebrew_general_ci", "hebrew"},
+	18:  {"tis620_thai_ci", "tis620"},
+	19:  {"euckr_korean_ci", "euckr"},
+	20:  {"latin7_estonian_cs", "latin7"},
+	21:  {"latin2_hungarian_ci", "latin2"},
+	22:  {"koi8u_general_ci", "koi8u"},
+	23:  {"cp1251_ukrainian_ci", "cp1251"},
+	24:  {"gb2312_chinese_ci", "gb2312"},
+	25:  {"greek_general_ci", "greek"},
+	26:  {"cp1250_general_ci", "cp1250"},
+	27:  {"latin2_croatian_ci", "latin2"},
+	28:  {"gbk_chinese_ci", "gbk"},
+	29:  {"cp1257_lithuanian_ci", "cp1257"},
+	30:  {"latin5_turkish_ci", "latin5"},
+	31:  {"latin1_german2_ci", "latin1"},
+	32:  {"armscii8_general_ci", "armscii8"},
+	33:  {"utf8_general_ci", "utf8"},
+	34:  {"cp1250_czech_cs", "cp1250"},
+	35:  {"ucs2_general_ci", "ucs2"},
+	36:  {"cp866_general_ci", "cp866"},
+	37:  {"keybcs2_general_ci", "keybcs2"},
+	38:  {"macce_general_ci", "macce"},
+	39:  {"macroman_general_ci", "macroman"},
+	40:  {"cp852_general_ci", "cp852"},
+	41:  {"latin7_general_ci", "latin7"},
+	42:  {"latin7_general_cs", "latin7"},
+	43:  {"macce_bin", "macce"},
+	44:  {"cp1250_croatian_ci", "cp1250"},
+	45:  {"utf8mb4_general_ci", "utf8mb4"},
+	46:  {"utf8mb4_bin", "utf8mb4"},
+	47:  {"latin1_bin", "latin1"},
+	48:  {"latin1_general_ci", "latin1"},
+	49:  {"latin1_general_cs", "latin1"},
+	50:  {"cp1251_bin", "cp1251"},
+	51:  {"cp1251_general_ci", "cp1251"},
+	52:  {"cp1251_general_cs", "cp1251"},
+	53:  {"macroman_bin", "macroman"},
+	54:  {"utf16_general_ci", "utf16"},
+	55:  {"utf16_bin", "utf16"},
+	56:  {"utf16le_general_ci", "utf16le"},
+	57:  {"cp1256_general_ci", "cp1256"},
+	58:  {"cp1257_bin", "cp1257"},
+	59:  {"cp1257_general_ci", "cp1257"},
+	60:  {"utf32_general_ci", "utf32"},
+	61:  {"utf32_bin", "utf32"},
+	62:  {"utf16le_bin", "utf16le"},
+	63:  {"binary", "binary"},
+	64:  {"armscii8_bin", "armscii8"},
+	65:  {"ascii_bin", "ascii"},
+	66:  {"cp1250_bin", "cp1250"},
+	67:  {"cp1256_bin", "cp1256"},
+	68:  {"cp866_bin", "cp866"},
+	69:  {"dec8_bin", "dec8"},
+	70:  {"greek_bin", "greek"},
+	71:  {"hebrew_bin", "hebrew"},
+	72:  {"hp8_bin", "hp8"},
+	73:  {"keybcs2_bin", "keybcs2"},
+	74:  {"koi8r_bin", "koi8r"},
+	75:  {"koi8u_bin", "koi8u"},
+	77:  {"latin2_bin", "latin2"},
+	78:  {"latin5_bin", "latin5"},
+	79:  {"latin7_bin", "latin7"},
+	80:  {"cp850_bin", "cp850"},
+	81:  {"cp852_bin", "cp852"},
+	82:  {"swe7_bin", "swe7"},
+	83:  {"utf8_bin", "utf8"},
+	84:  {"big5_bin", "big5"},
+	85:  {"euckr_bin", "euckr"},
+	86:  {"gb2312_bin", "gb2312"},
+	87:  {"gbk_bin", "gbk"},
+	88:  {"sjis_bin", "sjis"},
+	89:  {"tis620_bin", "tis620"},
+	90:  {"ucs2_bin", "ucs2"},
+	91:  {"ujis_bin", "ujis"},
+	92:  {"geostd8_general_ci", "geostd8"},
+	93:  {"geostd8_bin", "geostd8"},
+	94:  {"latin1_spanish_ci", "latin1"},
+	95:  {"cp932_japanese_ci", "cp932"},
+	96:  {"cp932_bin", "cp932"},
+	97:  {"eucjpms_japanese_ci", "eucjpms"},
+	98:  {"eucjpms_bin", "eucjpms"},
+	99:  {"cp1250_polish_ci", "cp1250"},
+	192: {"utf8_unicode_ci", "utf8"},
+	224: {"utf8mb4_unicode_ci", "utf8mb4"},
+	246: {"utf8mb4_unicode_520_ci", "utf8mb4"},
+	248: {"gb18030_chinese_ci", "gb18030"},
+	249: {"gb18030_bin", "gb18030"},
+	255: {"utf8mb4_0900_ai_ci", "utf8mb4"},
+	278: {"utf8mb4_0900_as_cs", "utf8mb4"},
+	305: {"utf8mb4_0900_as_ci", "utf8mb4"},
+	309: {"utf8mb4_0900_bin", "utf8mb4"},
+}
+
+// maxBytesPerChar maps character set to maximum bytes per character.
+// character sets not listed use single byte per character.
+var maxBytesPerChar = map[string]int{
+	"big5": 2, "cp932": 2, "eucjpms": 3, "euckr": 2, "gb18030": 4, "gb2312": 2,
+	"gbk": 2, "sjis": 2, "ucs2": 2, "ujis": 3, "utf16": 4, "utf16le": 4,
+	"utf32": 4, "utf8": 3, "utf8mb4": 4,
+}
+
+// CollationName returns name of the collation of this column, such as
+// "utf8mb4_0900_ai_ci". Returns empty string if collation is unknown.
+// Note that Charset field is actually collation id.
+func (col Column) CollationName() string {
+	return collations[col.Charset].name
+}
+
+// CharsetName returns name of the character set of this column, such
+// as "utf8mb4". Returns empty string if character set is unknown.
+func (col Column) CharsetName() string {
+	return collations[col.Charset].charset
+}
+
+// SQLType returns the column type as used in CREATE TABLE, such as
+// "varchar(255)", "decimal(6,3)" or "enum('a','b')", reconstructed
+// from Type, Meta, Unsigned, Charset and Values.
+//
+// Display width of integer types is not available in binlog. If
+// Charset is unknown, length of char and varchar is in bytes, rather
+// than in characters. If Values is unknown, enum and set are returned
+// without members, such as "enum".
+func (col Column) SQLType() string {
+	unsigned := ""
+	if col.Unsigned {
+		unsigned = " unsigned"
+	}
+	binary := col.Charset == 63
+	switch col.Type {
+	case TypeTiny:
+		return "tinyint" + unsigned
+	case TypeShort:
+		return "smallint" + unsigned
+	case TypeInt24:
+		return "mediumint" + unsigned
+	case TypeLong:
+		return "int" + unsigned
+	case TypeLongLong:
+		return "bigint" + unsigned
+	case TypeFloat:
+		return "float" + unsigned
+	case TypeDouble:
+		return "double" + unsigned
+	case TypeDecimal:
+		return "decimal" + unsigned
+	case TypeNewDecimal:
+		return fmt.Sprintf("decimal(%d,%d)%s", byte(col.Meta), byte(col.Meta>>8), unsigned)
+	case TypeVarchar, TypeVarString:
+		if binary {
+			return fmt.Sprintf("varbinary(%d)", col.Meta)
+		}
+		return fmt.Sprintf("varchar(%d)", col.charLength())
+	case TypeString:
+		if binary {
+			return fmt.Sprintf("binary(%d)", col.Meta)
+		}
+		return fmt.Sprintf("char(%d)", col.charLength())
+	case TypeBlob:
+		var prefix string
+		switch col.Meta {
+		case 1:
+			prefix = "tiny"
+		case 3:
+			prefix = "medium"
+		case 4:
+			prefix = "long"
+		}
+		if col.Charset != 0 && !binary {
+			return prefix + "text"
+		}
+		return prefix + "blob"
+	case TypeTinyBlob:
+		return "tinyblob"
+	case TypeMediumBlob:
+		return "mediumblob"
+	case TypeLongBlob:
+		return "longblob"
+	case TypeEnum, TypeSet:
+		name := "enum"
+		if col.Type == TypeSet {
+			name = "set"
+		}
+		if len(col.Values) == 0 {
+			return name
+		}
+		values := make([]string, len(col.Values))
+		for i, v := range col.Values {
+			values[i] = sqlQuote(v)
+		}
+		return name + "(" + strings.Join(values, ",") + ")"
+	case TypeBit:
+		return fmt.Sprintf("bit(%d)", (col.Meta>>8)*8+col.Meta&0xff)
+	case TypeYear:
+		return "year"
+	case TypeDate, TypeNewDate:
+		return "date"
+	case TypeTime, TypeTime2:
+		return withFSP("time", col)
+	case TypeDateTime, TypeDateTime2:
+		return withFSP("datetime", col)
+	case TypeTimestamp, TypeTimestamp2:
+		return withFSP("timestamp", col)
+	case TypeJSON:
+		return "json"
+	case TypeGeometry:
+		return "geometry"
+	}
+	return col.Type.String()
+}
+
+// charLength returns length of char or varchar column in characters.
+func (col Column) charLength() int {
+	if n, ok := maxBytesPerChar[col.CharsetName()]; ok {
+		return int(col.Meta) / n
+	}
+	return int(col.Meta)
+}
+
+// withFSP appends fractional seconds precision of temporal column.
+func withFSP(name string, col Column) string {
+	switch col.Type {
+	case TypeTime2, TypeDateTime2, TypeTimestamp2:
+		if col.Meta > 0 {
+			return fmt.Sprintf("%s(%d)", name, col.Meta)
+		}
+	}
+	return name
+}
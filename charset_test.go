@@ -0,0 +1,44 @@
+package binlog
+
+import "testing"
+
+func TestColumn_SQLType(t *testing.T) {
+	testCases := []struct {
+		col  Column
+		want string
+	}{
+		{Column{Type: TypeTiny, Unsigned: true}, "tinyint unsigned"},
+		{Column{Type: TypeLongLong}, "bigint"},
+		{Column{Type: TypeNewDecimal, Meta: 3<<8 | 6}, "decimal(6,3)"},
+		{Column{Type: TypeVarchar, Meta: 1020, Charset: 255}, "varchar(255)"},
+		{Column{Type: TypeVarchar, Meta: 20}, "varchar(20)"},
+		{Column{Type: TypeVarchar, Meta: 20, Charset: 63}, "varbinary(20)"},
+		{Column{Type: TypeString, Meta: 30, Charset: 33}, "char(10)"},
+		{Column{Type: TypeBlob, Meta: 2, Charset: 8}, "text"},
+		{Column{Type: TypeBlob, Meta: 4, Charset: 63}, "longblob"},
+		{Column{Type: TypeEnum, Meta: 1, Values: []string{"a", "it's"}}, `enum('a','it\'s')`},
+		{Column{Type: TypeSet, Meta: 1}, "set"},
+		{Column{Type: TypeBit, Meta: 1<<8 | 2}, "bit(10)"},
+		{Column{Type: TypeDateTime2, Meta: 0}, "datetime"},
+		{Column{Type: TypeTimestamp2, Meta: 3}, "timestamp(3)"},
+		{Column{Type: TypeJSON, Meta: 4}, "json"},
+	}
+	for _, tc := range testCases {
+		if got := tc.col.SQLType(); got != tc.want {
+			t.Errorf("SQLType(%s, meta=%d): got %s, want %s", tc.col.Type, tc.col.Meta, got, tc.want)
+		}
+	}
+}
+
+func TestColumn_CollationName(t *testing.T) {
+	col := Column{Charset: 255}
+	if got := col.CollationName(); got != "utf8mb4_0900_ai_ci" {
+		t.Errorf("CollationName: got %q", got)
+	}
+	if got := col.CharsetName(); got != "utf8mb4" {
+		t.Errorf("CharsetName: got %q", got)
+	}
+	if got := (Column{}).CharsetName(); got != "" {
+		t.Errorf("CharsetName of unknown: got %q", got)
+	}
+}
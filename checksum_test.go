@@ -0,0 +1,54 @@
+package binlog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTableChecksums(t *testing.T) {
+	tme := &TableMapEvent{SchemaName: "db", TableName: "t", Columns: []Column{
+		{Ordinal: 0, Type: TypeLong},
+		{Ordinal: 1, Type: TypeVarchar},
+	}}
+	ins := RowsEvent{eventType: WRITE_ROWS_EVENTv2, TableMap: tme, columns: [][]Column{tme.Columns}}
+	upd := RowsEvent{eventType: UPDATE_ROWS_EVENTv2, TableMap: tme, columns: [][]Column{tme.Columns, tme.Columns}}
+	del := RowsEvent{eventType: DELETE_ROWS_EVENTv2, TableMap: tme, columns: [][]Column{tme.Columns}}
+	r1 := []interface{}{int32(1), "a"}
+	r2 := []interface{}{int32(2), "b"}
+	r2u := []interface{}{int32(2), "c"}
+
+	var c TableChecksums
+	c.Apply(WRITE_ROWS_EVENTv2, ins, r1, nil)
+	c.Apply(WRITE_ROWS_EVENTv2, ins, r2, nil)
+	c.Apply(UPDATE_ROWS_EVENTv2, upd, r2u, r2)
+	c.Apply(DELETE_ROWS_EVENTv2, del, r1, nil)
+
+	// order of rows must not matter
+	var want TableChecksums
+	want.Apply(WRITE_ROWS_EVENTv2, ins, r2u, nil)
+	if err := c.Verify("db", "t", want.Sum("db", "t")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Verify("db", "t", 0); err == nil {
+		t.Fatal("mismatch must return error")
+	}
+}
+
+func TestRowChecksum_sqlValues(t *testing.T) {
+	cols := []Column{
+		{Ordinal: 0, Type: TypeLong},
+		{Ordinal: 1, Type: TypeVarchar},
+		{Ordinal: 2, Type: TypeDateTime2},
+		{Ordinal: 3, Type: TypeDouble},
+		{Ordinal: 4, Type: TypeNewDecimal},
+	}
+	binlogRow := []interface{}{int32(7), "a", time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC), float64(1.5), Decimal("2.50")}
+	sqlRow := []interface{}{[]byte("7"), []byte("a"), []byte("2021-01-01 10:00:00"), []byte("1.5"), []byte("2.50")}
+	if got, want := RowChecksum(cols, sqlRow), RowChecksum(cols, binlogRow); got != want {
+		t.Fatalf("got %016x, want %016x", got, want)
+	}
+	sqlRow[1] = []byte("b")
+	if RowChecksum(cols, sqlRow) == RowChecksum(cols, binlogRow) {
+		t.Fatal("different rows must have different checksum")
+	}
+}
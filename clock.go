@@ -0,0 +1,40 @@
+package binlog
+
+import "time"
+
+// Clock is source of time, used for timestamps, retry delays and
+// timers. It can be replaced to test timing behavior deterministically.
+// see SystemClock.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+
+	// AfterFunc calls f in its own goroutine after duration d.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is timer created by Clock.AfterFunc.
+type Timer interface {
+	Reset(d time.Duration) bool
+	Stop() bool
+}
+
+// SystemClock is Clock using package time. It is used by default.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time        { return time.Now() }
+func (systemClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// clockOrSystem returns c, or SystemClock if c is nil.
+func clockOrSystem(c Clock) Clock {
+	if c == nil {
+		return SystemClock
+	}
+	return c
+}
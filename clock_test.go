@@ -0,0 +1,130 @@
+package binlog
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeClock is Clock whose time advances only by Sleep.
+type fakeClock struct {
+	now     time.Time
+	sleeps  []time.Duration
+	onSleep func() // called after each Sleep
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.now = c.now.Add(d)
+	c.sleeps = append(c.sleeps, d)
+	if c.onSleep != nil {
+		c.onSleep()
+	}
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+func TestLocal_SetClock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "binlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeFile := func(name string, xid uint64) {
+		s := newBinlogStream()
+		s.XID(xid)
+		if err := ioutil.WriteFile(filepath.Join(dir, name), s.Bytes(), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("binlog.000001", 1)
+
+	clock := &fakeClock{now: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}
+	clock.onSleep = func() {
+		if len(clock.sleeps) == 2 {
+			writeFile("binlog.000002", 2)
+		}
+	}
+	bl, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bl.Close()
+	bl.SetClock(clock)
+	bl.SetBackoff(ExponentialBackoff{Initial: time.Second, Jitter: -1, MaxAttempts: -1})
+	if err := bl.Seek(1, "binlog.000001", 4); err != nil {
+		t.Fatal(err)
+	}
+	var xids []uint64
+	for len(xids) < 2 {
+		e, err := bl.NextEvent()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if xe, ok := e.Data.(XIDEvent); ok {
+			xids = append(xids, xe.XID)
+			if !e.Meta().ReceivedAt.Equal(clock.now) || e.Meta().DecodeTime != 0 {
+				t.Fatalf("got %+v, want fake time", e.Meta())
+			}
+		}
+	}
+	if !reflect.DeepEqual(xids, []uint64{1, 2}) {
+		t.Fatal("got", xids)
+	}
+	if want := []time.Duration{time.Second, 2 * time.Second}; !reflect.DeepEqual(clock.sleeps, want) {
+		t.Fatal("sleeps: got", clock.sleeps, "want", want)
+	}
+}
+
+// chunkReader returns a chunk per Read, after sleeping a second on clock.
+type chunkReader struct {
+	clock  *fakeClock
+	chunks [][]byte
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if len(r.chunks) == 0 {
+		return 0, io.EOF
+	}
+	r.clock.Sleep(time.Second)
+	n := copy(p, r.chunks[0])
+	if r.chunks[0] = r.chunks[0][n:]; len(r.chunks[0]) == 0 {
+		r.chunks = r.chunks[1:]
+	}
+	return n, nil
+}
+
+func TestReader_receivedAt(t *testing.T) {
+	s := newBinlogStream()
+	fdeEnd := s.Len()
+	s.XID(7)
+	b := s.Bytes()
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: start}
+	// header of XIDEvent arrives in two reads
+	rd := &chunkReader{clock, [][]byte{b[:fdeEnd], b[fdeEnd : fdeEnd+10], b[fdeEnd+10 : fdeEnd+19], b[fdeEnd+19:]}}
+	r := NewReader(rd)
+	r.SetClock(clock)
+	for {
+		e, err := r.NextEvent()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := e.Data.(XIDEvent); ok {
+			if want := start.Add(3 * time.Second); !e.Meta().ReceivedAt.Equal(want) {
+				t.Fatalf("ReceivedAt: got %v, want %v", e.Meta().ReceivedAt, want)
+			}
+			if e.Meta().DecodeTime != time.Second {
+				t.Fatalf("DecodeTime: got %v, want %v", e.Meta().DecodeTime, time.Second)
+			}
+			return
+		}
+	}
+}
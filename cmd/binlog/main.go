@@ -1,21 +1,22 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/santhosh-tekuri/binlog"
+	"github.com/santhosh-tekuri/binlog/config"
 )
 
-type binLog interface {
-	ListFiles() ([]string, error)
-	MasterStatus() (file string, pos uint32, err error)
-	Seek(serverID uint32, fileName string, position uint32) error
+type eventReader interface {
 	NextEvent() (binlog.Event, error)
 	NextRow() (values []interface{}, valuesBeforeUpdate []interface{}, err error)
 }
@@ -30,6 +31,8 @@ Arguments:
 Examples:
   binlog view tcp:localhost:3306,ssl,user=root,password=password 10 binlog.000002:4
   binlog view dir:./dump 10 binlog.000002
+  binlog view file:./binlog.000002
+  binlog view tcp:localhost:3306,user=root,password=password,trace=wire.log
 
 binlog dump SERVER-URL DIR SERVER-ID FROM-FILE
 Arguments:
@@ -39,6 +42,21 @@ Arguments:
               resumes since last location.
 Examples:
   binlog dump tcp:localhost:3306,ssl,user=root,password=password ./dump 10 binlog.000001
+
+binlog pipeline CONFIG-FILE
+Arguments:
+  CONFIG-FILE pipeline configuration in JSON. see package config.
+              YAML is not supported, convert it to JSON.
+Examples:
+  binlog pipeline ./pipeline.json
+
+binlog serve CONFIG-FILE
+  CONFIG-FILE is JSON, same as for pipeline command.
+  runs pipeline as daemon, with http endpoints /healthz, /metrics and
+  /position on http.addr of config. if http.admin is true, /pause,
+  /resume and /rewind are also served. stops gracefully on SIGTERM or SIGINT.
+Examples:
+  binlog serve ./pipeline.json
 `
 
 func main() {
@@ -46,18 +64,43 @@ func main() {
 		errln(usage)
 		os.Exit(1)
 	}
+	switch os.Args[1] {
+	case "pipeline":
+		if err := pipeline(os.Args[2]); err != nil {
+			panic(err)
+		}
+		return
+	case "serve":
+		if err := serve(os.Args[2]); err != nil {
+			errln(err)
+			os.Exit(1)
+		}
+		return
+	}
 	address := os.Args[2]
 	colon := strings.IndexByte(address, ':')
 	network, address := address[:colon], address[colon+1:]
 	var err error
 	switch os.Args[1] {
 	case "view":
-		var bl binLog
+		if network == "file" {
+			f, err := binlog.OpenFile(address)
+			if err != nil {
+				panic(err)
+			}
+			defer f.Close()
+			if err := view(f); err != nil {
+				panic(err)
+			}
+			return
+		}
+		var bl binlog.BinlogSource
 		if network == "dir" {
 			bl = openLocal(address)
 		} else {
 			bl = openRemote(network, address)
 		}
+		defer bl.Close()
 		var serverID = 0
 		if len(os.Args) >= 4 {
 			serverID, err = strconv.Atoi(os.Args[3])
@@ -125,7 +168,17 @@ func main() {
 
 func openRemote(network, address string) *binlog.Remote {
 	tok := strings.Split(address, ",")
-	bl, err := binlog.Dial(network, tok[0], 5*time.Second)
+	opts := binlog.DialOptions{Timeout: 5 * time.Second}
+	for _, t := range tok[1:] {
+		if strings.HasPrefix(t, "trace=") {
+			f, err := os.Create(strings.TrimPrefix(t, "trace="))
+			if err != nil {
+				panic(err)
+			}
+			opts.Trace = f
+		}
+	}
+	bl, err := binlog.DialWithOptions(network, tok[0], opts)
 	if err != nil {
 		panic(err)
 	}
@@ -157,7 +210,7 @@ func openRemote(network, address string) *binlog.Remote {
 	return bl
 }
 
-func getLocation(bl binLog, arg string) (file string, pos uint32) {
+func getLocation(bl binlog.BinlogSource, arg string) (file string, pos uint32) {
 	switch arg {
 	case "earliest":
 		files, err := bl.ListFiles()
@@ -193,7 +246,7 @@ func openLocal(address string) *binlog.Local {
 	return bl
 }
 
-func view(bl binLog) error {
+func view(bl eventReader) error {
 	for {
 		e, err := bl.NextEvent()
 		if err != nil {
@@ -208,6 +261,9 @@ func view(bl binLog) error {
 			e.Header.NextPos,
 			e.Header.EventType,
 		)
+		if flags := e.Header.FlagNames(); len(flags) > 0 {
+			fmt.Printf("[%s] ", strings.Join(flags, ","))
+		}
 		//fmt.Printf(" %#v\n", e.Data)
 		switch d := e.Data.(type) {
 		case binlog.FormatDescriptionEvent:
@@ -232,30 +288,10 @@ func view(bl binLog) error {
 				} else {
 					fmt.Print("   SET: ")
 				}
-				m := make(map[string]interface{})
-				for i, v := range row {
-					col := d.Columns()[i].Name
-					if col == "" {
-						col = "@" + strconv.Itoa(d.Columns()[i].Ordinal)
-					}
-					m[col] = v
-				}
-				if err := json.NewEncoder(os.Stdout).Encode(m); err != nil {
-					panic(err)
-				}
+				printRow(d.Columns(), row)
 				if before != nil {
 					fmt.Print(" WHERE: ")
-					m := make(map[string]interface{})
-					for i, v := range before {
-						col := d.ColumnsBeforeUpdate()[i].Name
-						if col == "" {
-							col = "@" + strconv.Itoa(d.ColumnsBeforeUpdate()[i].Ordinal)
-						}
-						m[col] = v
-					}
-					if err := json.NewEncoder(os.Stdout).Encode(m); err != nil {
-						panic(err)
-					}
+					printRow(d.ColumnsBeforeUpdate(), before)
 				}
 			}
 		default:
@@ -264,6 +300,79 @@ func view(bl binLog) error {
 	}
 }
 
+func printRow(cols []binlog.Column, values []interface{}) {
+	for i, v := range values {
+		col := cols[i].Name
+		if col == "" {
+			col = "@" + strconv.Itoa(cols[i].Ordinal)
+		}
+		if i > 0 {
+			fmt.Print(", ")
+		}
+		fmt.Print(col, "=", cols[i].DisplayLiteral(v))
+	}
+	fmt.Println()
+}
+
+func pipeline(file string) error {
+	c, err := config.LoadFile(file)
+	if err != nil {
+		return err
+	}
+	p, err := config.Build(c)
+	if err != nil {
+		return err
+	}
+	if err := p.Run(); err != nil {
+		_ = p.Close()
+		return err
+	}
+	return p.Close()
+}
+
+func serve(file string) error {
+	c, err := config.LoadFile(file)
+	if err != nil {
+		return err
+	}
+	p, err := config.Build(c)
+	if err != nil {
+		return err
+	}
+	addr := c.HTTP.Addr
+	if addr == "" {
+		addr = ":8080"
+	}
+	handler := p.Handler()
+	if c.HTTP.Admin {
+		handler = p.AdminHandler()
+	}
+	srv := &http.Server{Addr: addr, Handler: handler}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			errln(err)
+		}
+	}()
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGTERM, os.Interrupt)
+	go func() {
+		<-sig
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := p.Stop(ctx); err != nil {
+			errln("stop:", err)
+		}
+	}()
+	err = p.Run()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = srv.Shutdown(ctx)
+	if cerr := p.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
 func errln(args ...interface{}) {
 	_, _ = fmt.Fprintln(os.Stderr, args...)
 }
@@ -0,0 +1,59 @@
+package binlog
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// ChangedColumns returns names of columns whose values differ in
+// before and after images of an updated row. Values are compared as
+// in Row.Hash, so decoding quirks do not matter. With partial row
+// images, as in binlog_row_image=MINIMAL, a column missing in after
+// image is unchanged, and a column only in after image is changed.
+//
+// Column names are available only if binlog_row_metadata=FULL.
+// Otherwise columns are named as "@ordinal".
+func ChangedColumns(before, after Row) []string {
+	var changed []string
+	for i, col := range after.Columns {
+		j := columnIndex(before.Columns, col.Ordinal)
+		if j == -1 || !sameValue(col, after.Values[i], before.Columns[j], before.Values[j]) {
+			changed = append(changed, columnName(col))
+		}
+	}
+	return changed
+}
+
+// ColumnsChanged tells whether any of given columns changed in update
+// of row. Names are matched case insensitively. see ChangedColumns.
+func ColumnsChanged(before, after Row, columns ...string) bool {
+	for _, name := range ChangedColumns(before, after) {
+		for _, c := range columns {
+			if strings.EqualFold(name, c) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func columnIndex(cols []Column, ordinal int) int {
+	for i, col := range cols {
+		if col.Ordinal == ordinal {
+			return i
+		}
+	}
+	return -1
+}
+
+func columnName(col Column) string {
+	if col.Name == "" {
+		return "@" + strconv.Itoa(col.Ordinal)
+	}
+	return col.Name
+}
+
+func sameValue(col1 Column, v1 interface{}, col2 Column, v2 interface{}) bool {
+	return bytes.Equal(appendCanonical(nil, col1, v1), appendCanonical(nil, col2, v2))
+}
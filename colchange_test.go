@@ -0,0 +1,37 @@
+package binlog
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChangedColumns(t *testing.T) {
+	cols := []Column{
+		{Ordinal: 0, Name: "id", Type: TypeLong},
+		{Ordinal: 1, Name: "status", Type: TypeVarchar},
+		{Ordinal: 2, Name: "price", Type: TypeNewDecimal},
+	}
+	before := Row{cols, []interface{}{int32(1), "new", Decimal("9.99")}}
+	after := Row{cols, []interface{}{int32(1), []byte("new"), Decimal("10.99")}}
+	if got, want := ChangedColumns(before, after), []string{"price"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if !ColumnsChanged(before, after, "STATUS", "Price") || ColumnsChanged(before, after, "status") {
+		t.Fatal("ColumnsChanged mismatch")
+	}
+
+	// binlog_row_image=MINIMAL: before has primary key, after has changed columns
+	before = Row{cols[:1], []interface{}{int32(1)}}
+	after = Row{cols[1:2], []interface{}{"paid"}}
+	if got, want := ChangedColumns(before, after), []string{"status"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	// without column names
+	noNames := []Column{{Ordinal: 0, Type: TypeLong}, {Ordinal: 1, Type: TypeVarchar}}
+	before = Row{noNames, []interface{}{int32(1), "a"}}
+	after = Row{noNames, []interface{}{int32(1), "b"}}
+	if got, want := ChangedColumns(before, after), []string{"@1"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,173 @@
+package config
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/santhosh-tekuri/binlog"
+)
+
+// rewind is a pending Rewind, applied by Run between events.
+type rewind struct {
+	pos   binlog.Position
+	gtids binlog.GTIDSet
+	done  chan error
+}
+
+// Pause pauses Run at next event boundary, till Resume is called.
+// Sources waiting for new events are not interrupted, so Run pauses
+// after the next event arrives.
+func (p *Pipeline) Pause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.paused = true
+}
+
+// Resume resumes Run paused by Pause.
+func (p *Pipeline) Resume() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.paused = false
+	p.signal()
+}
+
+// Paused tells whether Pause is in effect.
+func (p *Pipeline) Paused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.paused
+}
+
+// Rewind reopens source at given position, discarding uncommitted
+// changes of current transaction. Changes from pos onwards are sent to
+// sink again, and checkpointed as usual. If paused, Run stays paused.
+//
+// Remote source is interrupted at event boundary. Other sources are
+// rewound after the next event, unless paused. Rewind waits till the
+// source is reopened, or ctx is done.
+func (p *Pipeline) Rewind(ctx context.Context, pos binlog.Position) error {
+	if pos.File == "" {
+		return fmt.Errorf("config: rewind position missing")
+	}
+	return p.doRewind(ctx, &rewind{pos: pos})
+}
+
+// RewindGTID is like Rewind, but reopens remote source after given
+// executed gtid set. see binlog.Remote.SeekGTID.
+func (p *Pipeline) RewindGTID(ctx context.Context, executed binlog.GTIDSet) error {
+	if executed == nil {
+		executed = binlog.GTIDSet{}
+	}
+	return p.doRewind(ctx, &rewind{gtids: executed})
+}
+
+func (p *Pipeline) doRewind(ctx context.Context, r *rewind) error {
+	if p.open == nil {
+		return fmt.Errorf("config: pipeline is not built from config")
+	}
+	p.mu.Lock()
+	if p.rewind != nil || p.rewinding {
+		p.mu.Unlock()
+		return fmt.Errorf("config: rewind is in progress")
+	}
+	if !p.running {
+		// Run is refused, till source is reopened
+		p.rewinding = true
+		p.mu.Unlock()
+		err := p.applyRewind(r)
+		p.mu.Lock()
+		p.rewinding = false
+		p.mu.Unlock()
+		return err
+	}
+	r.done = make(chan error, 1)
+	p.rewind = r
+	p.signal()
+	src := p.Source // to interrupt, before Run reopens it
+	p.mu.Unlock()
+	if err := interrupt(ctx, src); err != nil {
+		return err
+	}
+	select {
+	case err := <-r.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Pipeline) applyRewind(r *rewind) error {
+	if err := p.open(r.pos, r.gtids); err != nil {
+		return err
+	}
+	if w, ok := p.Sink.(ChangeResetter); ok {
+		w.Reset(r.pos)
+	}
+	p.mu.Lock()
+	p.pos = r.pos
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *Pipeline) rewindPending() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.rewind != nil
+}
+
+// control is called by Run before each event. It waits while paused,
+// and applies pending rewind. returns errStopped if Stop is called.
+func (p *Pipeline) control() (rewound bool, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for {
+		switch {
+		case p.stopped:
+			return rewound, errStopped
+		case p.rewind != nil:
+			r := p.rewind
+			p.mu.Unlock()
+			err := p.applyRewind(r)
+			p.mu.Lock()
+			p.rewind = nil
+			r.done <- err
+			if err != nil {
+				return rewound, err
+			}
+			rewound = true
+		case p.paused:
+			p.cond.Wait()
+		default:
+			return rewound, nil
+		}
+	}
+}
+
+// signal wakes up control waiting while paused. p.mu must be held.
+func (p *Pipeline) signal() {
+	if p.cond != nil {
+		p.cond.Broadcast()
+	}
+}
+
+// interrupt stops the wait for next event of Source, if supported.
+func (p *Pipeline) interrupt(ctx context.Context) error {
+	p.mu.Lock()
+	src := p.Source
+	p.mu.Unlock()
+	return interrupt(ctx, src)
+}
+
+// interrupt stops the wait of src for next event, if it supports it.
+func interrupt(ctx context.Context, src binlog.EventSource) error {
+	if en, ok := src.(*binlog.Enricher); ok {
+		src = en.Src
+	}
+	if s, ok := src.(interface {
+		Stop(ctx context.Context) (string, uint32, error)
+	}); ok {
+		_, _, err := s.Stop(ctx)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package config
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/santhosh-tekuri/binlog"
+	"github.com/santhosh-tekuri/binlog/generator"
+)
+
+// memCheckpoint records saved positions.
+type memCheckpoint []binlog.Position
+
+func (m *memCheckpoint) Load() (binlog.Position, error) { return binlog.Position{}, nil }
+
+func (m *memCheckpoint) Save(pos binlog.Position) error {
+	*m = append(*m, pos)
+	return nil
+}
+
+func TestPipeline_AdminHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	f, err := os.Create(filepath.Join(dir, "binlog.000001"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = generator.Generate(f, generator.Config{
+		Tables:       []generator.Table{{Schema: "shop", Name: "orders", Columns: []generator.Column{{Name: "id", Type: binlog.TypeLong}}}},
+		Transactions: 20,
+		Seed:         1,
+	})
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := Build(&Config{Source: Source{Type: "dir", Path: dir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	p.Sink = nopWriter{}
+	commits := &memCheckpoint{}
+	p.Checkpoint = commits
+	if err := p.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if len(*commits) != 20 {
+		t.Fatalf("got %d commits, want 20", len(*commits))
+	}
+
+	srv := httptest.NewServer(p.AdminHandler())
+	defer srv.Close()
+	post := func(path string, form url.Values) int {
+		t.Helper()
+		resp, err := http.PostForm(srv.URL+path, form)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if resp, err := http.Get(srv.URL + "/pause"); err != nil || resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("GET /pause: got %v %v", resp, err)
+	}
+	if code := post("/rewind", url.Values{"gtid": {""}}); code != http.StatusBadRequest {
+		t.Fatalf("rewind without position: got %d", code)
+	}
+	if code := post("/rewind", url.Values{"gtid": {"3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5"}}); code != http.StatusBadRequest {
+		t.Fatalf("rewind dir source by gtid: got %d", code)
+	}
+
+	// run paused, and rewind to 15th commit
+	if code := post("/pause", nil); code != http.StatusOK || !p.Paused() {
+		t.Fatalf("pause: got %d", code)
+	}
+	events := p.Stats().Events
+	errCh := make(chan error, 1)
+	go func() { errCh <- p.Run() }()
+	time.Sleep(50 * time.Millisecond)
+	if p.Stats().Events != events {
+		t.Fatal("events read while paused")
+	}
+	target := (*commits)[14]
+	*commits = nil
+	form := url.Values{"pos": {target.File + ":" + strconv.FormatUint(uint64(target.Pos), 10)}}
+	if code := post("/rewind", form); code != http.StatusOK {
+		t.Fatalf("rewind: got %d", code)
+	}
+	if p.Position() != target || !p.Paused() {
+		t.Fatalf("Position: got %v, want %v", p.Position(), target)
+	}
+	if code := post("/resume", nil); code != http.StatusOK {
+		t.Fatalf("resume: got %d", code)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+	if len(*commits) != 5 {
+		t.Fatalf("got %d commits after rewind, want 5", len(*commits))
+	}
+}
+
+func TestPipeline_Rewind_notRunning(t *testing.T) {
+	dir, err := ioutil.TempDir("", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	f, err := os.Create(filepath.Join(dir, "binlog.000001"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = generator.Generate(f, generator.Config{
+		Tables:       []generator.Table{{Schema: "shop", Name: "orders", Columns: []generator.Column{{Name: "id", Type: binlog.TypeLong}}}},
+		Transactions: 2,
+	})
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := Build(&Config{Source: Source{Type: "dir", Path: dir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	sink := &resetWriter{}
+	p.Sink = sink
+	p.Checkpoint = &memCheckpoint{}
+
+	// Run is refused, while Rewind reopens source
+	open, opening, release := p.open, make(chan struct{}), make(chan struct{})
+	p.open = func(pos binlog.Position, gtids binlog.GTIDSet) error {
+		close(opening)
+		<-release
+		return open(pos, gtids)
+	}
+	errCh := make(chan error, 1)
+	go func() { errCh <- p.Rewind(context.Background(), binlog.Position{File: "binlog.000001", Pos: 4}) }()
+	<-opening
+	if err := p.Run(); err == nil || !strings.Contains(err.Error(), "rewind is in progress") {
+		t.Fatal("Run during Rewind: got", err)
+	}
+	close(release)
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+	if want := (binlog.Position{File: "binlog.000001", Pos: 4}); sink.reset != want {
+		t.Fatal("sink reset at", sink.reset, "want", want)
+	}
+	if err := p.Run(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// resetWriter records position of last Reset.
+type resetWriter struct {
+	nopWriter
+	reset binlog.Position
+}
+
+func (w *resetWriter) Reset(pos binlog.Position) { w.reset = pos }
@@ -0,0 +1,216 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/binlog"
+)
+
+// BatchWriter writes changes as JSON, one per line, into batch files
+// in Dir, named by binlog positions before and after the batch:
+//
+//	binlog.000001@0000000004-binlog.000001@0000012345.json
+//
+// It is both ChangeWriter and CheckpointStore of the pipeline. Changes
+// are buffered in memory, and a batch file is written on Save, once
+// BatchSize changes are committed. The file is synced and renamed into
+// place, before checkpoint advances to its end position. Load returns
+// end position of the last batch file, so that after crash, the
+// pipeline resumes right after the last complete file, and each change
+// is written exactly once. On Rewind, uncommitted changes are discarded
+// by Reset, as they are sent again.
+//
+// For the first batch, start position is position after the event of
+// its first change.
+type BatchWriter struct {
+	Dir       string
+	BatchSize int // defaults to 10000
+
+	buf        bytes.Buffer
+	count      int             // changes in buf
+	committed  int             // bytes of buf, upto last Save
+	numCommit  int             // changes of buf, upto last Save
+	start, end binlog.Position // of batch in buf
+}
+
+const batchTmp = "batch.tmp"
+
+// NewBatchWriter returns BatchWriter writing into dir, creating dir if
+// necessary. Temporary file left by previous crash, if any, is removed.
+func NewBatchWriter(dir string, batchSize int) (*BatchWriter, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(filepath.Join(dir, batchTmp)); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if batchSize <= 0 {
+		batchSize = 10000
+	}
+	return &BatchWriter{Dir: dir, BatchSize: batchSize}, nil
+}
+
+// Write buffers the change.
+func (w *BatchWriter) Write(c Change) error {
+	if w.count == 0 && w.start.File == "" {
+		w.start = binlog.Position{File: c.File, Pos: c.Pos}
+	}
+	if err := json.NewEncoder(&w.buf).Encode(c); err != nil {
+		return err
+	}
+	w.count++
+	return nil
+}
+
+// Reset discards changes written after last Save, as source is reopened
+// at pos by Pipeline.Rewind. If no changes are saved since last batch
+// file, the next batch starts at pos.
+func (w *BatchWriter) Reset(pos binlog.Position) {
+	w.buf.Truncate(w.committed)
+	w.count = w.numCommit
+	if w.numCommit == 0 {
+		w.start = pos
+	}
+}
+
+// Flush does nothing, as changes are durable only after Save.
+func (w *BatchWriter) Flush() error {
+	return nil
+}
+
+// Save marks buffered changes as committed upto pos, and writes batch
+// file if BatchSize changes are committed.
+func (w *BatchWriter) Save(pos binlog.Position) error {
+	w.end, w.committed, w.numCommit = pos, w.buf.Len(), w.count
+	if w.numCommit < w.BatchSize {
+		return nil
+	}
+	return w.writeBatch()
+}
+
+// Load returns end position of last batch file. It returns zero
+// position if there are no batch files.
+func (w *BatchWriter) Load() (binlog.Position, error) {
+	files, err := ioutil.ReadDir(w.Dir)
+	if err != nil {
+		return binlog.Position{}, err
+	}
+	var last binlog.Position
+	for _, f := range files {
+		_, end, ok := parseBatchName(f.Name())
+		if ok && last.Less(end) {
+			last = end
+		}
+	}
+	if w.count == 0 {
+		w.start = last
+	}
+	return last, nil
+}
+
+// Close writes committed changes, if any, into batch file. Uncommitted
+// changes are discarded, as they are sent again after restart.
+func (w *BatchWriter) Close() error {
+	if w.numCommit == 0 {
+		return nil
+	}
+	return w.writeBatch()
+}
+
+// writeBatch writes committed changes into batch file.
+func (w *BatchWriter) writeBatch() error {
+	name := filepath.Join(w.Dir, batchName(w.start, w.end))
+	if err := writeFileAtomic(filepath.Join(w.Dir, batchTmp), name, w.buf.Bytes()[:w.committed]); err != nil {
+		return err
+	}
+	rest := append([]byte(nil), w.buf.Bytes()[w.committed:]...)
+	w.buf.Reset()
+	w.buf.Write(rest)
+	w.count -= w.numCommit
+	w.committed, w.numCommit = 0, 0
+	w.start = w.end
+	return nil
+}
+
+// writeFileAtomic writes data to file tmp, and renames it to name.
+// Both file and its directory are synced, so that after crash, name
+// either does not exist or has complete data.
+func writeFileAtomic(tmp, name string, data []byte) error {
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, name)
+	}
+	if err == nil {
+		err = syncDir(filepath.Dir(name))
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+	}
+	return err
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if cerr := d.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func batchName(start, end binlog.Position) string {
+	return fmt.Sprintf("%s@%010d-%s@%010d.json", start.File, start.Pos, end.File, end.Pos)
+}
+
+// parseBatchName parses name of batch file. Binlog file names may
+// contain '-', but positions are digits, so the first '-' after the
+// first '@' separates start and end.
+func parseBatchName(name string) (start, end binlog.Position, ok bool) {
+	if !strings.HasSuffix(name, ".json") {
+		return start, end, false
+	}
+	name = strings.TrimSuffix(name, ".json")
+	at := strings.IndexByte(name, '@')
+	if at == -1 {
+		return start, end, false
+	}
+	dash := strings.IndexByte(name[at:], '-')
+	if dash == -1 {
+		return start, end, false
+	}
+	start, ok1 := parseBatchPos(name[:at+dash])
+	end, ok2 := parseBatchPos(name[at+dash+1:])
+	return start, end, ok1 && ok2
+}
+
+func parseBatchPos(s string) (binlog.Position, bool) {
+	at := strings.LastIndexByte(s, '@')
+	if at == -1 {
+		return binlog.Position{}, false
+	}
+	pos, err := strconv.ParseUint(s[at+1:], 10, 32)
+	if err != nil {
+		return binlog.Position{}, false
+	}
+	return binlog.Position{File: s[:at], Pos: uint32(pos)}, true
+}
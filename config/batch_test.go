@@ -0,0 +1,172 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/santhosh-tekuri/binlog"
+	"github.com/santhosh-tekuri/binlog/generator"
+)
+
+func TestParseBatchName(t *testing.T) {
+	start := binlog.Position{File: "mysql-bin.000001", Pos: 4}
+	end := binlog.Position{File: "mysql-bin.000002", Pos: 1234}
+	name := batchName(start, end)
+	if name != "mysql-bin.000001@0000000004-mysql-bin.000002@0000001234.json" {
+		t.Fatalf("got %q", name)
+	}
+	if s, e, ok := parseBatchName(name); !ok || s != start || e != end {
+		t.Fatalf("got %v %v %v", s, e, ok)
+	}
+	for _, bad := range []string{batchTmp, "x.json", "a@1.json", "a@1-b.json", "a@x-b@1.json"} {
+		if _, _, ok := parseBatchName(bad); ok {
+			t.Errorf("parseBatchName(%q): got ok", bad)
+		}
+	}
+}
+
+func TestBatchWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "batch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dump := filepath.Join(dir, "dump")
+	if err := os.Mkdir(dump, 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(filepath.Join(dump, "binlog.000001"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = generator.Generate(f, generator.Config{
+		Tables:       []generator.Table{{Schema: "shop", Name: "orders", Columns: []generator.Column{{Name: "id", Type: binlog.TypeLong}}}},
+		Transactions: 20,
+		Seed:         1,
+	})
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(dir, "out")
+	c := &Config{
+		Source: Source{Type: "dir", Path: dump},
+		Sink:   Sink{Type: "batch", Path: out, BatchSize: 7},
+	}
+	run := func() {
+		t.Helper()
+		p, err := Build(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Run(); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run()
+	run() // resumes after last batch file
+
+	files, err := ioutil.ReadDir(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("got %d files, want 3", len(files))
+	}
+	var prev binlog.Position
+	var lines []int
+	for i, f := range files {
+		start, end, ok := parseBatchName(f.Name())
+		if !ok {
+			t.Fatalf("invalid batch file %q", f.Name())
+		}
+		if !start.Less(end) || (i > 0 && start != prev) {
+			t.Fatalf("batch %q does not follow %v", f.Name(), prev)
+		}
+		prev = end
+		r, err := os.Open(filepath.Join(out, f.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := 0
+		for s := bufio.NewScanner(r); s.Scan(); {
+			n++
+		}
+		r.Close()
+		lines = append(lines, n)
+	}
+	if lines[0] != 7 || lines[1] != 7 || lines[2] != 6 {
+		t.Fatalf("got %v changes per batch, want [7 7 6]", lines)
+	}
+}
+
+func TestBatchWriter_Reset(t *testing.T) {
+	dir, err := ioutil.TempDir("", "batch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	w, err := NewBatchWriter(dir, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pos := func(p uint32) binlog.Position { return binlog.Position{File: "binlog.000001", Pos: p} }
+	write := func(p uint32) {
+		t.Helper()
+		if err := w.Write(Change{File: "binlog.000001", Pos: p, Type: "insert"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// uncommitted change, before any save
+	write(100)
+	w.Reset(pos(50))
+	write(60)
+	if err := w.Save(pos(70)); err != nil {
+		t.Fatal(err)
+	}
+	// uncommitted change, after save
+	write(200)
+	w.Reset(pos(70))
+	write(80)
+	if err := w.Save(pos(90)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name() != batchName(pos(50), pos(90)) {
+		t.Fatalf("got %v, want %s", files, batchName(pos(50), pos(90)))
+	}
+	buf, err := ioutil.ReadFile(filepath.Join(dir, files[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []uint32
+	for s := bufio.NewScanner(bytes.NewReader(buf)); s.Scan(); {
+		var c Change
+		if err := json.Unmarshal(s.Bytes(), &c); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, c.Pos)
+	}
+	if len(got) != 2 || got[0] != 60 || got[1] != 80 {
+		t.Fatalf("got changes at %v, want [60 80]", got)
+	}
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// Blobs configures offloading of large values of columns to BlobStore,
+// so that size of changes stays bounded for messaging sinks.
+type Blobs struct {
+	// Columns are named as "schema.table.column", and matched using
+	// path.Match patterns. Empty Columns disables offloading.
+	Columns []string `json:"columns"`
+
+	MinSize int    `json:"minSize"` // values smaller than this are kept. defaults to 64KiB
+	Dir     string `json:"dir"`     // directory of DirBlobStore
+}
+
+// BlobStore stores large values outside of changes, such as in an
+// object store.
+type BlobStore interface {
+	// Put stores data under key, and returns URI to fetch it.
+	Put(key string, data []byte) (uri string, err error)
+}
+
+// BlobRef replaces an offloaded value in Change.
+type BlobRef struct {
+	URI    string `json:"uri"`
+	SHA256 string `json:"sha256"` // hex encoded
+	Size   int    `json:"size"`
+}
+
+// BlobOffloader replaces values of Columns, of at least MinSize bytes,
+// with BlobRef after storing them in Store. Values are stored with
+// their sha256 as key, so that retries and duplicates are idempotent.
+type BlobOffloader struct {
+	Store   BlobStore
+	Columns []string // see Blobs.Columns
+	MinSize int
+}
+
+// offload replaces large values of row, in place.
+func (o *BlobOffloader) offload(schema, table string, row map[string]interface{}) error {
+	for col, v := range row {
+		var data []byte
+		switch v := v.(type) {
+		case []byte:
+			data = v
+		case string:
+			data = []byte(v)
+		default:
+			continue
+		}
+		if len(data) < o.MinSize || !o.match(schema+"."+table+"."+col) {
+			continue
+		}
+		sum := sha256.Sum256(data)
+		key := hex.EncodeToString(sum[:])
+		uri, err := o.Store.Put(key, data)
+		if err != nil {
+			return err
+		}
+		row[col] = BlobRef{URI: uri, SHA256: key, Size: len(data)}
+	}
+	return nil
+}
+
+func (o *BlobOffloader) match(name string) bool {
+	for _, pattern := range o.Columns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// DirBlobStore is BlobStore, that stores values as files in named
+// directory. URIs returned are file URIs.
+type DirBlobStore string
+
+// Put writes data to temporary file, and renames it to key. The file
+// and directory are synced, so that the blob outlives crash, as the
+// batch file referring to it does. Existing file is not written again.
+func (d DirBlobStore) Put(key string, data []byte) (string, error) {
+	dir, err := filepath.Abs(string(d))
+	if err != nil {
+		return "", err
+	}
+	name := filepath.Join(dir, key)
+	uri := "file://" + filepath.ToSlash(name)
+	if _, err := os.Stat(name); err == nil {
+		return uri, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	if err := writeFileAtomic(name+".tmp", name, data); err != nil {
+		return "", err
+	}
+	return uri, nil
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBlobOffloader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	o := &BlobOffloader{Store: DirBlobStore(dir), Columns: []string{"shop.docs.*"}, MinSize: 4}
+	row := map[string]interface{}{"body": []byte("hello world"), "title": "hi", "id": 1}
+	if err := o.offload("shop", "docs", row); err != nil {
+		t.Fatal(err)
+	}
+	if row["title"] != "hi" || row["id"] != 1 {
+		t.Fatalf("small values must be kept: %v", row)
+	}
+	ref, ok := row["body"].(BlobRef)
+	if !ok {
+		t.Fatalf("got %#v, want BlobRef", row["body"])
+	}
+	sum := sha256.Sum256([]byte("hello world"))
+	if ref.SHA256 != hex.EncodeToString(sum[:]) || ref.Size != 11 || !strings.HasPrefix(ref.URI, "file://") {
+		t.Fatalf("got %+v", ref)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, ref.SHA256))
+	if err != nil || string(b) != "hello world" {
+		t.Fatalf("got %q %v", b, err)
+	}
+
+	// unmatched table
+	row = map[string]interface{}{"body": "hello world"}
+	if err := o.offload("shop", "orders", row); err != nil {
+		t.Fatal(err)
+	}
+	if row["body"] != "hello world" {
+		t.Fatalf("got %v", row)
+	}
+}
@@ -0,0 +1,277 @@
+// Package config builds a complete pipeline, from binlog source through
+// filters and transforms to a sink, along with checkpoint store and
+// metrics, from a JSON document:
+//
+//	{
+//	    "source": {
+//	        "type": "remote", "addr": "localhost:3306",
+//	        "user": "root", "password": "password",
+//	        "serverID": 10, "from": "earliest"
+//	    },
+//	    "filter":     {"include": ["shop.*"], "exclude": ["shop.tmp_*"]},
+//	    "transform":  {"sample": [{"table": "^shop\\.clicks$", "rate": 0.1}]},
+//	    "sink":       {"type": "file", "path": "changes.json"},
+//	    "checkpoint": {"path": "pipeline.pos"},
+//	    "metrics":    {"expvar": "binlog"}
+//	}
+//
+// The same document is run by the command "binlog pipeline FILE", or as
+// daemon by "binlog serve FILE", so that the command and programs
+// embedding the pipeline are wired alike.
+// Only JSON is accepted: YAML documents must be converted to JSON, as
+// this module has no YAML dependency.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"time"
+
+	"github.com/santhosh-tekuri/binlog"
+)
+
+// Config describes a pipeline.
+type Config struct {
+	Source     Source     `json:"source"`
+	Filter     Filter     `json:"filter"`
+	Transform  Transform  `json:"transform"`
+	Sink       Sink       `json:"sink"`
+	Blobs      Blobs      `json:"blobs"`
+	Enrich     []Enrich   `json:"enrich"`
+	Checkpoint Checkpoint `json:"checkpoint"`
+	Metrics    Metrics    `json:"metrics"`
+	HTTP       HTTP       `json:"http"`
+}
+
+// Source describes where events are read from.
+type Source struct {
+	// Type is one of:
+	//
+	//	remote  mysql server at Addr
+	//	dir     dump directory at Path, as written by Remote.Dump
+	//	file    standalone binlog file at Path
+	Type string `json:"type"`
+
+	Network  string `json:"network"` // network of Addr. defaults to "tcp"
+	Addr     string `json:"addr"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+
+	// PasswordFile, if set, is read for password, on each connection,
+	// so that passwords rotated by secret managers are picked up on
+	// reconnect. Trailing newline is ignored.
+	PasswordFile string `json:"passwordFile"`
+
+	SSL       bool     `json:"ssl"`       // upgrade to ssl, if server supports it
+	Heartbeat Duration `json:"heartbeat"` // see Remote.SetHeartbeatPeriod
+	Path      string   `json:"path"`
+
+	// ServerID, if non-zero, makes remote and dir sources wait for
+	// new events, instead of stopping at the end. see Remote.Seek.
+	ServerID uint32 `json:"serverID"`
+
+	// RDSRetentionHours, if non-zero, extends binlog retention hours of
+	// remote source on Amazon RDS or Aurora, to at least this. see
+	// Remote.EnsureRDSBinlogRetention.
+	RDSRetentionHours int `json:"rdsRetentionHours"`
+
+	// From is where to start, when there is no checkpoint. valid values
+	// are "earliest", "latest" or "FILE[:POS]". defaults to "earliest".
+	From string `json:"from"`
+}
+
+// Filter selects tables, whose changes are sent to sink. Tables are
+// named as "schema.table", and matched using path.Match patterns.
+// Empty Include selects all tables. Exclude takes precedence.
+//
+// Where maps table patterns to binlog.RowFilter expressions, such as
+// `after.amount > 1000 && after.country == "US"`. Row changes of
+// matching tables are sent to sink only if all their expressions match.
+// Where is compiled by Build.
+type Filter struct {
+	Include []string          `json:"include"`
+	Exclude []string          `json:"exclude"`
+	Where   map[string]string `json:"where"`
+}
+
+// Transform configures decoding of events.
+type Transform struct {
+	Rename []RenameRule `json:"rename"` // see binlog.TableRenameRule
+	Sample []SampleRule `json:"sample"` // see binlog.SamplingRule
+}
+
+// RenameRule renames tables matching regular expression Pattern.
+type RenameRule struct {
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement"`
+}
+
+// SampleRule samples rows of tables, whose "schema.table" name
+// matches regular expression Table.
+type SampleRule struct {
+	Table string  `json:"table"`
+	Rate  float64 `json:"rate"`
+	Every int     `json:"every"`
+}
+
+// Sink describes where changes are written.
+type Sink struct {
+	// Type is one of the following. Changes are written as JSON, one
+	// per line.
+	//
+	//	stdout  default
+	//	file    file at Path, appended to if it exists
+	//	batch   batch files in directory Path. see BatchWriter
+	//
+	// batch sink is also the checkpoint store, so Checkpoint must not
+	// be configured with it.
+	Type      string `json:"type"`
+	Path      string `json:"path"`
+	BatchSize int    `json:"batchSize"` // see BatchWriter
+}
+
+// Checkpoint describes where position of last committed transaction
+// is saved. The pipeline resumes from it on restart. Empty Path
+// disables checkpoints.
+type Checkpoint struct {
+	Path string `json:"path"`
+}
+
+// Metrics configures publishing of Stats.
+type Metrics struct {
+	// Expvar, if non-empty, is the name under which Stats are
+	// published using expvar package.
+	Expvar string `json:"expvar"`
+}
+
+// Duration is time.Duration, encoded as string such as "30s" in JSON.
+type Duration time.Duration
+
+// UnmarshalJSON parses duration using time.ParseDuration.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("config: invalid duration %s", b)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("config: invalid duration %q", s)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalJSON returns duration as string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Load reads and validates JSON config from r. Unknown fields are
+// reported as error, to catch misspelled options.
+func Load(r io.Reader) (*Config, error) {
+	d := json.NewDecoder(r)
+	d.DisallowUnknownFields()
+	c := &Config{}
+	if err := d.Decode(c); err != nil {
+		return nil, fmt.Errorf("config: %v", err)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// LoadFile reads and validates JSON config from named file.
+func LoadFile(name string) (*Config, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+// Validate checks config for errors, without connecting to source.
+func (c *Config) Validate() error {
+	switch c.Source.Type {
+	case "remote":
+		if c.Source.Addr == "" {
+			return fmt.Errorf("config: source.addr missing")
+		}
+		if c.Source.Password != "" && c.Source.PasswordFile != "" {
+			return fmt.Errorf("config: both source.password and source.passwordFile given")
+		}
+	case "dir", "file":
+		if c.Source.Path == "" {
+			return fmt.Errorf("config: source.path missing")
+		}
+	default:
+		return fmt.Errorf("config: invalid source.type %q", c.Source.Type)
+	}
+	if c.Source.Type == "file" && c.Checkpoint.Path != "" {
+		return fmt.Errorf("config: checkpoint is not supported for file source")
+	}
+	for _, list := range [][]string{c.Filter.Include, c.Filter.Exclude} {
+		for _, pattern := range list {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("config: invalid filter pattern %q", pattern)
+			}
+		}
+	}
+	for pattern, expr := range c.Filter.Where {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("config: invalid filter pattern %q", pattern)
+		}
+		if _, err := binlog.CompileRowFilter(expr); err != nil {
+			return fmt.Errorf("config: invalid filter.where for %q: %v", pattern, err)
+		}
+	}
+	for _, pattern := range c.Blobs.Columns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("config: invalid blobs column pattern %q", pattern)
+		}
+	}
+	for _, e := range c.Enrich {
+		if err := e.validate(); err != nil {
+			return err
+		}
+	}
+	if len(c.Blobs.Columns) > 0 && c.Blobs.Dir == "" {
+		return fmt.Errorf("config: blobs.dir missing")
+	}
+	for _, rule := range c.Transform.Rename {
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("config: invalid rename pattern %q: %v", rule.Pattern, err)
+		}
+	}
+	for _, rule := range c.Transform.Sample {
+		if _, err := regexp.Compile(rule.Table); err != nil {
+			return fmt.Errorf("config: invalid sample table %q: %v", rule.Table, err)
+		}
+		if rule.Rate < 0 || rule.Rate > 1 || rule.Every < 0 {
+			return fmt.Errorf("config: invalid sample rule for %q", rule.Table)
+		}
+	}
+	switch c.Sink.Type {
+	case "", "stdout":
+	case "file", "batch":
+		if c.Sink.Path == "" {
+			return fmt.Errorf("config: sink.path missing")
+		}
+	default:
+		return fmt.Errorf("config: invalid sink.type %q", c.Sink.Type)
+	}
+	if c.Sink.Type == "batch" {
+		if c.Checkpoint.Path != "" {
+			return fmt.Errorf("config: checkpoint must not be configured with batch sink")
+		}
+		if c.Source.Type == "file" {
+			return fmt.Errorf("config: batch sink is not supported for file source")
+		}
+	}
+	return nil
+}
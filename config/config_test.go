@@ -0,0 +1,129 @@
+package config
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/santhosh-tekuri/binlog"
+	"github.com/santhosh-tekuri/binlog/generator"
+)
+
+func TestLoad(t *testing.T) {
+	c, err := Load(strings.NewReader(`{
+		"source": {"type": "remote", "addr": "localhost:3306", "heartbeat": "30s"},
+		"transform": {"sample": [{"table": "^test\\.t$", "every": 10}]}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Source.Heartbeat != Duration(30*time.Second) || c.Transform.Sample[0].Every != 10 {
+		t.Fatalf("got %+v", c)
+	}
+	for _, bad := range []string{
+		`{"source": {"type": "remote"}}`,
+		`{"source": {"type": "remote", "addr": "db:3306", "password": "p", "passwordFile": "pass.txt"}}`,
+		`{"source": {"type": "dir", "path": "dump"}, "sink": {"type": "kafka"}}`,
+		`{"source": {"type": "dir", "path": "dump"}, "filter": {"include": ["["]}}`,
+		`{"source": {"type": "dir", "path": "dump"}, "filter": {"where": {"shop.*": "after.id >"}}}`,
+		`{"source": {"type": "dir", "path": "dump"}, "enrich": [{"table": "shop.orders", "query": "select 1"}]}`,
+		`{"source": {"type": "dir", "path": "dump"}, "transform": {"sample": [{"table": "t", "rate": 2}]}}`,
+		`{"source": {"type": "file", "path": "binlog.000001"}, "checkpoint": {"path": "pos"}}`,
+		`{"source": {"type": "dir", "path": "dump", "heartbeat": "1x"}}`,
+		`{"source": {"type": "dir", "path": "dump"}, "sinks": {}}`,
+	} {
+		if _, err := Load(strings.NewReader(bad)); err == nil {
+			t.Errorf("Load(%s): error expected", bad)
+		}
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dump := filepath.Join(dir, "dump")
+	if err := os.Mkdir(dump, 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(filepath.Join(dump, "binlog.000001"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = generator.Generate(f, generator.Config{
+		Tables: []generator.Table{
+			{Schema: "shop", Name: "orders", Columns: []generator.Column{{Name: "id", Type: binlog.TypeLong}}},
+			{Schema: "shop", Name: "tmp_orders", Columns: []generator.Column{{Name: "id", Type: binlog.TypeLong}}},
+		},
+		Transactions: 20,
+		Ops:          generator.Ops{Insert: 1, Update: 1},
+		Seed:         1,
+	})
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Config{
+		Source:     Source{Type: "dir", Path: dump},
+		Filter:     Filter{Include: []string{"shop.*"}, Exclude: []string{"shop.tmp_*"}},
+		Sink:       Sink{Type: "file", Path: filepath.Join(dir, "changes.json")},
+		Checkpoint: Checkpoint{Path: filepath.Join(dir, "pos.json")},
+	}
+	run := func() Stats {
+		t.Helper()
+		p, err := Build(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Run(); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return p.Stats()
+	}
+	stats := run()
+	if stats.Commits != 20 || stats.Changes == 0 {
+		t.Fatalf("got %+v", stats)
+	}
+
+	out, err := os.Open(c.Sink.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	var lines int64
+	scanner := bufio.NewScanner(out)
+	for scanner.Scan() {
+		var ch Change
+		if err := json.Unmarshal(scanner.Bytes(), &ch); err != nil {
+			t.Fatal(err)
+		}
+		if ch.Table != "orders" || (ch.Type != "insert" && ch.Type != "update") || ch.Row["id"] == nil {
+			t.Fatalf("got %+v", ch)
+		}
+		if (ch.Type == "update") != (ch.Before != nil) {
+			t.Fatalf("got %+v", ch)
+		}
+		lines++
+	}
+	if lines != stats.Changes {
+		t.Fatalf("got %d lines, want %d", lines, stats.Changes)
+	}
+
+	// resumes from checkpoint
+	if stats := run(); stats.Changes != 0 || stats.Commits != 0 {
+		t.Fatalf("got %+v after restart", stats)
+	}
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql" // driver for Enrich.DSN
+
+	"github.com/santhosh-tekuri/binlog"
+)
+
+// Enrich appends columns to changes of Table, selected by Query from
+// database at DSN, such as from a dimension table. see binlog.Lookup.
+type Enrich struct {
+	Table     string   `json:"table"` // as "schema.table"
+	DSN       string   `json:"dsn"`   // as in github.com/go-sql-driver/mysql, e.g. "user:pass@tcp(host:3306)/"
+	Query     string   `json:"query"`
+	Args      []string `json:"args"`
+	Columns   []string `json:"columns"`
+	CacheSize int      `json:"cacheSize"`
+	Timeout   Duration `json:"timeout"`
+}
+
+func (e Enrich) validate() error {
+	switch {
+	case strings.Count(e.Table, ".") != 1:
+		return fmt.Errorf("config: invalid enrich.table %q", e.Table)
+	case e.DSN == "":
+		return fmt.Errorf("config: enrich.dsn missing for %s", e.Table)
+	case e.Query == "":
+		return fmt.Errorf("config: enrich.query missing for %s", e.Table)
+	case len(e.Columns) == 0:
+		return fmt.Errorf("config: enrich.columns missing for %s", e.Table)
+	}
+	return nil
+}
+
+// lookups opens databases of enrich, one per DSN, and sets Lookups.
+func (p *Pipeline) lookups(enrich []Enrich) error {
+	dbs := make(map[string]*sql.DB)
+	for _, e := range enrich {
+		db, ok := dbs[e.DSN]
+		if !ok {
+			var err error
+			if db, err = sql.Open("mysql", e.DSN); err != nil {
+				return err
+			}
+			dbs[e.DSN] = db
+			p.dbs = append(p.dbs, db)
+		}
+		p.Lookups = append(p.Lookups, &binlog.Lookup{
+			Table:     e.Table,
+			DB:        db,
+			Query:     e.Query,
+			Args:      e.Args,
+			Columns:   e.Columns,
+			CacheSize: e.CacheSize,
+			Timeout:   time.Duration(e.Timeout),
+		})
+	}
+	return nil
+}
@@ -0,0 +1,732 @@
+package config
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/santhosh-tekuri/binlog"
+)
+
+// Change is a change written to Sink.
+type Change struct {
+	Time   time.Time              `json:"time"`
+	File   string                 `json:"file"` // binlog file of next event
+	Pos    uint32                 `json:"pos"`  // position of next event
+	Type   string                 `json:"type"` // insert, update, delete or ddl
+	Schema string                 `json:"schema"`
+	Table  string                 `json:"table,omitempty"`
+	Row    map[string]interface{} `json:"row,omitempty"`
+	Before map[string]interface{} `json:"before,omitempty"` // row before update
+	Query  string                 `json:"query,omitempty"`  // statement of ddl
+}
+
+// ChangeWriter writes changes. Flush is called at the end of each
+// transaction, before its position is checkpointed.
+type ChangeWriter interface {
+	Write(c Change) error
+	Flush() error
+	Close() error
+}
+
+// ChangeResetter is implemented by ChangeWriter, which holds changes
+// till they are checkpointed. Reset is called once Rewind reopens the
+// source at pos, to discard changes written after last checkpoint, as
+// they are sent again.
+type ChangeResetter interface {
+	Reset(pos binlog.Position)
+}
+
+// CheckpointStore saves position of last committed transaction.
+// Load returns zero Position, if nothing is saved.
+type CheckpointStore interface {
+	Load() (binlog.Position, error)
+	Save(pos binlog.Position) error
+}
+
+// Stats are counters of running pipeline.
+type Stats struct {
+	Events  int64 // events read
+	Changes int64 // changes written
+	Commits int64 // transactions checkpointed
+}
+
+// Pipeline streams changes from Source to Sink. Build returns pipeline
+// wired as per Config. Embedders may replace Sink or Checkpoint before
+// calling Run.
+type Pipeline struct {
+	Source     binlog.EventSource
+	Filter     Filter
+	Sink       ChangeWriter
+	Checkpoint CheckpointStore  // nil means no checkpoints
+	Blobs      *BlobOffloader   // nil means no offloading
+	Lookups    []*binlog.Lookup // enrich rows. see binlog.Enricher
+
+	// Tracer, if non-nil, traces each transaction from BEGIN till its
+	// commit is checkpointed, and operations of remote source. see
+	// binlog.Remote.SetSpanTracer.
+	Tracer binlog.SpanTracer
+
+	closer io.Closer
+	dbs    []*sql.DB // of Lookups
+	stats  Stats
+	where  map[string]*binlog.RowFilter // compiled Filter.Where
+	txSpan binlog.Span                  // of current transaction, if Tracer is set
+
+	// open reopens source at given position. see Rewind.
+	open func(pos binlog.Position, gtids binlog.GTIDSet) error
+
+	mu        sync.Mutex
+	cond      *sync.Cond      // signaled on Resume, Rewind and Stop
+	pos       binlog.Position // position after last committed transaction
+	running   bool
+	err       error         // error returned by Run
+	stopped   bool          // Stop called
+	paused    bool          // Pause called
+	rewind    *rewind       // pending Rewind
+	rewinding bool          // Rewind reopening source, while not running
+	done      chan struct{} // closed when Run returns
+}
+
+// Build opens source positioned at checkpoint, or at Source.From if
+// there is no checkpoint, and returns pipeline as per config.
+func Build(c *Config) (*Pipeline, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	p := &Pipeline{Filter: c.Filter}
+	for pattern, expr := range c.Filter.Where {
+		f, err := binlog.CompileRowFilter(expr)
+		if err != nil {
+			return nil, err
+		}
+		if p.where == nil {
+			p.where = make(map[string]*binlog.RowFilter)
+		}
+		p.where[pattern] = f
+	}
+	if len(c.Blobs.Columns) > 0 {
+		minSize := c.Blobs.MinSize
+		if minSize <= 0 {
+			minSize = 64 << 10
+		}
+		p.Blobs = &BlobOffloader{Store: DirBlobStore(c.Blobs.Dir), Columns: c.Blobs.Columns, MinSize: minSize}
+	}
+	if err := p.lookups(c.Enrich); err != nil {
+		p.Close()
+		return nil, err
+	}
+	if c.Checkpoint.Path != "" {
+		p.Checkpoint = FileCheckpoint(c.Checkpoint.Path)
+	}
+	switch c.Sink.Type {
+	case "file":
+		f, err := os.OpenFile(c.Sink.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		p.Sink = NewJSONWriter(f)
+	case "batch":
+		w, err := NewBatchWriter(c.Sink.Path, c.Sink.BatchSize)
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		p.Sink, p.Checkpoint = w, w
+	default:
+		p.Sink = NewJSONWriter(nopCloser{os.Stdout})
+	}
+	if err := p.openSource(c); err != nil {
+		p.Close()
+		return nil, err
+	}
+	if c.Metrics.Expvar != "" && expvar.Get(c.Metrics.Expvar) == nil {
+		expvar.Publish(c.Metrics.Expvar, expvar.Func(func() interface{} { return p.Stats() }))
+	}
+	return p, nil
+}
+
+// decoder is implemented by binlog.Remote, binlog.Local and binlog.Reader.
+type decoder interface {
+	SetTableRenameRules(rules []binlog.TableRenameRule)
+	SetSampling(rules []binlog.SamplingRule)
+}
+
+func (p *Pipeline) openSource(c *Config) error {
+	var pos binlog.Position
+	if p.Checkpoint != nil {
+		var err error
+		if pos, err = p.Checkpoint.Load(); err != nil {
+			return err
+		}
+	}
+	p.open = func(pos binlog.Position, gtids binlog.GTIDSet) error {
+		return p.reopen(c, pos, gtids)
+	}
+	return p.open(pos, nil)
+}
+
+// reopen opens source as per config, positioned at pos, or after gtids
+// if non-nil. Zero pos means Source.From.
+func (p *Pipeline) reopen(c *Config, pos binlog.Position, gtids binlog.GTIDSet) error {
+	s := c.Source
+	if gtids != nil && s.Type != "remote" {
+		return fmt.Errorf("config: gtid position is not supported for %s source", s.Type)
+	}
+	var bl binlog.BinlogSource
+	switch s.Type {
+	case "file":
+		if pos.File != "" {
+			return fmt.Errorf("config: position is not supported for file source")
+		}
+		r, err := binlog.OpenFile(s.Path)
+		if err != nil {
+			return err
+		}
+		configure(r, c.Transform)
+		p.setSource(r, r)
+		return nil
+	case "dir":
+		local, err := binlog.Open(s.Path)
+		if err != nil {
+			return err
+		}
+		configure(local, c.Transform)
+		bl = local
+	case "remote":
+		remote, err := dial(s)
+		if err != nil {
+			return err
+		}
+		configure(remote, c.Transform)
+		if gtids != nil {
+			if err := remote.SeekGTID(s.ServerID, gtids); err != nil {
+				_ = remote.Close()
+				return err
+			}
+			p.setSource(remote, remote)
+			return nil
+		}
+		bl = remote
+	}
+	if pos.File == "" {
+		var err error
+		if pos, err = location(bl, s.From); err != nil {
+			_ = bl.Close()
+			return err
+		}
+	}
+	if err := bl.Seek(s.ServerID, pos.File, pos.Pos); err != nil {
+		_ = bl.Close()
+		return err
+	}
+	p.setSource(bl, bl)
+	return nil
+}
+
+// setSource replaces source, closing the previous one.
+func (p *Pipeline) setSource(src binlog.EventSource, closer io.Closer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closer != nil {
+		_ = p.closer.Close()
+	}
+	if len(p.Lookups) > 0 {
+		src = &binlog.Enricher{Src: src, Lookups: p.Lookups}
+	}
+	p.Source, p.closer = src, closer
+	p.traceSource()
+}
+
+// traceSource sets Tracer on remote source.
+func (p *Pipeline) traceSource() {
+	if r, ok := p.closer.(*binlog.Remote); ok && p.Tracer != nil {
+		r.SetSpanTracer(context.Background(), p.Tracer)
+	}
+}
+
+// spanTransaction is name of span of transaction. see Pipeline.Tracer.
+const spanTransaction = "binlog.Transaction"
+
+// beginSpan starts span of transaction, if not started yet.
+func (p *Pipeline) beginSpan(e binlog.Event) {
+	if p.Tracer != nil && p.txSpan == nil {
+		_, p.txSpan = p.Tracer.Start(context.Background(), spanTransaction)
+		p.txSpan.SetAttribute(binlog.AttrFile, e.Header.LogFile)
+	}
+}
+
+// endSpan ends span of transaction, if any.
+func (p *Pipeline) endSpan(pos binlog.Position, err error) {
+	if p.txSpan == nil {
+		return
+	}
+	p.txSpan.SetAttribute(binlog.AttrPos, int64(pos.Pos))
+	if err != nil {
+		p.txSpan.RecordError(err)
+	}
+	p.txSpan.End()
+	p.txSpan = nil
+}
+
+func configure(d decoder, t Transform) {
+	var renames []binlog.TableRenameRule
+	for _, rule := range t.Rename {
+		renames = append(renames, binlog.TableRenameRule{Pattern: regexp.MustCompile(rule.Pattern), Replacement: rule.Replacement})
+	}
+	d.SetTableRenameRules(renames)
+	var samples []binlog.SamplingRule
+	for _, rule := range t.Sample {
+		samples = append(samples, binlog.SamplingRule{Pattern: regexp.MustCompile(rule.Table), Rate: rule.Rate, Every: rule.Every})
+	}
+	d.SetSampling(samples)
+}
+
+func dial(s Source) (*binlog.Remote, error) {
+	network := s.Network
+	if network == "" {
+		network = "tcp"
+	}
+	bl, err := binlog.Dial(network, s.Addr, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if s.SSL && bl.IsSSLSupported() {
+		err = bl.UpgradeSSL(nil)
+	}
+	if err == nil {
+		err = bl.AuthenticateWith(context.Background(), s.credentials)
+	}
+	if err == nil && s.Heartbeat > 0 {
+		err = bl.SetHeartbeatPeriod(time.Duration(s.Heartbeat))
+	}
+	if err == nil && s.RDSRetentionHours > 0 {
+		err = bl.EnsureRDSBinlogRetention(s.RDSRetentionHours, true)
+	}
+	if err != nil {
+		_ = bl.Close()
+		return nil, err
+	}
+	return bl, nil
+}
+
+// credentials returns user and password of s, reading PasswordFile
+// if set.
+func (s Source) credentials(ctx context.Context) (string, string, error) {
+	if s.PasswordFile == "" {
+		return s.User, s.Password, nil
+	}
+	b, err := ioutil.ReadFile(s.PasswordFile)
+	if err != nil {
+		return "", "", err
+	}
+	return s.User, strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// location resolves "earliest", "latest" or "FILE[:POS]".
+func location(bl binlog.BinlogSource, from string) (binlog.Position, error) {
+	switch from {
+	case "", "earliest":
+		files, err := bl.ListFiles()
+		if err != nil {
+			return binlog.Position{}, err
+		}
+		if len(files) == 0 {
+			return binlog.Position{}, fmt.Errorf("config: no binlog files")
+		}
+		return binlog.Position{File: files[0], Pos: 4}, nil
+	case "latest":
+		file, pos, err := bl.MasterStatus()
+		return binlog.Position{File: file, Pos: pos}, err
+	}
+	return parsePosition(from)
+}
+
+// parsePosition parses "FILE[:POS]". POS defaults to 4.
+func parsePosition(s string) (binlog.Position, error) {
+	colon := strings.IndexByte(s, ':')
+	if colon == -1 {
+		return binlog.Position{File: s, Pos: 4}, nil
+	}
+	pos, err := strconv.ParseUint(s[colon+1:], 0, 32)
+	if err != nil || colon == 0 {
+		return binlog.Position{}, fmt.Errorf("config: invalid position %q", s)
+	}
+	return binlog.Position{File: s[:colon], Pos: uint32(pos)}, nil
+}
+
+// Stats returns snapshot of counters.
+func (p *Pipeline) Stats() Stats {
+	return Stats{
+		Events:  atomic.LoadInt64(&p.stats.Events),
+		Changes: atomic.LoadInt64(&p.stats.Changes),
+		Commits: atomic.LoadInt64(&p.stats.Commits),
+	}
+}
+
+// Run streams changes till source returns io.EOF or an error, or Stop
+// is called. Changes of tables not selected by Filter are skipped.
+func (p *Pipeline) Run() error {
+	p.mu.Lock()
+	if p.running {
+		p.mu.Unlock()
+		return fmt.Errorf("config: pipeline is already running")
+	}
+	if p.rewinding {
+		p.mu.Unlock()
+		return fmt.Errorf("config: rewind is in progress")
+	}
+	if p.cond == nil {
+		p.cond = sync.NewCond(&p.mu)
+	}
+	p.running, p.err, p.done = true, nil, make(chan struct{})
+	p.traceSource()
+	p.mu.Unlock()
+	err := p.run()
+	p.endSpan(p.Position(), err)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopped && (err == binlog.ErrStopped || err == errStopped) {
+		err = p.Sink.Flush()
+	}
+	p.running, p.err = false, err
+	close(p.done)
+	return err
+}
+
+var errStopped = errors.New("config: stopped")
+
+func (p *Pipeline) run() error {
+	var begun bool
+	for {
+		rewound, err := p.control()
+		if err != nil {
+			return err
+		}
+		if rewound {
+			begun = false
+			p.endSpan(p.Position(), nil)
+		}
+		e, err := p.Source.NextEvent()
+		if err == binlog.ErrStopped && p.rewindPending() {
+			continue
+		}
+		if err == io.EOF {
+			return p.Sink.Flush()
+		}
+		if err != nil {
+			return err
+		}
+		atomic.AddInt64(&p.stats.Events, 1)
+		switch d := e.Data.(type) {
+		case binlog.RowsEvent:
+			if err := p.rows(e, d); err != nil {
+				return err
+			}
+		case binlog.XIDEvent:
+			begun = false
+			err = p.commit(e)
+		case binlog.QueryEvent:
+			switch q := strings.ToUpper(strings.TrimSpace(d.Query)); {
+			case q == "BEGIN":
+				begun = true
+				p.beginSpan(e)
+			case q == "COMMIT":
+				begun = false
+				err = p.commit(e)
+			default:
+				if ddl, ok := d.DDL(); ok {
+					p.beginSpan(e)
+					err = p.ddl(e, ddl)
+					if err == nil && !begun {
+						err = p.commit(e)
+					}
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (p *Pipeline) rows(e binlog.Event, d binlog.RowsEvent) error {
+	if d.TableMap == nil || !p.selected(d.TableMap.SchemaName, d.TableMap.TableName) {
+		return nil
+	}
+	typ := "insert"
+	switch {
+	case e.Header.EventType.IsUpdateRows():
+		typ = "update"
+	case e.Header.EventType.IsDeleteRows():
+		typ = "delete"
+	}
+	for {
+		values, before, err := p.Source.NextRow()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !p.matches(e, d, values, before) {
+			continue
+		}
+		c := p.change(e, typ, d.TableMap.SchemaName)
+		c.Table = d.TableMap.TableName
+		c.Row = rowMap(d.Columns(), values)
+		if before != nil {
+			c.Before = rowMap(d.ColumnsBeforeUpdate(), before)
+		}
+		if p.Blobs != nil {
+			for _, row := range []map[string]interface{}{c.Row, c.Before} {
+				if err := p.Blobs.offload(c.Schema, c.Table, row); err != nil {
+					return err
+				}
+			}
+		}
+		if err := p.write(c); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *Pipeline) ddl(e binlog.Event, d binlog.DDLEvent) error {
+	selected := len(d.Targets) == 0 && p.selected(d.Schema, "")
+	for _, t := range d.Targets {
+		schema := t.Schema
+		if schema == "" {
+			schema = d.Schema
+		}
+		selected = selected || p.selected(schema, t.Name)
+	}
+	if !selected {
+		return nil
+	}
+	c := p.change(e, "ddl", d.Schema)
+	c.Query = d.Query
+	return p.write(c)
+}
+
+func (p *Pipeline) change(e binlog.Event, typ, schema string) Change {
+	return Change{
+		Time:   time.Unix(int64(e.Header.Timestamp), 0).UTC(),
+		File:   e.Header.LogFile,
+		Pos:    e.Header.NextPos,
+		Type:   typ,
+		Schema: schema,
+	}
+}
+
+func (p *Pipeline) write(c Change) error {
+	if err := p.Sink.Write(c); err != nil {
+		return err
+	}
+	atomic.AddInt64(&p.stats.Changes, 1)
+	return nil
+}
+
+// commit flushes sink and saves position after event e.
+func (p *Pipeline) commit(e binlog.Event) (err error) {
+	pos := binlog.Position{File: e.Header.LogFile, Pos: e.Header.NextPos}
+	defer func() { p.endSpan(pos, err) }()
+	if err := p.Sink.Flush(); err != nil {
+		return err
+	}
+	if p.Checkpoint != nil && pos.File != "" {
+		if err := p.Checkpoint.Save(pos); err != nil {
+			return err
+		}
+	}
+	p.mu.Lock()
+	p.pos = pos
+	p.mu.Unlock()
+	atomic.AddInt64(&p.stats.Commits, 1)
+	return nil
+}
+
+// Position returns binlog position after last committed transaction.
+// It is zero before first commit.
+func (p *Pipeline) Position() binlog.Position {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.pos
+}
+
+// Health returns nil if Run is in progress. Otherwise it returns
+// error returned by Run, or error saying that pipeline is not running.
+func (p *Pipeline) Health() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch {
+	case p.running:
+		return nil
+	case p.err != nil:
+		return p.err
+	default:
+		return fmt.Errorf("config: pipeline is not running")
+	}
+}
+
+// Stop stops Run, running in another goroutine, and waits for it to
+// return. Remote source is interrupted at event boundary, other sources
+// are checked for stop between events. Run returns nil after flushing
+// sink; uncommitted changes of current transaction, if any, are sent
+// again on restart from checkpoint.
+//
+// If ctx is done before Run returns, ctx.Err() is returned.
+func (p *Pipeline) Stop(ctx context.Context) error {
+	p.mu.Lock()
+	p.stopped = true
+	p.signal()
+	running, done := p.running, p.done
+	p.mu.Unlock()
+	if !running {
+		return nil
+	}
+	if err := p.interrupt(ctx); err != nil {
+		return err
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// selected tells whether changes of given table are selected by
+// Filter. Empty table matches schema level objects.
+func (p *Pipeline) selected(schema, table string) bool {
+	name := schema + "." + table
+	match := func(patterns []string) bool {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+		}
+		return false
+	}
+	if match(p.Filter.Exclude) {
+		return false
+	}
+	return len(p.Filter.Include) == 0 || match(p.Filter.Include)
+}
+
+// matches tells whether row change satisfies Filter.Where expressions
+// of its table.
+func (p *Pipeline) matches(e binlog.Event, d binlog.RowsEvent, values, before []interface{}) bool {
+	name := d.TableMap.SchemaName + "." + d.TableMap.TableName
+	for pattern, f := range p.where {
+		if ok, _ := path.Match(pattern, name); ok && !f.MatchEvent(e, values, before) {
+			return false
+		}
+	}
+	return true
+}
+
+func rowMap(cols []binlog.Column, values []interface{}) map[string]interface{} {
+	m := make(map[string]interface{}, len(values))
+	for i, v := range values {
+		name := cols[i].Name
+		if name == "" {
+			name = "@" + strconv.Itoa(cols[i].Ordinal)
+		}
+		m[name] = v
+	}
+	return m
+}
+
+// Close closes source and sink.
+func (p *Pipeline) Close() error {
+	var err error
+	if p.closer != nil {
+		err = p.closer.Close()
+	}
+	if p.Sink != nil {
+		if serr := p.Sink.Close(); err == nil {
+			err = serr
+		}
+	}
+	for _, db := range p.dbs {
+		if derr := db.Close(); err == nil {
+			err = derr
+		}
+	}
+	return err
+}
+
+// jsonWriter writes changes as JSON, one per line.
+type jsonWriter struct {
+	w   io.WriteCloser
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+// NewJSONWriter returns ChangeWriter, that writes changes to w as
+// JSON, one per line.
+func NewJSONWriter(w io.WriteCloser) ChangeWriter {
+	buf := bufio.NewWriter(w)
+	return &jsonWriter{w, buf, json.NewEncoder(buf)}
+}
+
+func (w *jsonWriter) Write(c Change) error { return w.enc.Encode(c) }
+func (w *jsonWriter) Flush() error         { return w.buf.Flush() }
+
+func (w *jsonWriter) Close() error {
+	err := w.buf.Flush()
+	if cerr := w.w.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+// FileCheckpoint is CheckpointStore, that saves position as JSON in
+// named file. The file is replaced atomically on each Save.
+type FileCheckpoint string
+
+// Load returns saved position, or zero Position if the file does not exist.
+func (f FileCheckpoint) Load() (binlog.Position, error) {
+	var pos binlog.Position
+	b, err := ioutil.ReadFile(string(f))
+	if os.IsNotExist(err) {
+		return pos, nil
+	}
+	if err != nil {
+		return pos, err
+	}
+	if err := json.Unmarshal(b, &pos); err != nil {
+		return pos, fmt.Errorf("config: invalid checkpoint %s: %v", f, err)
+	}
+	return pos, nil
+}
+
+// Save writes position to temporary file and renames it. Both file
+// and its directory are synced, so saved position survives crash.
+func (f FileCheckpoint) Save(pos binlog.Position) error {
+	b, err := json.Marshal(pos)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(string(f)+".tmp", filepath.Clean(string(f)), b)
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/santhosh-tekuri/binlog"
+)
+
+// HTTP configures http endpoint of pipeline run as daemon, by the
+// command "binlog serve FILE", where FILE is JSON config document.
+// see Pipeline.Handler.
+type HTTP struct {
+	Addr  string `json:"addr"`  // listen address. defaults to ":8080"
+	Admin bool   `json:"admin"` // serve AdminHandler instead of Handler
+}
+
+// Handler returns http handler serving:
+//
+//	/healthz   200 if pipeline is running, otherwise 503 with error
+//	/metrics   Stats in prometheus text format
+//	/position  Position as JSON
+func (p *Pipeline) Handler() http.Handler {
+	mux := http.NewServeMux()
+	p.handle(mux)
+	return mux
+}
+
+func (p *Pipeline) handle(mux *http.ServeMux) {
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		if err := p.Health(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		s := p.Stats()
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		for _, m := range []struct {
+			name, help string
+			value      int64
+		}{
+			{"binlog_events_total", "Events read from source.", s.Events},
+			{"binlog_changes_total", "Changes written to sink.", s.Changes},
+			{"binlog_commits_total", "Transactions checkpointed.", s.Commits},
+		} {
+			fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", m.name, m.help, m.name, m.name, m.value)
+		}
+	})
+	mux.HandleFunc("/position", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(p.Position())
+	})
+}
+
+// AdminHandler returns http handler serving endpoints of Handler, and
+// the following endpoints, which accept only POST:
+//
+//	/pause              see Pause
+//	/resume             see Resume
+//	/rewind?pos=FILE[:POS]
+//	/rewind?gtid=SET    see Rewind and RewindGTID
+//
+// It must not be exposed to untrusted clients.
+func (p *Pipeline) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	p.handle(mux)
+	post := func(path string, f func(r *http.Request) error) {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				w.Header().Set("Allow", http.MethodPost)
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			if err := f(r); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			fmt.Fprintln(w, "ok")
+		})
+	}
+	post("/pause", func(r *http.Request) error {
+		p.Pause()
+		return nil
+	})
+	post("/resume", func(r *http.Request) error {
+		p.Resume()
+		return nil
+	})
+	post("/rewind", func(r *http.Request) error {
+		if s := r.FormValue("gtid"); s != "" {
+			gtids, err := binlog.ParseGTIDSet(s)
+			if err != nil {
+				return err
+			}
+			return p.RewindGTID(r.Context(), gtids)
+		}
+		pos, err := parsePosition(r.FormValue("pos"))
+		if err != nil {
+			return err
+		}
+		return p.Rewind(r.Context(), pos)
+	})
+	return mux
+}
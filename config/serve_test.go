@@ -0,0 +1,146 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/santhosh-tekuri/binlog"
+)
+
+// chanSource returns events sent on its channel, till stopped.
+type chanSource struct {
+	events chan binlog.Event
+	stop   chan struct{}
+}
+
+func (s *chanSource) NextEvent() (binlog.Event, error) {
+	select {
+	case e := <-s.events:
+		return e, nil
+	case <-s.stop:
+		return binlog.Event{}, binlog.ErrStopped
+	}
+}
+
+func (s *chanSource) NextRow() ([]interface{}, []interface{}, error) {
+	return nil, nil, nil
+}
+
+func (s *chanSource) Stop(ctx context.Context) (string, uint32, error) {
+	close(s.stop)
+	return "", 0, nil
+}
+
+type nopWriter struct{}
+
+func (nopWriter) Write(c Change) error { return nil }
+func (nopWriter) Flush() error         { return nil }
+func (nopWriter) Close() error         { return nil }
+
+func TestPipeline_Handler(t *testing.T) {
+	src := &chanSource{make(chan binlog.Event), make(chan struct{})}
+	p := &Pipeline{Source: src, Sink: nopWriter{}}
+	srv := httptest.NewServer(p.Handler())
+	defer srv.Close()
+	get := func(path string) (int, string) {
+		t.Helper()
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, string(b)
+	}
+
+	if code, _ := get("/healthz"); code != http.StatusServiceUnavailable {
+		t.Fatalf("healthz before Run: got %d", code)
+	}
+	errCh := make(chan error, 1)
+	go func() { errCh <- p.Run() }()
+	src.events <- binlog.Event{
+		Header: binlog.EventHeader{EventType: binlog.XID_EVENT, LogFile: "binlog.000001", NextPos: 120},
+		Data:   binlog.XIDEvent{XID: 1},
+	}
+	// unbuffered send returns after previous event is processed
+	src.events <- binlog.Event{Data: binlog.QueryEvent{Query: "BEGIN"}}
+	src.events <- binlog.Event{Data: binlog.TableMapEvent{}}
+
+	if code, body := get("/healthz"); code != http.StatusOK {
+		t.Fatalf("healthz: got %d %q", code, body)
+	}
+	if _, body := get("/metrics"); !strings.Contains(body, "\nbinlog_commits_total 1\n") {
+		t.Fatalf("metrics: got %q", body)
+	}
+	if _, body := get("/position"); strings.TrimSpace(body) != `{"File":"binlog.000001","Pos":120}` {
+		t.Fatalf("position: got %q", body)
+	}
+
+	if err := p.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("Run: got %v, want nil", err)
+	}
+	if code, _ := get("/healthz"); code != http.StatusServiceUnavailable {
+		t.Fatalf("healthz after Stop: got %d", code)
+	}
+}
+
+type countingTracer struct{ ended chan string }
+
+func (t countingTracer) Start(ctx context.Context, name string) (context.Context, binlog.Span) {
+	return ctx, &countingSpan{t: t, name: name}
+}
+
+type countingSpan struct {
+	t    countingTracer
+	name string
+	pos  interface{}
+}
+
+func (s *countingSpan) SetAttribute(key string, value interface{}) {
+	if key == binlog.AttrPos {
+		s.pos = value
+	}
+}
+func (s *countingSpan) RecordError(err error) {}
+func (s *countingSpan) End()                  { s.t.ended <- fmt.Sprint(s.name, " ", s.pos) }
+
+func TestPipeline_Tracer(t *testing.T) {
+	src := &chanSource{make(chan binlog.Event), make(chan struct{})}
+	tracer := countingTracer{make(chan string, 10)}
+	p := &Pipeline{Source: src, Sink: nopWriter{}, Tracer: tracer}
+	errCh := make(chan error, 1)
+	go func() { errCh <- p.Run() }()
+	src.events <- binlog.Event{Data: binlog.QueryEvent{Query: "BEGIN"}}
+	src.events <- binlog.Event{
+		Header: binlog.EventHeader{EventType: binlog.XID_EVENT, LogFile: "binlog.000001", NextPos: 120},
+		Data:   binlog.XIDEvent{XID: 1},
+	}
+	if got, want := <-tracer.ended, "binlog.Transaction 120"; got != want {
+		t.Fatalf("got span %q, want %q", got, want)
+	}
+	// commit without BEGIN has no span
+	src.events <- binlog.Event{
+		Header: binlog.EventHeader{EventType: binlog.XID_EVENT, LogFile: "binlog.000001", NextPos: 150},
+		Data:   binlog.XIDEvent{XID: 2},
+	}
+	if err := p.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+	if len(tracer.ended) != 0 {
+		t.Fatalf("got span %q", <-tracer.ended)
+	}
+}
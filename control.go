@@ -0,0 +1,102 @@
+package binlog
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+)
+
+// SetControlConn enables companion control connection. When enabled,
+// queries such as MasterStatus, ListFiles, GlobalPrivileges and
+// ValidateSource, made while this connection is streaming binlog, are
+// sent over a second connection to the same server, instead of failing
+// with ErrStreaming. So the binlog stream is never disturbed. They are
+// safe to call from another goroutine, while NextEvent blocks.
+//
+// The control connection is dialed on first use, with same DialOptions
+// and credentials as this connection, and redialed if it is broken.
+// With AuthenticateWith, credentials are fetched again for each dial.
+// Statements setting session variables are never sent over it, as they
+// must apply to the streaming connection.
+//
+// This works only for Remote created by Dial functions, and must be
+// called before Authenticate, as credentials are remembered by it.
+func (bl *Remote) SetControlConn(enable bool) {
+	bl.controlEnabled = enable
+	if !enable {
+		bl.closeControl()
+	}
+}
+
+// controlDo runs fn on control connection, redialing once if the
+// connection is broken. fn must read whole response of its queries.
+func (bl *Remote) controlDo(fn func(c *Remote) error) error {
+	bl.controlMu.Lock()
+	defer bl.controlMu.Unlock()
+	for attempt := 0; ; attempt++ {
+		c, err := bl.controlConn()
+		if err != nil {
+			return err
+		}
+		err = fn(c)
+		if err != nil && isConnErr(err) {
+			bl.dropControl()
+			if attempt == 0 {
+				continue
+			}
+		}
+		return err
+	}
+}
+
+// controlConn returns control connection, dialing it if needed.
+// bl.controlMu must be held.
+func (bl *Remote) controlConn() (*Remote, error) {
+	if bl.control != nil {
+		return bl.control, nil
+	}
+	bl.mu.Lock()
+	credentials := bl.credentials
+	bl.mu.Unlock()
+	if bl.redial == nil || credentials == nil {
+		return nil, ErrStreaming
+	}
+	c, err := bl.redial()
+	if err != nil {
+		return nil, err
+	}
+	c.azureCompat, c.minimalPrivileges = bl.azureCompat, bl.minimalPrivileges
+	c.expectedServerUUID = bl.serverUUID
+	if err := c.AuthenticateWith(context.Background(), credentials); err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+	bl.control = c
+	return c, nil
+}
+
+func (bl *Remote) closeControl() {
+	bl.controlMu.Lock()
+	bl.dropControl()
+	bl.controlMu.Unlock()
+}
+
+// dropControl closes control connection. bl.controlMu must be held.
+func (bl *Remote) dropControl() {
+	if bl.control != nil {
+		_ = bl.control.Close()
+		bl.control = nil
+	}
+}
+
+// isConnErr tells whether err is due to broken connection,
+// rather than error reported by server.
+func isConnErr(err error) bool {
+	switch err {
+	case io.EOF, io.ErrUnexpectedEOF, io.ErrClosedPipe:
+		return true
+	}
+	var ne net.Error
+	return errors.As(err, &ne)
+}
@@ -0,0 +1,236 @@
+package binlog
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// ChecksumPolicy tells what to do with checksum of events, when
+// binlog_checksum=CRC32.
+type ChecksumPolicy uint8
+
+// ChecksumPolicy constants.
+const (
+	// ChecksumVerify fails NextEvent on checksum mismatch.
+	ChecksumVerify ChecksumPolicy = iota
+
+	// ChecksumIgnore skips computing checksum, for speed.
+	ChecksumIgnore
+
+	// ChecksumRecover returns CorruptionEvent on checksum mismatch,
+	// and resumes from next plausible event, i.e. with known event
+	// type, sane size and matching checksum. Remote resumes from
+	// next event, as each event is framed in its own packet.
+	ChecksumRecover
+)
+
+// CorruptionEvent is a synthetic event, returned with ChecksumRecover
+// policy, right before the event whose checksum does not match. That
+// event is corrupt and must be discarded. It is never written to
+// binlog.
+//
+// Checksum of FormatDescriptionEvent, and of event not yet fully written
+// to file being followed by Local, is verified only after the event is
+// returned, so CorruptionEvent is returned right after such event.
+type CorruptionEvent struct {
+	Got, Want uint32 // computed and stored checksums of corrupt event
+	Skipped   int    // bytes skipped after corrupt event, to find next event
+}
+
+// maxScanEventSize is maximum size of event, recognized when scanning
+// for next event after corrupt region. it is max_allowed_packet limit.
+const maxScanEventSize = 1 << 30
+
+// fileReader is implemented by readers of binlog files, whose size
+// bounds events recognized when scanning for next event.
+type fileReader interface {
+	// filePos returns offset of next byte read, and size of current file.
+	filePos() (pos, size int64, err error)
+}
+
+// checkEvent verifies checksum of current event, whose header is just
+// decoded, before its body is decoded, so that CorruptionEvent can be
+// returned ahead of it. The event is buffered fully. On mismatch, it
+// sets r.corrupt, and finds next plausible event if r.scan is set.
+func (r *reader) checkEvent() error {
+	if r.hash == nil || r.checksum <= 0 || r.opts.checksumPolicy != ChecksumRecover || r.limit < 0 {
+		return nil
+	}
+	n, limit := r.limit+r.checksum, r.limit
+	if n > maxScanEventSize {
+		return nil
+	}
+	if r.scan {
+		_, avail, err := r.filePos()
+		if err != nil {
+			return err
+		}
+		if avail >= 0 && int64(n) > avail {
+			return nil // not yet written, verified after it is read
+		}
+	}
+	r.limit = -1
+	err := r.ensure(n)
+	r.limit = limit
+	if err == io.ErrUnexpectedEOF {
+		r.err = nil // reported when body is decoded
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	buf := r.buf[r.off:]
+	got := crc32.Update(r.hash.Sum32(), crc32.IEEETable, buf[:n-4])
+	want := binary.LittleEndian.Uint32(buf[n-4:])
+	r.verified = true
+	if got == want {
+		return nil
+	}
+	c := &CorruptionEvent{Got: got, Want: want}
+	if r.scan {
+		c.Skipped, err = r.scanEvent(n)
+		r.limit = limit
+		if err != nil {
+			return err
+		}
+		r.skipAfter = c.Skipped
+	}
+	r.corrupt = c
+	return nil
+}
+
+// verifyChecksum reads checksum of current event, which is drained,
+// and verifies it as per checksum policy, unless verified by
+// checkEvent. With ChecksumRecover, it returns CorruptionEvent on
+// mismatch, after skipping to next plausible event if r.scan is set.
+func (r *reader) verifyChecksum() (*CorruptionEvent, error) {
+	var got uint32
+	if r.hash != nil {
+		got = r.hash.Sum32()
+	}
+	want := r.int4()
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.verified {
+		skip := r.skipAfter
+		r.verified, r.skipAfter = false, 0
+		r.limit = -1
+		return nil, r.skip(skip)
+	}
+	if r.hash == nil || got == want || r.opts.checksumPolicy == ChecksumIgnore {
+		return nil, nil
+	}
+	if r.opts.checksumPolicy != ChecksumRecover {
+		return nil, fmt.Errorf("binlog.NextEvent: checksum failed got=%d want=%d", got, want)
+	}
+	c := &CorruptionEvent{Got: got, Want: want}
+	if r.scan {
+		var err error
+		if c.Skipped, err = r.scanEvent(0); err != nil {
+			return nil, err
+		}
+		r.limit = -1
+		if err := r.skip(c.Skipped); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// filePos returns file position of first buffered byte, and bytes
+// available in file from there. Both are -1 if r does not read file.
+func (r *reader) filePos() (base, avail int64, err error) {
+	f, ok := r.rd.(fileReader)
+	if !ok {
+		return -1, -1, nil
+	}
+	pos, size, err := f.filePos()
+	if err != nil {
+		return -1, -1, err
+	}
+	buffered := int64(len(r.buf) - r.off)
+	return pos - buffered, size - pos + buffered, nil
+}
+
+// scanEvent returns number of bytes, after first from bytes of buffer,
+// till next plausible event, without consuming them. If r reads file,
+// scan stops at end of file.
+func (r *reader) scanEvent(from int) (int, error) {
+	base, avail, err := r.filePos()
+	if err != nil {
+		return 0, err
+	}
+	r.limit = -1
+	for off := from; ; off++ {
+		if avail >= 0 && int64(off+19) > avail {
+			return int(avail) - from, nil
+		}
+		if err := r.ensure(off + 19); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				r.err = nil
+				return len(r.buffer()) - from, nil
+			}
+			return 0, err
+		}
+		ok, err := r.plausibleEvent(off, base, avail)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			return off - from, nil
+		}
+	}
+}
+
+// plausibleEvent tells whether buffer has plausible event at offset off.
+// base and avail are as returned by filePos. Header is validated before
+// the event is buffered, whose size is bounded by avail.
+func (r *reader) plausibleEvent(off int, base, avail int64) (bool, error) {
+	buf := r.buffer()[off:]
+	if _, ok := eventTypeNames[EventType(buf[4])]; !ok {
+		return false, nil
+	}
+	size := binary.LittleEndian.Uint32(buf[9:])
+	nextPos := binary.LittleEndian.Uint32(buf[13:])
+	if size < 19+4 || size > maxScanEventSize || nextPos < size {
+		return false, nil
+	}
+	if avail >= 0 {
+		if int64(off)+int64(size) > avail || int64(nextPos) != base+int64(off)+int64(size) {
+			return false, nil
+		}
+	}
+	if err := r.ensure(off + int(size)); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			r.err = nil
+			return false, nil
+		}
+		return false, err
+	}
+	buf = r.buffer()[off:]
+	return crc32.ChecksumIEEE(buf[:size-4]) == binary.LittleEndian.Uint32(buf[size-4:]), nil
+}
+
+// corruptionFirst returns CorruptionEvent c of previous event if non-nil,
+// and CorruptionEvent of e found by checkEvent, ahead of e. e is
+// dropped if it fails to decode, as it is corrupt.
+func corruptionFirst(r *reader, c *CorruptionEvent, e Event, err error) (Event, error) {
+	if r.corrupt != nil {
+		if err == nil {
+			r.pending = append([]Event{e}, r.pending...)
+		}
+		e, err = synthetic(e, *r.corrupt), nil
+		r.corrupt = nil
+	}
+	if c == nil {
+		return e, err
+	}
+	if err != nil {
+		return Event{}, fmt.Errorf("binlog.NextEvent: checksum failed got=%d want=%d", c.Got, c.Want)
+	}
+	r.pending = append([]Event{e}, r.pending...)
+	return synthetic(e, *c), nil
+}
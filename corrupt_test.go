@@ -0,0 +1,147 @@
+package binlog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// corruptStream returns stream with xids 7, 8 and 9, where checksum of
+// xid 8 is corrupt, followed by garbage bytes.
+func corruptStream(garbage []byte) []byte {
+	s := newBinlogStream()
+	s.XID(7)
+	s.XID(8)
+	b := s.Bytes()
+	b[len(b)-1] ^= 0xff
+	s.Write(garbage)
+	s.SetPos(uint32(s.Len()))
+	s.XID(9)
+	return s.Bytes()
+}
+
+func TestChecksumPolicy(t *testing.T) {
+	garbage := []byte("\x00\x01garbage\x10\xff")
+	tests := []struct {
+		policy  ChecksumPolicy
+		garbage []byte
+		want    string
+	}{
+		{ChecksumVerify, nil, "checksum failed"},
+		{ChecksumIgnore, nil, "7 8 9"},
+		{ChecksumRecover, nil, "7 corrupt(0) 8 9"},
+		{ChecksumRecover, garbage, "7 corrupt(11) 8 9"},
+	}
+	for _, tc := range tests {
+		r := NewReader(bytes.NewReader(corruptStream(tc.garbage)))
+		r.SetChecksumPolicy(tc.policy)
+		if got := checksumEvents(r.NextEvent, 0); !strings.Contains(got, tc.want) {
+			t.Errorf("policy %d: got %q, want %q", tc.policy, got, tc.want)
+		}
+	}
+}
+
+// checksumEvents returns xids and corruptions returned by next, till
+// io.EOF, error or xid last if non-zero.
+func checksumEvents(next func() (Event, error), last uint64) string {
+	var got []string
+	for {
+		e, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			got = append(got, err.Error())
+			break
+		}
+		switch d := e.Data.(type) {
+		case XIDEvent:
+			got = append(got, fmt.Sprint(d.XID))
+			if d.XID == last {
+				return strings.Join(got, " ")
+			}
+		case CorruptionEvent:
+			got = append(got, fmt.Sprintf("corrupt(%d)", d.Skipped))
+		}
+	}
+	return strings.Join(got, " ")
+}
+
+func TestChecksumPolicy_local(t *testing.T) {
+	dir, err := ioutil.TempDir("", "binlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// garbage at end of file, with header of event beyond end of file
+	s := newBinlogStream()
+	s.XID(7)
+	s.XID(8)
+	b := s.Bytes()
+	b[len(b)-1] ^= 0xff
+	garbage := make([]byte, 23)
+	garbage[4] = byte(XID_EVENT)
+	binary.LittleEndian.PutUint32(garbage[9:], 1<<29)
+	binary.LittleEndian.PutUint32(garbage[13:], uint32(len(b))+1<<29)
+	f1 := append(b, garbage...)
+	f2 := newBinlogStream()
+	f2.XID(9)
+	for name, b := range map[string][]byte{"binlog.000001": f1, "binlog.000002": f2.Bytes()} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), b, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	bl, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bl.SetChecksumPolicy(ChecksumRecover)
+	if err := bl.Seek(1, "binlog.000001", 4); err != nil { // blocking
+		t.Fatal(err)
+	}
+	if got, want := checksumEvents(bl.NextEvent, 9), "7 corrupt(23) 8 9"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestChecksumPolicy_remote(t *testing.T) {
+	tests := []struct {
+		policy ChecksumPolicy
+		want   string
+	}{
+		{ChecksumVerify, "7 8 binlog.NextEvent: checksum failed"},
+		{ChecksumIgnore, "7 8 9"},
+		{ChecksumRecover, "7 corrupt(0) 8 9"},
+	}
+	for _, tc := range tests {
+		s := newFakeServer()
+		f := newBinlogStream()
+		f.XID(7)
+		f.XID(8)
+		b := f.Bytes()
+		b[len(b)-1] ^= 0xff
+		f.XID(9)
+		s.addFile("binlog.000001", f)
+		bl, err := s.dial()
+		if err != nil {
+			t.Fatal(err)
+		}
+		bl.SetChecksumPolicy(tc.policy)
+		if err := bl.Seek(0, "binlog.000001", 4); err != nil {
+			t.Fatal(err)
+		}
+		got := checksumEvents(bl.NextEvent, 0)
+		bl.Close()
+		if !strings.HasPrefix(got, tc.want) {
+			t.Errorf("policy %d: got %q, want %q", tc.policy, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package binlog
+
+import "context"
+
+// CredentialsProvider returns username and password to authenticate
+// a new connection. It is called for each connection, so that rotated
+// passwords and short lived tokens, such as from Vault, AWS Secrets
+// Manager or RDS IAM, are picked up on reconnect without restart.
+type CredentialsProvider func(ctx context.Context) (user, password string, err error)
+
+// StaticCredentials returns CredentialsProvider of fixed credentials.
+func StaticCredentials(user, password string) CredentialsProvider {
+	return func(context.Context) (string, string, error) {
+		return user, password, nil
+	}
+}
+
+// AuthenticateWith is like Authenticate, but with credentials returned
+// by p. Companion control connection, which is dialed later, calls p
+// again to authenticate. see SetControlConn.
+func (bl *Remote) AuthenticateWith(ctx context.Context, p CredentialsProvider) error {
+	user, password, err := p(ctx)
+	if err != nil {
+		return err
+	}
+	if err := bl.Authenticate(user, password); err != nil {
+		return err
+	}
+	if bl.controlEnabled {
+		bl.mu.Lock()
+		bl.credentials = p
+		bl.mu.Unlock()
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package binlog
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRemote_AuthenticateWith(t *testing.T) {
+	s := newFakeServer()
+	s.idle = make(chan struct{})
+	defer close(s.idle)
+	f := newBinlogStream()
+	f.XID(7)
+	s.addFile("binlog.000001", f)
+	bl, err := DialWith(s, "tcp", "fake:3306")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bl.Close()
+	bl.SetControlConn(true)
+
+	calls := 0
+	password := s.password
+	creds := func(ctx context.Context) (string, string, error) {
+		calls++
+		return s.user, password, nil
+	}
+	if err := bl.AuthenticateWith(context.Background(), creds); err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.Seek(1, "binlog.000001", 4); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bl.NextEvent(); err != nil {
+		t.Fatal(err)
+	}
+
+	// rotated password is used by control connection
+	s.password, password = "rotated", "rotated"
+	if _, _, err := bl.MasterStatus(); err != nil {
+		t.Fatal("MasterStatus:", err)
+	}
+	if calls != 2 {
+		t.Fatalf("credentials provider called %d times, want 2", calls)
+	}
+}
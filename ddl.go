@@ -0,0 +1,327 @@
+package binlog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DDLEvent is QueryEvent classified as DDL statement.
+//
+// The classification is done by a lightweight tokenizer, not by a
+// full SQL parser. It understands comments, quoted identifiers and
+// the common forms of CREATE, ALTER, DROP, TRUNCATE and RENAME.
+type DDLEvent struct {
+	QueryEvent
+	Kind    string       // CREATE, ALTER, DROP, TRUNCATE or RENAME
+	Object  string       // TABLE, DATABASE, INDEX, VIEW, TRIGGER, PROCEDURE, FUNCTION, EVENT etc
+	Targets []ObjectName // objects affected by statement
+
+	// NewNames has new name of each target for RENAME TABLE and
+	// ALTER TABLE ... RENAME statements.
+	NewNames []ObjectName
+}
+
+// ObjectName is a schema qualified name. For DATABASE objects,
+// Name is empty.
+type ObjectName struct {
+	Schema string
+	Name   string
+}
+
+func (n ObjectName) String() string {
+	if n.Name == "" {
+		return n.Schema
+	}
+	return n.Schema + "." + n.Name
+}
+
+// DDL classifies this query. It returns false if the query is
+// not a DDL statement. Unqualified names are resolved against
+// the default schema of the query.
+func (e QueryEvent) DDL() (DDLEvent, bool) {
+	p := &ddlParser{toks: sqlTokens(e.Query), schema: e.Schema}
+	ddl := DDLEvent{QueryEvent: e, Kind: strings.ToUpper(p.next())}
+	switch ddl.Kind {
+	case "CREATE", "ALTER", "DROP":
+		p.skipWords("OR", "REPLACE", "TEMPORARY", "UNIQUE", "FULLTEXT", "SPATIAL", "ONLINE", "OFFLINE", "IGNORE", "UNDO")
+		p.skipDefinerClauses()
+		ddl.Object = strings.ToUpper(p.next())
+		switch ddl.Object {
+		case "SCHEMA":
+			ddl.Object = "DATABASE"
+		case "":
+			return DDLEvent{}, false
+		}
+		p.skipWords("IF", "NOT", "EXISTS")
+		switch {
+		case ddl.Object == "DATABASE":
+			ddl.Targets = []ObjectName{{Schema: p.ident()}}
+		case ddl.Object == "INDEX":
+			p.ident() // index name
+			if !p.skipWords("ON") {
+				return ddl, true
+			}
+			ddl.Targets = []ObjectName{p.name()}
+		case !schemaObjects[ddl.Object]:
+			// users, roles, tablespaces etc
+		case ddl.Kind == "DROP":
+			ddl.Targets = p.names()
+		default:
+			ddl.Targets = []ObjectName{p.name()}
+			if ddl.Kind == "ALTER" && ddl.Object == "TABLE" {
+				for !p.done() {
+					if strings.EqualFold(p.next(), "RENAME") {
+						p.skipWords("TO", "AS")
+						if p.skipWords("COLUMN", "INDEX", "KEY") {
+							continue
+						}
+						ddl.NewNames = []ObjectName{p.name()}
+						break
+					}
+				}
+			}
+		}
+	case "TRUNCATE":
+		p.skipWords("TABLE")
+		ddl.Object = "TABLE"
+		ddl.Targets = []ObjectName{p.name()}
+	case "RENAME":
+		ddl.Object = strings.ToUpper(p.next())
+		if ddl.Object != "TABLE" {
+			return ddl, true
+		}
+		for !p.done() {
+			ddl.Targets = append(ddl.Targets, p.name())
+			p.skipWords("TO")
+			ddl.NewNames = append(ddl.NewNames, p.name())
+			if !p.skipPunct(",") {
+				break
+			}
+		}
+	default:
+		return DDLEvent{}, false
+	}
+	return ddl, true
+}
+
+// schemaObjects are objects whose name is qualified with schema.
+var schemaObjects = map[string]bool{
+	"TABLE": true, "VIEW": true, "TRIGGER": true, "PROCEDURE": true,
+	"FUNCTION": true, "EVENT": true, "SEQUENCE": true,
+}
+
+type sqlToken struct {
+	text   string
+	quoted bool // backtick quoted identifier
+}
+
+// sqlTokens splits query into words, quoted identifiers and
+// punctuations, skipping whitespace, comments and string literals.
+func sqlTokens(q string) []sqlToken {
+	var toks []sqlToken
+	for i := 0; i < len(q); {
+		c := q[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '#' || strings.HasPrefix(q[i:], "-- "):
+			if j := strings.IndexByte(q[i:], '\n'); j != -1 {
+				i += j + 1
+			} else {
+				i = len(q)
+			}
+		case strings.HasPrefix(q[i:], "/*"):
+			if j := strings.Index(q[i+2:], "*/"); j != -1 {
+				i += j + 4
+			} else {
+				i = len(q)
+			}
+		case c == '`':
+			var b strings.Builder
+			j := i + 1
+			for j < len(q) {
+				if q[j] == '`' {
+					if j+1 < len(q) && q[j+1] == '`' {
+						b.WriteByte('`')
+						j += 2
+						continue
+					}
+					break
+				}
+				b.WriteByte(q[j])
+				j++
+			}
+			toks = append(toks, sqlToken{b.String(), true})
+			i = j + 1
+		case c == '\'' || c == '"':
+			j := i + 1
+			for j < len(q) && q[j] != c {
+				if q[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			i = j + 1
+		case c == '_' || c == '$' || c >= 0x80 || ('0' <= c && c <= '9') || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z'):
+			j := i + 1
+			for j < len(q) {
+				c := q[j]
+				if c == '_' || c == '$' || c >= 0x80 || ('0' <= c && c <= '9') || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') {
+					j++
+					continue
+				}
+				break
+			}
+			toks = append(toks, sqlToken{text: q[i:j]})
+			i = j
+		default:
+			toks = append(toks, sqlToken{text: q[i : i+1]})
+			i++
+		}
+	}
+	return toks
+}
+
+type ddlParser struct {
+	toks   []sqlToken
+	schema string // default schema
+}
+
+func (p *ddlParser) done() bool {
+	return len(p.toks) == 0
+}
+
+func (p *ddlParser) next() string {
+	if p.done() {
+		return ""
+	}
+	t := p.toks[0]
+	p.toks = p.toks[1:]
+	return t.text
+}
+
+// skipWords skips leading unquoted words, that are in given list.
+// returns true if anything is skipped.
+func (p *ddlParser) skipWords(words ...string) bool {
+	skipped := false
+	for !p.done() && !p.toks[0].quoted {
+		found := false
+		for _, w := range words {
+			if strings.EqualFold(p.toks[0].text, w) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			break
+		}
+		p.toks, skipped = p.toks[1:], true
+	}
+	return skipped
+}
+
+func (p *ddlParser) skipPunct(s string) bool {
+	if !p.done() && !p.toks[0].quoted && p.toks[0].text == s {
+		p.toks = p.toks[1:]
+		return true
+	}
+	return false
+}
+
+// skipDefinerClauses skips ALGORITHM=x, DEFINER=user, SQL SECURITY x
+// which may appear before VIEW, TRIGGER etc.
+func (p *ddlParser) skipDefinerClauses() {
+	for !p.done() {
+		switch strings.ToUpper(p.toks[0].text) {
+		case "ALGORITHM":
+			p.toks = p.toks[1:]
+			p.skipPunct("=")
+			p.next()
+		case "DEFINER":
+			p.toks = p.toks[1:]
+			p.skipPunct("=")
+			p.next() // user
+			if p.skipPunct("@") {
+				p.next() // host
+			} else if p.skipPunct("(") {
+				p.skipPunct(")") // CURRENT_USER()
+			}
+		case "SQL":
+			p.toks = p.toks[1:]
+			p.skipWords("SECURITY")
+			p.next()
+		default:
+			return
+		}
+	}
+}
+
+func (p *ddlParser) ident() string {
+	return p.next()
+}
+
+// name parses [schema.]name
+func (p *ddlParser) name() ObjectName {
+	n := ObjectName{Schema: p.schema, Name: p.ident()}
+	if p.skipPunct(".") {
+		n.Schema, n.Name = n.Name, p.ident()
+	}
+	return n
+}
+
+// names parses comma separated list of names.
+func (p *ddlParser) names() []ObjectName {
+	var names []ObjectName
+	for !p.done() {
+		names = append(names, p.name())
+		if !p.skipPunct(",") {
+			break
+		}
+	}
+	return names
+}
+
+// ToCreateTable returns approximate CREATE TABLE statement for this
+// table, so that target tables can be provisioned. It needs column
+// names, which are available only if binlog_row_metadata=FULL.
+//
+// Only column types, character sets, nullability and primary key are
+// included. Defaults, auto increment, secondary indexes, comments and
+// table options are not available in binlog. See Column.SQLType for
+// limitations of column types.
+func (e TableMapEvent) ToCreateTable() (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "CREATE TABLE %s.%s (", quoteIdent(e.SchemaName), quoteIdent(e.TableName))
+	for i, col := range e.Columns {
+		if col.Name == "" {
+			return "", errNoColumnNames
+		}
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "\n  %s %s", quoteIdent(col.Name), col.SQLType())
+		if (col.Type.isString() || col.Type.isEnumSet()) && col.Charset != 63 {
+			if c, ok := collations[col.Charset]; ok {
+				fmt.Fprintf(&b, " CHARACTER SET %s COLLATE %s", c.charset, c.name)
+			}
+		}
+		if !col.Nullable {
+			b.WriteString(" NOT NULL")
+		}
+	}
+	if len(e.PrimaryKey) > 0 {
+		b.WriteString(",\n  PRIMARY KEY (")
+		for i, ord := range e.PrimaryKey {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(quoteIdent(e.Columns[ord].Name))
+			if e.PrimaryKeyPrefix[i] > 0 {
+				fmt.Fprintf(&b, "(%d)", e.PrimaryKeyPrefix[i])
+			}
+		}
+		b.WriteByte(')')
+	}
+	b.WriteString("\n)")
+	return b.String(), nil
+}
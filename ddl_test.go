@@ -0,0 +1,96 @@
+package binlog
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestQueryEvent_DDL(t *testing.T) {
+	testCases := []struct {
+		query    string
+		kind     string
+		object   string
+		targets  []ObjectName
+		newNames []ObjectName
+	}{
+		{"BEGIN", "", "", nil, nil},
+		{"insert into t values(1)", "", "", nil, nil},
+		{"CREATE TABLE t1 (id int)", "CREATE", "TABLE", []ObjectName{{"db", "t1"}}, nil},
+		{"create temporary table if not exists `my db`.`t``1` (id int)", "CREATE", "TABLE", []ObjectName{{"my db", "t`1"}}, nil},
+		{"/* comment */ ALTER TABLE db2.t1 ADD COLUMN c int", "ALTER", "TABLE", []ObjectName{{"db2", "t1"}}, nil},
+		{"ALTER TABLE t1 RENAME COLUMN a TO b, RENAME TO t2", "ALTER", "TABLE", []ObjectName{{"db", "t1"}}, []ObjectName{{"db", "t2"}}},
+		{"DROP TABLE IF EXISTS `t1`,db2.t2 /* generated by server */", "DROP", "TABLE", []ObjectName{{"db", "t1"}, {"db2", "t2"}}, nil},
+		{"TRUNCATE t1", "TRUNCATE", "TABLE", []ObjectName{{"db", "t1"}}, nil},
+		{"RENAME TABLE t1 TO _t1_old, _t1_gho TO t1", "RENAME", "TABLE", []ObjectName{{"db", "t1"}, {"db", "_t1_gho"}}, []ObjectName{{"db", "_t1_old"}, {"db", "t1"}}},
+		{"CREATE SCHEMA IF NOT EXISTS db3", "CREATE", "DATABASE", []ObjectName{{"db3", ""}}, nil},
+		{"create unique index idx on t1(c)", "CREATE", "INDEX", []ObjectName{{"db", "t1"}}, nil},
+		{"CREATE ALGORITHM=UNDEFINED DEFINER=`root`@`%` SQL SECURITY DEFINER VIEW v1 AS select 1", "CREATE", "VIEW", []ObjectName{{"db", "v1"}}, nil},
+		{"CREATE USER 'u'@'%' IDENTIFIED BY 'x'", "CREATE", "USER", nil, nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			ddl, ok := QueryEvent{Schema: "db", Query: tc.query}.DDL()
+			if ok != (tc.kind != "") {
+				t.Fatalf("ok: got %v", ok)
+			}
+			if !ok {
+				return
+			}
+			if ddl.Kind != tc.kind || ddl.Object != tc.object {
+				t.Errorf("got %s %s, want %s %s", ddl.Kind, ddl.Object, tc.kind, tc.object)
+			}
+			if !reflect.DeepEqual(ddl.Targets, tc.targets) {
+				t.Errorf("targets: got %v, want %v", ddl.Targets, tc.targets)
+			}
+			if !reflect.DeepEqual(ddl.NewNames, tc.newNames) {
+				t.Errorf("newNames: got %v, want %v", ddl.NewNames, tc.newNames)
+			}
+		})
+	}
+}
+
+func TestTableMapEvent_ToCreateTable(t *testing.T) {
+	w := newEncodedStream(4, true)
+	w.FormatDescription("8.0.23", 40, 1)
+	types := []byte{byte(TypeLong), byte(TypeVarchar), byte(TypeBlob)}
+	w.TableMap(100, "test", "t`1", types, []byte{0x50, 0, 2}, []byte{0x04},
+		TableMetadata{4, []byte{2, 'i', 'd', 4, 'n', 'a', 'm', 'e', 4, 'n', 'o', 't', 'e'}}, // column names
+		TableMetadata{2, []byte{0xfc, 0xff, 0x00, 2, 8}},                                    // default charset utf8mb4, note latin1
+		TableMetadata{9, []byte{0, 0, 1, 10}},                                               // primary key (id, name(10))
+	)
+
+	bl := NewReader(bytes.NewReader(w.Bytes()))
+	var tme TableMapEvent
+	for {
+		e, err := bl.NextEvent()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v, ok := e.Data.(TableMapEvent); ok {
+			tme = v
+			break
+		}
+	}
+	if !reflect.DeepEqual(tme.PrimaryKey, []int{0, 1}) || !reflect.DeepEqual(tme.PrimaryKeyPrefix, []int{0, 10}) {
+		t.Fatalf("primaryKey: got %v %v", tme.PrimaryKey, tme.PrimaryKeyPrefix)
+	}
+	got, err := tme.ToCreateTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "CREATE TABLE `test`.`t``1` (\n" +
+		"  `id` int NOT NULL,\n" +
+		"  `name` varchar(20) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL,\n" +
+		"  `note` text CHARACTER SET latin1 COLLATE latin1_swedish_ci,\n" +
+		"  PRIMARY KEY (`id`,`name`(10))\n" +
+		")"
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	tme.Columns[0].Name = ""
+	if _, err := tme.ToCreateTable(); err != errNoColumnNames {
+		t.Fatalf("got %v, want errNoColumnNames", err)
+	}
+}
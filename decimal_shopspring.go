@@ -0,0 +1,14 @@
+//go:build shopspring
+// +build shopspring
+
+package binlog
+
+// this file is compiled only with `-tags shopspring`.
+
+import "github.com/shopspring/decimal"
+
+// Shopspring returns the number as decimal.Decimal
+// from github.com/shopspring/decimal.
+func (d Decimal) Shopspring() (decimal.Decimal, error) {
+	return decimal.NewFromString(string(d))
+}
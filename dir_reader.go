@@ -4,32 +4,39 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
-	"time"
 )
 
 var fileHeader = []byte{0xfe, 'b', 'i', 'n'}
 
 type dirReader struct {
-	file     *os.File
+	fs       FS
+	file     File
+	path     string // slash separated path of file
 	name     *string
 	nonBlock bool
 	tmeCache map[uint64]*TableMapEvent
 	checksum int
+	rotated  bool // set when switched to next file
+	clock    Clock
+	backoff  Backoff
 }
 
-func newDirReader(dir string, file *string, pos uint32, nonBlock bool) (*dirReader, error) {
-	f, err := openBinlogFile(path.Join(dir, *file))
+func newDirReader(fsys FS, dir string, file *string, pos uint32, nonBlock bool, clock Clock, backoff Backoff) (*dirReader, error) {
+	name := path.Join(dir, *file)
+	f, err := openBinlogFile(fsys, name)
 	if err != nil {
 		return nil, err
 	}
 	checksum := 0
 	if pos > 4 {
 		// Decode FormatDescriptionEvent to find checksum.
-		v, err := findBinlogVersion(f.Name())
+		v, err := findBinlogVersion(fsys, name)
 		if err != nil {
 			return nil, err
 		}
@@ -48,11 +55,11 @@ func newDirReader(dir string, file *string, pos uint32, nonBlock bool) (*dirRead
 		_ = f.Close()
 		return nil, err
 	}
-	return &dirReader{f, file, nonBlock, make(map[uint64]*TableMapEvent), checksum}, nil
+	return &dirReader{fsys, f, name, file, nonBlock, make(map[uint64]*TableMapEvent), checksum, false, clockOrSystem(clock), backoff}, nil
 }
 
 func (r *dirReader) Read(p []byte) (int, error) {
-	delay := time.Second
+	attempt := 0
 	for {
 		n, err := r.file.Read(p)
 		if n > 0 {
@@ -69,23 +76,21 @@ func (r *dirReader) Read(p []byte) (int, error) {
 		}
 
 		// Check for next file.
-		next, err := nextBinlogFile(r.file.Name())
+		next, err := nextBinlogFile(r.fs, r.path)
 		if err != nil {
 			return 0, err
 		}
 		if next == "" {
-			if r.nonBlock {
+			if r.nonBlock || !r.wait(&attempt) {
 				return 0, io.EOF
 			}
-			time.Sleep(delay)
 			continue
 		}
-		if _, err = os.Stat(next); err != nil {
+		if _, err = r.fs.Stat(next); err != nil {
 			if os.IsNotExist(err) {
-				if r.nonBlock {
+				if r.nonBlock || !r.wait(&attempt) {
 					return 0, io.EOF
 				}
-				time.Sleep(delay)
 				continue
 			} else {
 				return 0, err
@@ -93,12 +98,13 @@ func (r *dirReader) Read(p []byte) (int, error) {
 		}
 
 		// Switch to next file.
-		f, err := openBinlogFile(next)
+		f, err := openBinlogFile(r.fs, next)
 		if err != nil {
 			return 0, err
 		}
 		_ = r.file.Close()
-		r.file = f
+		r.file, r.path = f, next
+		r.rotated = true
 		*r.name = path.Base(next)
 		for k := range r.tmeCache {
 			delete(r.tmeCache, k)
@@ -106,10 +112,25 @@ func (r *dirReader) Read(p []byte) (int, error) {
 	}
 }
 
+// wait waits for new events, before next attempt. returns
+// false if backoff gives up.
+func (r *dirReader) wait(attempt *int) bool {
+	*attempt++
+	b := r.backoff
+	if b == nil {
+		b = pollBackoff
+	}
+	d, ok := b.Next(*attempt)
+	if ok {
+		r.clock.Sleep(d)
+	}
+	return ok
+}
+
 // openBinlogFile opens file and seeks location
 // to just after the magic header.
-func openBinlogFile(file string) (*os.File, error) {
-	f, err := os.Open(file)
+func openBinlogFile(fsys FS, file string) (File, error) {
+	f, err := fsys.Open(file)
 	if err != nil {
 		return nil, err
 	}
@@ -129,19 +150,95 @@ func openBinlogFile(file string) (*os.File, error) {
 // nextBinlogFile returns next file given current file
 // by reading '.next' file. returns nil if next file
 // does not exist.
-func nextBinlogFile(name string) (string, error) {
-	dir, file := path.Split(name)
-	f, err := os.Open(path.Join(dir, file+".next"))
+//
+// if '.next' files are not used in dir, next file is
+// found using indexFiles.
+func nextBinlogFile(fsys FS, name string) (string, error) {
+	dir, file := path.Dir(name), path.Base(name)
+	buff, err := readFile(fsys, path.Join(dir, file+".next"))
 	if err != nil {
-		if os.IsNotExist(err) {
-			return "", nil
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		if _, err := fsys.Stat(path.Join(dir, ".next")); !os.IsNotExist(err) {
+			return "", err
+		}
+		files, err := indexFiles(fsys, dir)
+		if err != nil {
+			return "", err
+		}
+		for i := 0; i+1 < len(files); i++ {
+			if files[i] == file {
+				return path.Join(dir, files[i+1]), nil
+			}
 		}
-		return "", err
+		return "", nil
 	}
-	defer f.Close()
-	buff, err := ioutil.ReadAll(f)
+	return path.Join(dir, strings.TrimSpace(string(buff))), nil
+}
+
+// indexFiles lists binlog files in dir, which is not created by Dump.
+// It reads the mysql index file(*.index) if present, otherwise lists
+// files with names of the form basename.NNNNNN in order of sequence
+// number. Relay logs with default names, such as host-relay-bin.000001
+// and host-relay-bin.index, are not listed.
+func indexFiles(fsys FS, dir string) ([]string, error) {
+	matches, err := glob(fsys, dir, "*.index")
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return path.Join(dir, strings.TrimSpace(string(buff))), nil
+	var indexes []string
+	for _, index := range matches {
+		if !isRelayLog(path.Base(index)) {
+			indexes = append(indexes, index)
+		}
+	}
+	if len(indexes) == 1 {
+		buf, err := readFile(fsys, indexes[0])
+		if err != nil {
+			return nil, err
+		}
+		var files []string
+		for _, line := range strings.Split(string(buf), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				files = append(files, path.Base(filepath.ToSlash(line)))
+			}
+		}
+		return files, nil
+	}
+	infos, err := fsys.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, info := range infos {
+		if !info.IsDir() && binlogFileName.MatchString(info.Name()) && !isRelayLog(info.Name()) {
+			files = append(files, info.Name())
+		}
+	}
+	// lexical order is wrong, once sequence number exceeds 6 digits
+	sort.Slice(files, func(i, j int) bool {
+		return Position{File: files[i]}.Less(Position{File: files[j]})
+	})
+	return files, nil
+}
+
+// isRelayLog tells whether name is of relay log file or its index,
+// with default relay_log name.
+func isRelayLog(name string) bool {
+	return strings.Contains(name, "-relay-bin.")
+}
+
+// binlogFileName matches names of binlog files, such as binlog.000001.
+var binlogFileName = regexp.MustCompile(`^.+\.[0-9]{6,}$`)
+
+func (r *dirReader) filePos() (pos, size int64, err error) {
+	if pos, err = r.file.Seek(0, io.SeekCurrent); err != nil {
+		return 0, 0, err
+	}
+	fi, err := r.file.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+	return pos, fi.Size(), nil
 }
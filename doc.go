@@ -65,10 +65,15 @@ to get binlog events from server:
 		}
 	}
 
+the user needs REPLICATION SLAVE privilege to get binlog events, and
+REPLICATION CLIENT privilege to list binlog files. see PermissionError
+and Remote.SetMinimalPrivileges.
+
 this package also supports the following:
-	- dump to local directory
-	- resume dump from where it left
-	- read binlog files from dump directory as if it is server
+  - dump to local directory
+  - resume dump from where it left
+  - read binlog files from dump directory as if it is server
+  - read raw binlog byte stream from io.Reader
 
 for example usage see cmd/binlog/main.go
 */
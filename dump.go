@@ -3,36 +3,163 @@ package binlog
 import (
 	"bytes"
 	"encoding/binary"
-	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
+	"time"
 )
 
+// Dump writes binlog events requested by Seek, to given dump directory,
+// which can be read using Local.
 func (bl *Remote) Dump(dir string) error {
-	local, err := Open(dir)
+	return bl.DumpFS(OSFS, filepath.ToSlash(dir))
+}
+
+// DumpedFile describes binary log file, completely written by Dump.
+// see SetDumpFileFuncs.
+type DumpedFile struct {
+	Name     string
+	Size     int64
+	SHA256   string        // hex encoded, as in ManifestEntry
+	Duration time.Duration // since Dump started writing the file
+}
+
+// SetDumpFileFuncs sets functions called by Dump, when it starts writing
+// a binary log file, and when it rotates to next file, after completely
+// writing the file, so that files can be compressed, uploaded or
+// verified as soon as possible. Error returned by complete is returned
+// by Dump. Either can be nil.
+func (bl *Remote) SetDumpFileFuncs(start func(file string), complete func(f DumpedFile) error) {
+	bl.dumpFileStart, bl.dumpFileComplete = start, complete
+}
+
+// dumpProgressInterval is number of bytes dumped, after which .progress
+// file of dump directory is updated. see ResumeDump.
+var dumpProgressInterval uint32 = 1 << 20
+
+// DumpFS is like Dump, but writes to dump directory in given file system.
+func (bl *Remote) DumpFS(fsys FS, dir string) error {
+	return bl.dumpFS(fsys, dir, bl.requestPos)
+}
+
+// SetDumpIgnoredEvents sets types of events not written by Dump, such as
+// ROWS_QUERY_EVENT or PREVIOUS_GTIDS_EVENT, to reduce size of dump or to
+// keep queries out of it. Positions in headers of events following
+// ignored events are rewritten, so that dumped files remain valid.
+// nil means UNKNOWN_EVENT, SLAVE_EVENT and IGNORABLE_EVENT, which is
+// the default. HEARTBEAT_EVENT is always ignored, as it is not part of
+// binlog. Only events not needed to decode others can be ignored, i.e.
+// the default ones, ROWS_QUERY_EVENT and PREVIOUS_GTIDS_EVENT.
+//
+// Once events are ignored, positions in dumped files no longer match
+// those of server. Such dump must be resumed only by ResumeDump, which
+// maps them using .progress file, and not by seeking to MasterStatus
+// of Local. Ignoring PREVIOUS_GTIDS_EVENT also disables Local.SeekGTID
+// on dumped files, as it finds the file to start from using it.
+func (bl *Remote) SetDumpIgnoredEvents(types []EventType) error {
+	for _, t := range types {
+		switch t {
+		case UNKNOWN_EVENT, SLAVE_EVENT, IGNORABLE_EVENT, ROWS_QUERY_EVENT, PREVIOUS_GTIDS_EVENT:
+		default:
+			return fmt.Errorf("binlog: %s cannot be ignored by dump", t)
+		}
+	}
+	bl.dumpIgnored = types
+	return nil
+}
+
+var defaultDumpIgnored = []EventType{UNKNOWN_EVENT, SLAVE_EVENT, IGNORABLE_EVENT}
+
+// dumpFS writes events to dump directory. localPos is the position in
+// dumped file, corresponding to requestPos, which differ if events
+// before it are ignored.
+func (bl *Remote) dumpFS(fsys FS, dir string, localPos uint32) (err error) {
+	if !bl.stop.begin() {
+		return ErrStopped
+	}
+	defer bl.stop.end()
+	span := startSpan(bl.spanCtx, bl.spans, SpanDump)
+	span.SetAttribute(AttrFile, bl.requestFile)
+	span.SetAttribute(AttrPos, int64(bl.requestPos))
+	defer func() { endSpan(span, err) }()
+	local, err := OpenFS(fsys, dir)
 	if err != nil {
 		return err
 	}
+	local.SetClock(bl.opts.clock)
 	v, err := bl.binlogVersion()
 	if err != nil {
 		return err
 	}
-	var f *os.File
+	ignored := bl.dumpIgnored
+	if ignored == nil {
+		ignored = defaultDumpIgnored
+	}
+	redactor := newRedactor(bl.dumpRedaction)
+	var f File
+	var fileName string
+	var pos uint32     // position after last complete event in f
+	var saved uint32   // pos recorded in .progress file
+	var skipped uint32 // size of events ignored in f, i.e. server position is pos+skipped
+	var started time.Time
+	clock := clockOrSystem(bl.opts.clock)
+	saveProgress := func() error {
+		saved = pos
+		progress := fmt.Sprintf("%s %d\n", fileName, pos)
+		if skipped != 0 {
+			progress = fmt.Sprintf("%s %d %d\n", fileName, pos, pos+skipped)
+		}
+		return writeFileAtomic(fsys, path.Join(dir, ".progress"), []byte(progress))
+	}
 	defer func() {
+		if err != nil {
+			// stream is left within an event, so it can not continue
+			bl.setStreaming(false)
+		}
+		if err != nil && bl.stop.isStopped() {
+			err = ErrStopped
+			if f != nil {
+				// discard partially written event
+				if t, ok := f.(interface{ Truncate(int64) error }); ok {
+					_ = t.Truncate(int64(pos))
+				}
+				if s, ok := f.(interface{ Sync() error }); ok {
+					_ = s.Sync()
+				}
+			}
+		}
 		if f != nil {
 			_ = f.Close()
+			_ = saveProgress()
 		}
 	}()
 	// ignore FormatDescriptionEvent if it is not the first event in file
 	ignoreFME := bl.requestPos > 4
-	buf := make([]byte, 14)
+	var limiter *rateLimiter
+	if bl.dumpRate > 0 {
+		limiter = newRateLimiter(bl.dumpRate, bl.dumpBurst, clock)
+	}
+	hlen := 13 // size of event header read
+	if v > 1 {
+		hlen = 19
+	}
+	buf := make([]byte, 1+hlen)
 	for {
-		pr := &packetReader{rd: bl.conn, seq: &bl.seq}
+		if bl.stop.isStopped() {
+			return ErrStopped
+		}
+		var rd io.Reader = bl.rw()
+		if limiter != nil {
+			rd = limitedReader{rd, limiter}
+		}
+		pr := &packetReader{rd: rd, seq: &bl.seq}
+		buf = buf[:1+hlen]
 		if n, err := io.ReadFull(pr, buf); err != nil {
-			if err != io.ErrUnexpectedEOF { // non-ok packets can have size <14
+			if err != io.ErrUnexpectedEOF { // non-ok packets can have size <1+hlen
 				return err
 			}
 			buf = buf[:n]
@@ -48,78 +175,265 @@ func (bl *Remote) Dump(dir string) error {
 				if err := ep.decode(r, bl.hs.capabilityFlags); err != nil {
 					return err
 				}
-				return errors.New(ep.errorMessage)
+				bl.setStreaming(false)
+				return ep.error("COM_BINLOG_DUMP")
 			case eofMarker:
 				ep := eofPacket{}
 				if err := ep.decode(r, bl.hs.capabilityFlags); err != nil {
 					return err
 				}
+				bl.setStreaming(false)
 				return io.EOF
 			}
 			return fmt.Errorf("binlog.Dump: got %0x want OK-byte", buf[0])
 		}
-		if len(buf) != 14 {
+		if len(buf) != 1+hlen {
 			return io.ErrUnexpectedEOF
 		}
-		// Timestamp = buf[1:5]
-		eventType := EventType(buf[5])
-		// ServerID = buf[6:10]
-		eventSize := binary.LittleEndian.Uint32(buf[10:])
-		//fmt.Printf("EventType: %s EventSize: 0x%02x\n", eventType, eventSize)
+		header := buf[1 : 1+hlen : 1+hlen]
+		// Timestamp = header[0:4]
+		eventType := EventType(header[4])
+		// ServerID = header[5:9]
+		eventSize := binary.LittleEndian.Uint32(header[9:])
+		rest := io.LimitReader(pr, int64(eventSize)-int64(hlen))
 		switch eventType {
 		case ROTATE_EVENT:
-			lr := io.LimitReader(pr, int64(eventSize-13))
-			buf, err := ioutil.ReadAll(lr)
+			buf, err := ioutil.ReadAll(rest)
 			if err != nil {
 				return err
 			}
 			if v > 1 {
-				buf = buf[4+2+8 : len(buf)-bl.checksum] // skip EventHeader{LogPos, Flags}, RotateEvent.position
+				if bl.checksum < 0 {
+					bl.checksum = 0
+					if hasChecksum(append(header, buf...)) {
+						bl.checksum = 4
+					}
+				}
+				buf = buf[8 : len(buf)-bl.checksum] // skip RotateEvent.position
 			}
 			if f != nil {
 				if err := f.Close(); err != nil {
 					return err
 				}
 			}
-			fileName := string(buf)
-			pos := bl.requestPos
+			prevFile := fileName
+			fileName = string(buf)
+			pos, skipped = localPos, bl.requestPos-localPos
 			if bl.requestFile != fileName {
 				ignoreFME = false
-				pos = 4
+				pos, skipped = 4, 0
 			}
 			if err := local.addFile(fileName); err != nil {
 				return err
 			}
-			f, err = os.OpenFile(path.Join(dir, fileName), os.O_RDWR, 0)
+			if prevFile != "" && prevFile != fileName {
+				e, err := addManifestEntry(fsys, dir, prevFile, clock)
+				if err != nil {
+					return err
+				}
+				if bl.dumpFileComplete != nil {
+					if err := bl.dumpFileComplete(DumpedFile{e.File, e.Size, e.SHA256, clock.Now().Sub(started)}); err != nil {
+						return err
+					}
+				}
+			}
+			f, err = fsys.OpenFile(path.Join(dir, fileName), os.O_RDWR, 0)
 			if err != nil {
 				return err
 			}
 			if _, err := f.Seek(int64(pos), io.SeekStart); err != nil {
 				return err
 			}
+			if err := saveProgress(); err != nil {
+				return err
+			}
+			bl.stop.advance(fileName, pos+skipped)
+			if prevFile != fileName {
+				started = clock.Now()
+				if bl.dumpFileStart != nil {
+					bl.dumpFileStart(fileName)
+				}
+			}
 		default:
 			var ignore bool
+			// event is part of binlog file, unless artificial
+			inFile := v > 1 && binary.LittleEndian.Uint16(header[17:])&LOG_EVENT_ARTIFICIAL_F == 0
 			switch eventType {
-			case HEARTBEAT_EVENT, UNKNOWN_EVENT, SLAVE_EVENT, IGNORABLE_EVENT:
+			case HEARTBEAT_EVENT:
 				ignore = true
 			case FORMAT_DESCRIPTION_EVENT:
 				ignore = ignoreFME
 				ignoreFME = false
+			default:
+				for _, t := range ignored {
+					if t == eventType {
+						ignore = true
+						if inFile {
+							skipped += eventSize
+						}
+						break
+					}
+				}
 			}
 			if ignore {
-				fmt.Println("ignoring...")
 				if _, err := io.Copy(ioutil.Discard, pr); err != nil {
 					return err
 				}
+				continue
+			}
+			var e []byte // event read, to rewrite it
+			if inFile && redactor.inspects(eventType) {
+				if e, err = readEvent(header, rest); err != nil {
+					return err
+				}
+				if e, err = redactor.redact(e, bl.checksum, pos); err != nil {
+					return err
+				}
+				if e == nil {
+					skipped += eventSize
+					if err := redactor.moveStmtEnd(f); err != nil {
+						return err
+					}
+					continue
+				}
+			} else if skipped != 0 && binary.LittleEndian.Uint32(header[13:]) != 0 {
+				// rewrite position, as events are ignored before it
+				if e, err = readEvent(header, rest); err != nil {
+					return err
+				}
+			}
+			if e != nil {
+				skipped += eventSize - uint32(len(e))
+				if err := writeEvent(f, e, skipped, bl.checksum); err != nil {
+					return err
+				}
+				eventSize = uint32(len(e))
 			} else {
-				lr := io.LimitReader(pr, int64(eventSize-13))
-				if _, err := f.Write(buf[1:]); err != nil {
+				if _, err := f.Write(header); err != nil {
 					return err
 				}
-				if _, err := io.Copy(f, lr); err != nil {
+				if _, err := io.Copy(f, rest); err != nil {
 					return err
 				}
 			}
+			pos += eventSize
+			bl.stop.advance(fileName, pos+skipped)
+			if pos-saved >= dumpProgressInterval {
+				if err := saveProgress(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// readEvent reads event with given header and rest.
+func readEvent(header []byte, rest io.Reader) ([]byte, error) {
+	body, err := ioutil.ReadAll(rest)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte(nil), header...), body...), nil
+}
+
+// writeEvent writes v4 event e, updating its size, and its position
+// by subtracting skipped, and recomputing its checksum if any.
+func writeEvent(f io.Writer, e []byte, skipped uint32, checksum int) error {
+	binary.LittleEndian.PutUint32(e[9:], uint32(len(e)))
+	if logPos := binary.LittleEndian.Uint32(e[13:]); logPos != 0 {
+		binary.LittleEndian.PutUint32(e[13:], logPos-skipped)
+	}
+	if checksum > 0 && len(e) >= 4 {
+		binary.LittleEndian.PutUint32(e[len(e)-4:], crc32.ChecksumIEEE(e[:len(e)-4]))
+	}
+	_, err := f.Write(e)
+	return err
+}
+
+// ResumeDump seeks to where previous Dump into given dump directory
+// stopped, and resumes it, even if it was interrupted in the middle of
+// an event, without downloading the current file from its beginning.
+//
+// Dump records position of the last complete event in .progress file
+// of dump directory, at least once every MiB. ResumeDump scans events
+// from that position, and truncates any partial event at the end. The
+// partial event is downloaded again, as its bytes are not kept aside.
+// If .progress is beyond the end of file, as data written before a
+// crash may not be synced, events are scanned from the beginning of
+// file. If the directory has no .progress file, it resumes from
+// MasterStatus of Local. It returns error if dump directory has no
+// files.
+func (bl *Remote) ResumeDump(serverID uint32, dir string) error {
+	return bl.ResumeDumpFS(serverID, OSFS, filepath.ToSlash(dir))
+}
+
+// ResumeDumpFS is like ResumeDump, but uses dump directory in given
+// file system.
+func (bl *Remote) ResumeDumpFS(serverID uint32, fsys FS, dir string) error {
+	file, pos, serverPos, err := dumpProgress(fsys, dir)
+	if err != nil {
+		return err
+	}
+	if file == "" {
+		return fmt.Errorf("binlog: no dump to resume in %s", dir)
+	}
+	if err := bl.Seek(serverID, file, serverPos); err != nil {
+		return err
+	}
+	return bl.dumpFS(fsys, dir, pos)
+}
+
+// dumpProgress returns position after the last complete event in dump
+// directory, truncating partial event after it. serverPos is the
+// corresponding position on server, which differs from pos if Dump
+// ignored events in file.
+func dumpProgress(fsys FS, dir string) (file string, pos, serverPos uint32, err error) {
+	pos = 4
+	buf, err := readFile(fsys, path.Join(dir, ".progress"))
+	switch {
+	case err == nil:
+		n, err := fmt.Sscanf(string(buf), "%s %d %d", &file, &pos, &serverPos)
+		if n < 2 {
+			return "", 0, 0, fmt.Errorf("binlog: invalid .progress file in %s: %v", dir, err)
+		}
+	case os.IsNotExist(err):
+		files, err := (&Local{fs: fsys, dir: dir}).ListFiles()
+		if err != nil || len(files) == 0 {
+			return "", 0, 0, err
+		}
+		file = files[len(files)-1]
+	default:
+		return "", 0, 0, err
+	}
+	f, err := fsys.OpenFile(path.Join(dir, file), os.O_RDWR, 0)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return "", 0, 0, err
+	}
+	// after crash, .progress may be ahead of data synced to file
+	ahead := int64(pos) > fi.Size()
+	switch {
+	case serverPos == 0 || serverPos == pos:
+		// no events ignored: resume after last complete event
+		if ahead {
+			pos = 4
+		}
+		if pos, err = eventsEnd(f, pos); err != nil {
+			return "", 0, 0, err
+		}
+		serverPos = pos
+	case ahead:
+		// server positions of events before pos are not known
+		pos, serverPos = 4, 4
+	}
+	if t, ok := f.(interface{ Truncate(int64) error }); ok {
+		if err := t.Truncate(int64(pos)); err != nil {
+			return "", 0, 0, err
 		}
 	}
+	return file, pos, serverPos, nil
 }
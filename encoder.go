@@ -0,0 +1,263 @@
+package binlog
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+)
+
+// EventEncoder writes binlog events to binlog byte stream, as written
+// by MySQL server to binlog file, computing their sizes, positions and
+// checksums. It is meant for fabricating binlog streams for tests and
+// benchmarks, without MySQL server. see generator package.
+//
+// Event bodies are encoded as given, without validation. Once a write
+// fails, subsequent writes return the same error.
+type EventEncoder struct {
+	ServerID  uint32 // in header of events written
+	Timestamp uint32 // in header of events written
+
+	// Flags in header of events written. Events with
+	// LOG_EVENT_ARTIFICIAL_F are not part of binlog file, so their
+	// position is zero, and they do not advance Pos.
+	Flags uint16
+
+	w        io.Writer
+	version  uint16
+	checksum bool
+	pos      uint32
+	err      error
+}
+
+// NewEventEncoder returns EventEncoder writing events of given binlog
+// version, which is 1, 3 or 4, to w. It writes binlog magic header
+// first. If checksum is true, crc32 checksum is appended to each event.
+func NewEventEncoder(w io.Writer, version uint16, checksum bool) *EventEncoder {
+	enc := &EventEncoder{w: w, version: version, checksum: checksum, pos: 4}
+	_, enc.err = w.Write(fileHeader)
+	return enc
+}
+
+// Pos returns position of next event.
+func (enc *EventEncoder) Pos() uint32 {
+	return enc.pos
+}
+
+// SetPos sets position of next event, such as after bytes written
+// directly to underlying writer.
+func (enc *EventEncoder) SetPos(pos uint32) {
+	enc.pos = pos
+}
+
+// Event writes event of given type, with body as concatenation of
+// given parts.
+func (enc *EventEncoder) Event(typ EventType, body ...[]byte) error {
+	if enc.err != nil {
+		return enc.err
+	}
+	headerSize := 19
+	if enc.version == 1 {
+		headerSize = 13
+	}
+	size := headerSize
+	for _, b := range body {
+		size += len(b)
+	}
+	if enc.checksum {
+		size += 4
+	}
+	artificial := enc.Flags&LOG_EVENT_ARTIFICIAL_F != 0
+	e := make([]byte, headerSize, size)
+	binary.LittleEndian.PutUint32(e, enc.Timestamp)
+	e[4] = byte(typ)
+	binary.LittleEndian.PutUint32(e[5:], enc.ServerID)
+	binary.LittleEndian.PutUint32(e[9:], uint32(size))
+	if enc.version > 1 {
+		if !artificial {
+			binary.LittleEndian.PutUint32(e[13:], enc.pos+uint32(size))
+		}
+		binary.LittleEndian.PutUint16(e[17:], enc.Flags)
+	}
+	for _, b := range body {
+		e = append(e, b...)
+	}
+	if enc.checksum {
+		e = append(e, 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(e[len(e)-4:], crc32.ChecksumIEEE(e[:len(e)-4]))
+	}
+	if _, enc.err = enc.w.Write(e); enc.err != nil {
+		return enc.err
+	}
+	if !artificial {
+		enc.pos += uint32(size)
+	}
+	return nil
+}
+
+func serverVersionBytes(v string) []byte {
+	b := make([]byte, 50)
+	copy(b, v)
+	return b
+}
+
+// StartV3 writes START_EVENT_V3, used by binlog versions 1 and 3.
+func (enc *EventEncoder) StartV3(serverVersion string) error {
+	return enc.Event(START_EVENT_V3, []byte{byte(enc.version), 0}, serverVersionBytes(serverVersion), make([]byte, 4))
+}
+
+// FormatDescription writes FormatDescriptionEvent, with post-header
+// lengths of first numTypes event types. checksumAlg is 0 for none and
+// 1 for crc32. -1 omits it, as do servers older than MySQL 5.6.1.
+func (enc *EventEncoder) FormatDescription(serverVersion string, numTypes int, checksumAlg int) error {
+	lengths := make([]byte, numTypes)
+	set := func(t EventType, n byte) {
+		if int(t) <= numTypes {
+			lengths[t-1] = n
+		}
+	}
+	set(QUERY_EVENT, 13)
+	set(ROTATE_EVENT, 8)
+	set(FORMAT_DESCRIPTION_EVENT, byte(2+50+4+1+numTypes))
+	set(TABLE_MAP_EVENT, 8)
+	for _, t := range []EventType{WRITE_ROWS_EVENTv1, UPDATE_ROWS_EVENTv1, DELETE_ROWS_EVENTv1} {
+		set(t, 8)
+	}
+	for _, t := range []EventType{WRITE_ROWS_EVENTv2, UPDATE_ROWS_EVENTv2, DELETE_ROWS_EVENTv2} {
+		set(t, 10)
+	}
+	var alg []byte
+	if checksumAlg >= 0 {
+		alg = []byte{byte(checksumAlg)}
+	}
+	return enc.Event(FORMAT_DESCRIPTION_EVENT, []byte{4, 0}, serverVersionBytes(serverVersion), make([]byte, 4), []byte{19}, lengths, alg)
+}
+
+// Rotate writes RotateEvent to next binlog file at pos.
+func (enc *EventEncoder) Rotate(next string, pos uint64) error {
+	b := make([]byte, 8, 8+len(next))
+	binary.LittleEndian.PutUint64(b, pos)
+	return enc.Event(ROTATE_EVENT, append(b, next...))
+}
+
+// Query writes QueryEvent of query, executed in schema.
+func (enc *EventEncoder) Query(schema, query string) error {
+	postHeader := []byte{
+		1, 0, 0, 0, // thread id
+		0, 0, 0, 0, // execution time
+		byte(len(schema)),
+		0, 0, // error code
+	}
+	if enc.version >= 4 {
+		postHeader = append(postHeader, 0, 0) // status vars length
+	}
+	return enc.Event(QUERY_EVENT, postHeader, []byte(schema), []byte{0}, []byte(query))
+}
+
+// XID writes XIDEvent, committing transaction.
+func (enc *EventEncoder) XID(xid uint64) error {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, xid)
+	return enc.Event(XID_EVENT, b)
+}
+
+// GTID writes GTIDEvent e.
+func (enc *EventEncoder) GTID(e GTIDEvent) error {
+	b := make([]byte, 1+16+8+1+16)
+	b[0] = e.Flags
+	copy(b[1:], e.SID[:])
+	binary.LittleEndian.PutUint64(b[17:], uint64(e.GNO))
+	b[25] = 2 // logical timestamp typecode
+	binary.LittleEndian.PutUint64(b[26:], uint64(e.LastCommitted))
+	binary.LittleEndian.PutUint64(b[34:], uint64(e.SequenceNumber))
+	return enc.Event(GTID_EVENT, b)
+}
+
+// PreviousGTIDs writes PREVIOUS_GTIDS_EVENT with given GTIDSet.
+func (enc *EventEncoder) PreviousGTIDs(set GTIDSet) error {
+	return enc.Event(PREVIOUS_GTIDS_EVENT, set.encode())
+}
+
+// TableMetadata is a field of optional metadata of TableMapEvent,
+// logged as per binlog_row_metadata, such as column names of Type 4.
+//
+// https://dev.mysql.com/worklog/task/?id=4618
+type TableMetadata struct {
+	Type  byte
+	Value []byte
+}
+
+// TableMap writes TableMapEvent of a table, whose columns have given
+// types, metadata and nullable bitmap, as encoded in the event.
+func (enc *EventEncoder) TableMap(tableID uint64, schema, table string, types, meta, nullable []byte, optional ...TableMetadata) error {
+	var b []byte
+	b = appendUint48(b, tableID)
+	b = append(b, 1, 0) // flags
+	b = append(b, byte(len(schema)))
+	b = append(b, schema...)
+	b = append(b, 0, byte(len(table)))
+	b = append(b, table...)
+	b = append(b, 0)
+	b = appendPacked(b, uint64(len(types)))
+	b = append(b, types...)
+	b = appendPacked(b, uint64(len(meta)))
+	b = append(b, meta...)
+	b = append(b, nullable...)
+	for _, m := range optional {
+		b = append(b, m.Type)
+		b = appendPacked(b, uint64(len(m.Value)))
+		b = append(b, m.Value...)
+	}
+	return enc.Event(TABLE_MAP_EVENT, b)
+}
+
+// Rows writes rows event of given type, with all numCol columns present.
+// rows are row images as encoded in the event, i.e. null bitmap followed
+// by values. For update events, before and after images alternate.
+// stmtEnd marks last rows event of a statement.
+func (enc *EventEncoder) Rows(typ EventType, tableID uint64, stmtEnd bool, numCol int, rows ...[]byte) error {
+	var b []byte
+	b = appendUint48(b, tableID)
+	if stmtEnd {
+		b = append(b, rowsEventStmtEnd, 0)
+	} else {
+		b = append(b, 0, 0)
+	}
+	switch typ {
+	case WRITE_ROWS_EVENTv2, UPDATE_ROWS_EVENTv2, DELETE_ROWS_EVENTv2:
+		b = append(b, 2, 0) // extra data length
+	}
+	b = appendPacked(b, uint64(numCol))
+	present := make([]byte, (numCol+7)/8)
+	for i := 0; i < numCol; i++ {
+		present[i/8] |= 1 << uint(i%8)
+	}
+	b = append(b, present...)
+	if typ.IsUpdateRows() {
+		b = append(b, present...)
+	}
+	for _, row := range rows {
+		b = append(b, row...)
+	}
+	return enc.Event(typ, b)
+}
+
+func appendUint48(b []byte, v uint64) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24), byte(v>>32), byte(v>>40))
+}
+
+// appendPacked appends length encoded integer.
+//
+// https://dev.mysql.com/doc/internals/en/integer.html#length-encoded-integer
+func appendPacked(b []byte, v uint64) []byte {
+	switch {
+	case v < 251:
+		return append(b, byte(v))
+	case v < 1<<16:
+		return append(b, 0xfc, byte(v), byte(v>>8))
+	case v < 1<<24:
+		return append(b, 0xfd, byte(v), byte(v>>8), byte(v>>16))
+	}
+	b = append(b, 0xfe, 0, 0, 0, 0, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint64(b[len(b)-8:], v)
+	return b
+}
@@ -0,0 +1,209 @@
+package binlog
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Lookup appends columns to rows of Table, selected by Query, such as
+// from a dimension table:
+//
+//	Lookup{
+//	    Table:   "shop.orders",
+//	    DB:      db,
+//	    Query:   "select name, country from shop.customers where id=?",
+//	    Args:    []string{"customer_id"},
+//	    Columns: []string{"customer_name", "customer_country"},
+//	}
+//
+// Query must select len(Columns) values, and is passed values of Args
+// columns of the row. Only the first row selected is used. If no row
+// is selected, appended values are nil.
+type Lookup struct {
+	Table   string   // as "schema.table"
+	DB      *sql.DB  // source, or another database
+	Query   string   // with a placeholder for each of Args
+	Args    []string // columns of row, passed to Query
+	Columns []string // names of appended columns
+
+	CacheSize int           // results cached, by Args values. defaults to 1000
+	Timeout   time.Duration // of each query. zero means no timeout
+
+	cache *lruCache
+}
+
+// Enricher appends columns to rows of RowsEvents, as per Lookups, so
+// that changes carry related data without consumers querying for it.
+// Appended columns are in Columns of the RowsEvent returned, with
+// ordinals following columns of the table, and type TypeVarString.
+// Their values are as returned by database/sql, with []byte converted
+// to string.
+//
+// Args columns are matched by name, so binlog_row_metadata=FULL is
+// required. Results are cached, so they may be stale.
+type Enricher struct {
+	Src     EventSource
+	Lookups []*Lookup
+
+	lookups []*Lookup // of current RowsEvent
+	args    [][]int   // index of Args of each lookup, in row image
+}
+
+// NextEvent returns next event from Src.
+func (en *Enricher) NextEvent() (Event, error) {
+	en.lookups, en.args = en.lookups[:0], en.args[:0]
+	e, err := en.Src.NextEvent()
+	if err != nil {
+		return e, err
+	}
+	re, ok := e.Data.(RowsEvent)
+	if !ok || re.TableMap == nil {
+		return e, nil
+	}
+	table := re.TableMap.SchemaName + "." + re.TableMap.TableName
+	cols := re.Columns()
+	merged := cols
+	for _, l := range en.Lookups {
+		if !strings.EqualFold(l.Table, table) {
+			continue
+		}
+		var idx []int
+		for _, arg := range l.Args {
+			i := -1
+			for j, col := range cols {
+				if strings.EqualFold(col.Name, arg) {
+					i = j
+					break
+				}
+			}
+			if i == -1 {
+				return e, fmt.Errorf("binlog: lookup column %s not in row image of %s", arg, table)
+			}
+			idx = append(idx, i)
+		}
+		if len(en.lookups) == 0 {
+			merged = append([]Column(nil), cols...)
+		}
+		for _, name := range l.Columns {
+			merged = append(merged, Column{
+				Ordinal:  len(re.TableMap.Columns) + len(merged) - len(cols),
+				Type:     TypeVarString,
+				Nullable: true,
+				Name:     name,
+			})
+		}
+		en.lookups = append(en.lookups, l)
+		en.args = append(en.args, idx)
+	}
+	if len(en.lookups) == 0 {
+		return e, nil
+	}
+	columns := append([][]Column(nil), re.columns...)
+	if e.Header.EventType.IsUpdateRows() {
+		columns[1] = merged
+	} else {
+		columns[0] = merged
+	}
+	re.columns = columns
+	e.Data = re
+	return e, nil
+}
+
+// NextRow returns next row of current RowsEvent, with columns appended.
+// Before images of updates are not enriched.
+func (en *Enricher) NextRow() (values []interface{}, valuesBeforeUpdate []interface{}, err error) {
+	values, valuesBeforeUpdate, err = en.Src.NextRow()
+	if err != nil || len(en.lookups) == 0 {
+		return
+	}
+	row := append([]interface{}(nil), values...)
+	for i, l := range en.lookups {
+		args := make([]interface{}, len(en.args[i]))
+		for j, idx := range en.args[i] {
+			args[j] = values[idx]
+		}
+		vals, err := l.lookup(args)
+		if err != nil {
+			return nil, nil, err
+		}
+		row = append(row, vals...)
+	}
+	return row, valuesBeforeUpdate, nil
+}
+
+// lookup returns values selected for args, from cache if possible.
+func (l *Lookup) lookup(args []interface{}) ([]interface{}, error) {
+	if l.cache == nil {
+		size := l.CacheSize
+		if size <= 0 {
+			size = 1000
+		}
+		l.cache = newLRUCache(size)
+	}
+	key := fmt.Sprintf("%#v", args)
+	if vals, ok := l.cache.get(key); ok {
+		return vals, nil
+	}
+	ctx := context.Background()
+	if l.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.Timeout)
+		defer cancel()
+	}
+	vals := make([]interface{}, len(l.Columns))
+	dest := make([]interface{}, len(vals))
+	for i := range vals {
+		dest[i] = &vals[i]
+	}
+	err := l.DB.QueryRowContext(ctx, l.Query, args...).Scan(dest...)
+	switch {
+	case err == sql.ErrNoRows:
+		vals = make([]interface{}, len(l.Columns))
+	case err != nil:
+		return nil, fmt.Errorf("binlog: lookup for %s: %v", l.Table, err)
+	}
+	for i, v := range vals {
+		if b, ok := v.([]byte); ok {
+			vals[i] = string(b)
+		}
+	}
+	l.cache.put(key, vals)
+	return vals, nil
+}
+
+// lruCache is a cache of bounded size, evicting least recently used.
+type lruCache struct {
+	size  int
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type lruEntry struct {
+	key  string
+	vals []interface{}
+}
+
+func newLRUCache(size int) *lruCache {
+	return &lruCache{size: size, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (c *lruCache) get(key string) ([]interface{}, bool) {
+	if e, ok := c.items[key]; ok {
+		c.ll.MoveToFront(e)
+		return e.Value.(*lruEntry).vals, true
+	}
+	return nil, false
+}
+
+func (c *lruCache) put(key string, vals []interface{}) {
+	c.items[key] = c.ll.PushFront(&lruEntry{key, vals})
+	if c.ll.Len() > c.size {
+		e := c.ll.Back()
+		c.ll.Remove(e)
+		delete(c.items, e.Value.(*lruEntry).key)
+	}
+}
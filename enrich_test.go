@@ -0,0 +1,100 @@
+package binlog
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestEnricher(t *testing.T) {
+	src := &fakeSource{}
+	src.pushRows(WRITE_ROWS_EVENTv2, "shop", "orders",
+		[2][]interface{}{{int32(1), "alice"}},
+		[2][]interface{}{{int32(2), "bob"}},
+		[2][]interface{}{{int32(3), "alice"}},
+	)
+	src.pushRows(UPDATE_ROWS_EVENTv2, "shop", "orders", [2][]interface{}{{int32(1), "carol"}, {int32(1), "alice"}})
+	src.pushRows(WRITE_ROWS_EVENTv2, "shop", "items", [2][]interface{}{{int32(1), "pen"}})
+
+	var queries int
+	d := &fakeDriver{
+		query: func(q string, args []driver.Value) ([]string, [][]driver.Value) {
+			queries++
+			if q != "select country from shop.customers where name=?" {
+				t.Fatalf("got query %q", q)
+			}
+			switch args[0] {
+			case "alice", "carol":
+				return []string{"country"}, [][]driver.Value{{[]byte("US")}}
+			}
+			return []string{"country"}, nil
+		},
+	}
+	en := &Enricher{Src: src, Lookups: []*Lookup{{
+		Table:     "shop.orders",
+		DB:        sql.OpenDB(d),
+		Query:     "select country from shop.customers where name=?",
+		Args:      []string{"name"},
+		Columns:   []string{"country"},
+		CacheSize: 1,
+	}}}
+	var rows [][]interface{}
+	for {
+		e, err := en.NextEvent()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		re := e.Data.(RowsEvent)
+		cols := re.Columns()
+		if re.TableMap.TableName == "orders" && (len(cols) != 3 || cols[2].Name != "country" || cols[2].Ordinal != 2) {
+			t.Fatalf("got columns %v", cols)
+		}
+		for {
+			values, before, err := en.NextRow()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if before != nil && len(before) != 2 {
+				t.Fatalf("got before %v", before)
+			}
+			rows = append(rows, values)
+		}
+	}
+	want := [][]interface{}{
+		{int32(1), "alice", "US"},
+		{int32(2), "bob", nil},
+		{int32(3), "alice", "US"},
+		{int32(1), "carol", "US"},
+		{int32(1), "pen"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Fatalf("got %v, want %v", rows, want)
+	}
+	if queries != 4 { // alice evicted by bob
+		t.Fatalf("got %d queries, want 4", queries)
+	}
+}
+
+func TestLRUCache(t *testing.T) {
+	c := newLRUCache(2)
+	c.put("a", []interface{}{1})
+	c.put("b", []interface{}{2})
+	c.get("a")
+	c.put("c", []interface{}{3})
+	if _, ok := c.get("b"); ok {
+		t.Fatal("b not evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.get(key); !ok {
+			t.Fatalf("%s evicted", key)
+		}
+	}
+}
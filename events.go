@@ -1,8 +1,11 @@
 package binlog
 
 import (
+	"encoding/binary"
 	"fmt"
+	"hash/crc32"
 	"strings"
+	"time"
 )
 
 // EventType represents Binlog Event Type.
@@ -13,87 +16,112 @@ type EventType uint8
 // https://dev.mysql.com/doc/internals/en/binlog-event-type.html
 // https://dev.mysql.com/doc/internals/en/event-meanings.html
 const (
-	UNKNOWN_EVENT            EventType = 0x00 // should never occur. used when event cannot be recognized.
-	START_EVENT_V3           EventType = 0x01 // descriptor event written to binlog beginning. deprecated.
-	QUERY_EVENT              EventType = 0x02 // written when an updating statement is done.
-	STOP_EVENT               EventType = 0x03 // written when mysqld stops.
-	ROTATE_EVENT             EventType = 0x04 // written when mysqld switches to a new binary log file.
-	INTVAR_EVENT             EventType = 0x05 // if stmt uses AUTO_INCREMENT col or LAST_INSERT_ID().
-	LOAD_EVENT               EventType = 0x06 // used for LOAD DATA INFILE statements in MySQL 3.23.
-	SLAVE_EVENT              EventType = 0x07 // not used.
-	CREATE_FILE_EVENT        EventType = 0x08 // used for LOAD DATA INFILE statements in MySQL 4.0 and 4.1.
-	APPEND_BLOCK_EVENT       EventType = 0x09 // used for LOAD DATA INFILE statements in MySQL 4.0 and 4.1.
-	EXEC_LOAD_EVENT          EventType = 0x0a // used for LOAD DATA INFILE statements in MySQL 4.0 and 4.1.
-	DELETE_FILE_EVENT        EventType = 0x0b // used for LOAD DATA INFILE statements in MySQL 4.0 and 4.1.
-	NEW_LOAD_EVENT           EventType = 0x0c // used for LOAD DATA INFILE statements in MySQL 4.0 and 4.1.
-	RAND_EVENT               EventType = 0x0d // if stmt uses RAND().
-	USER_VAR_EVENT           EventType = 0x0e // if stmt uses a user variable.
-	FORMAT_DESCRIPTION_EVENT EventType = 0x0f // descriptor event written to binlog beginning.
-	XID_EVENT                EventType = 0x10 // for XA commit transaction.
-	BEGIN_LOAD_QUERY_EVENT   EventType = 0x11 // used for LOAD DATA INFILE statements in MySQL 5.0.
-	EXECUTE_LOAD_QUERY_EVENT EventType = 0x12 // used for LOAD DATA INFILE statements in MySQL 5.0.
-	TABLE_MAP_EVENT          EventType = 0x13 // precedes rbr event. contains table definition.
-	WRITE_ROWS_EVENTv0       EventType = 0x14 // logs inserts of rows in a single table.
-	UPDATE_ROWS_EVENTv0      EventType = 0x15 // logs updates of rows in a single table.
-	DELETE_ROWS_EVENTv0      EventType = 0x16 // logs deletions of rows in a single table.
-	WRITE_ROWS_EVENTv1       EventType = 0x17 // logs inserts of rows in a single table.
-	UPDATE_ROWS_EVENTv1      EventType = 0x18 // logs updates of rows in a single table.
-	DELETE_ROWS_EVENTv1      EventType = 0x19 // logs inserts of rows in a single table.
-	INCIDENT_EVENT           EventType = 0x1a // used to log an out of the ordinary event that occurred on the master.
-	HEARTBEAT_EVENT          EventType = 0x1b // to signal that master is still alive. not written to file.
-	IGNORABLE_EVENT          EventType = 0x1c
-	ROWS_QUERY_EVENT         EventType = 0x1d
-	WRITE_ROWS_EVENTv2       EventType = 0x1e // logs inserts of rows in a single table.
-	UPDATE_ROWS_EVENTv2      EventType = 0x1f // logs updates of rows in a single table.
-	DELETE_ROWS_EVENTv2      EventType = 0x20 // logs inserts of rows in a single table.
-	GTID_EVENT               EventType = 0x21
-	ANONYMOUS_GTID_EVENT     EventType = 0x22
-	PREVIOUS_GTIDS_EVENT     EventType = 0x23
+	UNKNOWN_EVENT             EventType = 0x00 // should never occur. used when event cannot be recognized.
+	START_EVENT_V3            EventType = 0x01 // descriptor event written to binlog beginning. deprecated.
+	QUERY_EVENT               EventType = 0x02 // written when an updating statement is done.
+	STOP_EVENT                EventType = 0x03 // written when mysqld stops.
+	ROTATE_EVENT              EventType = 0x04 // written when mysqld switches to a new binary log file.
+	INTVAR_EVENT              EventType = 0x05 // if stmt uses AUTO_INCREMENT col or LAST_INSERT_ID().
+	LOAD_EVENT                EventType = 0x06 // used for LOAD DATA INFILE statements in MySQL 3.23.
+	SLAVE_EVENT               EventType = 0x07 // not used.
+	CREATE_FILE_EVENT         EventType = 0x08 // used for LOAD DATA INFILE statements in MySQL 4.0 and 4.1.
+	APPEND_BLOCK_EVENT        EventType = 0x09 // used for LOAD DATA INFILE statements in MySQL 4.0 and 4.1.
+	EXEC_LOAD_EVENT           EventType = 0x0a // used for LOAD DATA INFILE statements in MySQL 4.0 and 4.1.
+	DELETE_FILE_EVENT         EventType = 0x0b // used for LOAD DATA INFILE statements in MySQL 4.0 and 4.1.
+	NEW_LOAD_EVENT            EventType = 0x0c // used for LOAD DATA INFILE statements in MySQL 4.0 and 4.1.
+	RAND_EVENT                EventType = 0x0d // if stmt uses RAND().
+	USER_VAR_EVENT            EventType = 0x0e // if stmt uses a user variable.
+	FORMAT_DESCRIPTION_EVENT  EventType = 0x0f // descriptor event written to binlog beginning.
+	XID_EVENT                 EventType = 0x10 // for XA commit transaction.
+	BEGIN_LOAD_QUERY_EVENT    EventType = 0x11 // used for LOAD DATA INFILE statements in MySQL 5.0.
+	EXECUTE_LOAD_QUERY_EVENT  EventType = 0x12 // used for LOAD DATA INFILE statements in MySQL 5.0.
+	TABLE_MAP_EVENT           EventType = 0x13 // precedes rbr event. contains table definition.
+	WRITE_ROWS_EVENTv0        EventType = 0x14 // logs inserts of rows in a single table.
+	UPDATE_ROWS_EVENTv0       EventType = 0x15 // logs updates of rows in a single table.
+	DELETE_ROWS_EVENTv0       EventType = 0x16 // logs deletions of rows in a single table.
+	WRITE_ROWS_EVENTv1        EventType = 0x17 // logs inserts of rows in a single table.
+	UPDATE_ROWS_EVENTv1       EventType = 0x18 // logs updates of rows in a single table.
+	DELETE_ROWS_EVENTv1       EventType = 0x19 // logs inserts of rows in a single table.
+	INCIDENT_EVENT            EventType = 0x1a // used to log an out of the ordinary event that occurred on the master.
+	HEARTBEAT_EVENT           EventType = 0x1b // to signal that master is still alive. not written to file.
+	IGNORABLE_EVENT           EventType = 0x1c
+	ROWS_QUERY_EVENT          EventType = 0x1d
+	WRITE_ROWS_EVENTv2        EventType = 0x1e // logs inserts of rows in a single table.
+	UPDATE_ROWS_EVENTv2       EventType = 0x1f // logs updates of rows in a single table.
+	DELETE_ROWS_EVENTv2       EventType = 0x20 // logs inserts of rows in a single table.
+	GTID_EVENT                EventType = 0x21
+	ANONYMOUS_GTID_EVENT      EventType = 0x22
+	PREVIOUS_GTIDS_EVENT      EventType = 0x23
+	TRANSACTION_CONTEXT_EVENT EventType = 0x24 // used by group replication for certification.
+	VIEW_CHANGE_EVENT         EventType = 0x25 // written when group replication membership changes.
+	XA_PREPARE_LOG_EVENT      EventType = 0x26 // written when XA transaction is prepared.
 )
 
-// Event represents Binlog Event.
+// Event represents Binlog Event. Fakes constructing Events must use
+// keyed fields, such as Event{Header: h, Data: d}, as Event has
+// unexported fields.
 type Event struct {
 	Header EventHeader
 	Data   interface{} // one of XXXEvent
+	meta   EventMeta
+}
+
+// Meta returns timing and size of the event, as observed by this
+// package. It is zero for events not read by Remote, Local or Reader.
+func (e Event) Meta() EventMeta {
+	return e.meta
+}
+
+// EventMeta captures timing and size of an Event, as observed
+// by this package. Rows of RowsEvent are decoded lazily by NextRow,
+// so their decode time is not included in DecodeTime.
+type EventMeta struct {
+	ReceivedAt time.Time     // wall-clock time when header of event is received
+	Size       int           // bytes consumed, including header and checksum
+	DecodeTime time.Duration // time taken to read and decode body, after header is received
+	Source     *SourceInfo   // where the event is read from. nil for Reader
 }
 
 var eventTypeNames = map[EventType]string{
-	UNKNOWN_EVENT:            "unknown",
-	START_EVENT_V3:           "startV3",
-	QUERY_EVENT:              "query",
-	STOP_EVENT:               "stop",
-	ROTATE_EVENT:             "rotate",
-	INTVAR_EVENT:             "inVar",
-	LOAD_EVENT:               "load",
-	SLAVE_EVENT:              "slave",
-	CREATE_FILE_EVENT:        "createFile",
-	APPEND_BLOCK_EVENT:       "appendBlock",
-	EXEC_LOAD_EVENT:          "execLoad",
-	DELETE_FILE_EVENT:        "deleteFile",
-	NEW_LOAD_EVENT:           "newLoad",
-	RAND_EVENT:               "rand",
-	USER_VAR_EVENT:           "userVar",
-	FORMAT_DESCRIPTION_EVENT: "formatDescription",
-	XID_EVENT:                "xid",
-	BEGIN_LOAD_QUERY_EVENT:   "beginLoadQuery",
-	EXECUTE_LOAD_QUERY_EVENT: "executeLoadQuery",
-	TABLE_MAP_EVENT:          "tableMap",
-	WRITE_ROWS_EVENTv0:       "writeRowsV0",
-	UPDATE_ROWS_EVENTv0:      "updateRowsV0",
-	DELETE_ROWS_EVENTv0:      "deleteRowsV0",
-	WRITE_ROWS_EVENTv1:       "writeRowsV1",
-	UPDATE_ROWS_EVENTv1:      "updateRowsV1",
-	DELETE_ROWS_EVENTv1:      "deleteRowsV1",
-	INCIDENT_EVENT:           "incident",
-	HEARTBEAT_EVENT:          "heartbeat",
-	IGNORABLE_EVENT:          "ignorable",
-	ROWS_QUERY_EVENT:         "rowsQuery",
-	WRITE_ROWS_EVENTv2:       "writeRowsV2",
-	UPDATE_ROWS_EVENTv2:      "updateRowsV2",
-	DELETE_ROWS_EVENTv2:      "deleteRowsV2",
-	GTID_EVENT:               "gtid",
-	ANONYMOUS_GTID_EVENT:     "anonymousGTID",
-	PREVIOUS_GTIDS_EVENT:     "previousGTID",
+	UNKNOWN_EVENT:             "unknown",
+	START_EVENT_V3:            "startV3",
+	QUERY_EVENT:               "query",
+	STOP_EVENT:                "stop",
+	ROTATE_EVENT:              "rotate",
+	INTVAR_EVENT:              "inVar",
+	LOAD_EVENT:                "load",
+	SLAVE_EVENT:               "slave",
+	CREATE_FILE_EVENT:         "createFile",
+	APPEND_BLOCK_EVENT:        "appendBlock",
+	EXEC_LOAD_EVENT:           "execLoad",
+	DELETE_FILE_EVENT:         "deleteFile",
+	NEW_LOAD_EVENT:            "newLoad",
+	RAND_EVENT:                "rand",
+	USER_VAR_EVENT:            "userVar",
+	FORMAT_DESCRIPTION_EVENT:  "formatDescription",
+	XID_EVENT:                 "xid",
+	BEGIN_LOAD_QUERY_EVENT:    "beginLoadQuery",
+	EXECUTE_LOAD_QUERY_EVENT:  "executeLoadQuery",
+	TABLE_MAP_EVENT:           "tableMap",
+	WRITE_ROWS_EVENTv0:        "writeRowsV0",
+	UPDATE_ROWS_EVENTv0:       "updateRowsV0",
+	DELETE_ROWS_EVENTv0:       "deleteRowsV0",
+	WRITE_ROWS_EVENTv1:        "writeRowsV1",
+	UPDATE_ROWS_EVENTv1:       "updateRowsV1",
+	DELETE_ROWS_EVENTv1:       "deleteRowsV1",
+	INCIDENT_EVENT:            "incident",
+	HEARTBEAT_EVENT:           "heartbeat",
+	IGNORABLE_EVENT:           "ignorable",
+	ROWS_QUERY_EVENT:          "rowsQuery",
+	WRITE_ROWS_EVENTv2:        "writeRowsV2",
+	UPDATE_ROWS_EVENTv2:       "updateRowsV2",
+	DELETE_ROWS_EVENTv2:       "deleteRowsV2",
+	GTID_EVENT:                "gtid",
+	ANONYMOUS_GTID_EVENT:      "anonymousGTID",
+	PREVIOUS_GTIDS_EVENT:      "previousGTID",
+	TRANSACTION_CONTEXT_EVENT: "transactionContext",
+	VIEW_CHANGE_EVENT:         "viewChange",
+	XA_PREPARE_LOG_EVENT:      "xaPrepare",
 }
 
 func (t EventType) String() string {
@@ -135,6 +163,80 @@ type EventHeader struct {
 	Flags     uint16    // flags
 }
 
+// EventHeader flags
+//
+// see https://github.com/mysql/mysql-server/blob/8.0/libbinlogevents/include/binlog_event.h
+const (
+	LOG_EVENT_BINLOG_IN_USE_F   = 0x0001 // binlog file is in use, i.e. not closed properly
+	LOG_EVENT_THREAD_SPECIFIC_F = 0x0004 // query depends on thread, such as using temporary table
+	LOG_EVENT_SUPPRESS_USE_F    = 0x0008 // query must be executed without USE of default database
+	LOG_EVENT_ARTIFICIAL_F      = 0x0020 // event is generated, such as RotateEvent sent at the start of binlog stream
+	LOG_EVENT_RELAY_LOG_F       = 0x0040 // event is written by replica SQL thread to relay log
+	LOG_EVENT_IGNORABLE_F       = 0x0080 // event can be ignored by replica, if not understood
+	LOG_EVENT_NO_FILTER_F       = 0x0100 // event must not be filtered by replica
+	LOG_EVENT_MTS_ISOLATE_F     = 0x0200 // event must be applied in isolation by multi-threaded replica
+)
+
+var headerFlagNames = []struct {
+	flag uint16
+	name string
+}{
+	{LOG_EVENT_BINLOG_IN_USE_F, "binlogInUse"},
+	{LOG_EVENT_THREAD_SPECIFIC_F, "threadSpecific"},
+	{LOG_EVENT_SUPPRESS_USE_F, "suppressUse"},
+	{LOG_EVENT_ARTIFICIAL_F, "artificial"},
+	{LOG_EVENT_RELAY_LOG_F, "relayLog"},
+	{LOG_EVENT_IGNORABLE_F, "ignorable"},
+	{LOG_EVENT_NO_FILTER_F, "noFilter"},
+	{LOG_EVENT_MTS_ISOLATE_F, "mtsIsolate"},
+}
+
+// HasFlag tells whether all bits of flag, such as LOG_EVENT_ARTIFICIAL_F,
+// are set in Flags.
+func (h EventHeader) HasFlag(flag uint16) bool { return h.Flags&flag == flag }
+
+// BinlogInUse tells whether binlog file was in use, i.e. not closed
+// properly, when this FormatDescriptionEvent was read from it.
+func (h EventHeader) BinlogInUse() bool { return h.Flags&LOG_EVENT_BINLOG_IN_USE_F != 0 }
+
+// ThreadSpecific tells whether query depends on the thread that
+// executed it, such as queries using temporary tables.
+func (h EventHeader) ThreadSpecific() bool { return h.Flags&LOG_EVENT_THREAD_SPECIFIC_F != 0 }
+
+// SuppressUse tells whether query must be executed without setting
+// default database, as for CREATE DATABASE.
+func (h EventHeader) SuppressUse() bool { return h.Flags&LOG_EVENT_SUPPRESS_USE_F != 0 }
+
+// Artificial tells whether event is generated, rather than read from
+// binlog, such as RotateEvent sent at the start of binlog stream.
+func (h EventHeader) Artificial() bool { return h.Flags&LOG_EVENT_ARTIFICIAL_F != 0 }
+
+// RelayLog tells whether event was written by replica to relay log.
+func (h EventHeader) RelayLog() bool { return h.Flags&LOG_EVENT_RELAY_LOG_F != 0 }
+
+// Ignorable tells whether event can be ignored, if not understood.
+func (h EventHeader) Ignorable() bool { return h.Flags&LOG_EVENT_IGNORABLE_F != 0 }
+
+// FlagNames returns names of flags set, such as "artificial", for
+// display. Unknown flags are named in hex, such as "0x0200".
+func (h EventHeader) FlagNames() []string {
+	var names []string
+	flags := h.Flags
+	for _, f := range headerFlagNames {
+		if flags&f.flag != 0 {
+			names = append(names, f.name)
+			flags &^= f.flag
+		}
+	}
+	for bit := uint16(1); flags != 0; bit <<= 1 {
+		if flags&bit != 0 {
+			names = append(names, fmt.Sprintf("0x%04x", bit))
+			flags &^= bit
+		}
+	}
+	return names
+}
+
 func (h *EventHeader) decode(r *reader) error {
 	h.Timestamp = r.int4()
 	h.EventType = EventType(r.int1())
@@ -171,6 +273,12 @@ func (e *FormatDescriptionEvent) decode(r *reader, eventSize uint32) error {
 		return err
 	}
 	fmeSize := r.buffer()[FORMAT_DESCRIPTION_EVENT-1]
+	if eventSize == 19+uint32(fmeSize) {
+		// no checksumType, written by server older than 5.6.1
+		r.checksum = 0
+		e.EventTypeHeaderLengths = r.bytesEOF()
+		return r.err
+	}
 	r.checksum = int(eventSize - 19 /*eventHeader*/ - uint32(fmeSize) - 1 /*checksumType*/)
 	r.limit -= r.checksum
 	e.EventTypeHeaderLengths = r.bytesEOF()
@@ -204,6 +312,29 @@ func (e *RotateEvent) decode(r *reader) error {
 	return r.err
 }
 
+// hasChecksum tells whether event ends with valid crc32 checksum.
+func hasChecksum(event []byte) bool {
+	n := len(event) - 4
+	return n > 0 && crc32.ChecksumIEEE(event[:n]) == binary.LittleEndian.Uint32(event[n:])
+}
+
+// trimChecksum removes crc32 checksum decoded as part of NextBinlog,
+// when it is not known whether event has checksum. headerCRC is crc32
+// of event header.
+func (e *RotateEvent) trimChecksum(headerCRC uint32) {
+	n := len(e.NextBinlog) - 4
+	if n <= 0 {
+		return
+	}
+	pos := make([]byte, 8)
+	binary.LittleEndian.PutUint64(pos, e.Position)
+	crc := crc32.Update(headerCRC, crc32.IEEETable, pos)
+	crc = crc32.Update(crc, crc32.IEEETable, []byte(e.NextBinlog[:n]))
+	if crc == binary.LittleEndian.Uint32([]byte(e.NextBinlog[n:])) {
+		e.NextBinlog = e.NextBinlog[:n]
+	}
+}
+
 // QueryEvent is written when an updating statement is done.
 // The query event is used to send text query right the binlog.
 //
@@ -225,11 +356,13 @@ func (e *QueryEvent) decode(r *reader) error {
 		return r.err
 	}
 	e.ErrorCode = r.int2()
-	statusVarsLen := r.int2()
-	if r.err != nil {
-		return r.err
+	if r.fde.BinlogVersion >= 4 { // status vars added in v4
+		statusVarsLen := r.int2()
+		if r.err != nil {
+			return r.err
+		}
+		e.StatusVars = r.bytes(int(statusVarsLen))
 	}
-	e.StatusVars = r.bytes(int(statusVarsLen))
 	e.Schema = r.string(int(schemaLen))
 	r.skip(1)
 	e.Query = r.stringEOF()
@@ -254,6 +387,35 @@ func (e *IncidentEvent) decode(r *reader) error {
 	return r.err
 }
 
+// XAPrepareEvent is written when XA transaction is prepared by
+// XA PREPARE, or committed by XA COMMIT ... ONE PHASE. It ends the
+// transaction in binlog, as XA COMMIT is logged separately.
+//
+// https://dev.mysql.com/doc/refman/8.0/en/xa-statements.html
+type XAPrepareEvent struct {
+	OnePhase bool // true for XA COMMIT ... ONE PHASE
+	XID      XID
+}
+
+// XID identifies an XA transaction.
+//
+// https://dev.mysql.com/doc/refman/8.0/en/xa-statements.html
+type XID struct {
+	FormatID int32
+	GTRID    []byte // global transaction identifier
+	BQUAL    []byte // branch qualifier
+}
+
+func (e *XAPrepareEvent) decode(r *reader) error {
+	e.OnePhase = r.int1() != 0
+	e.XID.FormatID = int32(r.int4())
+	gtridLen := r.int4()
+	bqualLen := r.int4()
+	e.XID.GTRID = r.bytes(int(gtridLen))
+	e.XID.BQUAL = r.bytes(int(bqualLen))
+	return r.err
+}
+
 // RandEvent is written every time a statement uses the RAND() function.
 // It precedes other events for the statement. Indicates the seed values
 // to use for generating a random number with RAND() in the next statement.
@@ -318,7 +480,7 @@ func (e *UserVarEvent) decode(r *reader) error {
 		return r.err
 	}
 	e.Name = r.string(int(nameLen))
-	e.Null = r.int1() == 0
+	e.Null = r.int1() != 0
 	if r.err != nil {
 		return r.err
 	}
@@ -331,34 +493,35 @@ func (e *UserVarEvent) decode(r *reader) error {
 		}
 		e.Value = r.bytes(int(valueLen))
 		if r.more() {
-			e.Unsigned = (r.int1() | 0x01) != 0
+			e.Unsigned = r.int1()&0x01 != 0
 		}
 	}
 	return r.err
 }
 
+// XIDEvent is generated for a commit of a transaction that
+// modifies one or more tables of an XA-capable storage engine.
+//
+// https://dev.mysql.com/doc/internals/en/xid-event.html
+type XIDEvent struct {
+	XID uint64 // transaction id
+}
+
+func (e *XIDEvent) decode(r *reader) error {
+	e.XID = r.int8()
+	return r.err
+}
+
 // HeartbeatEvent sent by a master to a slave to let the slave
 // know that the master is still alive. Not written to log files.
 //
 // https://dev.mysql.com/doc/internals/en/heartbeat-event.html
 type HeartbeatEvent struct{}
 
-// UnknownEvent should never occur. It is never written to a binary log.
-// If an event is read from a binary log that cannot be recognized as
-// something else, it is treated as UNKNOWN_EVENT.
-type UnknownEvent struct{}
-
-type previousGTIDsEvent struct{}
-type anonymousGTIDEvent struct{}
-type xidEvent struct{}
-type gtidEvent struct{}
-type loadEvent struct{}
-type slaveEvent struct{}
-type createFileEvent struct{}
-type deleteFileEvent struct{}
-type beginLoadQueryEvent struct{}
-type executeLoadQueryEvent struct{}
-type newLoadEvent struct{}
-type execLoadEvent struct{}
-type appendBlockEvent struct{}
-type ignorableEvent struct{}
+// UnknownEvent is returned for event types, that are not decoded by
+// this package. It has raw body of the event, excluding header and
+// checksum, so that tools can archive or forward such events.
+type UnknownEvent struct {
+	Type EventType
+	Body []byte
+}
@@ -0,0 +1,22 @@
+package binlog
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEventHeader_FlagNames(t *testing.T) {
+	h := EventHeader{Flags: LOG_EVENT_BINLOG_IN_USE_F | LOG_EVENT_ARTIFICIAL_F | 0x0400}
+	if !h.BinlogInUse() || !h.Artificial() || h.ThreadSpecific() || h.SuppressUse() || h.RelayLog() || h.Ignorable() {
+		t.Fatalf("flags 0x%04x decoded wrongly", h.Flags)
+	}
+	if !h.HasFlag(LOG_EVENT_BINLOG_IN_USE_F|LOG_EVENT_ARTIFICIAL_F) || h.HasFlag(LOG_EVENT_ARTIFICIAL_F|LOG_EVENT_MTS_ISOLATE_F) {
+		t.Fatalf("HasFlag of 0x%04x is wrong", h.Flags)
+	}
+	if got, want := h.FlagNames(), []string{"binlogInUse", "artificial", "0x0400"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got := (EventHeader{}).FlagNames(); got != nil {
+		t.Fatalf("got %v, want nil", got)
+	}
+}
@@ -0,0 +1,391 @@
+package binlog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RowFilter is a compiled predicate over row values, such as:
+//
+//	after.amount > 1000 && after.country == "US"
+//	op == "delete" || before.status != after.status
+//
+// Operands are column values of row images, named as before.col and
+// after.col, literals of number, string in single or double quotes,
+// true, false and null, and op, which is "insert", "update" or
+// "delete". Operators, in increasing precedence, are ||, &&, !, and
+// comparisons ==, !=, <, <=, >, >=. Parentheses group.
+//
+// Numbers compare numerically, and other values compare as strings,
+// with temporal values formatted as in SQLLiteral. Comparison with
+// null, or of number with string, is false, except != which is true.
+// Columns missing in row image, such as before.col of insert, are null.
+//
+// Column names are matched case insensitively, and are available only
+// if binlog_row_metadata=FULL.
+type RowFilter struct {
+	src  string
+	eval exprFunc
+}
+
+type exprFunc func(env *exprEnv) interface{}
+
+type exprEnv struct {
+	op            string
+	before, after Row
+}
+
+// CompileRowFilter parses expression into RowFilter.
+func CompileRowFilter(expr string) (*RowFilter, error) {
+	p := &exprParser{src: expr}
+	p.next()
+	f := p.or()
+	if p.err == nil && p.tok != "" {
+		p.fail("unexpected " + p.tok)
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &RowFilter{src: expr, eval: f}, nil
+}
+
+// String returns source of the expression.
+func (f *RowFilter) String() string {
+	return f.src
+}
+
+// Match evaluates filter for row change of RowsEvent of given type.
+// before is zero Row for inserts, and after is zero Row for deletes.
+// Non-boolean result is false.
+func (f *RowFilter) Match(typ EventType, before, after Row) bool {
+	env := &exprEnv{before: before, after: after}
+	switch {
+	case typ.IsWriteRows():
+		env.op = "insert"
+	case typ.IsUpdateRows():
+		env.op = "update"
+	case typ.IsDeleteRows():
+		env.op = "delete"
+	}
+	b, _ := f.eval(env).(bool)
+	return b
+}
+
+// MatchEvent evaluates filter for row of RowsEvent e, as returned by
+// NextRow.
+func (f *RowFilter) MatchEvent(e Event, values, valuesBeforeUpdate []interface{}) bool {
+	re, _ := e.Data.(RowsEvent)
+	row := Row{Columns: re.Columns(), Values: values}
+	switch {
+	case e.Header.EventType.IsUpdateRows():
+		return f.Match(e.Header.EventType, Row{Columns: re.ColumnsBeforeUpdate(), Values: valuesBeforeUpdate}, row)
+	case e.Header.EventType.IsDeleteRows():
+		return f.Match(e.Header.EventType, row, Row{})
+	default:
+		return f.Match(e.Header.EventType, Row{}, row)
+	}
+}
+
+// exprParser is recursive descent parser, producing closures.
+type exprParser struct {
+	src string
+	off int    // offset of next token
+	pos int    // offset of tok
+	tok string // current token. empty at end
+	err error
+}
+
+func (p *exprParser) fail(msg string) {
+	if p.err == nil {
+		p.err = fmt.Errorf("binlog: invalid filter %q at offset %d: %s", p.src, p.pos, msg)
+	}
+	p.tok = ""
+}
+
+func (p *exprParser) next() {
+	s := p.src
+	for p.off < len(s) && (s[p.off] == ' ' || s[p.off] == '\t' || s[p.off] == '\n' || s[p.off] == '\r') {
+		p.off++
+	}
+	p.pos = p.off
+	if p.off == len(s) {
+		p.tok = ""
+		return
+	}
+	start := p.off
+	switch c := s[p.off]; {
+	case c == '"' || c == '\'':
+		p.off++
+		for p.off < len(s) && s[p.off] != c {
+			if s[p.off] == '\\' {
+				p.off++
+			}
+			p.off++
+		}
+		if p.off >= len(s) {
+			p.fail("unterminated string")
+			return
+		}
+		p.off++
+	case isIdentByte(c) || c == '-':
+		p.off++
+		for p.off < len(s) && (isIdentByte(s[p.off]) || s[p.off] == '.') {
+			p.off++
+		}
+	case strings.HasPrefix(s[p.off:], "&&"), strings.HasPrefix(s[p.off:], "||"),
+		strings.HasPrefix(s[p.off:], "=="), strings.HasPrefix(s[p.off:], "!="),
+		strings.HasPrefix(s[p.off:], "<="), strings.HasPrefix(s[p.off:], ">="):
+		p.off += 2
+	case strings.IndexByte("!<>()", c) != -1:
+		p.off++
+	default:
+		p.fail(fmt.Sprintf("unexpected character %q", c))
+		return
+	}
+	p.tok = s[start:p.off]
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func (p *exprParser) or() exprFunc {
+	x := p.and()
+	for p.tok == "||" {
+		p.next()
+		l, r := x, p.and()
+		x = func(env *exprEnv) interface{} {
+			if b, _ := l(env).(bool); b {
+				return true
+			}
+			b, _ := r(env).(bool)
+			return b
+		}
+	}
+	return x
+}
+
+func (p *exprParser) and() exprFunc {
+	x := p.not()
+	for p.tok == "&&" {
+		p.next()
+		l, r := x, p.not()
+		x = func(env *exprEnv) interface{} {
+			if b, _ := l(env).(bool); !b {
+				return false
+			}
+			b, _ := r(env).(bool)
+			return b
+		}
+	}
+	return x
+}
+
+func (p *exprParser) not() exprFunc {
+	if p.tok == "!" {
+		p.next()
+		x := p.not()
+		return func(env *exprEnv) interface{} {
+			b, _ := x(env).(bool)
+			return !b
+		}
+	}
+	return p.cmp()
+}
+
+func (p *exprParser) cmp() exprFunc {
+	x := p.operand()
+	switch op := p.tok; op {
+	case "==", "!=", "<", "<=", ">", ">=":
+		p.next()
+		l, r := x, p.operand()
+		return func(env *exprEnv) interface{} {
+			return compare(op, l(env), r(env))
+		}
+	}
+	return x
+}
+
+func (p *exprParser) operand() exprFunc {
+	tok := p.tok
+	switch {
+	case tok == "":
+		p.fail("unexpected end of expression")
+		return nil
+	case tok == "(":
+		p.next()
+		x := p.or()
+		if p.tok != ")" {
+			p.fail("expected )")
+		}
+		p.next()
+		return x
+	case tok[0] == '"' || tok[0] == '\'':
+		p.next()
+		s := unquoteExpr(tok)
+		return func(*exprEnv) interface{} { return s }
+	case tok[0] == '-' || '0' <= tok[0] && tok[0] <= '9':
+		p.next()
+		f, err := strconv.ParseFloat(tok, 64)
+		if err != nil {
+			p.fail("invalid number " + tok)
+		}
+		return func(*exprEnv) interface{} { return f }
+	}
+	p.next()
+	switch strings.ToLower(tok) {
+	case "true":
+		return func(*exprEnv) interface{} { return true }
+	case "false":
+		return func(*exprEnv) interface{} { return false }
+	case "null":
+		return func(*exprEnv) interface{} { return nil }
+	case "op":
+		return func(env *exprEnv) interface{} { return env.op }
+	}
+	dot := strings.IndexByte(tok, '.')
+	if dot == -1 || strings.IndexByte(tok[dot+1:], '.') != -1 {
+		p.fail("unknown identifier " + tok)
+		return nil
+	}
+	image, col := strings.ToLower(tok[:dot]), tok[dot+1:]
+	if image != "before" && image != "after" {
+		p.fail("unknown identifier " + tok)
+		return nil
+	}
+	return func(env *exprEnv) interface{} {
+		row := env.after
+		if image == "before" {
+			row = env.before
+		}
+		for i, c := range row.Columns {
+			if strings.EqualFold(c.Name, col) && i < len(row.Values) {
+				return exprValue(c, row.Values[i])
+			}
+		}
+		return nil
+	}
+}
+
+// unquoteExpr unquotes string literal in single or double quotes,
+// with backslash escapes.
+func unquoteExpr(tok string) string {
+	var buf strings.Builder
+	for i := 1; i < len(tok)-1; i++ {
+		c := tok[i]
+		if c == '\\' {
+			i++
+			switch c = tok[i]; c {
+			case 'n':
+				c = '\n'
+			case 't':
+				c = '\t'
+			case '0':
+				c = 0
+			}
+		}
+		buf.WriteByte(c)
+	}
+	return buf.String()
+}
+
+// exprValue converts decoded value into float64, string, bool or nil.
+func exprValue(col Column, v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil, string, bool:
+		return v
+	case []byte:
+		return string(v)
+	case int8:
+		return float64(v)
+	case int16:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case int:
+		return float64(v)
+	case uint8:
+		return float64(v)
+	case uint16:
+		return float64(v)
+	case uint32:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case float64:
+		return v
+	case Decimal:
+		f, err := v.Float64()
+		if err != nil {
+			return string(v)
+		}
+		return f
+	case time.Time, time.Duration:
+		return col.temporalLiteral(v)
+	case Enum:
+		return v.String()
+	case Set:
+		return v.String()
+	case JSON:
+		return v.Raw()
+	}
+	return fmt.Sprint(v)
+}
+
+func compare(op string, l, r interface{}) bool {
+	var c int
+	switch l := l.(type) {
+	case float64:
+		r, ok := r.(float64)
+		if !ok {
+			return op == "!="
+		}
+		switch {
+		case l < r:
+			c = -1
+		case l > r:
+			c = 1
+		}
+	case string:
+		r, ok := r.(string)
+		if !ok {
+			return op == "!="
+		}
+		c = strings.Compare(l, r)
+	case bool:
+		r, ok := r.(bool)
+		if !ok || (op != "==" && op != "!=") {
+			return op == "!="
+		}
+		if l != r {
+			c = 1
+		}
+	case nil:
+		if op == "==" {
+			return r == nil
+		}
+		return op == "!=" && r != nil
+	default:
+		return op == "!="
+	}
+	switch op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
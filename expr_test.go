@@ -0,0 +1,81 @@
+package binlog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRowFilter(t *testing.T) {
+	cols := []Column{
+		{Ordinal: 0, Name: "id", Type: TypeLong},
+		{Ordinal: 1, Name: "amount", Type: TypeNewDecimal},
+		{Ordinal: 2, Name: "country", Type: TypeVarchar},
+		{Ordinal: 3, Name: "status", Type: TypeEnum, Values: []string{"new", "paid"}},
+		{Ordinal: 4, Name: "created", Type: TypeDateTime2},
+		{Ordinal: 5, Name: "note", Type: TypeBlob},
+	}
+	before := Row{Columns: cols, Values: []interface{}{
+		int32(7), Decimal("999.50"), "US", Enum{Val: 1, Values: cols[3].Values}, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), nil,
+	}}
+	after := Row{Columns: cols, Values: []interface{}{
+		int32(7), Decimal("1500.00"), []byte("US"), Enum{Val: 2, Values: cols[3].Values}, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), nil,
+	}}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`after.amount > 1000 && after.country == "US"`, true},
+		{`after.amount > 1000 && after.country == 'IN'`, false},
+		{`before.amount > 1000 || after.amount > 1000`, true},
+		{`op == "update" && before.status != after.status`, true},
+		{`after.STATUS == "paid"`, true},
+		{`!(after.id == 7)`, false},
+		{`after.id >= 7 && after.id <= 7 && after.id != -1`, true},
+		{`after.created >= "2020-01-01" && after.created < "2020-01-03"`, true},
+		{`after.note == null && after.missing == null`, true},
+		{`after.note != null`, false},
+		{`after.note < 1`, false},
+		{`after.country > 1`, false},
+		{`after.country != 1`, true},
+		{`after.id`, false},
+		{`true`, true},
+	}
+	for _, test := range tests {
+		f, err := CompileRowFilter(test.expr)
+		if err != nil {
+			t.Errorf("CompileRowFilter(%q): %v", test.expr, err)
+			continue
+		}
+		if got := f.Match(UPDATE_ROWS_EVENTv2, before, after); got != test.want {
+			t.Errorf("%s: got %v, want %v", test.expr, got, test.want)
+		}
+	}
+
+	// before image of insert is null
+	f, err := CompileRowFilter(`before.id == null && after.id == 7`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.Match(WRITE_ROWS_EVENTv2, Row{}, after) {
+		t.Error("insert: got false")
+	}
+}
+
+func TestCompileRowFilter_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		``,
+		`after.id ==`,
+		`(after.id == 1`,
+		`after.id == 1)`,
+		`row.id == 1`,
+		`id == 1`,
+		`after.id = 1`,
+		`after.name == "x`,
+		`after.id == 1e`,
+		`after.id & 1`,
+	} {
+		if _, err := CompileRowFilter(expr); err == nil {
+			t.Errorf("CompileRowFilter(%q): error expected", expr)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package binlog
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Failover streams binlog events from one of candidate servers of a
+// replication topology, such as primary and its replicas. When the
+// connection fails, it switches to next healthy server, resuming by
+// gtid, since file names and positions differ across servers.
+//
+// Executed gtid set is updated as transactions complete, so that the
+// stream resumes after the last complete transaction. Events of the
+// incomplete transaction are sent again after switching, preceded by
+// FailoverEvent, so consumers must discard uncommitted changes on it.
+//
+// Requires gtid_mode=ON on all candidates.
+type Failover struct {
+	Addrs    []string // candidate addresses, in order of preference
+	Network  string   // network of Addrs. defaults to "tcp"
+	Options  DialOptions
+	Username string
+	Password string
+	ServerID uint32 // see Remote.Seek
+
+	// Credentials, if non-nil, is used instead of Username and Password,
+	// and is called for each connection.
+	Credentials CredentialsProvider
+
+	// Executed is gtid set already processed. nil means stream from
+	// first binlog available.
+	Executed GTIDSet
+
+	// HealthCheck, if non-nil, is called with each new authenticated
+	// connection. Returning error skips the server.
+	HealthCheck func(bl *Remote) error
+
+	// RecheckInterval, if positive, repeats HealthCheck at transaction
+	// boundaries at this interval, switching to another server if it
+	// fails. For example, GroupPrimaryCheck fails after the server is
+	// demoted by group replication primary election. The check is also
+	// run on a timer, while NextEvent waits for events, so that demoted
+	// server without traffic is detected. The check is run over
+	// companion control connection. see Remote.SetControlConn.
+	RecheckInterval time.Duration
+
+	// Backoff is used to retry, when all servers fail to connect.
+	// nil means DefaultBackoff. Options.Backoff is used to retry
+	// connect to each server.
+	Backoff Backoff
+
+	// Clock, if non-nil, is used instead of SystemClock, and is set
+	// on each connection. see Remote.SetClock.
+	Clock Clock
+
+	// Setup, if non-nil, is called to configure each new connection
+	// before streaming, for example to call SetHeartbeatPeriod.
+	Setup func(bl *Remote) error
+
+	bl         *Remote
+	addr       int        // index of current address in Addrs
+	pending    *GTIDEvent // gtid of current transaction
+	begun      bool       // BEGIN seen for current transaction
+	failedAddr string     // address of server failed
+	lastErr    error      // error that caused failover
+	checked    time.Time  // time of last HealthCheck
+
+	// recheck timer, while NextEvent waits for events
+	watchMu  sync.Mutex
+	watchT   Timer
+	watching *Remote // connection being checked, nil if not waiting
+	watchErr error   // HealthCheck failure of watching
+}
+
+// FailoverEvent is a synthetic event, returned when Failover switches
+// to another server. Changes of transaction in progress must be
+// discarded, as the transaction is sent again.
+type FailoverEvent struct {
+	From, To string // addresses of servers
+	Err      error  // error from the failed server
+}
+
+// Addr returns address of current server. It is empty before first
+// NextEvent and after failure of current server.
+func (f *Failover) Addr() string {
+	if f.bl == nil {
+		return ""
+	}
+	return f.Addrs[f.addr]
+}
+
+// NextEvent returns next binlog event, switching to another server if
+// current server fails. Returns io.EOF when there are no more events,
+// which happens only if ServerID is zero.
+func (f *Failover) NextEvent() (Event, error) {
+	if f.bl == nil {
+		if err := f.connect(); err != nil {
+			return Event{}, err
+		}
+		if f.lastErr != nil {
+			fe := FailoverEvent{From: f.failedAddr, To: f.Addrs[f.addr], Err: f.lastErr}
+			f.lastErr = nil
+			return Event{Header: EventHeader{EventType: UNKNOWN_EVENT}, Data: fe}, nil
+		}
+	}
+	if err := f.recheck(); err != nil {
+		f.fail(err)
+		return f.NextEvent()
+	}
+	watch := f.RecheckInterval > 0 && f.HealthCheck != nil && f.pending == nil && !f.begun
+	if watch {
+		f.watch(f.bl)
+	}
+	e, err := f.bl.NextEvent()
+	if watch {
+		if err := f.unwatch(); err != nil {
+			// e, if any, begins transaction not in Executed
+			f.fail(err)
+			return f.NextEvent()
+		}
+	}
+	if err != nil {
+		if err == io.EOF && !f.bl.isStreaming() {
+			return e, err
+		}
+		if isConnErr(err) {
+			f.fail(err)
+			return f.NextEvent()
+		}
+		return e, err
+	}
+	f.track(e)
+	return e, nil
+}
+
+// NextRow returns next row of current RowsEvent. see Remote.NextRow.
+// On connection failure, error is returned, and the next call to
+// NextEvent switches to another server.
+func (f *Failover) NextRow() (values []interface{}, valuesBeforeUpdate []interface{}, err error) {
+	if f.bl == nil {
+		return nil, nil, io.EOF
+	}
+	values, valuesBeforeUpdate, err = f.bl.NextRow()
+	if err != nil && err != io.EOF && isConnErr(err) {
+		f.fail(err)
+	}
+	return
+}
+
+// Close closes connection to current server.
+func (f *Failover) Close() error {
+	if f.bl == nil {
+		return nil
+	}
+	err := f.bl.Close()
+	f.bl = nil
+	return err
+}
+
+// fail closes current connection, so that next healthy server
+// is tried by NextEvent.
+func (f *Failover) fail(err error) {
+	_ = f.bl.Close()
+	f.bl, f.lastErr = nil, err
+	f.failedAddr = f.Addrs[f.addr]
+	f.addr = (f.addr + 1) % len(f.Addrs)
+	f.pending, f.begun = nil, false
+}
+
+// connect connects to first healthy server, starting from f.addr.
+func (f *Failover) connect() error {
+	if len(f.Addrs) == 0 {
+		return fmt.Errorf("binlog: no source addresses")
+	}
+	if f.Executed == nil {
+		f.Executed = GTIDSet{}
+	}
+	network := f.Network
+	if network == "" {
+		network = "tcp"
+	}
+	backoff := f.Backoff
+	if backoff == nil {
+		backoff = DefaultBackoff
+	}
+	err := retry(backoff, f.Clock, func() error {
+		var lastErr error
+		for i := 0; i < len(f.Addrs); i++ {
+			addr := (f.addr + i) % len(f.Addrs)
+			bl, err := f.dial(network, f.Addrs[addr])
+			if err != nil {
+				lastErr = fmt.Errorf("%s: %v", f.Addrs[addr], err)
+				continue
+			}
+			f.bl, f.addr = bl, addr
+			return nil
+		}
+		return lastErr
+	})
+	if err != nil {
+		return fmt.Errorf("binlog: no healthy source: %v", err)
+	}
+	return nil
+}
+
+func (f *Failover) dial(network, addr string) (*Remote, error) {
+	bl, err := DialWithOptions(network, addr, f.Options)
+	if err != nil {
+		return nil, err
+	}
+	if f.RecheckInterval > 0 {
+		bl.SetControlConn(true)
+	}
+	bl.SetClock(f.Clock)
+	creds := f.Credentials
+	if creds == nil {
+		creds = StaticCredentials(f.Username, f.Password)
+	}
+	err = bl.AuthenticateWith(context.Background(), creds)
+	if err == nil && f.HealthCheck != nil {
+		err = f.HealthCheck(bl)
+		f.checked = clockOrSystem(f.Clock).Now()
+	}
+	if err == nil && f.Setup != nil {
+		err = f.Setup(bl)
+	}
+	if err == nil {
+		err = bl.SeekGTID(f.ServerID, f.Executed)
+	}
+	if err != nil {
+		_ = bl.Close()
+		return nil, err
+	}
+	return bl, nil
+}
+
+// recheck runs HealthCheck if RecheckInterval has elapsed, and
+// no transaction is in progress.
+func (f *Failover) recheck() error {
+	if f.RecheckInterval <= 0 || f.HealthCheck == nil || f.pending != nil || f.begun {
+		return nil
+	}
+	if clockOrSystem(f.Clock).Now().Sub(f.checked) < f.RecheckInterval {
+		return nil
+	}
+	f.checked = clockOrSystem(f.Clock).Now()
+	return f.HealthCheck(f.bl)
+}
+
+// watch starts recheck timer, while NextEvent of bl waits for event.
+func (f *Failover) watch(bl *Remote) {
+	f.watchMu.Lock()
+	defer f.watchMu.Unlock()
+	f.watching, f.watchErr = bl, nil
+	if f.watchT == nil {
+		f.watchT = clockOrSystem(f.Clock).AfterFunc(f.RecheckInterval, f.fireRecheck)
+	} else {
+		f.watchT.Reset(f.RecheckInterval)
+	}
+}
+
+// unwatch stops recheck timer, and returns HealthCheck failure if any.
+func (f *Failover) unwatch() error {
+	f.watchMu.Lock()
+	defer f.watchMu.Unlock()
+	f.watchT.Stop()
+	err := f.watchErr
+	f.watching, f.watchErr = nil, nil
+	return err
+}
+
+// fireRecheck runs HealthCheck, and stops the stream if it fails.
+func (f *Failover) fireRecheck() {
+	f.watchMu.Lock()
+	bl := f.watching
+	f.watchMu.Unlock()
+	if bl == nil {
+		return
+	}
+	err := f.HealthCheck(bl)
+	f.watchMu.Lock()
+	if f.watching != bl {
+		f.watchMu.Unlock()
+		return
+	}
+	if err == nil {
+		f.watchT.Reset(f.RecheckInterval)
+		f.watchMu.Unlock()
+		return
+	}
+	f.watchErr = err
+	f.watchMu.Unlock()
+	_, _, _ = bl.Stop(context.Background())
+}
+
+// track adds gtid of transaction to Executed, when it completes.
+func (f *Failover) track(e Event) {
+	switch d := e.Data.(type) {
+	case GTIDEvent:
+		f.pending, f.begun = &d, false
+	case XIDEvent, XAPrepareEvent:
+		f.commit()
+	case QueryEvent:
+		begin, end := txBoundary(e)
+		switch {
+		case begin:
+			f.begun = true
+		case end || !f.begun: // DDL is transaction by itself
+			f.commit()
+		}
+	}
+}
+
+func (f *Failover) commit() {
+	if f.pending != nil {
+		f.Executed.AddGTID(*f.pending)
+	}
+	f.pending, f.begun = nil, false
+}
@@ -0,0 +1,524 @@
+package binlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeServer is an in-memory mysql server for tests. It implements
+// handshake, authentication, COM_QUERY for the metadata queries used
+// by Remote and COM_BINLOG_DUMP by replaying binlog files.
+//
+// Connections are made over net.Pipe, so no network is required.
+type fakeServer struct {
+	version  string // server version
+	plugin   string // default auth plugin
+	user     string
+	password string
+	checksum bool   // whether artificial RotateEvent has crc32 checksum
+	scramble []byte // 20 bytes
+
+	// files has contents of binlog files including magic header,
+	// in the order they were created.
+	files []fakeBinlogFile
+
+	// queries maps query to its result. first row has column names.
+	// use setQuery, once connections are made.
+	mu      sync.Mutex
+	queries map[string][][]string
+
+	// denied has queries failing with access denied error.
+	denied map[string]bool
+
+	// idle, if non-nil, is waited on after replaying files,
+	// to simulate master waiting for new events.
+	idle chan struct{}
+
+	// drop closes the connection after replaying files,
+	// to simulate server failure.
+	drop bool
+}
+
+type fakeBinlogFile struct {
+	name string
+	data []byte
+}
+
+func newFakeServer() *fakeServer {
+	return &fakeServer{
+		version:  "8.0.23",
+		plugin:   "mysql_native_password",
+		user:     "root",
+		password: "password",
+		checksum: true,
+		scramble: []byte("abcdefghijklmnopqrst"),
+		queries:  make(map[string][][]string),
+		denied:   make(map[string]bool),
+	}
+}
+
+// addFile adds binlog file with given stream.
+func (s *fakeServer) addFile(name string, stream *binlogStream) {
+	s.files = append(s.files, fakeBinlogFile{name, stream.Bytes()})
+}
+
+// setQuery sets result of query q, safe for use while serving.
+func (s *fakeServer) setQuery(q string, rows [][]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queries[q] = rows
+}
+
+// DialContext implements Dialer.
+func (s *fakeServer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	client, server := net.Pipe()
+	go func() {
+		defer server.Close()
+		_ = s.serve(server)
+	}()
+	return client, nil
+}
+
+// listen serves connections over loopback tcp, for tests
+// of socket options. Returns address of listener.
+func (s *fakeServer) listen(tb testing.TB) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tb.Skip("tcp listen:", err)
+	}
+	tb.Cleanup(func() { _ = l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_ = s.serve(conn)
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+// dial returns authenticated Remote.
+func (s *fakeServer) dial() (*Remote, error) {
+	bl, err := DialWith(s, "tcp", "fake:3306")
+	if err != nil {
+		return nil, err
+	}
+	if err := bl.Authenticate(s.user, s.password); err != nil {
+		_ = bl.Close()
+		return nil, err
+	}
+	return bl, nil
+}
+
+type fakeConn struct {
+	conn net.Conn
+	seq  uint8
+}
+
+func (c *fakeConn) readPacket() ([]byte, error) {
+	return ioutil.ReadAll(&packetReader{rd: c.conn, seq: &c.seq})
+}
+
+func (c *fakeConn) writePacket(b []byte) error {
+	if len(b) < maxPacketSize {
+		// avoid allocating max sized buffer of writer
+		p := append([]byte{byte(len(b)), byte(len(b) >> 8), byte(len(b) >> 16), c.seq}, b...)
+		c.seq++
+		_, err := c.conn.Write(p)
+		return err
+	}
+	w := newWriter(c.conn, &c.seq)
+	if _, err := w.Write(b); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+func (c *fakeConn) writeOK() error {
+	return c.writePacket([]byte{okMarker, 0, 0, 0, 0, 0, 0})
+}
+
+func (c *fakeConn) writeErr(code uint16, msg string) error {
+	b := []byte{errMarker, byte(code), byte(code >> 8), '#'}
+	b = append(b, "HY000"...)
+	return c.writePacket(append(b, msg...))
+}
+
+func (c *fakeConn) writeEOF() error {
+	return c.writePacket([]byte{eofMarker, 0, 0, 0, 0})
+}
+
+func (s *fakeServer) serve(conn net.Conn) error {
+	c := &fakeConn{conn: conn}
+	if err := s.handshake(c); err != nil {
+		return err
+	}
+	for {
+		c.seq = 0
+		p, err := c.readPacket()
+		if err != nil {
+			return err
+		}
+		if len(p) == 0 {
+			return ErrMalformedPacket
+		}
+		switch p[0] {
+		case 0x01: // COM_QUIT
+			return nil
+		case 0x03: // COM_QUERY
+			if err := s.query(c, string(p[1:])); err != nil {
+				return err
+			}
+		case 0x12: // COM_BINLOG_DUMP
+			if err := s.binlogDump(c, p[1:]); err != nil {
+				return err
+			}
+		case 0x1e: // COM_BINLOG_DUMP_GTID
+			if err := s.binlogDumpGTID(c, p[1:]); err != nil {
+				return err
+			}
+		default:
+			if err := c.writeErr(1047, "Unknown command"); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *fakeServer) handshake(c *fakeConn) error {
+	caps := uint32(capLongPassword | capLongFlag | capProtocol41 | capTransactions | capSecureConnection | capPluginAuth)
+	var b []byte
+	b = append(b, 10)
+	b = append(b, s.version...)
+	b = append(b, 0)
+	b = append(b, 1, 0, 0, 0) // connection id
+	b = append(b, s.scramble[:8]...)
+	b = append(b, 0)
+	b = append(b, byte(caps), byte(caps>>8))
+	b = append(b, 0xff, 0, 0) // charset, status
+	b = append(b, byte(caps>>16), byte(caps>>24))
+	b = append(b, 21)
+	b = append(b, make([]byte, 10)...)
+	b = append(b, s.scramble[8:]...)
+	b = append(b, 0)
+	b = append(b, s.plugin...)
+	b = append(b, 0)
+	if err := c.writePacket(b); err != nil {
+		return err
+	}
+
+	// handshakeResponse41
+	p, err := c.readPacket()
+	if err != nil {
+		return err
+	}
+	if len(p) < 32 {
+		return ErrMalformedPacket
+	}
+	p = p[32:]
+	i := bytes.IndexByte(p, 0)
+	if i == -1 || i+1 >= len(p) {
+		return ErrMalformedPacket
+	}
+	user := string(p[:i])
+	p = p[i+1:]
+	n := int(p[0])
+	if 1+n > len(p) {
+		return ErrMalformedPacket
+	}
+	authResponse := p[1 : 1+n]
+	plugin := strings.TrimRight(string(p[1+n:]), "\x00")
+
+	if user != s.user || !bytes.Equal(authResponse, s.authResponse(plugin)) {
+		return c.writeErr(1045, fmt.Sprintf("Access denied for user '%s'", user))
+	}
+	if plugin == "caching_sha2_password" {
+		// fast auth success
+		if err := c.writePacket([]byte{0x01, 0x03}); err != nil {
+			return err
+		}
+	}
+	return c.writeOK()
+}
+
+// authResponse returns expected auth response for plugin.
+func (s *fakeServer) authResponse(plugin string) []byte {
+	resp, _ := (&Remote{}).encryptPassword(plugin, []byte(s.password), s.scramble)
+	return resp
+}
+
+func (s *fakeServer) query(c *fakeConn, q string) error {
+	if s.denied[q] {
+		return c.writeErr(1227, "Access denied; you need (at least one of) the SUPER privilege(s) for this operation")
+	}
+	s.mu.Lock()
+	rows, ok := s.queries[q]
+	s.mu.Unlock()
+	if ok {
+		return c.writeResultSet(rows)
+	}
+	switch strings.ToLower(q) {
+	case "select version()":
+		return c.writeResultSet([][]string{{"version()"}, {s.version}})
+	case "show binary logs":
+		rows := [][]string{{"Log_name", "File_size"}}
+		for _, f := range s.files {
+			rows = append(rows, []string{f.name, strconv.Itoa(len(f.data))})
+		}
+		return c.writeResultSet(rows)
+	case "show master status", "show binary log status":
+		rows := [][]string{{"File", "Position"}}
+		if len(s.files) > 0 {
+			f := s.files[len(s.files)-1]
+			rows = append(rows, []string{f.name, strconv.Itoa(len(f.data))})
+		}
+		return c.writeResultSet(rows)
+	}
+	if upper := strings.ToUpper(q); strings.HasPrefix(upper, "SET ") || strings.HasPrefix(upper, "CALL ") {
+		return c.writeOK()
+	}
+	return c.writeErr(1064, "unsupported query: "+q)
+}
+
+// writeResultSet writes text resultset. first row has column names.
+func (c *fakeConn) writeResultSet(rows [][]string) error {
+	if err := c.writePacket([]byte{byte(len(rows[0]))}); err != nil {
+		return err
+	}
+	for _, name := range rows[0] {
+		w := newWriter(c.conn, &c.seq)
+		for _, s := range []string{"def", "", "", "", name, name} {
+			w.stringN(s)
+		}
+		w.Write([]byte{0x0c, 0xff, 0, 0, 1, 0, 0, 253, 0, 0, 0, 0, 0})
+		if err := w.Close(); err != nil {
+			return err
+		}
+	}
+	if err := c.writeEOF(); err != nil {
+		return err
+	}
+	for _, row := range rows[1:] {
+		w := newWriter(c.conn, &c.seq)
+		for _, v := range row {
+			w.stringN(v)
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+	}
+	return c.writeEOF()
+}
+
+// binlogDump replays events of binlog files starting from requested
+// position, preceded by artificial RotateEvent as mysql server does.
+func (s *fakeServer) binlogDump(c *fakeConn, p []byte) error {
+	if len(p) < 10 {
+		return ErrMalformedPacket
+	}
+	pos := binary.LittleEndian.Uint32(p)
+	name := string(p[10:])
+	i := 0
+	for i < len(s.files) && s.files[i].name != name {
+		i++
+	}
+	if i == len(s.files) {
+		return c.writeErr(1236, "Could not find first log file name in binary log index file")
+	}
+	for ; i < len(s.files); i++ {
+		f := s.files[i]
+		if err := c.writePacket(append([]byte{okMarker}, s.rotateEvent(f.name, uint64(pos))...)); err != nil {
+			return err
+		}
+		off := uint32(4)
+		for off < uint32(len(f.data)) {
+			size := binary.LittleEndian.Uint32(f.data[off+9:])
+			typ := EventType(f.data[off+4])
+			if off == 4 || off >= pos || typ == FORMAT_DESCRIPTION_EVENT {
+				if err := c.writePacket(append([]byte{okMarker}, f.data[off:off+size]...)); err != nil {
+					return err
+				}
+			}
+			off += size
+		}
+		pos = 4
+	}
+	return s.endDump(c)
+}
+
+// endDump waits on idle and ends the dump, by closing the
+// connection if drop is set.
+func (s *fakeServer) endDump(c *fakeConn) error {
+	if s.idle != nil {
+		<-s.idle
+	}
+	if s.drop {
+		return io.ErrClosedPipe
+	}
+	return c.writeEOF()
+}
+
+// binlogDumpGTID replays events of all binlog files, skipping the
+// transactions whose gtids are in the requested set.
+func (s *fakeServer) binlogDumpGTID(c *fakeConn, p []byte) error {
+	if len(p) < 10 {
+		return ErrMalformedPacket
+	}
+	n := int(binary.LittleEndian.Uint32(p[6:]))
+	p = p[10:]
+	if len(p) < n+12 {
+		return ErrMalformedPacket
+	}
+	p = p[n+8+4:] // name, pos, data size
+	executed := GTIDSet{}
+	nsids := binary.LittleEndian.Uint64(p)
+	p = p[8:]
+	for i := uint64(0); i < nsids; i++ {
+		var sid [16]byte
+		copy(sid[:], p)
+		nivs := binary.LittleEndian.Uint64(p[16:])
+		p = p[24:]
+		for j := uint64(0); j < nivs; j++ {
+			start := int64(binary.LittleEndian.Uint64(p))
+			end := int64(binary.LittleEndian.Uint64(p[8:]))
+			executed.addInterval(formatUUID(sid), GTIDInterval{start, end - 1})
+			p = p[16:]
+		}
+	}
+	for _, f := range s.files {
+		if err := c.writePacket(append([]byte{okMarker}, s.rotateEvent(f.name, 4)...)); err != nil {
+			return err
+		}
+		skip := false
+		off := uint32(4)
+		for off < uint32(len(f.data)) {
+			size := binary.LittleEndian.Uint32(f.data[off+9:])
+			typ := EventType(f.data[off+4])
+			if typ == GTID_EVENT {
+				var sid [16]byte
+				copy(sid[:], f.data[off+20:])
+				gno := int64(binary.LittleEndian.Uint64(f.data[off+36:]))
+				skip = executed.Contains(formatUUID(sid), gno)
+			}
+			if !skip || typ == FORMAT_DESCRIPTION_EVENT {
+				if err := c.writePacket(append([]byte{okMarker}, f.data[off:off+size]...)); err != nil {
+					return err
+				}
+			}
+			off += size
+		}
+	}
+	return s.endDump(c)
+}
+
+// rotateEvent returns artificial RotateEvent.
+func (s *fakeServer) rotateEvent(name string, pos uint64) []byte {
+	buf := &bytes.Buffer{}
+	enc := NewEventEncoder(buf, 4, s.checksum)
+	enc.Flags = LOG_EVENT_ARTIFICIAL_F
+	enc.Rotate(name, pos)
+	return buf.Bytes()[len(fileHeader):]
+}
+
+func TestFakeServer_auth(t *testing.T) {
+	for _, plugin := range []string{"mysql_native_password", "caching_sha2_password"} {
+		t.Run(plugin, func(t *testing.T) {
+			s := newFakeServer()
+			s.plugin = plugin
+			bl, err := s.dial()
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = bl.Close()
+
+			s.password = "wrong"
+			bl, err = DialWith(s, "tcp", "fake:3306")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bl.Close()
+			if err := bl.Authenticate("root", "password"); err == nil {
+				t.Fatal("authentication must fail for wrong password")
+			}
+		})
+	}
+}
+
+func TestFakeServer_binlogDump(t *testing.T) {
+	s := newFakeServer()
+	f1 := newBinlogStream()
+	f1.XID(7)
+	f1.XID(8)
+	s.addFile("binlog.000001", f1)
+	f2 := newBinlogStream()
+	f2.XID(9)
+	s.addFile("binlog.000002", f2)
+
+	bl, err := s.dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bl.Close()
+	files, err := bl.ListFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(files, []string{"binlog.000001", "binlog.000002"}) {
+		t.Fatal("ListFiles:", files)
+	}
+	file, pos, err := bl.MasterStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file != "binlog.000002" || pos != uint32(f2.Len()) {
+		t.Fatal("MasterStatus:", file, pos)
+	}
+
+	// seek past xid 7
+	xid8 := uint32(f1.Len() - 31)
+	if err := bl.Seek(0, "binlog.000001", xid8); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for {
+		e, err := bl.NextEvent()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch d := e.Data.(type) {
+		case RotateEvent:
+			got = append(got, fmt.Sprintf("rotate %s:%d", d.NextBinlog, d.Position))
+		case FormatDescriptionEvent:
+			got = append(got, "fde")
+		case XIDEvent:
+			got = append(got, fmt.Sprintf("xid %d", d.XID))
+		default:
+			t.Fatalf("unexpected event %#v", e.Data)
+		}
+	}
+	want := []string{
+		fmt.Sprintf("rotate binlog.000001:%d", xid8), "fde", "xid 8",
+		"rotate binlog.000002:4", "fde", "xid 9",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
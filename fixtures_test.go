@@ -0,0 +1,347 @@
+package binlog
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// binlog files in testdata/fixtures are golden. They are written by
+// the fixture builders below and committed. They are not captured from
+// real servers: each mimics binlog of the server version it is named
+// after, in binlog version, FormatDescriptionEvent, event types used,
+// and column types of its tables. Regenerate them with:
+//
+//	go test -run TestFixtures -update-fixtures
+//
+// Decode tests always read the committed files, so that a change in
+// the builders does not silently change what is being tested.
+var updateFixtures = flag.Bool("update-fixtures", false, "regenerate binlog files in testdata/fixtures")
+
+// newFixtureStream returns binlogStream for fixtures, with events
+// stamped as written by server 1.
+func newFixtureStream(version uint16, checksum bool) *binlogStream {
+	s := newEncodedStream(version, checksum)
+	s.ServerID, s.Timestamp = 1, 1600000000
+	return s
+}
+
+// allTypes table has one column of each type ---
+
+var allTypesColumns = []byte{
+	byte(TypeTiny), byte(TypeTiny), byte(TypeShort), byte(TypeInt24), byte(TypeLong),
+	byte(TypeLongLong), byte(TypeFloat), byte(TypeDouble), byte(TypeNewDecimal),
+	byte(TypeVarchar), byte(TypeString), byte(TypeBlob), byte(TypeBlob),
+	byte(TypeString), byte(TypeString), // enum, set
+	byte(TypeBit), byte(TypeYear), byte(TypeDate), byte(TypeDateTime2),
+	byte(TypeTimestamp2), byte(TypeTime2), byte(TypeJSON),
+}
+
+var allTypesMeta = []byte{
+	4,     // float
+	8,     // double
+	10, 2, // decimal(10,2)
+	0x50, 0, // varchar(20) utf8mb4
+	0xfe, 40, // char(10) utf8mb4
+	2,       // text
+	2,       // blob
+	0xf7, 1, // enum
+	0xf8, 1, // set
+	2, 1, // bit(10)
+	0, // datetime
+	3, // timestamp(3)
+	0, // time
+	4, // json
+}
+
+var allTypesNames = []string{
+	"c_tiny", "c_utiny", "c_short", "c_int24", "c_long", "c_longlong", "c_float", "c_double",
+	"c_decimal", "c_varchar", "c_char", "c_text", "c_blob", "c_enum", "c_set", "c_bit",
+	"c_year", "c_date", "c_datetime", "c_timestamp", "c_time", "c_json",
+}
+
+// binlog_row_metadata=FULL
+func allTypesExtMeta() []TableMetadata {
+	var names []byte
+	for _, name := range allTypesNames {
+		names = append(names, byte(len(name)))
+		names = append(names, name...)
+	}
+	return []TableMetadata{
+		{1, []byte{0x40, 0x00}},                // unsigned: c_utiny
+		{2, []byte{0xfc, 0xff, 0x00, 12, 63}},  // default charset utf8mb4, c_blob binary
+		{4, names},                             // column names
+		{5, []byte{3, 1, 'x', 1, 'y', 1, 'z'}}, // set values
+		{6, []byte{3, 1, 'a', 1, 'b', 1, 'c'}}, // enum values
+		{10, []byte{0xfc, 0xff, 0x00}},         // enum/set default charset utf8mb4
+		{12, []byte{0xff, 0xff, 0xfc}},         // column visibility: ignored
+	}
+}
+
+var allTypesRow = bytes.Join([][]byte{
+	{0, 0, 0},                // null bitmap
+	{0xfb},                   // -5
+	{0xfa},                   // 250
+	{0xd4, 0xfe},             // -300
+	{0xfe, 0xff, 0xff},       // -2
+	{0xa0, 0x86, 0x01, 0x00}, // 100000
+	{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, // -1
+	{0x00, 0x00, 0xc0, 0x3f},                         // 1.5
+	{0, 0, 0, 0, 0, 0, 0x02, 0xc0},                   // -2.25
+	{0x80, 0x00, 0x04, 0xd2, 0x38},                   // 1234.56
+	{6, 'h', 0xc3, 0xa9, 'l', 'l', 'o'},
+	{3, 'a', 'b', 'c'},
+	{4, 0, 't', 'e', 'x', 't'},
+	{2, 0, 1, 2},
+	{2},                                  // b
+	{5},                                  // x,z
+	{0x02, 0x01},                         // 513
+	{121},                                // 2021
+	{0x64, 0xca, 0x0f},                   // 2021-03-04
+	{0x99, 0xa9, 0x08, 0x51, 0x87},       // 2021-03-04 05:06:07
+	{0x5f, 0x5e, 0x10, 0x00, 0x04, 0xce}, // 1600000000.123
+	{0x80, 0xc8, 0xb8},                   // 12:34:56
+	{13, 0, 0, 0, 0x00, 1, 0, 12, 0, 11, 0, 1, 0, 0x05, 1, 0, 'a'}, // {"a":1}
+}, nil)
+
+var allTypesNullRow = []byte{0xff, 0xff, 0x3f}
+
+func allTypesFixture(full bool) []byte {
+	w := newFixtureStream(4, true)
+	w.FormatDescription("8.0.23", 40, 1)
+	w.Query("test", "BEGIN")
+	var extMeta []TableMetadata
+	if full {
+		extMeta = allTypesExtMeta()
+	}
+	w.TableMap(100, "test", "all_types", allTypesColumns, allTypesMeta, []byte{0xff, 0xff, 0x3f}, extMeta...)
+	w.Rows(WRITE_ROWS_EVENTv2, 100, true, len(allTypesColumns), allTypesRow, allTypesNullRow)
+	w.XID(10)
+	return w.Bytes()
+}
+
+// single int column table. used by fixtures other than allTypes
+func singleColumnTx(w *binlogStream, rowsType EventType, xid uint64) {
+	w.Query("test", "BEGIN")
+	w.TableMap(101, "test", "t", []byte{byte(TypeLong)}, nil, []byte{0x01})
+	w.Rows(rowsType, 101, true, 1, []byte{0, 42, 0, 0, 0})
+	w.XID(xid)
+}
+
+// legacyTypes table has column types of MySQL 5.5 and older, along with
+// the types not covered by allTypes.
+var legacyTypesColumns = []byte{
+	byte(TypeLong), byte(TypeTimestamp), byte(TypeDateTime), byte(TypeTime),
+	byte(TypeNewDate), byte(TypeVarString), byte(TypeGeometry),
+}
+
+var legacyTypesMeta = []byte{
+	20, 0, // varstring(20)
+	4, // geometry
+}
+
+var legacyTypesRow = bytes.Join([][]byte{
+	{0},                                      // null bitmap
+	{42, 0, 0, 0},                            // 42
+	{0x00, 0x10, 0x5e, 0x5f},                 // 1600000000
+	{175, 101, 254, 147, 97, 18, 0, 0},       // 2021-03-04 05:06:07
+	{0xc0, 0x1d, 0xfe},                       // -12:34:56
+	{0x64, 0xca, 0x0f},                       // 2021-03-04
+	{3, 'a', 'b', 'c'},                       // abc
+	append([]byte{25, 0, 0, 0}, pointWKB...), // POINT(1 2)
+}, nil)
+
+// pointWKB is POINT(1 2) with SRID 0, as stored by MySQL.
+var pointWKB = []byte{0, 0, 0, 0, 1, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xf0, 0x3f, 0, 0, 0, 0, 0, 0, 0, 0x40}
+
+var fixtures = map[string]func() []byte{
+	"mysql80_full":    func() []byte { return allTypesFixture(true) },
+	"mysql80_minimal": func() []byte { return allTypesFixture(false) },
+	"mysql80_nochecksum": func() []byte {
+		w := newFixtureStream(4, false)
+		w.FormatDescription("8.0.23", 40, 0)
+		singleColumnTx(w, WRITE_ROWS_EVENTv2, 11)
+		return w.Bytes()
+	},
+	"mysql55": func() []byte {
+		w := newFixtureStream(4, false)
+		w.FormatDescription("5.5.62-log", 27, -1)
+		w.Query("test", "BEGIN")
+		w.TableMap(102, "test", "legacy_types", legacyTypesColumns, legacyTypesMeta, []byte{0x7f})
+		w.Rows(WRITE_ROWS_EVENTv1, 102, true, len(legacyTypesColumns), legacyTypesRow)
+		w.XID(12)
+		return w.Bytes()
+	},
+	"mariadb": func() []byte {
+		w := newFixtureStream(4, true)
+		w.FormatDescription("10.5.8-MariaDB-log", 164, 1)
+		w.Event(163, []byte{0, 0, 0, 0})                            // GTID_LIST_EVENT
+		w.Event(162, []byte{1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}) // GTID_EVENT
+		singleColumnTx(w, WRITE_ROWS_EVENTv1, 13)
+		return w.Bytes()
+	},
+	"mysql40_v3": func() []byte {
+		w := newFixtureStream(3, false)
+		w.StartV3("4.0.30-log")
+		w.Query("test", "insert into t values(42)")
+		return w.Bytes()
+	},
+	"mysql323_v1": func() []byte {
+		w := newFixtureStream(1, false)
+		w.StartV3("3.23.58-log")
+		w.Query("test", "insert into t values(42)")
+		return w.Bytes()
+	},
+}
+
+func TestFixtures_update(t *testing.T) {
+	if !*updateFixtures {
+		t.Skip("run with -update-fixtures to regenerate")
+	}
+	for name, fixture := range fixtures {
+		dir := filepath.Join("testdata", "fixtures", name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "binlog.000001"), fixture(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFixtures(t *testing.T) {
+	allTypesValues := func(full bool) []interface{} {
+		utiny, text := interface{}(int8(-6)), interface{}([]byte("text"))
+		var enumValues, setValues []string
+		if full {
+			utiny, text = uint8(250), "text"
+			enumValues, setValues = []string{"a", "b", "c"}, []string{"x", "y", "z"}
+		}
+		return []interface{}{
+			int8(-5), utiny, int16(-300), int32(-2), int32(100000), int64(-1),
+			float32(1.5), float64(-2.25), Decimal("1234.56"), "héllo", "abc", text, []byte{1, 2},
+			Enum{2, enumValues}, Set{5, setValues}, uint64(513), 2021,
+			time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC),
+			time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC),
+			time.Unix(1600000000, 123000000),
+			12*time.Hour + 34*time.Minute + 56*time.Second,
+			JSON{map[string]interface{}{"a": int16(1)}},
+		}
+	}
+	nulls := make([]interface{}, len(allTypesColumns))
+	singleColumnRows := [][]interface{}{{int32(42)}}
+
+	tests := []struct {
+		name   string
+		events []EventType
+		rows   [][]interface{}
+	}{
+		{
+			name:   "mysql80_full",
+			events: []EventType{FORMAT_DESCRIPTION_EVENT, QUERY_EVENT, TABLE_MAP_EVENT, WRITE_ROWS_EVENTv2, XID_EVENT},
+			rows:   [][]interface{}{allTypesValues(true), nulls},
+		},
+		{
+			name:   "mysql80_minimal",
+			events: []EventType{FORMAT_DESCRIPTION_EVENT, QUERY_EVENT, TABLE_MAP_EVENT, WRITE_ROWS_EVENTv2, XID_EVENT},
+			rows:   [][]interface{}{allTypesValues(false), nulls},
+		},
+		{
+			name:   "mysql80_nochecksum",
+			events: []EventType{FORMAT_DESCRIPTION_EVENT, QUERY_EVENT, TABLE_MAP_EVENT, WRITE_ROWS_EVENTv2, XID_EVENT},
+			rows:   singleColumnRows,
+		},
+		{
+			name:   "mysql55",
+			events: []EventType{FORMAT_DESCRIPTION_EVENT, QUERY_EVENT, TABLE_MAP_EVENT, WRITE_ROWS_EVENTv1, XID_EVENT},
+			rows: [][]interface{}{{
+				int32(42), time.Unix(1600000000, 0),
+				time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC),
+				-(12*time.Hour + 34*time.Minute + 56*time.Second),
+				time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC),
+				"abc", pointWKB,
+			}},
+		},
+		{
+			name:   "mariadb",
+			events: []EventType{FORMAT_DESCRIPTION_EVENT, 163, 162, QUERY_EVENT, TABLE_MAP_EVENT, WRITE_ROWS_EVENTv1, XID_EVENT},
+			rows:   singleColumnRows,
+		},
+		{
+			name:   "mysql40_v3",
+			events: []EventType{START_EVENT_V3, QUERY_EVENT},
+		},
+		{
+			name:   "mysql323_v1",
+			events: []EventType{START_EVENT_V3, QUERY_EVENT},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bl, err := Open(filepath.Join("testdata", "fixtures", test.name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bl.Close()
+			if err := bl.Seek(0, "binlog.000001", 4); err != nil {
+				t.Fatal(err)
+			}
+			var events []EventType
+			var rows [][]interface{}
+			for {
+				e, err := bl.NextEvent()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				events = append(events, e.Header.EventType)
+				switch d := e.Data.(type) {
+				case RowsEvent:
+					for {
+						values, _, err := bl.NextRow()
+						if err == io.EOF {
+							break
+						}
+						if err != nil {
+							t.Fatal(err)
+						}
+						rows = append(rows, values)
+					}
+				case TableMapEvent:
+					if test.name == "mysql80_full" {
+						for i, col := range d.Columns {
+							if col.Name != allTypesNames[i] {
+								t.Errorf("column %d: got name %q, want %q", i, col.Name, allTypesNames[i])
+							}
+						}
+					}
+				case QueryEvent:
+					if d.Schema != "test" {
+						t.Errorf("got schema %q, want test", d.Schema)
+					}
+				}
+			}
+			if !reflect.DeepEqual(events, test.events) {
+				t.Fatalf("events: got %v, want %v", events, test.events)
+			}
+			if len(rows) != len(test.rows) {
+				t.Fatalf("got %d rows, want %d", len(rows), len(test.rows))
+			}
+			for i := range rows {
+				for j := range rows[i] {
+					if got, want := rows[i][j], test.rows[i][j]; !reflect.DeepEqual(got, want) {
+						t.Errorf("row %d column %d: got %#v, want %#v", i, j, got, want)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package binlog
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FS is the file system on which dump directory is accessed by
+// Local and Dump. This allows to read binlogs from in-memory file
+// systems in tests, or network file systems with custom semantics.
+//
+// Names are slash separated paths. Read-only file systems need
+// to implement only Open, Stat and ReadDir; others can return error.
+//
+// Rename must replace newname atomically, if it exists.
+type FS interface {
+	Open(name string) (File, error)
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+	Stat(name string) (os.FileInfo, error)
+	ReadDir(name string) ([]os.FileInfo, error) // sorted by name
+	Remove(name string) error
+	Rename(oldname, newname string) error
+}
+
+// File is an open file in FS.
+type File interface {
+	io.Reader
+	io.Writer
+	io.Seeker
+	io.Closer
+	Name() string
+	Stat() (os.FileInfo, error)
+}
+
+// OSFS is FS implemented using os package. Slash separated names
+// are converted to os specific paths.
+var OSFS FS = osFS{}
+
+type osFS struct{}
+
+func (osFS) Open(name string) (File, error) {
+	return os.Open(filepath.FromSlash(name))
+}
+
+func (osFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	return os.OpenFile(filepath.FromSlash(name), flag, perm)
+}
+
+func (osFS) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(filepath.FromSlash(name))
+}
+
+func (osFS) ReadDir(name string) ([]os.FileInfo, error) {
+	return ioutil.ReadDir(filepath.FromSlash(name))
+}
+
+func (osFS) Remove(name string) error {
+	return os.Remove(filepath.FromSlash(name))
+}
+
+func (osFS) Rename(oldname, newname string) error {
+	return os.Rename(filepath.FromSlash(oldname), filepath.FromSlash(newname))
+}
+
+func readFile(fsys FS, name string) ([]byte, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ioutil.ReadAll(f)
+}
+
+func writeFile(fsys FS, name string, data []byte) error {
+	f, err := fsys.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	return err
+}
+
+// writeFileAtomic writes data to a temporary file and renames it
+// to name, so that concurrent readers never see partial content.
+func writeFileAtomic(fsys FS, name string, data []byte) error {
+	tmp := name + ".tmp"
+	if err := writeFile(fsys, tmp, data); err != nil {
+		return err
+	}
+	return fsys.Rename(tmp, name)
+}
+
+// ErrDirLocked is returned when dump directory is locked by another
+// Dump or Local for longer than lockTimeout. The .lock file records
+// host and pid of its owner, and a lock left by a dead process on this
+// host is removed automatically. A lock left by a dead process on
+// another host, which shares the directory, must be removed manually.
+var ErrDirLocked = errors.New("binlog: dump directory is locked")
+
+var lockTimeout = 10 * time.Second
+
+// lockDir acquires lock on dump directory, by creating .lock file
+// in it exclusively. The returned function releases the lock.
+func lockDir(fsys FS, dir string, clock Clock) (unlock func() error, err error) {
+	clock = clockOrSystem(clock)
+	name := path.Join(dir, ".lock")
+	owner := lockOwner(os.Getpid())
+	deadline := clock.Now().Add(lockTimeout)
+	for {
+		f, err := fsys.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+		if err == nil {
+			_, err = f.Write([]byte(owner))
+			if err1 := f.Close(); err == nil {
+				err = err1
+			}
+			if err != nil {
+				_ = fsys.Remove(name)
+				return nil, err
+			}
+			return func() error { return fsys.Remove(name) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if removeStaleLock(fsys, name) {
+			continue
+		}
+		if clock.Now().After(deadline) {
+			return nil, ErrDirLocked
+		}
+		clock.Sleep(10 * time.Millisecond)
+	}
+}
+
+// lockOwner returns content of .lock file, owned by given process
+// of this host.
+func lockOwner(pid int) string {
+	host, _ := os.Hostname()
+	return host + " " + strconv.Itoa(pid) + "\n"
+}
+
+// removeStaleLock removes lock file, if its owner is a process of this
+// host, which is no longer running. It tells whether lock is removed.
+func removeStaleLock(fsys FS, name string) bool {
+	buf, err := readFile(fsys, name)
+	if err != nil {
+		return false
+	}
+	host, _ := os.Hostname()
+	fields := strings.Fields(string(buf))
+	if len(fields) != 2 || fields[0] != host {
+		return false
+	}
+	pid, err := strconv.Atoi(fields[1])
+	if err != nil || pid == os.Getpid() || processAlive(pid) {
+		return false
+	}
+	// another process may have found the same stale lock, and replaced
+	// it with its own by now
+	if now, err := readFile(fsys, name); err != nil || !bytes.Equal(now, buf) {
+		return false
+	}
+	return fsys.Remove(name) == nil
+}
+
+// glob returns names of files in dir matching pattern, in sorted order.
+func glob(fsys FS, dir, pattern string) ([]string, error) {
+	infos, err := fsys.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, info := range infos {
+		if ok, err := path.Match(pattern, info.Name()); err != nil {
+			return nil, err
+		} else if ok {
+			names = append(names, path.Join(dir, info.Name()))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,32 @@
+package binlog
+
+import "strings"
+
+// GeneratedColumns names generated columns of tables, keyed by
+// "schema.table". Columns are named, matched case insensitively, or
+// given as "@ordinal" if binlog_row_metadata is not FULL.
+//
+// Binlog does not record which columns are generated, so they must be
+// declared, typically from information_schema.COLUMNS where EXTRA has
+// "GENERATED". Values of virtual generated columns are not logged with
+// binlog_row_image=MINIMAL. Such columns are left out of the presence
+// bitmaps of RowsEvent, and hence of RowsEvent.Columns, which always
+// align with decoded values.
+type GeneratedColumns map[string][]string
+
+// mark sets Column.Generated of columns of e.
+func (g GeneratedColumns) mark(e *TableMapEvent) {
+	names, ok := g[e.SchemaName+"."+e.TableName]
+	if !ok && e.OrigTableName != "" {
+		names = g[e.SchemaName+"."+e.OrigTableName]
+	}
+	for i := range e.Columns {
+		col := &e.Columns[i]
+		for _, name := range names {
+			if strings.EqualFold(name, columnName(*col)) {
+				col.Generated = true
+				break
+			}
+		}
+	}
+}
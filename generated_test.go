@@ -0,0 +1,69 @@
+package binlog
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestReader_SetGeneratedColumns(t *testing.T) {
+	w := newEncodedStream(4, true)
+	w.FormatDescription("8.0.23", 40, 1)
+	w.Query("test", "BEGIN")
+	types := []byte{byte(TypeLong), byte(TypeLong), byte(TypeVarchar)}
+	names := []byte{2, 'i', 'd', 5, 't', 'o', 't', 'a', 'l', 4, 'n', 'a', 'm', 'e'}
+	w.TableMap(100, "test", "t", types, []byte{20, 0}, []byte{0x06}, TableMetadata{4, names})
+	// binlog_row_image=MINIMAL: before image has id, after image
+	// has id and name, but not virtual column total.
+	w.Event(UPDATE_ROWS_EVENTv2, []byte{100, 0, 0, 0, 0, 0, rowsEventStmtEnd, 0, 2, 0, 3, 0x01, 0x05},
+		[]byte{0, 7, 0, 0, 0},
+		[]byte{0, 7, 0, 0, 0, 3, 'b', 'o', 'b'},
+	)
+	w.XID(10)
+
+	bl := NewReader(bytes.NewReader(w.Bytes()))
+	bl.SetGeneratedColumns(GeneratedColumns{"test.t": {"TOTAL"}})
+	for {
+		e, err := bl.NextEvent()
+		if err == io.EOF {
+			t.Fatal("no rows event")
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tme, ok := e.Data.(TableMapEvent); ok {
+			var generated []bool
+			for _, col := range tme.Columns {
+				generated = append(generated, col.Generated)
+			}
+			if !reflect.DeepEqual(generated, []bool{false, true, false}) {
+				t.Fatalf("got generated %v", generated)
+			}
+			if !tme.Schema().Columns[1].Generated {
+				t.Fatal("schema: total not generated")
+			}
+		}
+		re, ok := e.Data.(RowsEvent)
+		if !ok {
+			continue
+		}
+		values, before, err := bl.NextRow()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(before, []interface{}{int32(7)}) {
+			t.Fatalf("got before %v", before)
+		}
+		if !reflect.DeepEqual(values, []interface{}{int32(7), "bob"}) {
+			t.Fatalf("got after %v", values)
+		}
+		if cols := re.Columns(); len(cols) != 2 || cols[1].Name != "name" {
+			t.Fatalf("got columns %v", cols)
+		}
+		if _, _, err := bl.NextRow(); err != io.EOF {
+			t.Fatalf("got %v, want io.EOF", err)
+		}
+		return
+	}
+}
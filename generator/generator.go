@@ -0,0 +1,267 @@
+// Package generator fabricates valid binlog streams, with row based
+// events of configurable tables, row sizes, mix of operations and
+// transaction sizes. It is meant for benchmarking consumers and testing
+// sinks without MySQL server.
+//
+//	err := generator.Generate(f, generator.Config{
+//	    Tables: []generator.Table{{
+//	        Schema: "test", Name: "users",
+//	        Columns: []generator.Column{
+//	            {Name: "id", Type: binlog.TypeLongLong},
+//	            {Name: "name", Type: binlog.TypeVarchar, Size: 64},
+//	        },
+//	    }},
+//	    Transactions: 10000,
+//	})
+//
+// The generated file can be read using binlog.NewReader, or placed
+// in dump directory to be read by binlog.Local.
+package generator
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/santhosh-tekuri/binlog"
+)
+
+// Table is a table, whose rows are generated.
+type Table struct {
+	Schema, Name string
+	Columns      []Column
+}
+
+// Column is a column of Table. Supported types are TypeLong,
+// TypeLongLong, TypeDouble, TypeVarchar and TypeBlob.
+type Column struct {
+	Name string
+	Type binlog.ColumnType
+
+	// Size is maximum length of values of TypeVarchar and TypeBlob.
+	// Each value has random length up to Size. Defaults to 255 for
+	// TypeVarchar, and 1024 for TypeBlob.
+	Size int
+}
+
+// Ops is relative frequency of row operations. Zero value means
+// inserts only.
+type Ops struct {
+	Insert, Update, Delete int
+}
+
+// Config configures generated stream.
+type Config struct {
+	Tables       []Table
+	Transactions int // number of transactions
+
+	// MinRows and MaxRows bound number of rows changed by each
+	// transaction. Both default to 1.
+	MinRows, MaxRows int
+
+	Ops Ops
+
+	ServerID      uint32 // defaults to 1
+	ServerVersion string // defaults to "8.0.23"
+
+	// Start is timestamp of first transaction. defaults to now.
+	// Interval is time between transactions.
+	Start    time.Time
+	Interval time.Duration
+
+	Seed int64 // seed for random values, for reproducible streams
+}
+
+// Generate writes binlog file with given config to w, including the
+// magic header. Events have crc32 checksum.
+func Generate(w io.Writer, c Config) error {
+	if len(c.Tables) == 0 {
+		return fmt.Errorf("generator: no tables")
+	}
+	for _, t := range c.Tables {
+		for _, col := range t.Columns {
+			if _, ok := sizes[col.Type]; !ok {
+				return fmt.Errorf("generator: unsupported type %s of column %s.%s", col.Type, t.Name, col.Name)
+			}
+			if col.Type == binlog.TypeVarchar && col.Size > math.MaxUint16 {
+				return fmt.Errorf("generator: size of varchar column %s.%s exceeds %d", t.Name, col.Name, math.MaxUint16)
+			}
+		}
+	}
+	if c.MinRows <= 0 {
+		c.MinRows = 1
+	}
+	if c.MaxRows < c.MinRows {
+		c.MaxRows = c.MinRows
+	}
+	if c.Ops == (Ops{}) {
+		c.Ops.Insert = 1
+	}
+	if c.ServerID == 0 {
+		c.ServerID = 1
+	}
+	if c.ServerVersion == "" {
+		c.ServerVersion = "8.0.23"
+	}
+	if c.Start.IsZero() {
+		c.Start = time.Now()
+	}
+	bw := bufio.NewWriter(w)
+	enc := binlog.NewEventEncoder(bw, 4, true)
+	enc.ServerID, enc.Timestamp = c.ServerID, uint32(c.Start.Unix())
+	g := &gen{
+		enc:  enc,
+		c:    c,
+		rand: rand.New(rand.NewSource(c.Seed)),
+	}
+	err := enc.FormatDescription(c.ServerVersion, 40, 1)
+	for i := 0; i < c.Transactions && err == nil; i++ {
+		enc.Timestamp = uint32(c.Start.Add(time.Duration(i) * c.Interval).Unix())
+		err = g.tx(uint64(i + 1))
+	}
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// sizes has default size of supported types.
+var sizes = map[binlog.ColumnType]int{
+	binlog.TypeLong:     4,
+	binlog.TypeLongLong: 8,
+	binlog.TypeDouble:   8,
+	binlog.TypeVarchar:  255,
+	binlog.TypeBlob:     1024,
+}
+
+type gen struct {
+	enc  *binlog.EventEncoder
+	c    Config
+	rand *rand.Rand
+}
+
+func (g *gen) tx(xid uint64) error {
+	g.enc.Query(g.c.Tables[0].Schema, "BEGIN")
+	mapped := make(map[int]bool)
+	n := g.c.MinRows + g.rand.Intn(g.c.MaxRows-g.c.MinRows+1)
+	for i := 0; i < n; i++ {
+		t := g.rand.Intn(len(g.c.Tables))
+		if !mapped[t] {
+			g.tableMap(t)
+			mapped[t] = true
+		}
+		g.rows(t, g.op(), i == n-1)
+	}
+	return g.enc.XID(xid)
+}
+
+func (g *gen) op() binlog.EventType {
+	ops := g.c.Ops
+	i := g.rand.Intn(ops.Insert + ops.Update + ops.Delete)
+	switch {
+	case i < ops.Insert:
+		return binlog.WRITE_ROWS_EVENTv2
+	case i < ops.Insert+ops.Update:
+		return binlog.UPDATE_ROWS_EVENTv2
+	default:
+		return binlog.DELETE_ROWS_EVENTv2
+	}
+}
+
+// tableID returns table id of t-th table.
+func tableID(t int) uint64 {
+	return uint64(t + 1)
+}
+
+func (g *gen) tableMap(t int) {
+	table := g.c.Tables[t]
+	var types, meta []byte
+	for _, col := range table.Columns {
+		types = append(types, byte(col.Type))
+		switch col.Type {
+		case binlog.TypeDouble:
+			meta = append(meta, 8)
+		case binlog.TypeVarchar:
+			size := g.size(col)
+			meta = append(meta, byte(size), byte(size>>8))
+		case binlog.TypeBlob:
+			meta = append(meta, byte(blobLengthSize(g.size(col))))
+		}
+	}
+	nullable := make([]byte, (len(table.Columns)+7)/8) // not nullable
+	var names []byte
+	for _, col := range table.Columns {
+		names = append(names, byte(len(col.Name)))
+		names = append(names, col.Name...)
+	}
+	g.enc.TableMap(tableID(t), table.Schema, table.Name, types, meta, nullable, binlog.TableMetadata{Type: 4, Value: names})
+}
+
+func (g *gen) rows(t int, typ binlog.EventType, stmtEnd bool) {
+	cols := g.c.Tables[t].Columns
+	var rows [][]byte
+	if typ == binlog.UPDATE_ROWS_EVENTv2 {
+		rows = append(rows, g.row(nil, cols)) // before image
+	}
+	rows = append(rows, g.row(nil, cols))
+	g.enc.Rows(typ, tableID(t), stmtEnd, len(cols), rows...)
+}
+
+// row appends random row of given columns to b.
+func (g *gen) row(b []byte, cols []Column) []byte {
+	b = append(b, make([]byte, (len(cols)+7)/8)...) // null bitmap
+	for _, col := range cols {
+		switch col.Type {
+		case binlog.TypeLong:
+			b = append(b, 0, 0, 0, 0)
+			binary.LittleEndian.PutUint32(b[len(b)-4:], g.rand.Uint32())
+		case binlog.TypeLongLong:
+			b = append(b, 0, 0, 0, 0, 0, 0, 0, 0)
+			binary.LittleEndian.PutUint64(b[len(b)-8:], g.rand.Uint64())
+		case binlog.TypeDouble:
+			b = append(b, 0, 0, 0, 0, 0, 0, 0, 0)
+			binary.LittleEndian.PutUint64(b[len(b)-8:], math.Float64bits(g.rand.Float64()))
+		case binlog.TypeVarchar, binlog.TypeBlob:
+			size := g.size(col)
+			n := g.rand.Intn(size + 1)
+			lenSize := 1
+			if col.Type == binlog.TypeBlob {
+				lenSize = blobLengthSize(size)
+			} else if size > 255 {
+				lenSize = 2
+			}
+			for i := 0; i < lenSize; i++ {
+				b = append(b, byte(n>>uint(8*i)))
+			}
+			for i := 0; i < n; i++ {
+				b = append(b, byte('a'+g.rand.Intn(26)))
+			}
+		}
+	}
+	return b
+}
+
+func (g *gen) size(col Column) int {
+	if col.Size > 0 {
+		return col.Size
+	}
+	return sizes[col.Type]
+}
+
+// blobLengthSize returns number of bytes used to store length
+// of blob with given maximum size.
+func blobLengthSize(size int) int {
+	switch {
+	case size < 1<<8:
+		return 1
+	case size < 1<<16:
+		return 2
+	case size < 1<<24:
+		return 3
+	}
+	return 4
+}
@@ -0,0 +1,107 @@
+package generator
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/santhosh-tekuri/binlog"
+)
+
+func TestGenerate(t *testing.T) {
+	c := Config{
+		Tables: []Table{{
+			Schema: "test", Name: "users",
+			Columns: []Column{
+				{Name: "id", Type: binlog.TypeLongLong},
+				{Name: "age", Type: binlog.TypeLong},
+				{Name: "score", Type: binlog.TypeDouble},
+				{Name: "name", Type: binlog.TypeVarchar, Size: 300},
+				{Name: "bio", Type: binlog.TypeBlob},
+			},
+		}, {
+			Schema: "test", Name: "logs",
+			Columns: []Column{{Name: "msg", Type: binlog.TypeVarchar}},
+		}},
+		Transactions: 50,
+		MinRows:      1,
+		MaxRows:      5,
+		Ops:          Ops{Insert: 2, Update: 1, Delete: 1},
+		Start:        time.Unix(1600000000, 0),
+		Interval:     time.Second,
+	}
+	buf := &bytes.Buffer{}
+	if err := Generate(buf, c); err != nil {
+		t.Fatal(err)
+	}
+	r := binlog.NewReader(buf)
+	var txs, rows int
+	ops := map[binlog.EventType]int{}
+	for {
+		e, err := r.NextEvent()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch d := e.Data.(type) {
+		case binlog.XIDEvent:
+			txs++
+			if want := uint32(1600000000 + txs - 1); e.Header.Timestamp != want {
+				t.Fatalf("timestamp: got %d, want %d", e.Header.Timestamp, want)
+			}
+		case binlog.RowsEvent:
+			ops[e.Header.EventType]++
+			if d.TableMap.Columns[0].Name == "" {
+				t.Fatal("column names missing")
+			}
+			for {
+				values, _, err := r.NextRow()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(values) != len(d.TableMap.Columns) {
+					t.Fatalf("got %d values, want %d", len(values), len(d.TableMap.Columns))
+				}
+				rows++
+			}
+		}
+	}
+	if txs != c.Transactions {
+		t.Fatalf("got %d transactions, want %d", txs, c.Transactions)
+	}
+	if rows < c.Transactions*c.MinRows || rows > c.Transactions*c.MaxRows {
+		t.Fatalf("got %d rows", rows)
+	}
+	for _, typ := range []binlog.EventType{binlog.WRITE_ROWS_EVENTv2, binlog.UPDATE_ROWS_EVENTv2, binlog.DELETE_ROWS_EVENTv2} {
+		if ops[typ] == 0 {
+			t.Fatalf("no %s", typ)
+		}
+	}
+
+	// reproducible with same seed
+	buf2 := &bytes.Buffer{}
+	if err := Generate(buf2, c); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := Generate(buf, c); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), buf2.Bytes()) {
+		t.Fatal("not reproducible")
+	}
+}
+
+func TestGenerate_unsupportedType(t *testing.T) {
+	c := Config{Tables: []Table{{Name: "t", Columns: []Column{{Name: "c", Type: binlog.TypeJSON}}}}}
+	if err := Generate(ioutil.Discard, c); err == nil {
+		t.Fatal("error expected")
+	}
+}
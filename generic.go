@@ -114,7 +114,7 @@ func (p *okPacket) decode(r *reader, capabilities uint32) error {
 
 // readOkErr reads ok/err packet based on marker.
 func (bl *Remote) readOkErr() error {
-	r := newReader(bl.conn, &bl.seq)
+	r := newReader(bl.rw(), &bl.seq)
 	marker, err := r.peek()
 	if err != nil {
 		return err
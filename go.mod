@@ -2,4 +2,8 @@ module github.com/santhosh-tekuri/binlog
 
 go 1.15
 
-require github.com/go-sql-driver/mysql v1.6.0
+require (
+	github.com/go-sql-driver/mysql v1.6.0
+	github.com/shopspring/decimal v1.3.1
+	golang.org/x/crypto v0.0.0-20210921155107-089bfa567519
+)
@@ -0,0 +1,120 @@
+package binlog
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// GroupMember is a member of group replication, or InnoDB Cluster,
+// as in performance_schema.replication_group_members.
+type GroupMember struct {
+	ID    string // server uuid
+	Host  string
+	Port  int
+	State string // such as "ONLINE", "RECOVERING", "UNREACHABLE"
+	Role  string // "PRIMARY" or "SECONDARY"
+	Self  bool   // whether it is the server connected to
+}
+
+// Addr returns address of the member, as used by Dial.
+func (m GroupMember) Addr() string {
+	return net.JoinHostPort(m.Host, strconv.Itoa(m.Port))
+}
+
+// IsPrimary tells whether the member is online primary.
+func (m GroupMember) IsPrimary() bool {
+	return m.State == "ONLINE" && m.Role == "PRIMARY"
+}
+
+const groupMembersQuery = `select MEMBER_ID, MEMBER_HOST, MEMBER_PORT, MEMBER_STATE, MEMBER_ROLE, MEMBER_ID = @@server_uuid from performance_schema.replication_group_members`
+
+// GroupMembers returns members of group replication, the server
+// belongs to. Returns empty list, if group replication is not
+// running. Requires MySQL 8.0 or later.
+func (bl *Remote) GroupMembers() ([]GroupMember, error) {
+	rows, err := bl.queryRows(groupMembersQuery)
+	if err != nil {
+		return nil, err
+	}
+	var members []GroupMember
+	for _, row := range rows {
+		var m GroupMember
+		m.ID, _ = row[0].(string)
+		m.Host, _ = row[1].(string)
+		port, _ := row[2].(string)
+		m.Port, _ = strconv.Atoi(port)
+		m.State, _ = row[3].(string)
+		m.Role, _ = row[4].(string)
+		self, _ := row[5].(string)
+		m.Self = self == "1"
+		members = append(members, m)
+	}
+	return members, nil
+}
+
+// GroupPrimary returns the online primary of group replication. In
+// multi-primary mode, the first primary is returned.
+func (bl *Remote) GroupPrimary() (GroupMember, error) {
+	members, err := bl.GroupMembers()
+	if err != nil {
+		return GroupMember{}, err
+	}
+	for _, m := range members {
+		if m.IsPrimary() {
+			return m, nil
+		}
+	}
+	return GroupMember{}, fmt.Errorf("binlog: no online primary in replication group")
+}
+
+// GroupPrimaryCheck fails if the server is not online primary of group
+// replication. Use it as Failover.HealthCheck, along with
+// Failover.RecheckInterval, to stream from the primary of InnoDB
+// Cluster, following primary elections:
+//
+//	f := &binlog.Failover{
+//	    Addrs:           []string{"node1:3306", "node2:3306", "node3:3306"},
+//	    HealthCheck:     binlog.GroupPrimaryCheck,
+//	    RecheckInterval: 10 * time.Second,
+//	    ...
+//	}
+func GroupPrimaryCheck(bl *Remote) error {
+	members, err := bl.GroupMembers()
+	if err != nil {
+		return err
+	}
+	for _, m := range members {
+		if m.Self {
+			if m.IsPrimary() {
+				return nil
+			}
+			return fmt.Errorf("binlog: %s is %s %s in replication group", m.Addr(), m.State, m.Role)
+		}
+	}
+	return fmt.Errorf("binlog: server is not member of replication group")
+}
+
+// ViewChangeEvent is written by group replication, when membership of
+// the group changes. It is logged in a transaction of its own.
+type ViewChangeEvent struct {
+	ViewID    string // such as "16090284421574624:3"
+	SeqNumber int64
+
+	// CertInfo is certification information of the group, which maps
+	// write set keys to gtid sets.
+	CertInfo map[string]string
+}
+
+func (e *ViewChangeEvent) decode(r *reader) error {
+	e.ViewID = strings.TrimRight(r.string(40), "\x00")
+	e.SeqNumber = int64(r.int8())
+	n := r.int4()
+	e.CertInfo = make(map[string]string)
+	for i := uint32(0); i < n && r.err == nil; i++ {
+		k := r.string(int(r.int2()))
+		e.CertInfo[k] = r.string(int(r.int4()))
+	}
+	return r.err
+}
@@ -0,0 +1,192 @@
+package binlog
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func groupMembers(primary string) [][]string {
+	rows := [][]string{{"MEMBER_ID", "MEMBER_HOST", "MEMBER_PORT", "MEMBER_STATE", "MEMBER_ROLE", "self"}}
+	for i, host := range []string{"s1", "s2"} {
+		role := "SECONDARY"
+		if host == primary {
+			role = "PRIMARY"
+		}
+		rows = append(rows, []string{fmt.Sprint("uuid", i+1), host, "3306", "ONLINE", role, "0"})
+	}
+	return rows
+}
+
+// setGroupPrimary sets group members reported by s1 and s2.
+func setGroupPrimary(s1, s2 *fakeServer, primary string) {
+	for i, s := range []*fakeServer{s1, s2} {
+		rows := groupMembers(primary)
+		rows[i+1][5] = "1"
+		s.setQuery(groupMembersQuery, rows)
+	}
+}
+
+func TestGroupMembers(t *testing.T) {
+	s := newFakeServer()
+	s.setQuery(groupMembersQuery, groupMembers("s2"))
+	bl, err := s.dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bl.Close()
+	members, err := bl.GroupMembers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []GroupMember{
+		{ID: "uuid1", Host: "s1", Port: 3306, State: "ONLINE", Role: "SECONDARY"},
+		{ID: "uuid2", Host: "s2", Port: 3306, State: "ONLINE", Role: "PRIMARY"},
+	}
+	if !reflect.DeepEqual(members, want) {
+		t.Fatalf("got %+v, want %+v", members, want)
+	}
+	primary, err := bl.GroupPrimary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if primary.Addr() != "s2:3306" {
+		t.Fatalf("got %q, want s2:3306", primary.Addr())
+	}
+	if err := GroupPrimaryCheck(bl); err == nil {
+		t.Fatal("error expected, as server is not member")
+	}
+}
+
+func TestFailover_groupPrimaryElection(t *testing.T) {
+	s1, s2 := newFakeServer(), newFakeServer()
+	for _, s := range []*fakeServer{s1, s2} {
+		f := newBinlogStream()
+		for gno := int64(1); gno <= 2; gno++ {
+			f.gtid(uuid1, gno)
+			f.XID(uint64(gno))
+		}
+		s.addFile("bin.000001", f)
+	}
+	setGroupPrimary(s1, s2, "s1")
+
+	f := &Failover{
+		Addrs:           []string{"s2", "s1"},
+		Options:         DialOptions{Dialer: fakeTopology{"s1": s1, "s2": s2}},
+		Username:        "root",
+		Password:        "password",
+		HealthCheck:     GroupPrimaryCheck,
+		RecheckInterval: time.Nanosecond,
+	}
+	defer f.Close()
+	var got []string
+	for {
+		e, err := f.NextEvent()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch d := e.Data.(type) {
+		case XIDEvent:
+			got = append(got, f.Addr()+fmt.Sprint(":xid", d.XID))
+			if d.XID == 1 {
+				setGroupPrimary(s1, s2, "s2")
+			}
+		case FailoverEvent:
+			got = append(got, "failover")
+		}
+	}
+	want := []string{"s1:xid1", "failover", "s2:xid2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestFailover_groupPrimaryElection_idle(t *testing.T) {
+	s1, s2 := newFakeServer(), newFakeServer()
+	for _, s := range []*fakeServer{s1, s2} {
+		f := newBinlogStream()
+		f.gtid(uuid1, 1)
+		f.XID(1)
+		s.addFile("bin.000001", f)
+	}
+	s1.idle = make(chan struct{}) // no more traffic
+	defer close(s1.idle)
+	setGroupPrimary(s1, s2, "s1")
+
+	f := &Failover{
+		Addrs:           []string{"s1", "s2"},
+		Options:         DialOptions{Dialer: fakeTopology{"s1": s1, "s2": s2}},
+		Username:        "root",
+		Password:        "password",
+		HealthCheck:     GroupPrimaryCheck,
+		RecheckInterval: 10 * time.Millisecond,
+	}
+	defer f.Close()
+	var got []string
+	for {
+		e, err := f.NextEvent()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch d := e.Data.(type) {
+		case XIDEvent:
+			got = append(got, f.Addr()+fmt.Sprint(":xid", d.XID))
+			setGroupPrimary(s1, s2, "s2")
+		case FailoverEvent:
+			got = append(got, "failover to "+d.To)
+		}
+	}
+	want := []string{"s1:xid1", "failover to s2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestViewChangeAndXAPrepareEvents(t *testing.T) {
+	s := newBinlogStream()
+	body := make([]byte, 52)
+	copy(body, "16090284421574624:3")
+	binary.LittleEndian.PutUint64(body[40:], 7)
+	binary.LittleEndian.PutUint32(body[48:], 1)
+	body = append(body, 3, 0)
+	body = append(body, "key"...)
+	body = append(body, 4, 0, 0, 0)
+	body = append(body, "u1:5"...)
+	s.Event(VIEW_CHANGE_EVENT, body)
+	body = []byte{1, 1, 0, 0, 0, 2, 0, 0, 0, 1, 0, 0, 0}
+	body = append(body, "g1b"...)
+	s.Event(XA_PREPARE_LOG_EVENT, body)
+
+	r := NewReader(s)
+	if _, err := r.NextEvent(); err != nil {
+		t.Fatal(err)
+	}
+	e, err := r.NextEvent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ViewChangeEvent{ViewID: "16090284421574624:3", SeqNumber: 7, CertInfo: map[string]string{"key": "u1:5"}}
+	if !reflect.DeepEqual(e.Data, want) {
+		t.Fatalf("got %#v, want %#v", e.Data, want)
+	}
+	e, err = r.NextEvent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	xpe := XAPrepareEvent{OnePhase: true, XID: XID{FormatID: 1, GTRID: []byte("g1"), BQUAL: []byte("b")}}
+	if !reflect.DeepEqual(e.Data, xpe) {
+		t.Fatalf("got %#v, want %#v", e.Data, xpe)
+	}
+	if _, end := txBoundary(e); !end {
+		t.Fatal("XAPrepareEvent must end transaction")
+	}
+}
@@ -0,0 +1,302 @@
+package binlog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GTIDEvent precedes each transaction, when gtid_mode=ON.
+// It carries global transaction identifier of the transaction.
+//
+// https://dev.mysql.com/doc/refman/8.0/en/replication-gtids-concepts.html
+type GTIDEvent struct {
+	Flags uint8
+	SID   [16]byte // server uuid of originating server
+	GNO   int64    // transaction sequence number on originating server
+
+	// logical clock used by multi threaded replica.
+	// zero if not available.
+	LastCommitted  int64
+	SequenceNumber int64
+}
+
+func (e *GTIDEvent) decode(r *reader) error {
+	e.Flags = r.int1()
+	copy(e.SID[:], r.bytesInternal(16))
+	e.GNO = int64(r.int8())
+	if r.more() && r.int1() == 2 { // logical timestamp typecode
+		e.LastCommitted = int64(r.int8())
+		e.SequenceNumber = int64(r.int8())
+	}
+	return r.err
+}
+
+// UUID returns SID formatted as uuid.
+func (e GTIDEvent) UUID() string {
+	return formatUUID(e.SID)
+}
+
+// GTID returns global transaction identifier, such as
+// "3e11fa47-71ca-11e1-9e33-c80aa9429562:23".
+func (e GTIDEvent) GTID() string {
+	return e.UUID() + ":" + strconv.FormatInt(e.GNO, 10)
+}
+
+func formatUUID(sid [16]byte) string {
+	s := hex.EncodeToString(sid[:])
+	return s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
+
+func parseUUID(s string) ([16]byte, error) {
+	var sid [16]byte
+	b, err := hex.DecodeString(strings.Replace(s, "-", "", -1))
+	if err != nil || len(b) != 16 {
+		return sid, fmt.Errorf("binlog: invalid uuid %q", s)
+	}
+	copy(sid[:], b)
+	return sid, nil
+}
+
+// GTIDInterval is a range of transaction sequence numbers,
+// including both Start and End.
+type GTIDInterval struct {
+	Start, End int64
+}
+
+// GTIDSet is set of global transaction identifiers, as in
+// gtid_executed system variable. It maps server uuid, in lower
+// case, to sorted non-overlapping intervals.
+type GTIDSet map[string][]GTIDInterval
+
+// ParseGTIDSet parses gtid set in MySQL format, such as
+//
+//	3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5:11,
+//	4a22fa47-71ca-11e1-9e33-c80aa9429562:23
+//
+// empty string results empty set.
+func ParseGTIDSet(s string) (GTIDSet, error) {
+	set := GTIDSet{}
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.Split(item, ":")
+		sid, err := parseUUID(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		uuid := formatUUID(sid)
+		if len(parts) == 1 {
+			return nil, fmt.Errorf("binlog: no intervals in gtid set %q", item)
+		}
+		for _, part := range parts[1:] {
+			var iv GTIDInterval
+			i := strings.IndexByte(part, '-')
+			if i == -1 {
+				iv.Start, err = strconv.ParseInt(part, 10, 64)
+				iv.End = iv.Start
+			} else {
+				iv.Start, err = strconv.ParseInt(part[:i], 10, 64)
+				if err == nil {
+					iv.End, err = strconv.ParseInt(part[i+1:], 10, 64)
+				}
+			}
+			if err != nil || iv.Start < 1 || iv.End < iv.Start {
+				return nil, fmt.Errorf("binlog: invalid gtid interval %q", part)
+			}
+			set.addInterval(uuid, iv)
+		}
+	}
+	return set, nil
+}
+
+// Add adds gtid with given server uuid and transaction sequence number.
+func (s GTIDSet) Add(uuid string, gno int64) error {
+	sid, err := parseUUID(uuid)
+	if err != nil {
+		return err
+	}
+	s.addInterval(formatUUID(sid), GTIDInterval{gno, gno})
+	return nil
+}
+
+// AddGTID adds gtid of the event.
+func (s GTIDSet) AddGTID(e GTIDEvent) {
+	s.addInterval(e.UUID(), GTIDInterval{e.GNO, e.GNO})
+}
+
+func (s GTIDSet) addInterval(uuid string, iv GTIDInterval) {
+	ivs := s[uuid]
+	i := sort.Search(len(ivs), func(i int) bool { return ivs[i].End+1 >= iv.Start })
+	j := i
+	for j < len(ivs) && ivs[j].Start <= iv.End+1 {
+		if ivs[j].Start < iv.Start {
+			iv.Start = ivs[j].Start
+		}
+		if ivs[j].End > iv.End {
+			iv.End = ivs[j].End
+		}
+		j++
+	}
+	merged := append([]GTIDInterval{}, ivs[:i]...)
+	merged = append(merged, iv)
+	s[uuid] = append(merged, ivs[j:]...)
+}
+
+// Contains tells whether gtid with given server uuid and transaction
+// sequence number is in this set.
+func (s GTIDSet) Contains(uuid string, gno int64) bool {
+	ivs := s[strings.ToLower(uuid)]
+	i := sort.Search(len(ivs), func(i int) bool { return ivs[i].End >= gno })
+	return i < len(ivs) && ivs[i].Start <= gno
+}
+
+// containsSet tells whether all gtids of other are in this set.
+func (s GTIDSet) containsSet(other GTIDSet) bool {
+	for uuid, ivs := range other {
+		for _, iv := range ivs {
+			have := s[uuid]
+			i := sort.Search(len(have), func(i int) bool { return have[i].End >= iv.End })
+			if i == len(have) || have[i].Start > iv.Start {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Clone returns a copy of this set.
+func (s GTIDSet) Clone() GTIDSet {
+	c := make(GTIDSet, len(s))
+	for uuid, ivs := range s {
+		c[uuid] = append([]GTIDInterval(nil), ivs...)
+	}
+	return c
+}
+
+// String returns this set in MySQL format, sorted by uuid.
+func (s GTIDSet) String() string {
+	uuids := make([]string, 0, len(s))
+	for uuid := range s {
+		uuids = append(uuids, uuid)
+	}
+	sort.Strings(uuids)
+	var buf strings.Builder
+	for i, uuid := range uuids {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(uuid)
+		for _, iv := range s[uuid] {
+			buf.WriteByte(':')
+			buf.WriteString(strconv.FormatInt(iv.Start, 10))
+			if iv.End != iv.Start {
+				buf.WriteByte('-')
+				buf.WriteString(strconv.FormatInt(iv.End, 10))
+			}
+		}
+	}
+	return buf.String()
+}
+
+// encode encodes in binary format used by COM_BINLOG_DUMP_GTID.
+// End of interval is exclusive in this format.
+func (s GTIDSet) encode() []byte {
+	uuids := make([]string, 0, len(s))
+	for uuid := range s {
+		uuids = append(uuids, uuid)
+	}
+	sort.Strings(uuids)
+	var buf bytes.Buffer
+	b := make([]byte, 8)
+	putInt8 := func(v uint64) {
+		binary.LittleEndian.PutUint64(b, v)
+		buf.Write(b)
+	}
+	putInt8(uint64(len(uuids)))
+	for _, uuid := range uuids {
+		sid, _ := parseUUID(uuid)
+		buf.Write(sid[:])
+		putInt8(uint64(len(s[uuid])))
+		for _, iv := range s[uuid] {
+			putInt8(uint64(iv.Start))
+			putInt8(uint64(iv.End + 1))
+		}
+	}
+	return buf.Bytes()
+}
+
+// decodeGTIDSet decodes body of PREVIOUS_GTIDS_EVENT, which is in
+// the format used by COM_BINLOG_DUMP_GTID.
+func decodeGTIDSet(b []byte) (GTIDSet, error) {
+	r := &reader{rd: bytes.NewReader(b), limit: len(b)}
+	s := GTIDSet{}
+	for n := r.int8(); n > 0 && r.err == nil; n-- {
+		var sid [16]byte
+		copy(sid[:], r.bytesInternal(16))
+		uuid := formatUUID(sid)
+		for m := r.int8(); m > 0 && r.err == nil; m-- {
+			iv := GTIDInterval{Start: int64(r.int8()), End: int64(r.int8()) - 1}
+			if r.err == nil && iv.End >= iv.Start {
+				s.addInterval(uuid, iv)
+			}
+		}
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("binlog: invalid gtid set: %v", r.err)
+	}
+	return s, nil
+}
+
+// SeekGTID requests binlog events, which are not in executed gtid set.
+// It is equivalent to replica with MASTER_AUTO_POSITION=1. Unlike Seek,
+// this can resume from any server of the replication topology, as
+// file names and positions differ across servers but gtids do not.
+//
+// Requires gtid_mode=ON. see Seek for serverID.
+func (bl *Remote) SeekGTID(serverID uint32, executed GTIDSet) error {
+	span := startSpan(bl.spanCtx, bl.spans, SpanSeek)
+	span.SetAttribute(AttrGTIDs, executed.String())
+	err := bl.seekGTID(serverID, executed)
+	endSpan(span, err)
+	return err
+}
+
+func (bl *Remote) seekGTID(serverID uint32, executed GTIDSet) error {
+	if bl.isStreaming() {
+		return ErrStreaming
+	}
+	return bl.requestDump(comBinlogDumpGTID{
+		flags:    bl.dumpFlags,
+		serverID: serverID,
+		gtids:    executed.encode(),
+	}, "", 4)
+}
+
+// comBinlogDumpGTID requests a binlog network stream from server,
+// starting from first gtid not in given set.
+//
+// https://dev.mysql.com/doc/internals/en/com-binlog-dump-gtid.html
+type comBinlogDumpGTID struct {
+	flags    uint16
+	serverID uint32
+	gtids    []byte // encoded GTIDSet
+}
+
+func (e comBinlogDumpGTID) encode(w *writer) error {
+	w.int1(0x1e) // COM_BINLOG_DUMP_GTID
+	w.int2(e.flags | BINLOG_THROUGH_GTID)
+	w.int4(e.serverID)
+	w.int4(0) // binlog-filename-len
+	w.int4(4) // binlog-pos
+	w.int4(0)
+	w.int4(uint32(len(e.gtids)))
+	w.Write(e.gtids)
+	return w.err
+}
@@ -0,0 +1,223 @@
+package binlog
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func (s *binlogStream) gtid(uuid string, gno int64) {
+	sid, err := parseUUID(uuid)
+	if err != nil {
+		panic(err)
+	}
+	s.GTID(GTIDEvent{Flags: 1, SID: sid, GNO: gno, LastCommitted: gno - 1, SequenceNumber: gno})
+}
+
+const (
+	uuid1 = "3e11fa47-71ca-11e1-9e33-c80aa9429562"
+	uuid2 = "4a22fa47-71ca-11e1-9e33-c80aa9429562"
+)
+
+func TestGTIDSet(t *testing.T) {
+	s, err := ParseGTIDSet(uuid2 + ":23, " + "3E11FA47-71CA-11E1-9E33-C80AA9429562:11:1-5:6-8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.String(), uuid1+":1-8:11,"+uuid2+":23"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if err := s.Add(uuid1, 10); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s[uuid1], []GTIDInterval{{1, 8}, {10, 11}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if err := s.Add(uuid1, 9); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s[uuid1], []GTIDInterval{{1, 11}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for _, tc := range []struct {
+		uuid string
+		gno  int64
+		want bool
+	}{
+		{uuid1, 1, true}, {uuid1, 11, true}, {uuid1, 12, false},
+		{uuid2, 22, false}, {uuid2, 23, true}, {"", 1, false},
+	} {
+		if got := s.Contains(tc.uuid, tc.gno); got != tc.want {
+			t.Errorf("Contains(%q, %d) = %v, want %v", tc.uuid, tc.gno, got, tc.want)
+		}
+	}
+	if s, err := ParseGTIDSet(""); err != nil || len(s) != 0 {
+		t.Fatal(s, err)
+	}
+	for _, bad := range []string{"xyz:1", uuid1, uuid1 + ":0", uuid1 + ":5-3", uuid1 + ":a"} {
+		if _, err := ParseGTIDSet(bad); err == nil {
+			t.Errorf("ParseGTIDSet(%q): error expected", bad)
+		}
+	}
+}
+
+// fakeTopology dials fake server by address.
+type fakeTopology map[string]*fakeServer
+
+func (t fakeTopology) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	s, ok := t[address]
+	if !ok {
+		return nil, fmt.Errorf("connection refused: %s", address)
+	}
+	return s.DialContext(ctx, network, address)
+}
+
+func TestFailover(t *testing.T) {
+	// s1 fails in the middle of transaction 2
+	s1 := newFakeServer()
+	f1 := newBinlogStream()
+	f1.gtid(uuid1, 1)
+	f1.XID(1)
+	f1.gtid(uuid1, 2)
+	s1.addFile("s1-bin.000001", f1)
+	s1.drop = true
+
+	s2 := newFakeServer()
+	f2 := newBinlogStream()
+	for gno := int64(1); gno <= 3; gno++ {
+		f2.gtid(uuid1, gno)
+		f2.XID(uint64(gno))
+	}
+	s2.addFile("s2-bin.000001", f2)
+
+	f := &Failover{
+		Addrs:    []string{"s1", "down", "s2"},
+		Options:  DialOptions{Dialer: fakeTopology{"s1": s1, "s2": s2}},
+		Username: "root",
+		Password: "password",
+	}
+	defer f.Close()
+	var got []string
+	for {
+		e, err := f.NextEvent()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch d := e.Data.(type) {
+		case GTIDEvent:
+			got = append(got, d.GTID()[len(uuid1)+1:])
+		case XIDEvent:
+			got = append(got, fmt.Sprint("xid", d.XID))
+		case FailoverEvent:
+			if d.From != "s1" || d.To != "s2" || d.Err == nil {
+				t.Fatalf("got %+v", d)
+			}
+			got = append(got, "failover")
+		}
+	}
+	want := []string{"1", "xid1", "2", "failover", "2", "xid2", "3", "xid3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := f.Executed.String(), uuid1+":1-3"; got != want {
+		t.Fatalf("Executed: got %q, want %q", got, want)
+	}
+	if f.Addr() != "s2" {
+		t.Fatalf("Addr: got %q, want s2", f.Addr())
+	}
+}
+
+func TestLocal_SeekGTID(t *testing.T) {
+	dir, err := ioutil.TempDir("", "binlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := []string{"binlog.000001", "binlog.000002"}
+	f1 := newBinlogStream()
+	f1.PreviousGTIDs(GTIDSet{})
+	f1.gtid(uuid1, 1)
+	f1.XID(1)
+	f1.gtid(uuid1, 2)
+	f1.XID(2)
+	f2 := newBinlogStream()
+	f2.PreviousGTIDs(GTIDSet{uuid1: {{1, 2}}})
+	f2.gtid(uuid1, 3)
+	f2.XID(3)
+	for i, f := range []*binlogStream{f1, f2} {
+		if err := ioutil.WriteFile(filepath.Join(dir, files[i]), f.Bytes(), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bl, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bl.Close()
+
+	xids := func() []uint64 {
+		t.Helper()
+		var xids []uint64
+		for {
+			e, err := bl.NextEvent()
+			if err == io.EOF {
+				return xids
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if xe, ok := e.Data.(XIDEvent); ok {
+				xids = append(xids, xe.XID)
+			}
+		}
+	}
+	tests := []struct {
+		executed string
+		xids     []uint64
+	}{
+		{"", []uint64{1, 2, 3}},
+		{uuid1 + ":1", []uint64{2, 3}},
+		{uuid1 + ":1-2", []uint64{3}},
+		{uuid1 + ":2", []uint64{1, 3}},
+		{uuid1 + ":1:3", []uint64{2}},
+	}
+	for _, test := range tests {
+		executed, err := ParseGTIDSet(test.executed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := bl.SeekGTID(0, executed); err != nil {
+			t.Fatal(err)
+		}
+		if got := xids(); !reflect.DeepEqual(got, test.xids) {
+			t.Fatalf("executed %q: got xids %v, want %v", test.executed, got, test.xids)
+		}
+	}
+
+	// all executed: seeks to end of last file
+	executed, _ := ParseGTIDSet(uuid1 + ":1-3")
+	if err := bl.SeekGTID(0, executed); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bl.NextEvent(); err != io.EOF {
+		t.Fatal("got", err, "want", io.EOF)
+	}
+
+	// previous gtids of first file not executed: purged
+	if err := ioutil.WriteFile(filepath.Join(dir, files[0]), f2.Bytes(), 0666); err != nil {
+		t.Fatal(err)
+	}
+	executed, _ = ParseGTIDSet(uuid2 + ":1")
+	if err := bl.SeekGTID(0, executed); err == nil {
+		t.Fatal("error expected for purged gtids")
+	}
+}
@@ -115,7 +115,8 @@ type handshakeResponse41 struct {
 	connectAttrs    map[string]string
 }
 
-func (e handshakeResponse41) encode(w *writer) error {
+// capabilities returns capability flags sent to server.
+func (e handshakeResponse41) capabilities() uint32 {
 	capabilities := e.capabilityFlags | capProtocol41
 	if e.database != "" {
 		capabilities |= capConnectWithDB
@@ -126,6 +127,11 @@ func (e handshakeResponse41) encode(w *writer) error {
 	if len(e.connectAttrs) > 0 {
 		capabilities |= capConnectAttrs
 	}
+	return capabilities
+}
+
+func (e handshakeResponse41) encode(w *writer) error {
+	capabilities := e.capabilities()
 
 	w.int4(capabilities)
 	w.int4(e.maxPacketSize)
@@ -0,0 +1,88 @@
+package binlog
+
+import (
+	"sync"
+	"time"
+)
+
+// PositionFunc is called with binlog position of master.
+type PositionFunc func(file string, pos uint32)
+
+// SetHeartbeatFunc sets f to be called by NextEvent for each
+// HeartbeatEvent received, with current binlog position of master.
+// The HeartbeatEvent is still returned by NextEvent.
+//
+// Master sends heartbeats only when there are no events to send, so
+// this tells that the stream is caught up with master at given position.
+// see SetHeartbeatPeriod.
+func (bl *Remote) SetHeartbeatFunc(f PositionFunc) {
+	bl.heartbeatFunc = f
+}
+
+// SetIdleFunc sets f to be called, when NextEvent has been waiting for
+// d without receiving any event, and every d thereafter till an event
+// is received. f is called with binlog position of last event received,
+// from a separate goroutine, so it must not call methods of bl.
+//
+// Unlike heartbeats, this works without master cooperation, so it
+// also detects stalled connections. Pass nil f to disable.
+func (bl *Remote) SetIdleFunc(d time.Duration, f PositionFunc) {
+	bl.idleTimeout, bl.idleFunc = d, f
+}
+
+// idleWatcher calls idleFunc, while NextEvent waits for an event. Its
+// timer is created once per connection, and reset by each NextEvent.
+type idleWatcher struct {
+	mu       sync.Mutex
+	t        Timer
+	d        time.Duration
+	f        PositionFunc
+	file     string
+	pos      uint32
+	watching bool
+}
+
+// watchIdle starts timer calling idleFunc, with position of last event
+// received. bl.idle.stop stops the timer.
+func (bl *Remote) watchIdle() {
+	file, pos := bl.requestFile, bl.requestPos
+	if r := bl.binlogReader; r != nil && r.binlogFile != "" {
+		file, pos = r.binlogFile, r.binlogPos
+	}
+	w := bl.idle
+	if w == nil {
+		w = &idleWatcher{}
+		bl.idle = w
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.d, w.f, w.file, w.pos, w.watching = bl.idleTimeout, bl.idleFunc, file, pos, true
+	if w.t == nil {
+		w.t = clockOrSystem(bl.opts.clock).AfterFunc(w.d, w.fire)
+	} else {
+		w.t.Reset(w.d)
+	}
+}
+
+func (w *idleWatcher) fire() {
+	w.mu.Lock()
+	if !w.watching {
+		w.mu.Unlock()
+		return
+	}
+	f, file, pos := w.f, w.file, w.pos
+	w.mu.Unlock()
+	f(file, pos)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.watching {
+		w.t.Reset(w.d)
+	}
+}
+
+func (w *idleWatcher) stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.watching = false
+	w.t.Stop()
+}
@@ -0,0 +1,141 @@
+package binlog
+
+import (
+	"bytes"
+	"io"
+	"time"
+)
+
+// ReconstructRow returns the state of a row as of given time, by
+// replaying the row changes in dump directory from its first file.
+//
+// Event timestamps are statement start times, so they are not monotonic:
+// a long transaction is logged at commit with its earlier start time.
+// Hence changes are applied in commit order: a transaction is applied
+// only if its commit event has timestamp not after asOf, and the replay
+// stops at first transaction committed after asOf. Only the files up to
+// that point are read. Transactions ended by ROLLBACK are applied, since
+// such transactions are logged only for their non-transactional changes,
+// which persist.
+//
+// The row is identified by pk, which maps column ordinal to value of
+// primary key columns. The values need not be of decoded go type: they
+// are converted as database/sql values are, so int for INT column or
+// string for DATETIME column work alike. The returned row is indexed by column ordinal.
+// It returns nil row, if the row did not exist at that time.
+//
+// This requires binlog_row_image=FULL, so that row images have all columns.
+func (bl *Local) ReconstructRow(schema, table string, pk map[int]interface{}, asOf time.Time) ([]interface{}, error) {
+	files, err := bl.ListFiles()
+	if err != nil || len(files) == 0 {
+		return nil, err
+	}
+	l := &Local{fs: bl.fs, dir: bl.dir}
+	if err := l.Seek(0, files[0], 4); err != nil {
+		return nil, err
+	}
+	defer l.Close()
+
+	// row is the committed state, txRow the state within transaction
+	var row, txRow []interface{}
+	inTx := false
+	for {
+		e, err := l.NextEvent()
+		if err == io.EOF {
+			return row, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		late := time.Unix(int64(e.Header.Timestamp), 0).After(asOf)
+		switch begin, end := txBoundary(e); {
+		case begin:
+			inTx, txRow = true, row
+			continue
+		case end:
+			if late {
+				return row, nil
+			}
+			if inTx {
+				row = txRow
+			}
+			inTx = false
+			continue
+		}
+		re, ok := e.Data.(RowsEvent)
+		if !ok || re.TableMap == nil || re.TableMap.SchemaName != schema || re.TableMap.TableName != table {
+			continue
+		}
+		if !inTx {
+			// autocommitted change to non-transactional table
+			if late {
+				return row, nil
+			}
+			txRow = row
+		}
+		for {
+			values, before, err := l.NextRow()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case e.Header.EventType.IsWriteRows():
+				if matchRow(re.Columns(), values, pk) {
+					txRow = fullRow(re.TableMap, re.Columns(), values)
+				}
+			case e.Header.EventType.IsUpdateRows():
+				if matchRow(re.ColumnsBeforeUpdate(), before, pk) {
+					txRow = nil
+				}
+				if matchRow(re.Columns(), values, pk) {
+					txRow = fullRow(re.TableMap, re.Columns(), values)
+				}
+			case e.Header.EventType.IsDeleteRows():
+				if matchRow(re.Columns(), values, pk) {
+					txRow = nil
+				}
+			}
+		}
+		if !inTx {
+			row = txRow
+		}
+	}
+}
+
+// matchRow tells whether values has given primary key.
+func matchRow(cols []Column, values []interface{}, pk map[int]interface{}) bool {
+	matched := 0
+	for i, col := range cols {
+		if want, ok := pk[col.Ordinal]; ok {
+			if !keyEqual(col, values[i], want) {
+				return false
+			}
+			matched++
+		}
+	}
+	return matched == len(pk)
+}
+
+// fullRow returns values indexed by column ordinal.
+func fullRow(tme *TableMapEvent, cols []Column, values []interface{}) []interface{} {
+	row := make([]interface{}, len(tme.Columns))
+	for i, col := range cols {
+		row[col.Ordinal] = values[i]
+	}
+	return row
+}
+
+// keyEqual tells whether decoded value v equals key value want, which
+// is converted to decoded type of col as in Backfiller, and compared by
+// canonical encoding.
+func keyEqual(col Column, v, want interface{}) bool {
+	want = sqlValue(col, want)
+	if x, ok := v.(Decimal); ok {
+		y, ok := want.(Decimal)
+		return ok && x.Equal(y)
+	}
+	return bytes.Equal(appendCanonical(nil, col, v), appendCanonical(nil, col, want))
+}
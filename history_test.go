@@ -0,0 +1,77 @@
+package binlog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLocal_ReconstructRow(t *testing.T) {
+	dir, err := ioutil.TempDir("", "binlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := newBinlogStream()
+	row := func(id, v byte) []byte { return []byte{0, id, 0, 0, 0, v, 0, 0, 0} }
+	tx := func(begin, commit uint32, typ EventType, rows ...[]byte) {
+		s.Timestamp = begin
+		s.Query("test", "BEGIN")
+		s.TableMap(101, "test", "t", []byte{byte(TypeLong), byte(TypeLong)}, nil, []byte{0x00})
+		s.Rows(typ, 101, true, 2, rows...)
+		s.Timestamp = commit
+		s.XID(uint64(commit))
+	}
+	tx(10, 10, WRITE_ROWS_EVENTv2, row(1, 1), row(2, 1))
+	// long transaction, started before next one
+	tx(100, 300, UPDATE_ROWS_EVENTv2, row(1, 1), row(1, 2))
+	tx(120, 310, DELETE_ROWS_EVENTv2, row(1, 2))
+	// rolled back transaction with non-transactional change
+	s.Timestamp = 400
+	s.Query("test", "BEGIN")
+	s.TableMap(101, "test", "t", []byte{byte(TypeLong), byte(TypeLong)}, nil, []byte{0x00})
+	s.Rows(WRITE_ROWS_EVENTv2, 101, true, 2, row(1, 3))
+	s.Query("test", "ROLLBACK")
+	if err := ioutil.WriteFile(filepath.Join(dir, "binlog.000001"), s.Bytes(), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	bl, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		asOf int64
+		want []interface{}
+	}{
+		{5, nil},
+		{10, []interface{}{int32(1), int32(1)}},
+		{200, []interface{}{int32(1), int32(1)}},
+		{305, []interface{}{int32(1), int32(2)}},
+		{310, nil},
+		{500, []interface{}{int32(1), int32(3)}},
+	}
+	// key values need not be of decoded type
+	for _, key := range []interface{}{int32(1), 1, int64(1), "1", []byte("1")} {
+		for _, test := range tests {
+			got, err := bl.ReconstructRow("test", "t", map[int]interface{}{0: key}, time.Unix(test.asOf, 0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("key %#v asOf %d: got %v, want %v", key, test.asOf, got, test.want)
+			}
+		}
+	}
+	got, err := bl.ReconstructRow("test", "t", map[int]interface{}{0: 2}, time.Unix(500, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []interface{}{int32(2), int32(1)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("key 2: got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,177 @@
+package binlog
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRemote_integration runs Dial, Authenticate, Seek and decoding of
+// row changes against the server given by -mysql flag, both from the
+// stream and from its dump. matrix.sh runs it across server versions,
+// binlog_row_metadata and binlog_checksum.
+func TestRemote_integration(t *testing.T) {
+	if *mysql == "" {
+		t.Skip(skipReason)
+	}
+	db, err := sql.Open("mysql", driverURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	vars := map[string]string{}
+	rows, err := db.Query("show global variables where variable_name in ('version', 'binlog_checksum', 'binlog_row_metadata', 'binlog_format')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			t.Fatal(err)
+		}
+		vars[strings.ToLower(name)] = value
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("server variables: %v", vars)
+	if !strings.EqualFold(vars["binlog_format"], "ROW") {
+		t.Skip("binlog_format is not ROW")
+	}
+	fullMetadata := strings.EqualFold(vars["binlog_row_metadata"], "FULL")
+
+	dial := func() *Remote {
+		t.Helper()
+		r, err := Dial(network, address, 5*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ssl && r.IsSSLSupported() {
+			if err := r.UpgradeSSL(nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := r.Authenticate(user, passwd); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	r := dial()
+	defer r.Close()
+	file, pos, err := r.MasterStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []string{
+		"drop table if exists binlog_matrix",
+		"create table binlog_matrix(id int primary key, name varchar(20), amount decimal(10,2), note text)",
+		"insert into binlog_matrix values(1, 'one', 1.50, 'first'), (2, 'two', 2.50, null)",
+		"update binlog_matrix set name='uno' where id=1",
+		"delete from binlog_matrix where id=2",
+	} {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+	want := []string{
+		"insert [1 one 1.50 first]",
+		"insert [2 two 2.50 <nil>]",
+		"update [1 one 1.50 first] -> [1 uno 1.50 first]",
+		"delete [2 two 2.50 <nil>]",
+	}
+
+	// changes reads row changes of binlog_matrix, until the delete.
+	changes := func(src BinlogSource) {
+		t.Helper()
+		var got []string
+		for len(got) < len(want) {
+			e, err := src.NextEvent()
+			if err != nil {
+				t.Fatalf("after %v: %v", got, err)
+			}
+			re, ok := e.Data.(RowsEvent)
+			if !ok || re.TableMap == nil || re.TableMap.TableName != "binlog_matrix" {
+				continue
+			}
+			names := re.TableMap.Columns[0].Name != ""
+			if names != fullMetadata {
+				t.Fatalf("column names decoded: %v, binlog_row_metadata=%s", names, vars["binlog_row_metadata"])
+			}
+			if fullMetadata && !reflect.DeepEqual(re.TableMap.PrimaryKey, []int{0}) {
+				t.Fatalf("got primary key %v", re.TableMap.PrimaryKey)
+			}
+			typ := "insert"
+			switch {
+			case e.Header.EventType.IsUpdateRows():
+				typ = "update"
+			case e.Header.EventType.IsDeleteRows():
+				typ = "delete"
+			}
+			for {
+				values, before, err := src.NextRow()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				s := typ + " " + formatValues(values)
+				if before != nil {
+					s = typ + " " + formatValues(before) + " -> " + formatValues(values)
+				}
+				got = append(got, s)
+			}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+
+	if err := r.Seek(0, file, pos); err != nil {
+		t.Fatal(err)
+	}
+	changes(r)
+
+	// dump and decode from files, to check checksums on disk
+	dir, err := ioutil.TempDir("", "matrix")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	d := dial()
+	defer d.Close()
+	d.SetDumpFlags(BINLOG_DUMP_NON_BLOCK)
+	if err := d.Seek(0, file, 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Dump(dir); err != io.EOF {
+		t.Fatalf("dump: got %v, want io.EOF", err)
+	}
+	local, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer local.Close()
+	if err := local.Seek(0, file, pos); err != nil {
+		t.Fatal(err)
+	}
+	changes(local)
+}
+
+// formatValues formats values, with []byte as string.
+func formatValues(values []interface{}) string {
+	s := make([]string, len(values))
+	for i, v := range values {
+		if b, ok := v.([]byte); ok {
+			v = string(b)
+		}
+		s[i] = fmt.Sprint(v)
+	}
+	return "[" + strings.Join(s, " ") + "]"
+}
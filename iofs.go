@@ -0,0 +1,83 @@
+//go:build go1.16
+// +build go1.16
+
+package binlog
+
+import (
+	"io"
+	"io/fs"
+	"os"
+)
+
+// FromFS returns read-only FS backed by fsys, such as embed.FS.
+// Files in fsys must implement io.Seeker to be read by Local.
+func FromFS(fsys fs.FS) FS {
+	return ioFS{fsys}
+}
+
+type ioFS struct {
+	fsys fs.FS
+}
+
+func (f ioFS) Open(name string) (File, error) {
+	file, err := f.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return ioFile{file, name}, nil
+}
+
+func (f ioFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		return nil, &os.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	return f.Open(name)
+}
+
+func (f ioFS) Stat(name string) (os.FileInfo, error) {
+	return fs.Stat(f.fsys, name)
+}
+
+func (f ioFS) ReadDir(name string) ([]os.FileInfo, error) {
+	entries, err := fs.ReadDir(f.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]os.FileInfo, 0, len(entries))
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+func (f ioFS) Remove(name string) error {
+	return &os.PathError{Op: "remove", Path: name, Err: fs.ErrPermission}
+}
+
+func (f ioFS) Rename(oldname, newname string) error {
+	return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: fs.ErrPermission}
+}
+
+type ioFile struct {
+	fs.File
+	name string
+}
+
+func (f ioFile) Name() string {
+	return f.name
+}
+
+func (f ioFile) Write(p []byte) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: f.name, Err: fs.ErrPermission}
+}
+
+func (f ioFile) Seek(offset int64, whence int) (int64, error) {
+	if s, ok := f.File.(io.Seeker); ok {
+		return s.Seek(offset, whence)
+	}
+	return 0, &os.PathError{Op: "seek", Path: f.name, Err: fs.ErrPermission}
+}
@@ -0,0 +1,47 @@
+//go:build go1.16
+// +build go1.16
+
+package binlog
+
+import (
+	"io"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLocal_FromFS(t *testing.T) {
+	s := newBinlogStream()
+	s.XID(7)
+	fsys := fstest.MapFS{
+		"dump/binlog.000001": &fstest.MapFile{Data: s.Bytes()},
+		"dump/binlog.index":  &fstest.MapFile{Data: []byte("./binlog.000001\n")},
+	}
+	bl, err := OpenFS(FromFS(fsys), "dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, pos, err := bl.MasterStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file != "binlog.000001" || pos != uint32(s.Len()) {
+		t.Fatal("got", file, pos, "want", "binlog.000001", s.Len())
+	}
+	if err := bl.Seek(0, file, 4); err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for {
+		_, err := bl.NextEvent()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		n++
+	}
+	if n != 2 {
+		t.Fatal("got", n, "events, want", 2)
+	}
+}
@@ -1,16 +1,23 @@
 package binlog
 
 import (
+	"bytes"
 	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"math"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
 // https://dev.mysql.com/worklog/task/?id=8132#tabs-8132-4
-type jsonDecoder struct{}
+type jsonDecoder struct {
+	useNumber bool // decode numbers as json.Number
+}
 
 const (
 	jsonSmallObj = 0x00
@@ -50,22 +57,22 @@ func (d *jsonDecoder) decodeValueType(typ byte, data []byte) (interface{}, error
 		return d.decodeLiteral(data)
 	case jsonInt16:
 		v, err := d.decodeUInt16(data)
-		return int16(v), err
+		return d.number(int16(v), err)
 	case jsonUInt16:
-		return d.decodeUInt16(data)
+		return d.number(d.decodeUInt16(data))
 	case jsonInt32:
 		v, err := d.decodeUInt32(data)
-		return int32(v), err
+		return d.number(int32(v), err)
 	case jsonUInt32:
-		return d.decodeUInt32(data)
+		return d.number(d.decodeUInt32(data))
 	case jsonInt64:
 		v, err := d.decodeUInt64(data)
-		return int64(v), err
+		return d.number(int64(v), err)
 	case jsonUInt64:
-		return d.decodeUInt64(data)
+		return d.number(d.decodeUInt64(data))
 	case jsonDouble:
 		v, err := d.decodeUInt64(data)
-		return math.Float64frombits(v), err
+		return d.number(math.Float64frombits(v), err)
 	case jsonString:
 		return d.decodeString(data)
 	case jsonCustom:
@@ -166,6 +173,20 @@ func (d *jsonDecoder) decodeComposite(data []byte, small bool, obj bool) (interf
 	return vals, nil
 }
 
+// number converts numeric value v to json.Number, if useNumber is set.
+func (d *jsonDecoder) number(v interface{}, err error) (interface{}, error) {
+	if err != nil || !d.useNumber {
+		return v, err
+	}
+	switch v := v.(type) {
+	case float64:
+		return json.Number(strconv.FormatFloat(v, 'g', -1, 64)), nil
+	case Decimal:
+		return json.Number(v), nil
+	}
+	return json.Number(fmt.Sprint(v)), nil
+}
+
 func (d *jsonDecoder) decodeLiteral(data []byte) (interface{}, error) {
 	if len(data) < 1 {
 		return nil, io.ErrUnexpectedEOF
@@ -248,7 +269,7 @@ func (d *jsonDecoder) decodeCustom(data []byte) (interface{}, error) {
 	case TypeNewDecimal:
 		precision := int(data[0])
 		scale := int(data[1])
-		return decodeDecimal(data[2:], precision, scale)
+		return d.number(decodeDecimal(data[2:], precision, scale))
 	case TypeTime:
 		if len(data) < 8 {
 			return nil, io.ErrUnexpectedEOF
@@ -298,3 +319,273 @@ func (d *jsonDecoder) decodeCustom(data []byte) (interface{}, error) {
 		return string(data), nil
 	}
 }
+
+// JSON accessors ---
+
+// ErrJSONPathNotFound is returned by JSON lookups when
+// the path does not exist in the document.
+var ErrJSONPathNotFound = errors.New("binlog: json path not found")
+
+// Raw returns the JSON text of the value, in a fixed format similar
+// to, but not same as, that printed by MySQL. Object keys are sorted,
+// Decimal and json.Number are written without quotes, and float64 is
+// written with fraction or exponent, such as 1.0 or 1e21, so that it
+// reads back as double. time.Time is written as quoted
+// "YYYY-MM-DD hh:mm:ss.ffffff", even for DATE values, as decoded value
+// does not tell DATE from DATETIME, and time.Duration is written as
+// quoted "hh:mm:ss.ffffff".
+func (j JSON) Raw() string {
+	var buf bytes.Buffer
+	writeJSON(&buf, j.Val)
+	return buf.String()
+}
+
+func writeJSON(buf *bytes.Buffer, v interface{}) {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case string:
+		writeJSONString(buf, v)
+	case float64:
+		buf.WriteString(formatJSONDouble(v))
+	case Decimal:
+		buf.WriteString(string(v))
+	case json.Number:
+		buf.WriteString(string(v))
+	case time.Time:
+		writeJSONString(buf, v.Format("2006-01-02 15:04:05.000000"))
+	case time.Duration:
+		writeJSONString(buf, formatDuration(v))
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, e := range v {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			writeJSON(buf, e)
+		}
+		buf.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			writeJSONString(buf, k)
+			buf.WriteString(": ")
+			writeJSON(buf, v[k])
+		}
+		buf.WriteByte('}')
+	default:
+		fmt.Fprint(buf, v) // integers
+	}
+}
+
+func writeJSONString(buf *bytes.Buffer, s string) {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	buf.Truncate(buf.Len() - 1) // remove trailing newline
+}
+
+// formatJSONDouble formats v such as 1.0, 2.5, 1e21 or 1.5e-7. As in
+// javascript, exponent is used only for magnitudes outside [1e-6, 1e21).
+func formatJSONDouble(v float64) string {
+	if abs := math.Abs(v); v == 0 || (abs >= 1e-6 && abs < 1e21) {
+		s := strconv.FormatFloat(v, 'f', -1, 64)
+		if strings.IndexByte(s, '.') == -1 {
+			s += ".0"
+		}
+		return s
+	}
+	s := strconv.FormatFloat(v, 'e', -1, 64) // such as 1.5e-07
+	i := strings.IndexByte(s, 'e')
+	exp := strings.TrimPrefix(s[i+1:], "+")
+	if strings.HasPrefix(exp, "-") {
+		return s[:i+1] + "-" + strings.TrimLeft(exp[1:], "0")
+	}
+	return s[:i+1] + strings.TrimLeft(exp, "0")
+}
+
+// formatDuration formats d as [-]hh:mm:ss.ffffff
+func formatDuration(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	h := d / time.Hour
+	d -= h * time.Hour
+	m := d / time.Minute
+	d -= m * time.Minute
+	s := d / time.Second
+	d -= s * time.Second
+	return fmt.Sprintf("%s%02d:%02d:%02d.%06d", sign, h, m, s, d/time.Microsecond)
+}
+
+// Lookup returns the value at given path. The path uses MySQL
+// JSON path syntax, limited to member and array-index legs.
+// for example: `$`, `$.name`, `$.tags[0]`, `$."first name".last`.
+//
+// returns ErrJSONPathNotFound, if path does not exist.
+func (j JSON) Lookup(path string) (JSON, error) {
+	legs, err := parseJSONPath(path)
+	if err != nil {
+		return JSON{}, err
+	}
+	v := j.Val
+	for _, leg := range legs {
+		switch leg := leg.(type) {
+		case string:
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				return JSON{}, ErrJSONPathNotFound
+			}
+			if v, ok = m[leg]; !ok {
+				return JSON{}, ErrJSONPathNotFound
+			}
+		case int:
+			arr, ok := v.([]interface{})
+			if !ok || leg >= len(arr) {
+				return JSON{}, ErrJSONPathNotFound
+			}
+			v = arr[leg]
+		}
+	}
+	return JSON{v}, nil
+}
+
+// parseJSONPath returns the legs of the path. each leg
+// is either string(member) or int(array index).
+func parseJSONPath(path string) ([]interface{}, error) {
+	invalid := fmt.Errorf("binlog: invalid json path %q", path)
+	p := strings.TrimSpace(path)
+	if !strings.HasPrefix(p, "$") {
+		return nil, invalid
+	}
+	p = p[1:]
+	var legs []interface{}
+	for p != "" {
+		switch p[0] {
+		case '.':
+			p = p[1:]
+			if strings.HasPrefix(p, `"`) {
+				i := 1
+				for i < len(p) && p[i] != '"' {
+					if p[i] == '\\' {
+						i++
+					}
+					i++
+				}
+				if i >= len(p) {
+					return nil, invalid
+				}
+				key, err := strconv.Unquote(p[:i+1])
+				if err != nil {
+					return nil, invalid
+				}
+				legs, p = append(legs, key), p[i+1:]
+			} else {
+				i := strings.IndexAny(p, ".[")
+				if i == -1 {
+					i = len(p)
+				}
+				if i == 0 {
+					return nil, invalid
+				}
+				legs, p = append(legs, p[:i]), p[i:]
+			}
+		case '[':
+			i := strings.IndexByte(p, ']')
+			if i == -1 {
+				return nil, invalid
+			}
+			index, err := strconv.Atoi(strings.TrimSpace(p[1:i]))
+			if err != nil || index < 0 {
+				return nil, invalid
+			}
+			legs, p = append(legs, index), p[i+1:]
+		default:
+			return nil, invalid
+		}
+	}
+	return legs, nil
+}
+
+// GetString returns the string value at given path.
+func (j JSON) GetString(path string) (string, error) {
+	v, err := j.Lookup(path)
+	if err != nil {
+		return "", err
+	}
+	if s, ok := v.Val.(string); ok {
+		return s, nil
+	}
+	return "", fmt.Errorf("binlog: json value at %q is %T, not string", path, v.Val)
+}
+
+// GetInt returns the integer value at given path.
+func (j JSON) GetInt(path string) (int64, error) {
+	v, err := j.Lookup(path)
+	if err != nil {
+		return 0, err
+	}
+	switch n := v.Val.(type) {
+	case int16:
+		return int64(n), nil
+	case uint16:
+		return int64(n), nil
+	case int32:
+		return int64(n), nil
+	case uint32:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	case uint64:
+		if n <= math.MaxInt64 {
+			return int64(n), nil
+		}
+		return 0, fmt.Errorf("binlog: json value at %q overflows int64", path)
+	case json.Number:
+		return n.Int64()
+	}
+	return 0, fmt.Errorf("binlog: json value at %q is %T, not integer", path, v.Val)
+}
+
+// GetFloat returns the numeric value at given path as float64.
+func (j JSON) GetFloat(path string) (float64, error) {
+	v, err := j.Lookup(path)
+	if err != nil {
+		return 0, err
+	}
+	switch n := v.Val.(type) {
+	case float64:
+		return n, nil
+	case Decimal:
+		return n.Float64()
+	case json.Number:
+		return n.Float64()
+	}
+	if i, err := j.GetInt(path); err == nil {
+		return float64(i), nil
+	}
+	return 0, fmt.Errorf("binlog: json value at %q is %T, not number", path, v.Val)
+}
+
+// GetBool returns the boolean value at given path.
+func (j JSON) GetBool(path string) (bool, error) {
+	v, err := j.Lookup(path)
+	if err != nil {
+		return false, err
+	}
+	if b, ok := v.Val.(bool); ok {
+		return b, nil
+	}
+	return false, fmt.Errorf("binlog: json value at %q is %T, not bool", path, v.Val)
+}
@@ -0,0 +1,143 @@
+package binlog
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestJSON_Lookup(t *testing.T) {
+	j := JSON{map[string]interface{}{
+		"name":       "binlog",
+		"first name": "santhosh",
+		"stars":      int32(42),
+		"price":      Decimal("12.50"),
+		"active":     true,
+		"tags":       []interface{}{"mysql", map[string]interface{}{"k": uint16(7)}},
+	}}
+	if s, err := j.GetString("$.name"); err != nil || s != "binlog" {
+		t.Fatal("got", s, err)
+	}
+	if s, err := j.GetString(`$."first name"`); err != nil || s != "santhosh" {
+		t.Fatal("got", s, err)
+	}
+	if n, err := j.GetInt("$.stars"); err != nil || n != 42 {
+		t.Fatal("got", n, err)
+	}
+	if n, err := j.GetInt("$.tags[1].k"); err != nil || n != 7 {
+		t.Fatal("got", n, err)
+	}
+	if f, err := j.GetFloat("$.price"); err != nil || f != 12.5 {
+		t.Fatal("got", f, err)
+	}
+	if b, err := j.GetBool("$.active"); err != nil || !b {
+		t.Fatal("got", b, err)
+	}
+	if _, err := j.Lookup("$.tags[2]"); err != ErrJSONPathNotFound {
+		t.Fatal("got", err, "want", ErrJSONPathNotFound)
+	}
+	if _, err := j.GetString("$.stars"); err == nil {
+		t.Fatal("type mismatch must return error")
+	}
+	for _, path := range []string{"name", "$.", "$[x]", `$."name`} {
+		if _, err := j.Lookup(path); err == nil || err == ErrJSONPathNotFound {
+			t.Errorf("%q: got %v, want invalid path error", path, err)
+		}
+	}
+}
+
+func TestJSON_Raw(t *testing.T) {
+	j := JSON{map[string]interface{}{
+		"b":   []interface{}{int16(1), 2.5, nil, false},
+		"a":   "<x>",
+		"d":   Decimal("123.450"),
+		"n":   json.Number("9007199254740993"),
+		"t":   time.Date(2021, 2, 14, 20, 37, 12, 123456000, time.UTC),
+		"dur": -(12*time.Hour + 51*time.Minute + 58*time.Second + 123456*time.Microsecond),
+	}}
+	want := `{"a": "<x>", "b": [1, 2.5, null, false], "d": 123.450, "dur": "-12:51:58.123456", "n": 9007199254740993, "t": "2021-02-14 20:37:12.123456"}`
+	if got := j.Raw(); got != want {
+		t.Log(" got:", got)
+		t.Log("want:", want)
+		t.Fatal("raw did not match")
+	}
+	if got, _ := j.MarshalJSON(); string(got) != want {
+		t.Fatal("MarshalJSON must match Raw")
+	}
+}
+
+func TestJSON_Raw_doubles(t *testing.T) {
+	tests := []struct {
+		v    float64
+		want string
+	}{
+		{1, "1.0"},
+		{-3, "-3.0"},
+		{0, "0.0"},
+		{2.5, "2.5"},
+		{1e21, "1e21"},
+		{1.5e-7, "1.5e-7"},
+		{-2e-10, "-2e-10"},
+		{123456789012, "123456789012.0"},
+		{1e6, "1000000.0"},
+		{0.000001, "0.000001"},
+	}
+	for _, test := range tests {
+		if got := (JSON{test.v}).Raw(); got != test.want {
+			t.Errorf("%v: got %s, want %s", test.v, got, test.want)
+		}
+	}
+}
+
+func TestJSON_Raw_opaque(t *testing.T) {
+	// opaque value of given column type, with packed 8 bytes
+	opaque := func(typ ColumnType, packed int64) []byte {
+		b := []byte{jsonCustom, byte(typ), 8}
+		for i := 0; i < 8; i++ {
+			b = append(b, byte(uint64(packed)>>(8*uint(i))))
+		}
+		return b
+	}
+	packDate := func(year, month, day, hour, min, sec, usec int64) int64 {
+		ymd := (year*13+month)<<5 | day
+		hms := hour<<12 | min<<6 | sec
+		return (ymd<<17|hms)<<24 | usec
+	}
+	tests := []struct {
+		data []byte
+		want string
+	}{
+		{opaque(TypeDate, packDate(2006, 1, 2, 0, 0, 0, 0)), `"2006-01-02 00:00:00.000000"`},
+		{opaque(TypeDateTime, packDate(2006, 1, 2, 15, 4, 5, 123456)), `"2006-01-02 15:04:05.123456"`},
+		{opaque(TypeTime, (12<<12|34<<6|56)<<24|500000), `"12:34:56.500000"`},
+		{opaque(TypeTime, -((838<<12 | 59<<6 | 59) << 24)), `"-838:59:59.000000"`},
+	}
+	for _, test := range tests {
+		v, err := (&jsonDecoder{}).decodeValue(test.data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := (JSON{v}).Raw(); got != test.want {
+			t.Errorf("got %s, want %s", got, test.want)
+		}
+	}
+}
+
+func TestJSONDecoder_useNumber(t *testing.T) {
+	// double 123.45
+	data := []byte{jsonDouble, 0xcd, 0xcc, 0xcc, 0xcc, 0xcc, 0xdc, 0x5e, 0x40}
+	v, err := (&jsonDecoder{useNumber: true}).decodeValue(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != json.Number("123.45") {
+		t.Fatalf("got %T %v, want json.Number 123.45", v, v)
+	}
+	v, err = (&jsonDecoder{}).decodeValue(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != 123.45 {
+		t.Fatalf("got %T %v, want float64 123.45", v, v)
+	}
+}
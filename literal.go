@@ -0,0 +1,159 @@
+package binlog
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValueLiteral returns v formatted as MySQL literal.
+// It is same as SQLLiteral. Use DisplayLiteral for
+// human display.
+func (col Column) ValueLiteral(v interface{}) string {
+	return col.SQLLiteral(v)
+}
+
+// SQLLiteral returns v formatted as MySQL literal, so that it can
+// be used in SQL statements. v must be the value decoded for this
+// column.
+//
+// strings are single-quoted with MySQL escape sequences, binary
+// values use hexadecimal notation X'..', temporal values are quoted
+// with fractional digits as per column definition, and NULL for nil.
+// ENUM and SET values are quoted strings if permitted values are known,
+// otherwise their numeric values.
+func (col Column) SQLLiteral(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return sqlQuote(v)
+	case []byte:
+		return "X'" + hex.EncodeToString(v) + "'"
+	case time.Time, time.Duration:
+		return sqlQuote(col.temporalLiteral(v))
+	case ZeroDate:
+		return sqlQuote(string(v))
+	case Enum:
+		if len(v.Values) > 0 {
+			return sqlQuote(v.String())
+		}
+		return strconv.FormatUint(uint64(v.Val), 10)
+	case Set:
+		if len(v.Values) > 0 {
+			return sqlQuote(v.String())
+		}
+		return strconv.FormatUint(v.Val, 10)
+	case JSON:
+		return sqlQuote(v.Raw())
+	}
+	return col.numberLiteral(v)
+}
+
+// DisplayLiteral returns v formatted for human display.
+//
+// unlike SQLLiteral, strings are double-quoted with Go escape
+// sequences, binary values are written as x"..", temporal values
+// and JSON documents are not quoted.
+func (col Column) DisplayLiteral(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return strconv.Quote(v)
+	case []byte:
+		return `x"` + hex.EncodeToString(v) + `"`
+	case time.Time, time.Duration:
+		return col.temporalLiteral(v)
+	case ZeroDate:
+		return string(v)
+	case Enum:
+		if len(v.Values) > 0 {
+			return strconv.Quote(v.String())
+		}
+		return v.String()
+	case Set:
+		if len(v.Values) > 0 {
+			return strconv.Quote(v.String())
+		}
+		return v.String()
+	case JSON:
+		return v.Raw()
+	}
+	return col.numberLiteral(v)
+}
+
+func (col Column) numberLiteral(v interface{}) string {
+	switch v := v.(type) {
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+// temporalLiteral formats time.Time or time.Duration using
+// MySQL syntax, with fractional digits as per column definition.
+func (col Column) temporalLiteral(v interface{}) string {
+	var fsp int
+	switch col.Type {
+	case TypeDateTime2, TypeTimestamp2, TypeTime2:
+		fsp = int(col.Meta)
+	}
+	switch v := v.(type) {
+	case time.Time:
+		if col.Type == TypeDate || col.Type == TypeNewDate {
+			return v.Format("2006-01-02")
+		}
+		layout := "2006-01-02 15:04:05"
+		if fsp > 0 {
+			layout += "." + strings.Repeat("0", fsp)
+		}
+		return v.Format(layout)
+	case time.Duration:
+		s := formatDuration(v) // fixed 6 fractional digits
+		if fsp == 0 {
+			return s[:len(s)-7]
+		}
+		return s[:len(s)-6+fsp]
+	}
+	return fmt.Sprint(v)
+}
+
+// sqlQuote returns s as single-quoted MySQL string literal.
+//
+// https://dev.mysql.com/doc/refman/8.0/en/string-literals.html#character-escape-sequences
+func sqlQuote(s string) string {
+	var buf strings.Builder
+	buf.Grow(len(s) + 2)
+	buf.WriteByte('\'')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case 0:
+			buf.WriteString(`\0`)
+		case '\'':
+			buf.WriteString(`\'`)
+		case '"':
+			buf.WriteString(`\"`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case 0x1a:
+			buf.WriteString(`\Z`)
+		case '\\':
+			buf.WriteString(`\\`)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	buf.WriteByte('\'')
+	return buf.String()
+}
@@ -0,0 +1,42 @@
+package binlog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestColumn_SQLLiteral(t *testing.T) {
+	dt := time.Date(2021, 2, 14, 20, 37, 12, 123456000, time.UTC)
+	dur := -(838*time.Hour + 51*time.Minute + 58*time.Second + 123456*time.Microsecond)
+	testCases := []struct {
+		col     Column
+		v       interface{}
+		sql     string
+		display string
+	}{
+		{Column{Type: TypeLong}, nil, `NULL`, `NULL`},
+		{Column{Type: TypeLong}, int32(-23), `-23`, `-23`},
+		{Column{Type: TypeDouble}, 1.2345, `1.2345`, `1.2345`},
+		{Column{Type: TypeNewDecimal}, Decimal("-12.450"), `-12.450`, `-12.450`},
+		{Column{Type: TypeVarchar}, "it's \"x\"\n\\", `'it\'s \"x\"\n\\'`, `"it's \"x\"\n\\"`},
+		{Column{Type: TypeBlob}, []byte("hi"), `X'6869'`, `x"6869"`},
+		{Column{Type: TypeDate}, dt, `'2021-02-14'`, `2021-02-14`},
+		{Column{Type: TypeDateTime2, Meta: 3}, dt, `'2021-02-14 20:37:12.123'`, `2021-02-14 20:37:12.123`},
+		{Column{Type: TypeDateTime2, Meta: 0}, dt, `'2021-02-14 20:37:12'`, `2021-02-14 20:37:12`},
+		{Column{Type: TypeTime2, Meta: 6}, dur, `'-838:51:58.123456'`, `-838:51:58.123456`},
+		{Column{Type: TypeTime2, Meta: 2}, dur, `'-838:51:58.12'`, `-838:51:58.12`},
+		{Column{Type: TypeTime2, Meta: 0}, dur, `'-838:51:58'`, `-838:51:58`},
+		{Column{Type: TypeEnum}, Enum{2, []string{"a", "b"}}, `'b'`, `"b"`},
+		{Column{Type: TypeEnum}, Enum{2, nil}, `2`, `2`},
+		{Column{Type: TypeSet}, Set{3, []string{"a", "b"}}, `'a,b'`, `"a,b"`},
+		{Column{Type: TypeJSON}, JSON{map[string]interface{}{"k": "it's"}}, `'{\"k\": \"it\'s\"}'`, `{"k": "it's"}`},
+	}
+	for _, tc := range testCases {
+		if got := tc.col.SQLLiteral(tc.v); got != tc.sql {
+			t.Errorf("SQLLiteral(%s %#v): got %s, want %s", tc.col.Type, tc.v, got, tc.sql)
+		}
+		if got := tc.col.DisplayLiteral(tc.v); got != tc.display {
+			t.Errorf("DisplayLiteral(%s %#v): got %s, want %s", tc.col.Type, tc.v, got, tc.display)
+		}
+	}
+}
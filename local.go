@@ -5,33 +5,49 @@ import (
 	"fmt"
 	"hash/crc32"
 	"io"
-	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
 	"strings"
 )
 
 // Local represents connection to local dump directory.
 type Local struct {
+	fs   FS
 	dir  string
 	conn *dirReader
 
 	binlogReader *reader
+	options      // decodeOptions, with setters shared by Remote, Local and Reader
+	backoff      Backoff
+	pacer        pacer
+
+	executed GTIDSet // transactions skipped, after SeekGTID
+	skipping bool    // events of executed transaction are being skipped
 }
 
 // Open connects to dump directory specified.
 func Open(dir string) (*Local, error) {
-	fi, err := os.Stat(dir)
+	return OpenFS(OSFS, filepath.ToSlash(dir))
+}
+
+// OpenFS connects to dump directory specified in given file system.
+func OpenFS(fsys FS, dir string) (*Local, error) {
+	fi, err := fsys.Stat(dir)
 	if err != nil {
 		return nil, err
 	}
 	if !fi.IsDir() {
 		return nil, fmt.Errorf("binlog.Open: %q is not a directory", dir)
 	}
-	return &Local{dir: dir}, nil
+	return &Local{fs: fsys, dir: dir}, nil
 }
 
 // ListFiles lists the binary log files in dump directory.
+//
+// If the directory is not created by Dump, i.e. it has no .next
+// files, as in a copied datadir, the files are listed from
+// mysql index file, or by name if index file is not found.
 func (bl *Local) ListFiles() ([]string, error) {
 	var files []string
 	for {
@@ -39,27 +55,31 @@ func (bl *Local) ListFiles() ([]string, error) {
 		if len(files) > 0 {
 			name = files[len(files)-1] + ".next"
 		}
-		f, err := os.Open(path.Join(bl.dir, name))
+		buff, err := readFile(bl.fs, path.Join(bl.dir, name))
 		if err != nil {
 			if os.IsNotExist(err) {
+				if len(files) == 0 {
+					return indexFiles(bl.fs, bl.dir)
+				}
 				return files, nil
 			}
 			return nil, err
 		}
-		buff, err := ioutil.ReadAll(f)
-		if err != nil {
-			return nil, err
-		}
 		files = append(files, strings.TrimSpace(string(buff)))
 	}
 }
 
 func (bl *Local) addFile(name string) error {
+	unlock, err := lockDir(bl.fs, bl.dir, bl.opts.clock)
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	files, err := bl.ListFiles()
 	if err != nil {
 		return err
 	}
-	if err := ensureBinlogFile(path.Join(bl.dir, name)); err != nil {
+	if err := ensureBinlogFile(bl.fs, path.Join(bl.dir, name)); err != nil {
 		return err
 	}
 	next := ".next"
@@ -69,27 +89,35 @@ func (bl *Local) addFile(name string) error {
 		}
 		next = files[len(files)-1] + ".next"
 	}
-	return ioutil.WriteFile(path.Join(bl.dir, next), []byte(name), 0666)
+	return writeFileAtomic(bl.fs, path.Join(bl.dir, next), []byte(name))
 }
 
 // RemoveFirstFile deletes the first binary log file from dump directory.
 func (bl *Local) RemoveFirstFile() error {
-	buf, err := ioutil.ReadFile(path.Join(bl.dir, ".next"))
+	unlock, err := lockDir(bl.fs, bl.dir, bl.opts.clock)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	buf, err := readFile(bl.fs, path.Join(bl.dir, ".next"))
 	if err != nil {
 		return err
 	}
 	file1 := strings.TrimSpace(string(buf))
-	buf, err = ioutil.ReadFile(path.Join(bl.dir, file1+".next"))
+	buf, err = readFile(bl.fs, path.Join(bl.dir, file1+".next"))
 	if err != nil {
 		return err
 	}
-	if err = ioutil.WriteFile(path.Join(bl.dir, ".next"), buf, 0666); err != nil {
+	if err = writeFileAtomic(bl.fs, path.Join(bl.dir, ".next"), buf); err != nil {
 		return err
 	}
-	if err := os.Remove(path.Join(bl.dir, file1)); err != nil {
+	if err := bl.fs.Remove(path.Join(bl.dir, file1)); err != nil {
 		return err
 	}
-	return os.Remove(path.Join(bl.dir, file1+".next"))
+	if err := bl.fs.Remove(path.Join(bl.dir, file1+".next")); err != nil {
+		return err
+	}
+	return updateManifest(bl.fs, bl.dir, file1, nil)
 }
 
 // MasterStatus provides status information about the binary log files in dump directory.
@@ -103,32 +131,32 @@ func (bl *Local) MasterStatus() (file string, pos uint32, err error) {
 	}
 	file = files[len(files)-1]
 
-	f, err := os.Open(path.Join(bl.dir, file))
+	f, err := bl.fs.Open(path.Join(bl.dir, file))
 	if err != nil {
 		return "", 0, fmt.Errorf("binlog.Local.MasterStatus: error in open file: %v", err)
 	}
 	defer f.Close()
+	pos, err = eventsEnd(f, 4) // skip file header
+	return file, pos, err
+}
+
+// eventsEnd returns position after last complete event in f, scanning
+// events from pos.
+func eventsEnd(f File, pos uint32) (uint32, error) {
 	fi, err := f.Stat()
 	if err != nil {
-		return "", 0, err
+		return 0, err
 	}
-
-	// Skip file header.
-	_, err = f.Seek(4, io.SeekStart)
-	if err != nil {
-		return
+	if _, err = f.Seek(int64(pos), io.SeekStart); err != nil {
+		return 0, err
 	}
-	pos = 4
-
 	buf := make([]byte, 13)
 	for {
-		_, err = io.ReadFull(f, buf)
-		if err == io.EOF {
-			err = nil
-			return
+		if _, err = io.ReadFull(f, buf); err == io.EOF || err == io.ErrUnexpectedEOF {
+			return pos, nil
 		}
 		if err != nil {
-			return
+			return 0, err
 		}
 		// Timestamp = buf[:4]
 		// EventType := buf[4]
@@ -136,11 +164,11 @@ func (bl *Local) MasterStatus() (file string, pos uint32, err error) {
 		eventSize := binary.LittleEndian.Uint32(buf[9:])
 		if int64(pos+eventSize) > fi.Size() {
 			// partial record found
-			return
+			return pos, nil
 		}
 		pos += eventSize
 		if _, err = f.Seek(int64(pos), io.SeekStart); err != nil {
-			return
+			return 0, err
 		}
 	}
 }
@@ -150,21 +178,176 @@ func (bl *Local) MasterStatus() (file string, pos uint32, err error) {
 // if serverID is zero, NextEvent return io.EOF when there are no more events.
 // if serverID is non-zero, NextEvent waits for new events.
 func (bl *Local) Seek(serverID uint32, fileName string, position uint32) error {
-	r, err := newDirReader(bl.dir, &fileName, position, serverID == 0)
+	r, err := newDirReader(bl.fs, bl.dir, &fileName, position, serverID == 0, bl.opts.clock, bl.backoff)
 	if err != nil {
 		return err
 	}
 	bl.conn = r
+	bl.binlogReader = nil
+	bl.executed, bl.skipping = nil, false
 	return nil
 }
 
+// SeekGTID requests binlog events, which are not in executed gtid set,
+// as Remote.SeekGTID does, but offline. It locates the last file whose
+// PREVIOUS_GTIDS_EVENT is contained in executed, and seeks to the first
+// GTIDEvent not in executed, from that file onwards. If there is no
+// such event, it seeks to end of the last file.
+//
+// Transactions after the position, which are in executed, are skipped
+// by NextEvent, as the server does. Requires files written with
+// gtid_mode=ON.
+func (bl *Local) SeekGTID(serverID uint32, executed GTIDSet) error {
+	files, err := bl.ListFiles()
+	if err != nil {
+		return err
+	}
+	start := -1
+	for i := len(files) - 1; i >= 0 && start == -1; i-- {
+		prev, err := bl.scanGTIDs(files[i], nil)
+		if err != nil {
+			return err
+		}
+		if executed.containsSet(prev) {
+			start = i
+		}
+	}
+	if start == -1 {
+		return fmt.Errorf("binlog: gtids not in executed set are purged from %s", bl.dir)
+	}
+	for _, file := range files[start:] {
+		pos := uint32(0)
+		_, err := bl.scanGTIDs(file, func(e GTIDEvent, p uint32) bool {
+			if executed.Contains(e.UUID(), e.GNO) {
+				return true
+			}
+			pos = p
+			return false
+		})
+		if err != nil {
+			return err
+		}
+		if pos != 0 {
+			return bl.seekGTID(serverID, file, pos, executed)
+		}
+	}
+	file, pos, err := bl.MasterStatus()
+	if err != nil {
+		return err
+	}
+	return bl.seekGTID(serverID, file, pos, executed)
+}
+
+func (bl *Local) seekGTID(serverID uint32, file string, pos uint32, executed GTIDSet) error {
+	if err := bl.Seek(serverID, file, pos); err != nil {
+		return err
+	}
+	bl.executed = executed.Clone()
+	return nil
+}
+
+// scanGTIDs returns gtid set of PREVIOUS_GTIDS_EVENT of file. If fn
+// is non-nil, it is called with each GTIDEvent in file, and its
+// position, until it returns false.
+func (bl *Local) scanGTIDs(file string, fn func(e GTIDEvent, pos uint32) bool) (GTIDSet, error) {
+	f, err := bl.fs.Open(path.Join(bl.dir, file))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := NewReader(f)
+	var prev GTIDSet
+	for {
+		e, err := r.NextEvent()
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch d := e.Data.(type) {
+		case UnknownEvent:
+			if d.Type == PREVIOUS_GTIDS_EVENT {
+				if prev, err = decodeGTIDSet(d.Body); err != nil {
+					return nil, err
+				}
+				if fn == nil {
+					return prev, nil
+				}
+			}
+		case GTIDEvent:
+			if fn != nil && !fn(d, e.Header.NextPos-e.Header.EventSize) {
+				return prev, nil
+			}
+		}
+	}
+	if prev == nil {
+		return nil, fmt.Errorf("binlog: no previous gtids event in %s", file)
+	}
+	return prev, nil
+}
+
 // NextEvent return next binlog event.
 //
+// When it switches to next binlog file, an artificial RotateEvent
+// is returned as in Remote, before the first event of that file.
+//
 // return io.EOF when there are no more Events
 func (bl *Local) NextEvent() (Event, error) {
+	e, err := bl.nextEvent()
+	if err == nil {
+		bl.pacer.wait(bl.opts.clock, e)
+	}
+	return e, err
+}
+
+func (bl *Local) nextEvent() (Event, error) {
+	for {
+		e, err := bl.nextStreamEvent()
+		if err != nil || !bl.skipExecuted(e) {
+			return e, err
+		}
+	}
+}
+
+// skipExecuted tells whether e is part of transaction in executed gtid
+// set of SeekGTID, which is skipped.
+func (bl *Local) skipExecuted(e Event) bool {
+	if bl.executed == nil {
+		return false
+	}
+	switch d := e.Data.(type) {
+	case GTIDEvent:
+		bl.skipping = bl.executed.Contains(d.UUID(), d.GNO)
+		return bl.skipping
+	case RotateEvent:
+		bl.skipping = false // transaction does not span files
+		return false
+	case FormatDescriptionEvent, CorruptionEvent:
+		return false
+	}
+	if e.Header.EventType == ANONYMOUS_GTID_EVENT {
+		bl.skipping = false
+	}
+	return bl.skipping
+}
+
+func (bl *Local) nextStreamEvent() (Event, error) {
+	if e, ok := bl.binlogReader.nextPending(); ok {
+		return e, nil
+	}
+	e, err := bl.readEvent()
+	if err != nil || !bl.opts.coalesceRows {
+		return e, err
+	}
+	return coalesceRows(e, bl.binlogReader, bl.readEvent)
+}
+
+func (bl *Local) readEvent() (Event, error) {
 	r := bl.binlogReader
+	var corrupt *CorruptionEvent
 	if r == nil {
-		v, err := findBinlogVersion(bl.conn.file.Name())
+		v, err := findBinlogVersion(bl.fs, bl.conn.path)
 		if err != nil {
 			return Event{}, err
 		}
@@ -173,10 +356,15 @@ func (bl *Local) NextEvent() (Event, error) {
 			tmeCache:   bl.conn.tmeCache,
 			binlogFile: *bl.conn.name,
 			limit:      -1,
+			scan:       true,
+			opts:       &bl.opts,
+			source:     &SourceInfo{Addr: bl.dir},
 		}
 		bl.conn.name = &r.binlogFile
 		r.checksum = bl.conn.checksum
-		r.hash = crc32.NewIEEE()
+		if bl.opts.checksumPolicy != ChecksumIgnore {
+			r.hash = crc32.NewIEEE()
+		}
 		r.fde = FormatDescriptionEvent{BinlogVersion: v}
 		bl.binlogReader = r
 	} else {
@@ -184,14 +372,10 @@ func (bl *Local) NextEvent() (Event, error) {
 			return Event{}, fmt.Errorf("binlog.NextEvent: error in draining event: %v", err)
 		}
 		if r.checksum > 0 {
-			got := r.hash.Sum32()
 			r.limit += r.checksum
-			want := r.int4()
-			if r.err != nil {
-				return Event{}, r.err
-			}
-			if got != want {
-				return Event{}, fmt.Errorf("binlog.NextEvent: checksum failed got=%d want=%d", got, want)
+			var err error
+			if corrupt, err = r.verifyChecksum(); err != nil {
+				return Event{}, err
 			}
 		}
 		r.limit = -1
@@ -201,20 +385,73 @@ func (bl *Local) NextEvent() (Event, error) {
 		if err == nil {
 			err = io.EOF
 		}
-		return Event{}, err
+		return corruptionFirst(r, corrupt, Event{}, err)
+	}
+	if bl.conn.rotated {
+		// return artificial RotateEvent before first event of next file.
+		bl.conn.rotated = false
+		e, err := nextEvent(r, 0)
+		e, err = corruptionFirst(r, nil, e, err) // corruption of e ahead of it
+		if err != nil {
+			return e, err
+		}
+		r.pending = append([]Event{e}, r.pending...)
+		return corruptionFirst(r, corrupt, Event{
+			Header: EventHeader{
+				EventType: ROTATE_EVENT,
+				ServerID:  e.Header.ServerID,
+				LogFile:   r.binlogFile,
+				NextPos:   4,
+				Flags:     LOG_EVENT_ARTIFICIAL_F,
+			},
+			Data: RotateEvent{Position: 4, NextBinlog: r.binlogFile},
+		}, nil)
 	}
-	return nextEvent(r, 0)
+	e, err := nextEvent(r, 0)
+	return corruptionFirst(r, corrupt, e, err)
+}
+
+// SetBackoff sets delays between checks for new events, when NextEvent
+// is waiting for them. When b gives up, NextEvent returns io.EOF. must
+// be called before Seek. nil means exponential backoff from 50ms to 1s,
+// retrying forever.
+func (bl *Local) SetBackoff(b Backoff) {
+	bl.backoff = b
+}
+
+// SetClock sets source of time for EventMeta and for the delays when
+// waiting for new events. must be called before Seek. nil means
+// SystemClock.
+func (bl *Local) SetClock(c Clock) {
+	bl.opts.clock = c
 }
 
 // NextRow returns next row for RowsEvent. Returns io.EOF when there are no more rows.
-// valuesBeforeUpdate should be used only for events UPDATE_ROWS_EVENTv1, UPDATE_ROWS_EVENTv2.
+// values is the row inserted or deleted, or the row after update. valuesBeforeUpdate
+// is the row before update, and is nil for events other than UPDATE_ROWS_EVENTv1,
+// UPDATE_ROWS_EVENTv2.
 func (bl *Local) NextRow() (values []interface{}, valuesBeforeUpdate []interface{}, err error) {
 	return nextRow(bl.binlogReader)
 }
 
+// NextRows is like NextRow, but returns upto n rows at once, to amortize
+// per call overhead for RowsEvent with many rows. rowsBeforeUpdate is nil
+// unless rows were updated. Returns io.EOF when there are no more rows.
+func (bl *Local) NextRows(n int) (rows [][]interface{}, rowsBeforeUpdate [][]interface{}, err error) {
+	return nextRows(bl.binlogReader, n)
+}
+
+// Close closes the binlog file being read, if any.
+func (bl *Local) Close() error {
+	if bl.conn == nil {
+		return nil
+	}
+	return bl.conn.file.Close()
+}
+
 // todo: https://dev.mysql.com/doc/internals/en/determining-binary-log-version.html
-func findBinlogVersion(file string) (uint16, error) {
-	f, err := os.Open(file)
+func findBinlogVersion(fsys FS, file string) (uint16, error) {
+	f, err := fsys.Open(file)
 	if err != nil {
 		return 0, err
 	}
@@ -228,26 +465,25 @@ func findBinlogVersion(file string) (uint16, error) {
 	if r.err != nil {
 		return 0, r.err
 	}
-	if eventType != START_EVENT_V3 && eventType != FORMAT_DESCRIPTION_EVENT {
-		return 3, nil
-	}
-	if eventType == START_EVENT_V3 {
-		if eventSize < 75 {
-			return 1, nil
-		}
-		return 3, nil
-	}
-	if eventType == FORMAT_DESCRIPTION_EVENT {
-		return 4, nil
+	return binlogVersion(eventType, eventSize), nil
+}
+
+// binlogVersion determines binlog version from type and size of first event.
+func binlogVersion(eventType EventType, eventSize uint32) uint16 {
+	switch {
+	case eventType == FORMAT_DESCRIPTION_EVENT:
+		return 4
+	case eventType == START_EVENT_V3 && eventSize < 75:
+		return 1
 	}
-	return 0, fmt.Errorf("binlog: cannot determine binlog version for %q", file)
+	return 3
 }
 
-func ensureBinlogFile(file string) error {
-	stat, err := os.Stat(file)
+func ensureBinlogFile(fsys FS, file string) error {
+	stat, err := fsys.Stat(file)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return ioutil.WriteFile(file, fileHeader, 0666)
+			return writeFile(fsys, file, fileHeader)
 		}
 		return err
 	}
@@ -255,7 +491,7 @@ func ensureBinlogFile(file string) error {
 		return fmt.Errorf("binlog: %q is directory", file)
 	}
 	if stat.Size() < headerSize {
-		return ioutil.WriteFile(file, fileHeader, 0666)
+		return writeFile(fsys, file, fileHeader)
 	}
 	return nil
 }
@@ -0,0 +1,211 @@
+package binlog
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestLocal_indexFile(t *testing.T) {
+	for _, index := range []bool{true, false} {
+		dir, err := ioutil.TempDir("", "binlog")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		want := []string{"mysql-bin.000001", "mysql-bin.000002"}
+		for i, name := range want {
+			s := newBinlogStream()
+			s.XID(uint64(i))
+			if err := ioutil.WriteFile(filepath.Join(dir, name), s.Bytes(), 0666); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if index {
+			content := "./mysql-bin.000001\n./mysql-bin.000002\n"
+			if err := ioutil.WriteFile(filepath.Join(dir, "mysql-bin.index"), []byte(content), 0666); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		bl, err := Open(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files, err := bl.ListFiles()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(files, want) {
+			t.Fatal("got", files, "want", want)
+		}
+		if err := bl.Seek(0, files[0], 4); err != nil {
+			t.Fatal(err)
+		}
+		var xids []uint64
+		rotated := false
+		for {
+			e, err := bl.NextEvent()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch d := e.Data.(type) {
+			case XIDEvent:
+				xids = append(xids, d.XID)
+			case RotateEvent:
+				if d.NextBinlog != want[1] || e.Header.LogFile != want[1] || e.Header.NextPos != 4 {
+					t.Fatalf("got %#v, want rotate to %s", e, want[1])
+				}
+				rotated = true
+			}
+		}
+		if !reflect.DeepEqual(xids, []uint64{0, 1}) {
+			t.Fatal("index:", index, "got", xids, "want", []uint64{0, 1})
+		}
+		if !rotated {
+			t.Fatal("RotateEvent not returned")
+		}
+	}
+}
+
+func TestLocal_indexFile_order(t *testing.T) {
+	dir, err := ioutil.TempDir("", "binlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"mysql-bin.1000000", "mysql-bin.999999", "host-relay-bin.000001"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "host-relay-bin.index"), []byte("./host-relay-bin.000001\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	bl, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := bl.ListFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"mysql-bin.999999", "mysql-bin.1000000"}; !reflect.DeepEqual(files, want) {
+		t.Fatal("got", files, "want", want)
+	}
+
+	content := "./mysql-bin.999999\n./mysql-bin.1000000\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "mysql-bin.index"), []byte(content), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if files, err = bl.ListFiles(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"mysql-bin.999999", "mysql-bin.1000000"}; !reflect.DeepEqual(files, want) {
+		t.Fatal("with index: got", files, "want", want)
+	}
+}
+
+func TestLockDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "binlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	bl, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := &fakeClock{now: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}
+	bl.SetClock(clock)
+	unlock, err := lockDir(bl.fs, bl.dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.addFile("binlog.000001"); err != ErrDirLocked {
+		t.Fatal("got", err, "want", ErrDirLocked)
+	}
+	if waited := clock.now.Sub(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)); waited <= lockTimeout {
+		t.Fatal("waited", waited, "want more than", lockTimeout)
+	}
+	if err := unlock(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"binlog.000001", "binlog.000002"} {
+		if err := bl.addFile(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := bl.RemoveFirstFile(); err != nil {
+		t.Fatal(err)
+	}
+	files, err := bl.ListFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"binlog.000002"}; !reflect.DeepEqual(files, want) {
+		t.Fatal("got", files, "want", want)
+	}
+}
+
+func TestLockDir_stale(t *testing.T) {
+	dir, err := ioutil.TempDir("", "binlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// pid of exited process
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Skip("run test binary:", err)
+	}
+	pid := cmd.Process.Pid
+
+	clock := &fakeClock{now: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}
+	name := filepath.Join(dir, ".lock")
+	tests := []struct {
+		owner  string
+		locked bool
+	}{
+		{lockOwner(pid), false},
+		{lockOwner(os.Getpid()), true},
+		{"otherhost " + strconv.Itoa(pid) + "\n", true},
+		{"garbage", true},
+	}
+	for _, test := range tests {
+		if err := ioutil.WriteFile(name, []byte(test.owner), 0666); err != nil {
+			t.Fatal(err)
+		}
+		unlock, err := lockDir(OSFS, filepath.ToSlash(dir), clock)
+		if test.locked {
+			if err != ErrDirLocked {
+				t.Errorf("%q: got %v, want %v", test.owner, err, ErrDirLocked)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%q: %v", test.owner, err)
+		}
+		buf, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buf) != lockOwner(os.Getpid()) {
+			t.Errorf("%q: lock owner is %q", test.owner, buf)
+		}
+		if err := unlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package binlog
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+)
+
+// ManifestEntry describes a binary log file, completely written by
+// Dump. Dump records entries in .manifest file of dump directory, when
+// it rotates to next file. see Local.VerifyManifest.
+type ManifestEntry struct {
+	File     string `json:"file"`
+	Size     int64  `json:"size"`
+	SHA256   string `json:"sha256"`          // hex encoded
+	FirstPos uint32 `json:"firstPos"`        // position of first event
+	LastPos  uint32 `json:"lastPos"`         // position of last event
+	GTIDs    string `json:"gtids,omitempty"` // GTIDSet of transactions in file
+}
+
+// Manifest returns entries of .manifest file of dump directory, in
+// order of files. It returns nil if dump directory has no manifest.
+func (bl *Local) Manifest() ([]ManifestEntry, error) {
+	return readManifest(bl.fs, bl.dir)
+}
+
+// VerifyManifest checks that files of dump directory, recorded in its
+// manifest, are not modified since they were dumped. It returns error
+// describing the first mismatch.
+func (bl *Local) VerifyManifest() error {
+	entries, err := readManifest(bl.fs, bl.dir)
+	if err != nil {
+		return err
+	}
+	for _, want := range entries {
+		got, err := manifestEntry(bl.fs, bl.dir, want.File)
+		if err != nil {
+			return err
+		}
+		switch {
+		case got.Size != want.Size:
+			return fmt.Errorf("binlog: %s: size is %d, want %d", want.File, got.Size, want.Size)
+		case got.SHA256 != want.SHA256:
+			return fmt.Errorf("binlog: %s: sha256 mismatch", want.File)
+		case got != want:
+			return fmt.Errorf("binlog: %s: events mismatch", want.File)
+		}
+	}
+	return nil
+}
+
+func readManifest(fsys FS, dir string) ([]ManifestEntry, error) {
+	buf, err := readFile(fsys, path.Join(dir, ".manifest"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var entries []ManifestEntry
+	if err := json.Unmarshal(buf, &entries); err != nil {
+		return nil, fmt.Errorf("binlog: invalid .manifest file in %s: %v", dir, err)
+	}
+	return entries, nil
+}
+
+// addManifestEntry records entry of given file, which is completely
+// written by Dump, in manifest.
+func addManifestEntry(fsys FS, dir, file string, clock Clock) (ManifestEntry, error) {
+	e, err := manifestEntry(fsys, dir, file)
+	if err != nil {
+		return e, err
+	}
+	unlock, err := lockDir(fsys, dir, clock)
+	if err != nil {
+		return e, err
+	}
+	defer unlock()
+	return e, updateManifest(fsys, dir, file, &e)
+}
+
+// updateManifest records entry of given file in manifest, replacing
+// any previous entry of it. if e is nil, entry of file is removed.
+func updateManifest(fsys FS, dir, file string, e *ManifestEntry) error {
+	entries, err := readManifest(fsys, dir)
+	if err != nil {
+		return err
+	}
+	updated := entries[:0]
+	for _, entry := range entries {
+		if entry.File != file {
+			updated = append(updated, entry)
+		}
+	}
+	if e != nil {
+		updated = append(updated, *e)
+	} else if len(updated) == len(entries) {
+		return nil
+	}
+	buf, err := json.MarshalIndent(updated, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(fsys, path.Join(dir, ".manifest"), append(buf, '\n'))
+}
+
+// manifestEntry computes entry of given file, by scanning its events.
+func manifestEntry(fsys FS, dir, file string) (ManifestEntry, error) {
+	e := ManifestEntry{File: file}
+	f, err := fsys.Open(path.Join(dir, file))
+	if err != nil {
+		return e, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	r := io.TeeReader(f, h)
+	if _, err := io.CopyN(ioutil.Discard, r, 4); err != nil { // magic number
+		return e, err
+	}
+	gtids := GTIDSet{}
+	pos := uint32(4)
+	buf := make([]byte, 19+25) // header, and GTIDEvent upto GNO
+	for {
+		_, err := io.ReadFull(r, buf[:13])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if err == io.ErrUnexpectedEOF {
+				err = fmt.Errorf("binlog: %s: partial event at %d", file, pos)
+			}
+			return e, err
+		}
+		eventType := EventType(buf[4])
+		eventSize := binary.LittleEndian.Uint32(buf[9:])
+		if eventSize < 13 {
+			return e, fmt.Errorf("binlog: %s: invalid event size %d at %d", file, eventSize, pos)
+		}
+		if e.FirstPos == 0 {
+			e.FirstPos = pos
+		}
+		e.LastPos = pos
+		rest := int64(eventSize) - 13
+		if eventType == GTID_EVENT && eventSize >= uint32(len(buf)) {
+			if _, err := io.ReadFull(r, buf[13:]); err != nil {
+				return e, err
+			}
+			rest -= int64(len(buf)) - 13
+			var gtid GTIDEvent
+			copy(gtid.SID[:], buf[20:36])
+			gtid.GNO = int64(binary.LittleEndian.Uint64(buf[36:]))
+			gtids.AddGTID(gtid)
+		}
+		if _, err := io.CopyN(ioutil.Discard, r, rest); err != nil {
+			if err == io.EOF {
+				err = fmt.Errorf("binlog: %s: partial event at %d", file, pos)
+			}
+			return e, err
+		}
+		pos += eventSize
+	}
+	e.Size = int64(pos)
+	e.SHA256 = hex.EncodeToString(h.Sum(nil))
+	e.GTIDs = gtids.String()
+	return e, nil
+}
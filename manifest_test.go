@@ -0,0 +1,88 @@
+package binlog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRemote_DumpManifest(t *testing.T) {
+	s := newFakeServer()
+	f1 := newBinlogStream()
+	f1.gtid(uuid1, 1)
+	f1.XID(7)
+	lastPos := uint32(f1.Len())
+	f1.gtid(uuid1, 2)
+	s.addFile("binlog.000001", f1)
+	f2 := newBinlogStream()
+	f2.XID(9)
+	s.addFile("binlog.000002", f2)
+
+	bl, err := s.dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bl.Close()
+	if err := bl.Seek(0, "binlog.000001", 4); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := bl.Dump(dir); err != io.EOF {
+		t.Fatal("got", err, "want", io.EOF)
+	}
+
+	local, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer local.Close()
+	got, err := local.Manifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(f1.Bytes())
+	want := []ManifestEntry{{
+		File:     "binlog.000001",
+		Size:     int64(f1.Len()),
+		SHA256:   hex.EncodeToString(sum[:]),
+		FirstPos: 4,
+		LastPos:  lastPos,
+		GTIDs:    uuid1 + ":1-2",
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	if err := local.VerifyManifest(); err != nil {
+		t.Fatal(err)
+	}
+
+	// tamper with dumped file
+	name := filepath.Join(dir, "binlog.000001")
+	buf, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf[len(buf)-1] ^= 0xff
+	if err := ioutil.WriteFile(name, buf, 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := local.VerifyManifest(); err == nil {
+		t.Fatal("VerifyManifest of modified file: error expected")
+	}
+
+	if err := local.RemoveFirstFile(); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := local.Manifest(); err != nil || len(got) != 0 {
+		t.Fatal("manifest after RemoveFirstFile:", got, err)
+	}
+}
@@ -0,0 +1,76 @@
+#!/usr/bin/env bash
+
+# runs TestRemote_integration against docker images of server versions,
+# with each combination of binlog_row_metadata and binlog_checksum.
+#
+# Usage: ./matrix.sh [IMAGE...]
+# Example: ./matrix.sh mysql:8.0 mariadb:10.11
+
+set -e
+
+if ! [ -x "$(command -v docker)" ]; then
+    echo docker is not installed 1>&2
+    exit 1
+fi
+
+images=("$@")
+if [ ${#images[@]} -eq 0 ]; then
+    images=(mysql:5.6 mysql:5.7 mysql:8.0 mysql:8.4 mariadb:10.6 mariadb:10.11)
+fi
+
+cname=binlog-matrix
+port=3407
+password=dockword
+
+cleanup() {
+    docker rm -f $cname > /dev/null 2>&1 || true
+}
+trap cleanup EXIT
+
+# client runs mysql client inside the container.
+client() {
+    docker exec $cname sh -c "if command -v mysql > /dev/null; then mysql \"\$@\"; else mariadb \"\$@\"; fi" -- \
+        -uroot -p$password -h127.0.0.1 "$@"
+}
+
+failed=()
+for img in ${images[@]}; do
+    metadata=(MINIMAL)
+    if [[ $img == mysql:8.* ]]; then
+        metadata=(MINIMAL FULL) # binlog_row_metadata since 8.0.1
+    fi
+    ssl=
+    if [[ $img == mysql:5.7* || $img == mysql:8.* ]]; then
+        ssl=,ssl # these images generate certificates on startup
+    fi
+    for meta in ${metadata[@]}; do
+        for checksum in CRC32 NONE; do
+            args=(--server-id=1 --log-bin=binlog --binlog-format=ROW --binlog-checksum=$checksum)
+            if [ $meta == FULL ]; then
+                args+=(--binlog-row-metadata=FULL)
+            fi
+            name="$img row_metadata=$meta checksum=$checksum"
+            echo +++ $name
+            cleanup
+            docker run --name $cname -e MYSQL_ROOT_PASSWORD=$password -e MARIADB_ROOT_PASSWORD=$password \
+                -e MYSQL_ROOT_HOST=% -p $port:3306 -d $img "${args[@]}" > /dev/null
+            while ! client --execute=exit > /dev/null 2>&1; do
+                echo mysql is not up yet
+                sleep 3
+            done
+            client --execute="CREATE DATABASE IF NOT EXISTS binlog"
+            if go test -v -run TestRemote_integration -mysql tcp:localhost:$port$ssl,user=root,password=$password,db=binlog; then
+                echo +++ PASS $name
+            else
+                failed+=("$name")
+            fi
+        done
+    done
+done
+
+if [ ${#failed[@]} -ne 0 ]; then
+    echo +++ failed: 1>&2
+    printf '    %s\n' "${failed[@]}" 1>&2
+    exit 1
+fi
+echo +++ all passed
@@ -0,0 +1,196 @@
+package binlog
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Missing returns columns of the table, that are not present in row
+// image. It is the after image for inserts and updates, and the before
+// image for deletes.
+//
+// Columns are omitted with binlog_row_image=MINIMAL, and BLOB, TEXT,
+// JSON and GEOMETRY columns are omitted with binlog_row_image=NOBLOB
+// unless changed.
+func (e RowsEvent) Missing() []Column {
+	if e.TableMap == nil {
+		return nil
+	}
+	cols := e.Columns()
+	var missing []Column
+	for _, col := range e.TableMap.Columns {
+		if columnIndex(cols, col.Ordinal) == -1 {
+			missing = append(missing, col)
+		}
+	}
+	return missing
+}
+
+// isBlob tells whether column is omitted by binlog_row_image=NOBLOB.
+func (col Column) isBlob() bool {
+	switch col.Type {
+	case TypeBlob, TypeTinyBlob, TypeMediumBlob, TypeLongBlob, TypeJSON, TypeGeometry:
+		return true
+	}
+	return false
+}
+
+// BlobFetcher fills in blob columns, omitted from after images of
+// inserts and updates with binlog_row_image=NOBLOB, by selecting them
+// from DB by primary key, so that sinks receive complete rows. The
+// RowsEvent returned includes the filled columns in Columns.
+//
+// Fetched values are of the current state of the row, not as of the
+// change, and are nil if the row no longer exists. Before images of
+// updates are not filled. Requires binlog_row_metadata=FULL, for
+// column names and primary key.
+type BlobFetcher struct {
+	Src EventSource
+	DB  *sql.DB // connection to the source
+
+	fill   []Column // blob columns to fetch for current RowsEvent
+	layout []int    // index of value in after image, or -(1+index) in fill
+	pkIdx  []int    // index of primary key in after image
+	query  string
+}
+
+// NextEvent returns next event from Src.
+func (f *BlobFetcher) NextEvent() (Event, error) {
+	f.fill = nil
+	e, err := f.Src.NextEvent()
+	if err != nil {
+		return e, err
+	}
+	re, ok := e.Data.(RowsEvent)
+	if !ok || re.TableMap == nil || e.Header.EventType.IsDeleteRows() {
+		return e, nil
+	}
+	for _, col := range re.Missing() {
+		if col.isBlob() {
+			f.fill = append(f.fill, col)
+		}
+	}
+	if len(f.fill) == 0 {
+		return e, nil
+	}
+	tme := re.TableMap
+	if len(tme.PrimaryKey) == 0 {
+		return e, fmt.Errorf("binlog: cannot fetch blobs of %s.%s: primary key unknown", tme.SchemaName, tme.TableName)
+	}
+	cols := re.Columns()
+	f.pkIdx = f.pkIdx[:0]
+	var where []string
+	for _, ord := range tme.PrimaryKey {
+		i := columnIndex(cols, ord)
+		if i == -1 || cols[i].Name == "" {
+			return e, fmt.Errorf("binlog: cannot fetch blobs of %s.%s: primary key not in row image", tme.SchemaName, tme.TableName)
+		}
+		f.pkIdx = append(f.pkIdx, i)
+		where = append(where, quoteIdent(cols[i].Name)+"=?")
+	}
+	var names []string
+	for _, col := range f.fill {
+		if col.Name == "" {
+			return e, fmt.Errorf("binlog: cannot fetch blobs of %s.%s: column names unknown", tme.SchemaName, tme.TableName)
+		}
+		names = append(names, quoteIdent(col.Name))
+	}
+	f.query = fmt.Sprintf("select %s from %s.%s where %s", strings.Join(names, ","),
+		quoteIdent(tme.SchemaName), quoteIdent(tme.TableName), strings.Join(where, " and "))
+
+	// merge filled columns into after image, in ordinal order
+	merged := make([]Column, 0, len(cols)+len(f.fill))
+	f.layout = f.layout[:0]
+	i, j := 0, 0
+	for i < len(cols) || j < len(f.fill) {
+		if j == len(f.fill) || (i < len(cols) && cols[i].Ordinal < f.fill[j].Ordinal) {
+			merged = append(merged, cols[i])
+			f.layout = append(f.layout, i)
+			i++
+		} else {
+			merged = append(merged, f.fill[j])
+			f.layout = append(f.layout, -(1 + j))
+			j++
+		}
+	}
+	columns := append([][]Column(nil), re.columns...)
+	if e.Header.EventType.IsUpdateRows() {
+		columns[1] = merged
+	} else {
+		columns[0] = merged
+	}
+	re.columns = columns
+	e.Data = re
+	return e, nil
+}
+
+// NextRow returns next row of current RowsEvent, with blob columns
+// filled.
+func (f *BlobFetcher) NextRow() (values []interface{}, valuesBeforeUpdate []interface{}, err error) {
+	values, valuesBeforeUpdate, err = f.Src.NextRow()
+	if err != nil || len(f.fill) == 0 {
+		return
+	}
+	fetched, err := f.fetch(values)
+	if err != nil {
+		return nil, nil, err
+	}
+	row := make([]interface{}, len(f.layout))
+	for k, i := range f.layout {
+		if i >= 0 {
+			row[k] = values[i]
+		} else {
+			row[k] = fetched[-(1 + i)]
+		}
+	}
+	return row, valuesBeforeUpdate, nil
+}
+
+// fetch selects blob columns of row with primary key as in values.
+func (f *BlobFetcher) fetch(values []interface{}) ([]interface{}, error) {
+	args := make([]interface{}, len(f.pkIdx))
+	for i, idx := range f.pkIdx {
+		args[i] = values[idx]
+	}
+	fetched := make([]interface{}, len(f.fill))
+	dest := make([]interface{}, len(fetched))
+	for i := range fetched {
+		dest[i] = &fetched[i]
+	}
+	err := f.DB.QueryRow(f.query, args...).Scan(dest...)
+	if err == sql.ErrNoRows {
+		return make([]interface{}, len(f.fill)), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i, col := range f.fill {
+		if fetched[i], err = blobValue(col, fetched[i]); err != nil {
+			return nil, err
+		}
+	}
+	return fetched, nil
+}
+
+// blobValue converts value from database/sql, into value as decoded
+// from binlog.
+func blobValue(col Column, v interface{}) (interface{}, error) {
+	b, ok := v.([]byte)
+	if !ok {
+		return v, nil
+	}
+	b = append([]byte(nil), b...)
+	switch {
+	case col.Type == TypeJSON:
+		var val interface{}
+		if err := json.Unmarshal(b, &val); err != nil {
+			return nil, err
+		}
+		return JSON{val}, nil
+	case col.Type == TypeGeometry || col.Charset == 0 || col.Charset == 63:
+		return b, nil
+	}
+	return string(b), nil
+}
@@ -0,0 +1,92 @@
+package binlog
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestBlobFetcher(t *testing.T) {
+	w := newEncodedStream(4, true)
+	w.FormatDescription("8.0.23", 40, 1)
+	w.Query("test", "BEGIN")
+	types := []byte{byte(TypeLong), byte(TypeBlob), byte(TypeVarchar)}
+	w.TableMap(100, "test", "t", types, []byte{2, 20, 0}, []byte{0x06},
+		TableMetadata{2, []byte{0xfc, 0xff, 0x00}}, // default charset utf8mb4
+		TableMetadata{4, []byte{2, 'i', 'd', 4, 'b', 'o', 'd', 'y', 4, 'n', 'o', 't', 'e'}},
+		TableMetadata{8, []byte{0}}, // primary key: id
+	)
+	// binlog_row_image=NOBLOB: body is not logged
+	w.Event(WRITE_ROWS_EVENTv2, []byte{100, 0, 0, 0, 0, 0, rowsEventStmtEnd, 0, 2, 0, 3, 0x05},
+		[]byte{0, 1, 0, 0, 0, 1, 'x'},
+		[]byte{0, 2, 0, 0, 0, 1, 'y'},
+	)
+	w.XID(10)
+
+	var queries []string
+	d := &fakeDriver{
+		query: func(q string, args []driver.Value) ([]string, [][]driver.Value) {
+			queries = append(queries, q)
+			if args[0] == int64(1) {
+				return []string{"body"}, [][]driver.Value{{[]byte("hello")}}
+			}
+			return []string{"body"}, nil // deleted since
+		},
+	}
+	f := &BlobFetcher{Src: NewReader(bytes.NewReader(w.Bytes())), DB: sql.OpenDB(d)}
+	var rows [][]interface{}
+	for {
+		e, err := f.NextEvent()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		re, ok := e.Data.(RowsEvent)
+		if !ok {
+			continue
+		}
+		if missing := re.Missing(); len(missing) != 0 {
+			t.Fatalf("got missing %v", missing)
+		}
+		if cols := re.Columns(); len(cols) != 3 || cols[1].Name != "body" {
+			t.Fatalf("got columns %v", cols)
+		}
+		for {
+			values, _, err := f.NextRow()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			rows = append(rows, values)
+		}
+	}
+	want := [][]interface{}{{int32(1), "hello", "x"}, {int32(2), nil, "y"}}
+	if !reflect.DeepEqual(rows, want) {
+		t.Fatalf("got %v, want %v", rows, want)
+	}
+	if len(queries) != 2 || queries[0] != "select `body` from `test`.`t` where `id`=?" {
+		t.Fatalf("got queries %q", queries)
+	}
+
+	// without BlobFetcher, body is reported missing
+	bl := NewReader(bytes.NewReader(w.Bytes()))
+	for {
+		e, err := bl.NextEvent()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if re, ok := e.Data.(RowsEvent); ok {
+			if missing := re.Missing(); len(missing) != 1 || missing[0].Name != "body" {
+				t.Fatalf("got missing %v", missing)
+			}
+			break
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package binlog
+
+// options holds decodeOptions of Remote, Local and Reader, which embed
+// it for the setters shared by them.
+type options struct {
+	opts decodeOptions
+}
+
+// UseJSONNumber causes numbers in JSON column values to be decoded
+// as json.Number instead of int/float64/Decimal, preserving their
+// exact textual representation.
+func (o *options) UseJSONNumber() {
+	o.opts.jsonNumber = true
+}
+
+// SetLargeTxConfig configures detection of large transactions.
+// Pass nil to disable it.
+func (o *options) SetLargeTxConfig(c *LargeTxConfig) {
+	o.opts.largeTx = c
+}
+
+// SetTableRenameRules configures rules to rename tables
+// in TableMapEvent. see OSCRenameRules.
+func (o *options) SetTableRenameRules(rules []TableRenameRule) {
+	o.opts.renames = rules
+}
+
+// SetGeneratedColumns declares generated columns of tables, to be
+// marked as Column.Generated in TableMapEvent.
+func (o *options) SetGeneratedColumns(cols GeneratedColumns) {
+	o.opts.generated = cols
+}
+
+// SetCoalesceRows enables coalescing of RowsEvents that belong to
+// single statement, into StatementEvent. see StatementEvent.
+func (o *options) SetCoalesceRows(coalesce bool) {
+	o.opts.coalesceRows = coalesce
+}
+
+// SetBoundaryEvents enables synthetic events BeginTransactionEvent,
+// EndTransactionEvent, EndStatementEvent, XATransactionEvent and
+// SavepointEvent, which mark transaction and statement boundaries in
+// the event stream.
+func (o *options) SetBoundaryEvents(enable bool) {
+	o.opts.boundaryEvents = enable
+}
+
+// SetStatementMode enables SQLStatementEvent, which is returned after
+// each QueryEvent. This is useful to consume binlogs in STATEMENT or
+// MIXED format. see SQLStatementEvent.
+func (o *options) SetStatementMode(enable bool) {
+	o.opts.statementMode = enable
+}
+
+// SetStrictRowFormat makes NextEvent to return *StatementChangeError,
+// when a data change is logged as statement instead of row images, as
+// in MIXED binlog_format. Otherwise such changes are only counted in
+// TxStats.StatementChanges.
+func (o *options) SetStrictRowFormat(strict bool) {
+	o.opts.strictRowFormat = strict
+}
+
+// SetInvalidTimePolicy sets how zero dates such as '0000-00-00' are
+// decoded. Default is InvalidTimeNormalize.
+func (o *options) SetInvalidTimePolicy(p InvalidTimePolicy) {
+	o.opts.invalidTime = p
+}
+
+// SetIsolateColumnErrors makes NextRow return *ColumnDecodeError as
+// value of a column that fails to decode, such as due to bad metadata,
+// instead of failing the row. If size of the value is unknown, the row
+// still fails, as remaining columns cannot be located.
+func (o *options) SetIsolateColumnErrors(enable bool) {
+	o.opts.isolateColumnErrors = enable
+}
+
+// SetChecksumPolicy sets how checksums of events are handled. Default
+// is ChecksumVerify. must be called before Seek, or before first
+// NextEvent of Reader.
+func (o *options) SetChecksumPolicy(p ChecksumPolicy) {
+	o.opts.checksumPolicy = p
+}
+
+// SetSampling configures rules to sample rows of tables. Pass nil
+// to disable sampling. see SamplingRule.
+func (o *options) SetSampling(rules []SamplingRule) {
+	o.opts.sampling = newSampler(rules)
+}
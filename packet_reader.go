@@ -9,6 +9,7 @@ type packetReader struct {
 	seq  *uint8
 	last bool
 	size int
+	err  error // error in reading from rd
 }
 
 func (r *packetReader) Read(p []byte) (int, error) {
@@ -20,8 +21,9 @@ func (r *packetReader) Read(p []byte) (int, error) {
 		_, err := io.ReadFull(r.rd, h)
 		if err != nil {
 			if err == io.EOF {
-				return 0, io.ErrUnexpectedEOF
+				err = io.ErrUnexpectedEOF
 			}
+			r.err = err
 			return 0, err
 		}
 		r.size = int(uint32(h[0]) | uint32(h[1])<<8 | uint32(h[2])<<16)
@@ -39,7 +41,10 @@ func (r *packetReader) Read(p []byte) (int, error) {
 		return n, nil
 	}
 	if err == io.EOF {
-		return 0, io.ErrUnexpectedEOF
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		r.err = err
 	}
 	return 0, err
 }
@@ -5,6 +5,7 @@ import (
 	"io"
 	"io/ioutil"
 	"testing"
+	"testing/iotest"
 )
 
 func TestPacketReader_LessThanMaxPacketSize(t *testing.T) {
@@ -120,3 +121,23 @@ func newPacketData(data []byte) []byte {
 	b[headerSize+len(data)+headerSize-1] = 1
 	return b
 }
+
+// packets may be coalesced or split arbitrarily by network or proxies.
+func TestPacketReader_coalesced(t *testing.T) {
+	data := []byte{3, 0, 0, 0, 'a', 'b', 'c', 2, 0, 0, 1, 'd', 'e'}
+	for _, rd := range []io.Reader{bytes.NewReader(data), iotest.OneByteReader(bytes.NewReader(data))} {
+		var seq uint8
+		for _, want := range []string{"abc", "de"} {
+			got, err := ioutil.ReadAll(&packetReader{rd: rd, seq: &seq})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != want {
+				t.Fatalf("got %q, want %q", got, want)
+			}
+		}
+		if seq != 2 {
+			t.Fatal("got seq", seq, "want 2")
+		}
+	}
+}
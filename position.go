@@ -0,0 +1,75 @@
+package binlog
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// PositionError is returned by ValidatePosition, if position is not
+// valid in binary log file on the server.
+type PositionError struct {
+	File string
+	Pos  int64
+	Size int64 // of file. -1 if file is not on server
+	Err  error // from server, if position is not at start of an event
+}
+
+func (e *PositionError) Error() string {
+	switch {
+	case e.Size < 0:
+		return fmt.Sprintf("binlog: binary log %s not found on server", e.File)
+	case e.Pos > math.MaxUint32:
+		return fmt.Sprintf("binlog: position %d of %s exceeds 4GB", e.Pos, e.File)
+	case e.Pos > e.Size:
+		return fmt.Sprintf("binlog: position %d beyond size %d of %s", e.Pos, e.Size, e.File)
+	case e.Pos < 4:
+		return fmt.Sprintf("binlog: position %d of %s is before first event", e.Pos, e.File)
+	}
+	return fmt.Sprintf("binlog: position %d of %s is not at start of event: %v", e.Pos, e.File, e.Err)
+}
+
+// ValidatePosition checks that pos is at start of an event in the
+// binary log file on the server, or at end of the file, using sizes
+// from `SHOW BINARY LOGS` and `SHOW BINLOG EVENTS`. It returns
+// *PositionError if it is not, rather than server failing Seek
+// with an opaque error, or streaming from middle of an event.
+//
+// pos is int64, as positions from other sources may not fit in
+// binlog dump request, which is limited to 4GB.
+func (bl *Remote) ValidatePosition(file string, pos int64) error {
+	rows, err := bl.queryRows(`show binary logs`)
+	if err != nil {
+		return err
+	}
+	perr := &PositionError{File: file, Pos: pos, Size: -1}
+	for _, row := range rows {
+		if name, _ := row[0].(string); name == file {
+			if perr.Size, err = strconv.ParseInt(fmt.Sprint(row[1]), 10, 64); err != nil {
+				return fmt.Errorf("binlog: invalid size of binary log %s: %v", name, err)
+			}
+			break
+		}
+	}
+	if perr.Size < 0 || pos > math.MaxUint32 || pos > perr.Size || pos < 4 {
+		return perr
+	}
+	if pos == 4 || pos == perr.Size {
+		return nil
+	}
+	_, err = bl.queryRows(fmt.Sprintf("show binlog events in %s from %d limit 1", sqlQuote(file), pos))
+	if err != nil {
+		if _, ok := err.(*PermissionError); ok {
+			return err
+		}
+		perr.Err = err
+		return perr
+	}
+	return nil
+}
+
+// SetSeekValidation makes Seek call ValidatePosition, before requesting
+// binlog dump. This costs queries on each Seek, so it is off by default.
+func (bl *Remote) SetSeekValidation(enable bool) {
+	bl.validateSeek = enable
+}
@@ -0,0 +1,50 @@
+package binlog
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRemote_ValidatePosition(t *testing.T) {
+	s := newFakeServer()
+	f := newBinlogStream()
+	f.XID(7)
+	s.addFile("binlog.000002", f)
+	bl, err := s.dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bl.Close()
+
+	size := int64(f.Len())
+	s.queries["show binlog events in 'binlog.000002' from 10 limit 1"] = [][]string{{"Log_name"}}
+	tests := []struct {
+		file  string
+		pos   int64
+		valid bool
+	}{
+		{"binlog.000002", 4, true},
+		{"binlog.000002", 10, true},
+		{"binlog.000002", size, true},
+		{"binlog.000002", 11, false}, // mid-event
+		{"binlog.000002", size + 1, false},
+		{"binlog.000002", 1 << 32, false},
+		{"binlog.000002", 2, false},
+		{"binlog.000001", 4, false},
+	}
+	for _, test := range tests {
+		err := bl.ValidatePosition(test.file, test.pos)
+		var perr *PositionError
+		if test.valid && err != nil {
+			t.Errorf("%s:%d: got %v", test.file, test.pos, err)
+		} else if !test.valid && !errors.As(err, &perr) {
+			t.Errorf("%s:%d: got %v, want *PositionError", test.file, test.pos, err)
+		}
+	}
+
+	bl.SetSeekValidation(true)
+	var perr *PositionError
+	if err := bl.Seek(0, "binlog.000001", 4); !errors.As(err, &perr) || perr.Size != -1 {
+		t.Fatalf("got %v, want *PositionError", err)
+	}
+}
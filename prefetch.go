@@ -0,0 +1,94 @@
+package binlog
+
+import (
+	"io"
+)
+
+// SetPrefetch enables reading of binlog stream ahead of NextEvent, by
+// a separate goroutine, buffering up to n packets. This decouples
+// network latency from processing time of events, smoothing throughput
+// on high latency links. Each buffered packet holds one event, so the
+// memory used is n times the size of large events. Zero n disables it.
+//
+// Prefetching starts with Seek and stops at the end of binlog stream.
+// Must be called before Seek.
+func (bl *Remote) SetPrefetch(n int) {
+	bl.prefetchSize = n
+}
+
+// prefetcher reads packets from rd ahead, till binlog stream ends.
+type prefetcher struct {
+	ch   chan []byte // packets including header
+	err  error       // read error, set before closing ch
+	buf  []byte      // unread bytes of current packet
+	done chan struct{}
+}
+
+func startPrefetch(rd io.Reader, n int) *prefetcher {
+	p := &prefetcher{
+		ch:   make(chan []byte, n),
+		done: make(chan struct{}),
+	}
+	go p.run(rd)
+	return p
+}
+
+func (p *prefetcher) run(rd io.Reader) {
+	defer close(p.ch)
+	cont := false // whether next packet continues current payload
+	for {
+		h := make([]byte, headerSize)
+		if _, err := io.ReadFull(rd, h); err != nil {
+			p.err = err
+			return
+		}
+		size := int(uint32(h[0]) | uint32(h[1])<<8 | uint32(h[2])<<16)
+		pkt := make([]byte, headerSize+size)
+		copy(pkt, h)
+		if _, err := io.ReadFull(rd, pkt[headerSize:]); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			p.err = err
+			return
+		}
+		select {
+		case p.ch <- pkt:
+		case <-p.done:
+			return
+		}
+		// stop after eof or err packet, which ends binlog stream.
+		if !cont && size > 0 {
+			switch pkt[headerSize] {
+			case errMarker:
+				return
+			case eofMarker:
+				if size < 9 {
+					return
+				}
+			}
+		}
+		cont = size == maxPacketSize
+	}
+}
+
+func (p *prefetcher) Read(b []byte) (int, error) {
+	if len(p.buf) == 0 {
+		pkt, ok := <-p.ch
+		if !ok {
+			if p.err == nil {
+				return 0, io.EOF
+			}
+			return 0, p.err
+		}
+		p.buf = pkt
+	}
+	n := copy(b, p.buf)
+	p.buf = p.buf[n:]
+	return n, nil
+}
+
+// stop stops the goroutine, if it is blocked on sending packet.
+func (p *prefetcher) stop() {
+	close(p.done)
+}
@@ -0,0 +1,112 @@
+package binlog
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// PermissionError is returned when server denies a query or command,
+// because the user lacks the required privilege.
+//
+// Privileges required:
+//
+//	Seek, NextEvent, Dump      REPLICATION SLAVE
+//	ListFiles, MasterStatus    REPLICATION CLIENT
+//	EnsureFullRowMetadata      SYSTEM_VARIABLES_ADMIN or SUPER, only to change it
+//	EnsureRDSBinlogRetention   EXECUTE on mysql.rds_set_configuration, only to change it
+//
+// Other methods need no privileges.
+type PermissionError struct {
+	Query   string // query or command denied
+	Code    uint16 // mysql error code
+	Message string // error message from server
+}
+
+func (e *PermissionError) Error() string {
+	return fmt.Sprintf("binlog: permission denied for %s: %s", e.Query, e.Message)
+}
+
+// mysql error codes for access denied.
+const (
+	erDBAccessDenied       = 1044
+	erTableAccessDenied    = 1142
+	erColumnAccessDenied   = 1143
+	erSpecificAccessDenied = 1227
+	erProcAccessDenied     = 1370
+)
+
+// error returns error for the errPacket received for query.
+func (e errPacket) error(query string) error {
+	switch e.errorCode {
+	case erDBAccessDenied, erTableAccessDenied, erColumnAccessDenied, erSpecificAccessDenied, erProcAccessDenied:
+		return &PermissionError{Query: query, Code: e.errorCode, Message: e.errorMessage}
+	}
+	return errors.New(e.errorMessage)
+}
+
+// SetMinimalPrivileges restricts this connection to statements which
+// need no privileges other than REPLICATION SLAVE and REPLICATION CLIENT.
+// Optional statements needing more privileges are not attempted:
+//
+//   - failure of querying server version in Authenticate is ignored.
+//   - EnsureFullRowMetadata does not try to change binlog_row_metadata.
+//   - EnsureRDSBinlogRetention does not try to extend retention.
+func (bl *Remote) SetMinimalPrivileges(enable bool) {
+	bl.minimalPrivileges = enable
+}
+
+// GlobalPrivileges returns the global privileges of current user, as
+// reported by `SHOW GRANTS`, which needs no privileges. Privileges on
+// specific schemas or tables and granted roles are not included.
+func (bl *Remote) GlobalPrivileges() ([]string, error) {
+	rows, err := bl.queryRows(`show grants`)
+	if err != nil {
+		return nil, err
+	}
+	var privs []string
+	for _, row := range rows {
+		grant, _ := row[0].(string)
+		privs = append(privs, parseGlobalGrant(grant)...)
+	}
+	return privs, nil
+}
+
+// parseGlobalGrant returns privileges in grant statement of form:
+//
+//	GRANT REPLICATION SLAVE, REPLICATION CLIENT ON *.* TO `repl`@`%`
+//
+// returns nil if it is not on *.*.
+func parseGlobalGrant(grant string) []string {
+	if !strings.HasPrefix(grant, "GRANT ") {
+		return nil
+	}
+	i := strings.Index(grant, " ON ")
+	if i == -1 || !strings.HasPrefix(grant[i+len(" ON "):], "*.* ") {
+		return nil
+	}
+	privs := strings.Split(grant[len("GRANT "):i], ",")
+	for i := range privs {
+		privs[i] = strings.TrimSpace(privs[i])
+	}
+	return privs
+}
+
+// hasPrivilege tells whether current user has any of given global privileges.
+func (bl *Remote) hasPrivilege(privs ...string) (bool, error) {
+	granted, err := bl.GlobalPrivileges()
+	if err != nil {
+		return false, err
+	}
+	for _, g := range granted {
+		if g == "ALL PRIVILEGES" {
+			return true, nil
+		}
+		for _, p := range privs {
+			if g == p {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,48 @@
+package binlog
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseGlobalGrant(t *testing.T) {
+	tests := []struct {
+		grant string
+		want  []string
+	}{
+		{"GRANT REPLICATION SLAVE, REPLICATION CLIENT ON *.* TO `repl`@`%`", []string{"REPLICATION SLAVE", "REPLICATION CLIENT"}},
+		{"GRANT SYSTEM_VARIABLES_ADMIN ON *.* TO `repl`@`%`", []string{"SYSTEM_VARIABLES_ADMIN"}},
+		{"GRANT SELECT ON `test`.* TO `repl`@`%`", nil},
+		{"GRANT `reader`@`%` TO `repl`@`%`", nil},
+	}
+	for _, test := range tests {
+		if got := parseGlobalGrant(test.grant); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %q, want %q", test.grant, got, test.want)
+		}
+	}
+}
+
+func TestPermissionError(t *testing.T) {
+	s := newFakeServer()
+	s.denied["show master status"] = true
+	s.queries["show grants"] = [][]string{{"Grants for repl@%"}, {"GRANT REPLICATION SLAVE ON *.* TO `repl`@`%`"}}
+	s.queries["show global variables like 'binlog_row_metadata'"] = [][]string{{"Variable_name", "Value"}, {"binlog_row_metadata", "MINIMAL"}}
+	bl, err := s.dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bl.Close()
+
+	_, _, err = bl.MasterStatus()
+	var perr *PermissionError
+	if !errors.As(err, &perr) || perr.Query != "show master status" || perr.Code != erSpecificAccessDenied {
+		t.Fatalf("got %#v, want *PermissionError", err)
+	}
+
+	err = bl.EnsureFullRowMetadata(true)
+	var rerr *RowMetadataError
+	if !errors.As(err, &rerr) || !errors.As(rerr.Err, &perr) {
+		t.Fatalf("got %#v, want *RowMetadataError with *PermissionError", err)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris && !windows
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris,!windows
+
+package binlog
+
+// processAlive tells whether process with given pid is running. It is
+// not known on this platform, so stale locks are never removed.
+func processAlive(pid int) bool {
+	return true
+}
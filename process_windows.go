@@ -0,0 +1,13 @@
+package binlog
+
+import "os"
+
+// processAlive tells whether process with given pid is running.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = p.Release()
+	return true
+}
@@ -0,0 +1,48 @@
+package binlog
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+// proxyHeader returns PROXY protocol header of given version, carrying
+// src and dst addresses of client connection. Addresses other than tcp
+// are sent as UNKNOWN in version 1, and LOCAL command in version 2.
+//
+// https://www.haproxy.org/download/2.4/doc/proxy-protocol.txt
+func proxyHeader(version int, src, dst net.Addr) ([]byte, error) {
+	s, sok := src.(*net.TCPAddr)
+	d, dok := dst.(*net.TCPAddr)
+	tcp4 := sok && dok && s.IP.To4() != nil && d.IP.To4() != nil
+	switch version {
+	case 1:
+		switch {
+		case !sok || !dok:
+			return []byte("PROXY UNKNOWN\r\n"), nil
+		case tcp4:
+			return []byte(fmt.Sprintf("PROXY TCP4 %s %s %d %d\r\n", s.IP.To4(), d.IP.To4(), s.Port, d.Port)), nil
+		default:
+			return []byte(fmt.Sprintf("PROXY TCP6 %s %s %d %d\r\n", s.IP.To16(), d.IP.To16(), s.Port, d.Port)), nil
+		}
+	case 2:
+		b := []byte("\r\n\r\n\x00\r\nQUIT\n")
+		switch {
+		case !sok || !dok:
+			return append(b, 0x20, 0x00, 0, 0), nil // LOCAL, UNSPEC
+		case tcp4:
+			b = append(b, 0x21, 0x11, 0, 12) // PROXY, TCP over IPv4
+			b = append(b, s.IP.To4()...)
+			b = append(b, d.IP.To4()...)
+		default:
+			b = append(b, 0x21, 0x21, 0, 36) // PROXY, TCP over IPv6
+			b = append(b, s.IP.To16()...)
+			b = append(b, d.IP.To16()...)
+		}
+		b = append(b, 0, 0, 0, 0)
+		binary.BigEndian.PutUint16(b[len(b)-4:], uint16(s.Port))
+		binary.BigEndian.PutUint16(b[len(b)-2:], uint16(d.Port))
+		return b, nil
+	}
+	return nil, fmt.Errorf("binlog: invalid proxy protocol version %d", version)
+}
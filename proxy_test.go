@@ -0,0 +1,40 @@
+package binlog
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+func TestProxyHeader(t *testing.T) {
+	src4 := &net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: 56324}
+	dst4 := &net.TCPAddr{IP: net.ParseIP("192.168.0.11"), Port: 3306}
+	src6 := &net.TCPAddr{IP: net.ParseIP("::1"), Port: 56324}
+	dst6 := &net.TCPAddr{IP: net.ParseIP("::1"), Port: 3306}
+	unix := &net.UnixAddr{Name: "/tmp/mysql.sock", Net: "unix"}
+	sig := "\r\n\r\n\x00\r\nQUIT\n"
+	tests := []struct {
+		version  int
+		src, dst net.Addr
+		want     string
+	}{
+		{1, src4, dst4, "PROXY TCP4 192.168.0.1 192.168.0.11 56324 3306\r\n"},
+		{1, src6, dst6, "PROXY TCP6 ::1 ::1 56324 3306\r\n"},
+		{1, unix, unix, "PROXY UNKNOWN\r\n"},
+		{2, src4, dst4, sig + "\x21\x11\x00\x0c\xc0\xa8\x00\x01\xc0\xa8\x00\x0b\xdc\x04\x0c\xea"},
+		{2, src6, dst6, sig + "\x21\x21\x00\x24" + string(net.ParseIP("::1")) + string(net.ParseIP("::1")) + "\xdc\x04\x0c\xea"},
+		{2, unix, unix, sig + "\x20\x00\x00\x00"},
+	}
+	for _, tc := range tests {
+		got, err := proxyHeader(tc.version, tc.src, tc.dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, []byte(tc.want)) {
+			t.Errorf("v%d %v: got %q, want %q", tc.version, tc.src, got, tc.want)
+		}
+	}
+	if _, err := proxyHeader(3, src4, dst4); err == nil {
+		t.Fatal("error expected for version 3")
+	}
+}
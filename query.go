@@ -12,8 +12,15 @@ import (
 // https://dev.mysql.com/doc/internals/en/com-query-response.html
 type queryResponse interface{}
 
-func (bl *Remote) queryRows(q string) ([][]interface{}, error) {
-	resp, err := bl.query(q)
+func (bl *Remote) queryRows(q string) (rows [][]interface{}, err error) {
+	if bl.isStreaming() && bl.controlEnabled {
+		err = bl.controlDo(func(c *Remote) error {
+			rows, err = c.queryRows(q)
+			return err
+		})
+		return rows, err
+	}
+	resp, err := bl.sessionQuery(q)
 	if err != nil {
 		return nil, err
 	}
@@ -21,13 +28,36 @@ func (bl *Remote) queryRows(q string) ([][]interface{}, error) {
 	return rs.rows()
 }
 
-func (bl *Remote) query(q string) (queryResponse, error) {
+// query runs q, over control connection if streaming. Rows of its
+// result are discarded then. see SetControlConn.
+func (bl *Remote) query(q string) (resp queryResponse, err error) {
+	if bl.isStreaming() && bl.controlEnabled {
+		err = bl.controlDo(func(c *Remote) error {
+			if resp, err = c.query(q); err != nil {
+				return err
+			}
+			if rs, ok := resp.(*resultSet); ok {
+				_, err = rs.rows()
+			}
+			return err
+		})
+		return resp, err
+	}
+	return bl.sessionQuery(q)
+}
+
+// sessionQuery runs q on this connection, such as statement setting
+// session variable, which must not be sent over control connection.
+func (bl *Remote) sessionQuery(q string) (queryResponse, error) {
+	if bl.isStreaming() {
+		return nil, ErrStreaming
+	}
 	bl.seq = 0
-	w := newWriter(bl.conn, &bl.seq)
+	w := newWriter(bl.rw(), &bl.seq)
 	if err := w.query(q); err != nil {
 		return nil, err
 	}
-	r := newReader(bl.conn, &bl.seq)
+	r := newReader(bl.rw(), &bl.seq)
 	b, err := r.peek()
 	if err != nil {
 		return nil, err
@@ -44,7 +74,7 @@ func (bl *Remote) query(q string) (queryResponse, error) {
 		if err := ep.decode(r, bl.hs.capabilityFlags); err != nil {
 			return nil, err
 		}
-		return nil, errors.New(ep.errorMessage)
+		return nil, ep.error(q)
 	default:
 		rs := resultSet{}
 		if err := rs.decode(r, bl.hs.capabilityFlags); err != nil {
@@ -92,12 +122,13 @@ func (cd *columnDef) decode(r *reader, capabilities uint32) error {
 
 // resultSet made up of two parts.
 // 1. column definitions
-//    - starts with a packet containing the column-count
-//    - followed by as many columnDef packets as there are columns
-//    - terminated by eofPacket, if the capDeprecateEOF is not set
+//   - starts with a packet containing the column-count
+//   - followed by as many columnDef packets as there are columns
+//   - terminated by eofPacket, if the capDeprecateEOF is not set
+//
 // 2. rows
-//    - each row is a packet
-//    - terminated by eofPacket or errPacket
+//   - each row is a packet
+//   - terminated by eofPacket or errPacket
 //
 // https://dev.mysql.com/doc/internals/en/com-query-response.html#text-resultset
 type resultSet struct {
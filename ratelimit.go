@@ -0,0 +1,57 @@
+package binlog
+
+import (
+	"io"
+	"time"
+)
+
+// rateLimiter is token bucket of bytes, refilled at rate bytes per
+// second, up to burst bytes.
+type rateLimiter struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	clock  Clock
+}
+
+func newRateLimiter(rate, burst int, clock Clock) *rateLimiter {
+	if burst <= 0 {
+		burst = rate
+	}
+	return &rateLimiter{rate: float64(rate), burst: float64(burst), tokens: float64(burst), clock: clock}
+}
+
+// wait takes n tokens, sleeping until the bucket is no longer in debt.
+func (l *rateLimiter) wait(n int) {
+	now := l.clock.Now()
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	if l.tokens < 0 {
+		l.clock.Sleep(time.Duration(-l.tokens / l.rate * float64(time.Second)))
+		l.tokens, l.last = 0, l.clock.Now()
+	}
+}
+
+// limitedReader is io.Reader, whose reads are limited by rateLimiter.
+type limitedReader struct {
+	r io.Reader
+	l *rateLimiter
+}
+
+func (r limitedReader) Read(p []byte) (int, error) {
+	if len(p) > int(r.l.burst) {
+		p = p[:int(r.l.burst)]
+	}
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.l.wait(n)
+	}
+	return n, err
+}
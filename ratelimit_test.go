@@ -0,0 +1,86 @@
+package binlog
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := newRateLimiter(1000, 500, clock)
+	l.wait(500) // burst
+	if len(clock.sleeps) != 0 {
+		t.Fatalf("slept %v within burst", clock.sleeps)
+	}
+	l.wait(250)
+	l.wait(1000)
+	if got, want := clock.sleeps, []time.Duration{250 * time.Millisecond, time.Second}; !equalDurations(got, want) {
+		t.Fatalf("got sleeps %v, want %v", got, want)
+	}
+	clock.now = clock.now.Add(time.Hour) // refill is capped by burst
+	clock.sleeps = nil
+	l.wait(600)
+	if got, want := clock.sleeps, []time.Duration{100 * time.Millisecond}; !equalDurations(got, want) {
+		t.Fatalf("got sleeps %v, want %v", got, want)
+	}
+}
+
+func equalDurations(a, b []time.Duration) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if d := a[i] - b[i]; d < -time.Microsecond || d > time.Microsecond {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRemote_SetDumpRateLimit(t *testing.T) {
+	s := newFakeServer()
+	f := newBinlogStream()
+	for i := 0; i < 10; i++ {
+		f.XID(uint64(i))
+	}
+	s.addFile("binlog.000001", f)
+	bl, err := s.dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bl.Close()
+	clock := &fakeClock{now: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}
+	bl.SetClock(clock)
+	bl.SetDumpRateLimit(100, 50)
+	if err := bl.Seek(0, "binlog.000001", 4); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := bl.Dump(dir); err != io.EOF {
+		t.Fatal("got", err, "want", io.EOF)
+	}
+	got, err := ioutil.ReadFile(filepath.Join(dir, "binlog.000001"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, f.Bytes()) {
+		t.Fatal("dumped file does not match")
+	}
+	var slept time.Duration
+	for _, d := range clock.sleeps {
+		slept += d
+	}
+	// at least events, beyond burst, are read at 100 bytes per second
+	if want := time.Duration(len(f.Bytes())-4-50) * 10 * time.Millisecond; slept < want {
+		t.Fatalf("slept %v, want at least %v", slept, want)
+	}
+}
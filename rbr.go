@@ -4,6 +4,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"strconv"
 )
 
 // Column captures column info for TableMapEvent and RowsEvent.
@@ -19,6 +20,10 @@ type Column struct {
 	// system variable binlog_row_metadata==FULL
 	Name   string
 	Values []string // permitted values for Enum and Set type.
+
+	// Generated tells whether column is generated. Populated only for
+	// columns declared by SetGeneratedColumns.
+	Generated bool
 }
 
 // TableMapEvent is first event used in Row Based Replication declares
@@ -40,6 +45,16 @@ type TableMapEvent struct {
 	SchemaName string
 	TableName  string
 	Columns    []Column
+
+	// OrigTableName is the name of table as found in binlog, if TableName
+	// was renamed by TableRenameRule. Otherwise it is empty.
+	OrigTableName string
+
+	// PrimaryKey has ordinals of primary key columns, and PrimaryKeyPrefix
+	// has their prefix lengths, zero meaning whole column. Populated only
+	// if system variable binlog_row_metadata==FULL.
+	PrimaryKey       []int
+	PrimaryKeyPrefix []int
 }
 
 func (e *TableMapEvent) decode(r *reader) error {
@@ -49,6 +64,11 @@ func (e *TableMapEvent) decode(r *reader) error {
 	e.SchemaName = r.stringNull()
 	_ = r.int1() // table name length
 	e.TableName = r.stringNull()
+	if len(r.opts.renames) > 0 {
+		if name := renameTable(r.opts.renames, e.TableName); name != e.TableName {
+			e.OrigTableName, e.TableName = e.TableName, name
+		}
+	}
 	numCol := r.intN()
 	if r.err != nil {
 		return r.err
@@ -140,14 +160,23 @@ func (e *TableMapEvent) decode(r *reader) error {
 			if err := e.decodeCharset(r, size, ColumnType.isEnumSet); err != nil {
 				return err
 			}
+		case 8: // Primary key without prefix
+			if err := e.decodePrimaryKey(r, size, false); err != nil {
+				return err
+			}
+		case 9: // Primary key with prefix
+			if err := e.decodePrimaryKey(r, size, true); err != nil {
+				return err
+			}
 		default:
 			// 7 - Geometry type of geometry columns
-			// 8 - Primary key without prefix
-			// 9 - Primary key with prefix
 			// 12 - Column Visibility
 			r.skip(size)
 		}
 	}
+	if r.err == nil && len(r.opts.generated) > 0 {
+		r.opts.generated.mark(e)
+	}
 
 	return r.err
 }
@@ -199,6 +228,31 @@ func (e *TableMapEvent) decodeCharset(r *reader, size int, f func(ColumnType) bo
 	return nil
 }
 
+func (e *TableMapEvent) decodePrimaryKey(r *reader, size int, withPrefix bool) error {
+	e.PrimaryKey, e.PrimaryKeyPrefix = nil, nil
+	for size > 0 {
+		ord, n := r.intPacked()
+		size -= n
+		var prefix uint64
+		if withPrefix {
+			prefix, n = r.intPacked()
+			size -= n
+		}
+		if r.err != nil {
+			return r.err
+		}
+		if ord >= uint64(len(e.Columns)) {
+			return fmt.Errorf("invalid primaryKey column %d", ord)
+		}
+		e.PrimaryKey = append(e.PrimaryKey, int(ord))
+		e.PrimaryKeyPrefix = append(e.PrimaryKeyPrefix, int(prefix))
+	}
+	if size != 0 {
+		return fmt.Errorf("invalid primaryKey of columns")
+	}
+	return nil
+}
+
 func (e *TableMapEvent) decodeValues(r *reader, size int, typ ColumnType) error {
 	var icol int
 	for size > 0 {
@@ -232,6 +286,11 @@ func (e *TableMapEvent) decodeValues(r *reader, size int, typ ColumnType) error
 	return r.err
 }
 
+// RowsEvent flags
+const (
+	rowsEventStmtEnd = 0x0001 // last event of a statement
+)
+
 // RowsEvent captures changed rows in a table.
 //
 // see https://dev.mysql.com/doc/internals/en/rows-event.html
@@ -306,15 +365,8 @@ func (e *RowsEvent) decode(r *reader, eventType EventType) error {
 }
 
 func nextRow(r *reader) (values []interface{}, valuesBeforeUpdate []interface{}, err error) {
-	if r.tme == nil {
-		// dummy RowsEvent
-		return nil, nil, io.EOF
-	}
-	if !r.more() {
-		if r.err != nil {
-			return nil, nil, r.err
-		}
-		return nil, nil, io.EOF
+	if err := skipUnsampled(r); err != nil {
+		return nil, nil, err
 	}
 	row := make([][]interface{}, 2)
 	n := 1
@@ -323,23 +375,9 @@ func nextRow(r *reader) (values []interface{}, valuesBeforeUpdate []interface{},
 		n = 2
 	}
 	for m := 0; m < n; m++ {
-		nullValue := r.nullBitmap(uint64(len(r.re.columns[m])))
-		if r.err != nil {
-			return nil, nil, r.err
-		}
-		var values []interface{}
-		for i := range r.re.columns[m] {
-			if nullValue.isTrue(i) {
-				values = append(values, nil)
-			} else {
-				v, err := r.tme.Columns[i].decodeValue(r)
-				if err != nil {
-					return nil, nil, err
-				}
-				values = append(values, v)
-			}
+		if row[m], err = decodeRow(r, m, nil); err != nil {
+			return nil, nil, err
 		}
-		row[m] = values
 	}
 	switch r.re.eventType {
 	case UPDATE_ROWS_EVENTv1, UPDATE_ROWS_EVENTv2:
@@ -349,6 +387,134 @@ func nextRow(r *reader) (values []interface{}, valuesBeforeUpdate []interface{},
 	}
 }
 
+// nextRows returns upto n rows. values of all rows share
+// single backing array, to reduce allocations.
+func nextRows(r *reader, n int) (rows [][]interface{}, rowsBeforeUpdate [][]interface{}, err error) {
+	if n <= 0 {
+		return nil, nil, fmt.Errorf("binlog: invalid number of rows %d", n)
+	}
+	if err := rowsRemaining(r); err != nil {
+		return nil, nil, err
+	}
+	update := false
+	switch r.re.eventType {
+	case UPDATE_ROWS_EVENTv1, UPDATE_ROWS_EVENTv2:
+		update = true
+	}
+	numCol := len(r.re.columns[0]) + len(r.re.columns[1])
+	backing := make([]interface{}, 0, n*numCol)
+	rows = make([][]interface{}, 0, n)
+	if update {
+		rowsBeforeUpdate = make([][]interface{}, 0, n)
+	}
+	for len(rows) < n {
+		if err := skipUnsampled(r); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, err
+		}
+		start := len(backing)
+		if backing, err = decodeRow(r, 0, backing); err != nil {
+			return nil, nil, err
+		}
+		row := backing[start:len(backing):len(backing)]
+		if !update {
+			rows = append(rows, row)
+			continue
+		}
+		start = len(backing)
+		if backing, err = decodeRow(r, 1, backing); err != nil {
+			return nil, nil, err
+		}
+		rowsBeforeUpdate = append(rowsBeforeUpdate, row)
+		rows = append(rows, backing[start:len(backing):len(backing)])
+	}
+	if len(rows) == 0 {
+		// all remaining rows are skipped by sampling
+		return nil, nil, io.EOF
+	}
+	return rows, rowsBeforeUpdate, nil
+}
+
+// rowsRemaining returns io.EOF if there are no more rows in current RowsEvent.
+func rowsRemaining(r *reader) error {
+	if r.tme == nil {
+		// dummy RowsEvent
+		return io.EOF
+	}
+	if !r.more() {
+		if r.err != nil {
+			return r.err
+		}
+		return io.EOF
+	}
+	return nil
+}
+
+// decodeRow decodes values of row image m, appending them to values.
+func decodeRow(r *reader, m int, values []interface{}) ([]interface{}, error) {
+	nullValue := r.nullBitmap(uint64(len(r.re.columns[m])))
+	if r.err != nil {
+		return nil, r.err
+	}
+	for i := range r.re.columns[m] {
+		if nullValue.isTrue(i) {
+			values = append(values, nil)
+		} else {
+			col := r.re.columns[m][i]
+			limit := r.limit
+			v, err := col.decodeValue(r)
+			if err != nil {
+				if !r.opts.isolateColumnErrors || r.err != nil || !col.resync(r, limit-r.limit) {
+					return nil, err
+				}
+				v = &ColumnDecodeError{Column: col, Err: err}
+			}
+			values = append(values, v)
+		}
+	}
+	return values, nil
+}
+
+// ColumnDecodeError is the value of a column, which failed to decode,
+// in rows returned by NextRow, when column errors are isolated. see
+// Remote.SetIsolateColumnErrors.
+type ColumnDecodeError struct {
+	Column Column
+	Err    error
+}
+
+func (e *ColumnDecodeError) Error() string {
+	name := e.Column.Name
+	if name == "" {
+		name = "#" + strconv.Itoa(e.Column.Ordinal)
+	}
+	return fmt.Sprintf("binlog: decode of column %s failed: %v", name, e.Err)
+}
+
+// resync skips remaining bytes of value of the column, whose decoding
+// failed after reading n bytes. It returns false if size of value is
+// unknown, in which case rest of the row cannot be decoded.
+func (col Column) resync(r *reader, n int) bool {
+	switch col.Type {
+	case TypeVarchar, TypeString, TypeBlob, TypeGeometry, TypeJSON:
+		// length prefixed values fail only after being read
+		return n > 0
+	}
+	size, ok := col.fixedSize()
+	if !ok || n > size {
+		return false
+	}
+	return r.skip(size-n) == nil
+}
+
+// StmtEnd tells whether this is the last RowsEvent of the statement.
+// A statement's changes may span multiple RowsEvents, as each RowsEvent
+// is limited to binlog_row_event_max_size.
+func (e RowsEvent) StmtEnd() bool {
+	return e.flags&rowsEventStmtEnd != 0
+}
+
 // Columns returns columns info after update
 func (e RowsEvent) Columns() []Column {
 	switch e.eventType {
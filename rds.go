@@ -0,0 +1,134 @@
+package binlog
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RDSBinlogRetention returns 'binlog retention hours' configured on
+// Amazon RDS or Aurora MySQL, using mysql.rds_configuration table.
+// It returns 0 if it is not set, in which case RDS purges binary logs
+// as soon as possible, often before consumers resume after downtime.
+// It fails on servers other than RDS and Aurora.
+func (bl *Remote) RDSBinlogRetention() (hours int, err error) {
+	rows, err := bl.queryRows(`select value from mysql.rds_configuration where name = 'binlog retention hours'`)
+	if err != nil || len(rows) == 0 || rows[0][0] == nil {
+		return 0, err
+	}
+	v, _ := rows[0][0].(string)
+	if hours, err = strconv.Atoi(v); err != nil {
+		return 0, fmt.Errorf("binlog: invalid binlog retention hours %q", v)
+	}
+	return hours, nil
+}
+
+// RDSRetentionError is returned by EnsureRDSBinlogRetention, if binlog
+// retention hours is less than wanted.
+type RDSRetentionError struct {
+	Hours int   // detected value. zero if not set
+	Want  int   // wanted value
+	Err   error // error in extending it, if any
+}
+
+func (e *RDSRetentionError) Error() string {
+	value := "not set"
+	if e.Hours > 0 {
+		value = strconv.Itoa(e.Hours)
+	}
+	if e.Err != nil {
+		return fmt.Sprintf("binlog: binlog retention hours is %s: set to %d failed: %v", value, e.Want, e.Err)
+	}
+	return fmt.Sprintf("binlog: binlog retention hours is %s, want at least %d", value, e.Want)
+}
+
+// EnsureRDSBinlogRetention returns *RDSRetentionError if binlog
+// retention hours of Amazon RDS or Aurora MySQL is less than hours.
+// If set is true, it tries to extend it, by calling
+// mysql.rds_set_configuration, which needs EXECUTE privilege on it.
+// Lost retention is the most common cause of consumers unable to
+// resume on managed MySQL. see RDSBinlogRetention.
+func (bl *Remote) EnsureRDSBinlogRetention(hours int, set bool) error {
+	v, err := bl.RDSBinlogRetention()
+	if err != nil {
+		return err
+	}
+	if v >= hours {
+		return nil
+	}
+	if !set || bl.minimalPrivileges {
+		return &RDSRetentionError{Hours: v, Want: hours}
+	}
+	if _, err := bl.query(fmt.Sprintf("call mysql.rds_set_configuration('binlog retention hours', %d)", hours)); err != nil {
+		return &RDSRetentionError{Hours: v, Want: hours, Err: err}
+	}
+	return nil
+}
+
+// RDSSigner signs requests with AWS Signature Version 4, to generate
+// IAM authentication tokens without depending on AWS SDK. It can be
+// implemented using v4.Signer.PresignHTTP of AWS SDK, with credentials
+// of an IAM identity having rds-db:connect permission.
+type RDSSigner interface {
+	// PresignHTTP returns URL of r, presigned for given service and
+	// region, valid for expires from signingTime.
+	PresignHTTP(ctx context.Context, r *http.Request, service, region string, expires time.Duration, signingTime time.Time) (string, error)
+}
+
+// RDSAuthToken generates IAM authentication token, to connect as user
+// to Amazon RDS or Aurora at endpoint, given as "host:port". Tokens
+// are valid for 15 minutes, and only for new connections.
+func RDSAuthToken(ctx context.Context, signer RDSSigner, endpoint, region, user string, now time.Time) (string, error) {
+	u := &url.URL{
+		Scheme:   "https",
+		Host:     endpoint,
+		Path:     "/",
+		RawQuery: url.Values{"Action": {"connect"}, "DBUser": {user}}.Encode(),
+	}
+	r, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	signed, err := signer.PresignHTTP(ctx, r, "rds-db", region, 15*time.Minute, now)
+	if err != nil {
+		return "", fmt.Errorf("binlog: rds auth token: %v", err)
+	}
+	return strings.TrimPrefix(signed, "https://"), nil
+}
+
+// RDSCredentials returns CredentialsProvider of IAM authentication
+// tokens, generated by signer for each connection. see RDSAuthToken.
+func RDSCredentials(signer RDSSigner, endpoint, region, user string) CredentialsProvider {
+	return func(ctx context.Context) (string, string, error) {
+		token, err := RDSAuthToken(ctx, signer, endpoint, region, user, SystemClock.Now())
+		return user, token, err
+	}
+}
+
+// AuthenticateRDSIAM authenticates as user, with IAM authentication
+// token generated by signer, instead of password. The token is sent in
+// clear text, as RDS asks for mysql_clear_password, so connection must
+// be upgraded to SSL before calling this. see UpgradeSSL.
+//
+// This works only for Remote created by Dial functions, as token is
+// bound to the address dialed.
+func (bl *Remote) AuthenticateRDSIAM(ctx context.Context, signer RDSSigner, region, user string) error {
+	if _, ok := bl.raw.(*tls.Conn); !ok {
+		return errors.New("binlog: rds iam authentication requires ssl")
+	}
+	if bl.addr == "" {
+		return errors.New("binlog: rds iam authentication requires address dialed")
+	}
+	endpoint := bl.addr
+	if _, _, err := net.SplitHostPort(endpoint); err != nil {
+		endpoint = net.JoinHostPort(endpoint, "3306")
+	}
+	return bl.AuthenticateWith(ctx, RDSCredentials(signer, endpoint, region, user))
+}
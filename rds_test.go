@@ -0,0 +1,79 @@
+package binlog
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRemote_EnsureRDSBinlogRetention(t *testing.T) {
+	const (
+		query = "select value from mysql.rds_configuration where name = 'binlog retention hours'"
+		call  = "call mysql.rds_set_configuration('binlog retention hours', 24)"
+	)
+	s := newFakeServer()
+	s.queries[query] = [][]string{{"value"}}
+	bl, err := s.dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bl.Close()
+
+	var rerr *RDSRetentionError
+	if err := bl.EnsureRDSBinlogRetention(24, false); !errors.As(err, &rerr) || rerr.Hours != 0 {
+		t.Fatalf("got %v, want *RDSRetentionError for unset retention", err)
+	}
+	s.setQuery(query, [][]string{{"value"}, {"12"}})
+	if hours, err := bl.RDSBinlogRetention(); err != nil || hours != 12 {
+		t.Fatal("RDSBinlogRetention:", hours, err)
+	}
+	s.denied[call] = true
+	var perr *PermissionError
+	if err := bl.EnsureRDSBinlogRetention(24, true); !errors.As(err, &rerr) || rerr.Hours != 12 || !errors.As(rerr.Err, &perr) {
+		t.Fatalf("got %v, want *RDSRetentionError with *PermissionError", err)
+	}
+	delete(s.denied, call)
+	if err := bl.EnsureRDSBinlogRetention(24, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.EnsureRDSBinlogRetention(12, false); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type fakeRDSSigner struct{}
+
+func (fakeRDSSigner) PresignHTTP(ctx context.Context, r *http.Request, service, region string, expires time.Duration, signingTime time.Time) (string, error) {
+	q := r.URL.Query()
+	q.Set("X-Amz-Date", signingTime.UTC().Format("20060102T150405Z"))
+	q.Set("X-Amz-Expires", strconv.Itoa(int(expires.Seconds())))
+	q.Set("X-Amz-Credential", "KEY/"+region+"/"+service)
+	u := *r.URL
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+func TestRDSAuthToken(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	got, err := RDSAuthToken(context.Background(), fakeRDSSigner{}, "db.example.com:3306", "us-east-1", "cdc", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "db.example.com:3306/?Action=connect&DBUser=cdc&X-Amz-Credential=KEY%2Fus-east-1%2Frds-db&X-Amz-Date=20210101T000000Z&X-Amz-Expires=900"
+	if got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	s := newFakeServer()
+	bl, err := s.dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bl.Close()
+	if err := bl.AuthenticateRDSIAM(context.Background(), fakeRDSSigner{}, "us-east-1", "cdc"); err == nil {
+		t.Fatal("AuthenticateRDSIAM without ssl: error expected")
+	}
+}
@@ -4,11 +4,13 @@ import (
 	"bytes"
 	"hash"
 	"io"
+	"time"
 )
 
 const (
 	headerSize    = 4
 	maxPacketSize = 1<<24 - 1
+	maxEmptyReads = 100 // consecutive reads returning no data, before io.ErrNoProgress
 )
 
 func newReader(r io.Reader, seq *uint8) *reader {
@@ -16,9 +18,27 @@ func newReader(r io.Reader, seq *uint8) *reader {
 		rd:       &packetReader{rd: r, seq: seq},
 		tmeCache: make(map[uint64]*TableMapEvent),
 		limit:    -1,
+		opts:     &decodeOptions{},
 	}
 }
 
+// decodeOptions controls how events and row values are decoded.
+type decodeOptions struct {
+	jsonNumber          bool           // decode numbers in JSON values as json.Number
+	largeTx             *LargeTxConfig // detect large transactions
+	renames             []TableRenameRule
+	coalesceRows        bool // coalesce RowsEvents of a statement into StatementEvent
+	boundaryEvents      bool // emit synthetic transaction/statement boundary events
+	statementMode       bool // emit SQLStatementEvent after QueryEvent
+	strictRowFormat     bool // fail on data changes logged as statements
+	invalidTime         InvalidTimePolicy
+	isolateColumnErrors bool  // decode values of failing columns as *ColumnDecodeError
+	clock               Clock // nil means SystemClock
+	checksumPolicy      ChecksumPolicy
+	sampling            *sampler // nil means all rows
+	generated           GeneratedColumns
+}
+
 type reader struct {
 	rd    io.Reader
 	err   error
@@ -26,6 +46,8 @@ type reader struct {
 	off   int    // read at &buf[off], write at &buf[len(buf)]
 	limit int
 	hash  hash.Hash32
+	exact bool // if set, never reads beyond the bytes requested
+	want  int  // bytes requested and not yet consumed, in exact mode
 
 	// context for unmarshalers
 	checksum   int // checksum for current event
@@ -35,10 +57,38 @@ type reader struct {
 	tmeCache   map[uint64]*TableMapEvent
 	tme        *TableMapEvent
 	re         RowsEvent
+	opts       *decodeOptions
+	tx         txTracker
+	stmt       stmtContext
+	pending    []Event // synthetic events to be returned before next event
+	source     *SourceInfo
+	received   time.Time // when header of current event is received
+
+	// checksum recovery, see checkEvent
+	scan      bool             // scan for next plausible event after corrupt event
+	verified  bool             // checksum of current event is verified
+	corrupt   *CorruptionEvent // of current event, if verified and corrupt
+	skipAfter int              // bytes to skip after current event
+}
+
+// nextPending pops next synthetic event if any.
+func (r *reader) nextPending() (Event, bool) {
+	if r == nil || len(r.pending) == 0 {
+		return Event{}, false
+	}
+	e := r.pending[0]
+	r.pending = r.pending[1:]
+	return e, true
 }
 
 func (r *reader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
 	if len(r.buffer()) == 0 {
+		if r.exact {
+			r.want = len(p)
+		}
 		if err := r.readMore(); err != nil {
 			return 0, err
 		}
@@ -67,7 +117,26 @@ func (r *reader) readMore() error {
 			r.off = 0
 		}
 	}
-	n, err := r.rd.Read(r.buf[len(r.buf):cap(r.buf)])
+	p := r.buf[len(r.buf):cap(r.buf)]
+	if r.exact {
+		if r.want <= len(r.buffer()) {
+			// nothing requested beyond buffer
+			r.err = io.ErrNoProgress
+			return r.err
+		}
+		if r.want-len(r.buffer()) < len(p) {
+			p = p[:r.want-len(r.buffer())]
+		}
+	}
+	var n int
+	var err error
+	for i := 0; n == 0 && err == nil; i++ {
+		if i == maxEmptyReads {
+			err = io.ErrNoProgress
+			break
+		}
+		n, err = r.rd.Read(p)
+	}
 	r.buf = r.buf[:len(r.buf)+n]
 	if err == io.EOF {
 		return io.EOF
@@ -85,6 +154,7 @@ func (r *reader) buffer() []byte {
 }
 
 func (r *reader) ensure(n int) error {
+	r.want = n
 	if r.limit >= 0 && n > r.limit {
 		r.err = io.ErrUnexpectedEOF
 		return r.err
@@ -113,6 +183,9 @@ func (r *reader) skip(n int) error {
 		r.err = io.ErrUnexpectedEOF
 		return r.err
 	}
+	if r.exact && n > r.want {
+		r.want = n
+	}
 	for n > 0 {
 		if len(r.buffer()) == 0 {
 			if r.readMore() == io.EOF {
@@ -131,6 +204,9 @@ func (r *reader) skip(n int) error {
 		}
 		r.off += m
 		n -= m
+		if r.exact {
+			r.want -= m
+		}
 		if r.limit >= 0 {
 			r.limit -= m
 		}
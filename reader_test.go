@@ -31,3 +31,30 @@ func TestReader_stringNull(t *testing.T) {
 		t.Fatal("got", r.err, "want", io.ErrUnexpectedEOF)
 	}
 }
+
+type emptyReader struct{}
+
+func (emptyReader) Read(p []byte) (int, error) { return 0, nil }
+
+func TestReader_exact(t *testing.T) {
+	r := &reader{rd: bytes.NewReader([]byte{1, 2, 3, 4}), limit: -1, exact: true}
+	if err := r.skip(1); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 2)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf, []byte{2, 3}) {
+		t.Fatalf("got %v, want [2 3]", buf)
+	}
+	if len(r.buf) != 3 {
+		t.Fatalf("read %d bytes, want 3", len(r.buf))
+	}
+
+	// must not loop forever on empty reads
+	r = &reader{rd: emptyReader{}, limit: -1, exact: true}
+	if err := r.ensure(1); err != io.ErrNoProgress {
+		t.Fatal("got", err, "want", io.ErrNoProgress)
+	}
+}
@@ -0,0 +1,199 @@
+package binlog
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"regexp"
+)
+
+// DumpRedaction describes data removed by Dump, so that dumped binlogs
+// can be shared without leaking data. Sizes, positions and checksums of
+// events are rewritten, so that dumped files remain valid. ROWS_QUERY_EVENT
+// and MariaDB ANNOTATE_ROWS_EVENT, which have original query of rows
+// events, are removed if Tables or Query is set.
+// see SetDumpRedaction.
+type DumpRedaction struct {
+	// Tables matches "schema.table" of tables, whose TableMapEvent and
+	// RowsEvent are removed. If the last RowsEvent of a statement is
+	// removed, its end of statement flag is moved to the last RowsEvent
+	// of the statement kept.
+	Tables *regexp.Regexp
+
+	// Query, if non-nil, returns replacement of text of QueryEvent, such
+	// as "/* redacted */" or query with literals masked. Queries beginning
+	// and ending transactions are not replaced.
+	Query func(schema, query string) string
+}
+
+// SetDumpRedaction sets data removed by Dump. nil disables redaction,
+// which is the default. Events of existing dump files are not redacted
+// by ResumeDump, so redaction must not change across resumes.
+func (bl *Remote) SetDumpRedaction(r *DumpRedaction) {
+	bl.dumpRedaction = r
+}
+
+// annotateRowsEvent is MariaDB ANNOTATE_ROWS_EVENT, which has query of
+// following rows events.
+const annotateRowsEvent EventType = 0xa0
+
+// redactor redacts events of a dump, as per DumpRedaction.
+type redactor struct {
+	*DumpRedaction
+	tables map[uint64]bool // table ids of redacted tables
+
+	// last rows event kept in current statement, as written to file,
+	// to move end of statement flag of removed rows event onto it.
+	rows     []byte
+	rowsPos  uint32 // position of rows in file
+	checksum int
+}
+
+func newRedactor(r *DumpRedaction) *redactor {
+	if r == nil {
+		return nil
+	}
+	return &redactor{DumpRedaction: r, tables: make(map[uint64]bool)}
+}
+
+// inspects tells whether events of type t may be redacted.
+func (r *redactor) inspects(t EventType) bool {
+	switch {
+	case r == nil:
+		return false
+	case t == TABLE_MAP_EVENT || t.IsWriteRows() || t.IsUpdateRows() || t.IsDeleteRows():
+		return r.Tables != nil
+	case t == ROWS_QUERY_EVENT || t == annotateRowsEvent:
+		return r.Tables != nil || r.Query != nil
+	case t == QUERY_EVENT:
+		return r.Query != nil
+	}
+	return false
+}
+
+// redact returns event e, with v4 header, to be written at position pos
+// of file, after redaction. It returns nil if e is to be removed. Size,
+// position and checksum in header of returned event are not updated.
+func (r *redactor) redact(e []byte, checksum int, pos uint32) ([]byte, error) {
+	if checksum < 0 {
+		checksum = 0
+	}
+	r.checksum = checksum
+	if len(e) < 19+checksum {
+		return nil, fmt.Errorf("binlog: invalid event size %d", len(e))
+	}
+	eventType := EventType(e[4])
+	body := e[19 : len(e)-checksum]
+	switch {
+	case eventType == ROWS_QUERY_EVENT || eventType == annotateRowsEvent:
+		return nil, nil
+	case eventType == QUERY_EVENT:
+		return r.redactQuery(e, body, checksum)
+	case eventType == TABLE_MAP_EVENT:
+		schema, table, ok := tableMapNames(body)
+		if !ok {
+			return nil, fmt.Errorf("binlog: invalid %s", eventType)
+		}
+		drop := r.Tables.MatchString(schema + "." + table)
+		r.tables[uint48(body)] = drop
+		if drop {
+			return nil, nil
+		}
+	default: // rows event
+		// post-header: table id 6, flags 2
+		if len(body) < 8 {
+			return nil, fmt.Errorf("binlog: invalid %s", eventType)
+		}
+		stmtEnd := binary.LittleEndian.Uint16(body[6:])&rowsEventStmtEnd != 0
+		if r.tables[uint48(body)] {
+			if !stmtEnd {
+				return nil, nil
+			}
+			if r.rows != nil {
+				flags := r.rows[19+6:]
+				binary.LittleEndian.PutUint16(flags, binary.LittleEndian.Uint16(flags)|rowsEventStmtEnd)
+			}
+			return nil, nil
+		}
+		r.rows, r.rowsPos = nil, 0
+		if !stmtEnd {
+			r.rows, r.rowsPos = e, pos
+		}
+	}
+	return e, nil
+}
+
+// moveStmtEnd rewrites last rows event kept, at its position in f, if
+// redact moved end of statement flag onto it.
+func (r *redactor) moveStmtEnd(f File) error {
+	if r.rows == nil || binary.LittleEndian.Uint16(r.rows[19+6:])&rowsEventStmtEnd == 0 {
+		return nil
+	}
+	e, pos := r.rows, r.rowsPos
+	r.rows, r.rowsPos = nil, 0
+	if r.checksum > 0 {
+		binary.LittleEndian.PutUint32(e[len(e)-4:], crc32.ChecksumIEEE(e[:len(e)-4]))
+	}
+	cur, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(int64(pos), io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := f.Write(e); err != nil {
+		return err
+	}
+	_, err = f.Seek(cur, io.SeekStart)
+	return err
+}
+
+// redactQuery replaces query text of QUERY_EVENT e, with given body.
+func (r *redactor) redactQuery(e, body []byte, checksum int) ([]byte, error) {
+	// post-header: thread id 4, exec time 4, schema length 1, error code 2, status vars length 2
+	if len(body) < 13 {
+		return nil, fmt.Errorf("binlog: invalid %s", QUERY_EVENT)
+	}
+	schemaLen := int(body[8])
+	start := 13 + int(binary.LittleEndian.Uint16(body[11:])) // of schema
+	if len(body) < start+schemaLen+1 {
+		return nil, fmt.Errorf("binlog: invalid %s", QUERY_EVENT)
+	}
+	schema := string(body[start : start+schemaLen])
+	start += schemaLen + 1 // of query
+	query := string(body[start:])
+	if begin, end := txBoundary(Event{Header: EventHeader{EventType: QUERY_EVENT}, Data: QueryEvent{Query: query}}); begin || end {
+		return e, nil
+	}
+	redacted := r.Query(schema, query)
+	if redacted == query {
+		return e, nil
+	}
+	n := 19 + start
+	e = append(append(e[:n:n], redacted...), make([]byte, checksum)...)
+	return e, nil
+}
+
+// tableMapNames returns schema and table names in body of TABLE_MAP_EVENT.
+func tableMapNames(body []byte) (schema, table string, ok bool) {
+	// post-header: table id 6, flags 2
+	if len(body) < 9 {
+		return "", "", false
+	}
+	i := 9 + int(body[8])
+	if len(body) < i+2 {
+		return "", "", false
+	}
+	schema = string(body[9:i])
+	n := int(body[i+1])
+	i += 2
+	if len(body) < i+n {
+		return "", "", false
+	}
+	return schema, string(body[i : i+n]), true
+}
+
+func uint48(b []byte) uint64 {
+	return uint64(binary.LittleEndian.Uint32(b)) | uint64(binary.LittleEndian.Uint16(b[4:]))<<32
+}
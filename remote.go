@@ -1,6 +1,7 @@
 package binlog
 
 import (
+	"context"
 	"crypto/rsa"
 	"crypto/tls"
 	"errors"
@@ -9,55 +10,271 @@ import (
 	"io"
 	"net"
 	"strconv"
+	"sync"
 	"time"
 )
 
 // ErrMalformedPacket used to indicate malformed packet.
 var ErrMalformedPacket = errors.New("binlog: malformed packet")
 
+// ErrStreaming is returned when a query or Seek is attempted, while
+// connection is streaming binlog events. Server accepts commands again
+// only after the stream ends with io.EOF or error. see SetControlConn
+// for querying while streaming.
+var ErrStreaming = errors.New("binlog: connection is streaming binlog")
+
 // Remote represents connection to MySQL server.
 type Remote struct {
 	conn   net.Conn
 	seq    uint8
 	hs     handshake
 	pubKey *rsa.PublicKey // used by auth. cached here
+	tracer *tracer        // nil if tracing is disabled
+	wrap   ConnWrapper    // nil if not wrapped
+	raw    net.Conn       // conn without wrapper, to check transport
+
+	capabilities uint32 // negotiated in Authenticate
 
 	authFlow []string // for testing only
 
 	// binlog related
 	requestFile  string
 	requestPos   uint32
+	dumpFlags    uint16
+	validateSeek bool // see SetSeekValidation
+	dumpRate     int  // bytes per second read by Dump. zero means unlimited
+	dumpBurst    int
 	binlogReader *reader
-	checksum     int // captures binlog_checksum sys-var. used only for RotateEvent
+	checksum     int // checksum size of RotateEvent. -1 until detected from stream
+	options          // decodeOptions, with setters shared by Remote, Local and Reader
+	azureCompat  bool
+
+	dumpFileStart    func(file string) // see SetDumpFileFuncs
+	dumpFileComplete func(f DumpedFile) error
+	dumpIgnored      []EventType // see SetDumpIgnoredEvents
+	dumpRedaction    *DumpRedaction
+
+	minimalPrivileges bool
+
+	rowMetadataFunc func(err *RowMetadataError) // see SetRowMetadataFunc
+	rowMetadataSet  bool
+
+	// mu guards streaming and credentials, as they are used by other
+	// goroutines querying over control connection.
+	mu        sync.Mutex
+	streaming bool // COM_BINLOG_DUMP sent, and stream not ended
+
+	// control connection
+	controlEnabled bool
+	controlMu      sync.Mutex              // guards control, and serializes queries over it
+	control        *Remote                 // nil until first used
+	redial         func() (*Remote, error) // nil if not created by Dial functions
+	credentials    CredentialsProvider     // of last Authenticate, if controlEnabled
+
+	heartbeatFunc PositionFunc
+	idleFunc      PositionFunc
+	idleTimeout   time.Duration
+	idle          *idleWatcher // created by first NextEvent, with idleFunc
+
+	addr string // address dialed. empty if not created by Dial functions
+
+	serverUUID         string // read in Authenticate
+	expectedServerUUID string
+
+	prefetchSize int
+	prefetch     *prefetcher // non-nil while streaming, if prefetchSize > 0
+
+	spans   SpanTracer // nil if spans are disabled
+	spanCtx context.Context
+
+	stop stopper
 }
 
 // Dial connects to the MySQL server specified.
 func Dial(network, address string, timeout time.Duration) (*Remote, error) {
-	conn, err := net.DialTimeout(network, address, timeout)
+	return DialWithOptions(network, address, DialOptions{Timeout: timeout})
+}
+
+// Dialer establishes network connections. *net.Dialer implements it.
+// Custom implementations can connect through SOCKS proxies, SSH tunnels
+// or resolve addresses using custom DNS.
+type Dialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// ConnWrapper wraps network connection, to insert bandwidth meters,
+// PROXY protocol header or custom encryption layer.
+type ConnWrapper func(conn net.Conn) net.Conn
+
+// DialOptions configures how connection to MySQL server is made.
+type DialOptions struct {
+	Timeout time.Duration // timeout for connect. zero means no timeout
+
+	// KeepAlive is period between TCP keep-alive probes.
+	// zero means default period, negative disables keep-alive.
+	KeepAlive time.Duration
+
+	// Dialer, if non-nil, is used to connect. LocalAddr is ignored.
+	Dialer Dialer
+
+	// LocalAddr is local address to use when connecting.
+	LocalAddr net.Addr
+
+	// TLSConfig, if non-nil, upgrades the connection to SSL right
+	// after connect. Fails if server does not support SSL.
+	TLSConfig *tls.Config
+
+	// Trace, if non-nil, logs all bytes sent and received including
+	// the handshake. see Remote.SetTrace.
+	Trace io.Writer
+
+	// Backoff, if non-nil, retries connect on failure. Timeout applies
+	// to each attempt.
+	Backoff Backoff
+
+	// ReadBuffer, if positive, sets size of operating system's receive
+	// buffer (SO_RCVBUF) of TCP connection. Larger buffer lets server
+	// send more data before waiting for acknowledgement, which improves
+	// throughput of binlog stream on high latency links. The OS may
+	// cap it, for example by net.core.rmem_max on linux.
+	ReadBuffer int
+
+	// Nagle enables Nagle's algorithm, by clearing TCP_NODELAY, which
+	// Go sets by default. Binlog stream is mostly received, so this
+	// only affects the few requests sent.
+	Nagle bool
+
+	// ConnWrapper, if non-nil, wraps the connection right after connect,
+	// before handshake is read. It is called again with *tls.Conn after
+	// UpgradeSSL, which wraps previously wrapped connection. Wrappers
+	// meant to apply once can return *tls.Conn as is.
+	ConnWrapper ConnWrapper
+
+	// ProxyProtocol, if 1 or 2, sends PROXY protocol header of that
+	// version right after connect, as required by servers behind load
+	// balancers such as HAProxy. The header carries local and remote
+	// addresses of the connection.
+	ProxyProtocol int
+
+	// SpanTracer, if non-nil, traces Dial, and is set on the connection.
+	// see Remote.SetSpanTracer.
+	SpanTracer SpanTracer
+}
+
+// DialWith connects to the MySQL server specified, using given dialer.
+func DialWith(dialer Dialer, network, address string) (*Remote, error) {
+	return DialWithOptions(network, address, DialOptions{Dialer: dialer})
+}
+
+// DialWithOptions connects to the MySQL server specified, using given options.
+func DialWithOptions(network, address string, opts DialOptions) (*Remote, error) {
+	span := startSpan(nil, opts.SpanTracer, SpanDial)
+	span.SetAttribute(AttrAddr, address)
+	bl, err := dialWithOptions(network, address, opts)
+	endSpan(span, err)
+	if err != nil {
+		return nil, err
+	}
+	bl.spans = opts.SpanTracer
+	return bl, nil
+}
+
+func dialWithOptions(network, address string, opts DialOptions) (*Remote, error) {
+	dialer := opts.Dialer
+	if dialer == nil {
+		dialer = &net.Dialer{KeepAlive: opts.KeepAlive, LocalAddr: opts.LocalAddr}
+	}
+	var conn net.Conn
+	err := retry(opts.Backoff, nil, func() (err error) {
+		ctx := context.Background()
+		if opts.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+			defer cancel()
+		}
+		conn, err = dialer.DialContext(ctx, network, address)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 	// Enable TCP KeepAlive on TCP connections
+	if tc, ok := conn.(*net.TCPConn); ok && opts.Dialer != nil && opts.KeepAlive >= 0 {
+		err := tc.SetKeepAlive(true)
+		if err == nil && opts.KeepAlive > 0 {
+			err = tc.SetKeepAlivePeriod(opts.KeepAlive)
+		}
+		if err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
 	if tc, ok := conn.(*net.TCPConn); ok {
-		if err := tc.SetKeepAlive(true); err != nil {
+		var err error
+		if opts.ReadBuffer > 0 {
+			err = tc.SetReadBuffer(opts.ReadBuffer)
+		}
+		if err == nil && opts.Nagle {
+			err = tc.SetNoDelay(false)
+		}
+		if err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	var t *tracer
+	if opts.Trace != nil {
+		t = &tracer{w: opts.Trace}
+	}
+	if opts.ProxyProtocol != 0 {
+		h, err := proxyHeader(opts.ProxyProtocol, conn.LocalAddr(), conn.RemoteAddr())
+		if err == nil {
+			_, err = conn.Write(h)
+		}
+		if err != nil {
 			_ = conn.Close()
 			return nil, err
 		}
 	}
-	var seq uint8
-	r := newReader(conn, &seq)
+	raw := conn
+	if opts.ConnWrapper != nil {
+		conn = opts.ConnWrapper(conn)
+	}
+	bl, err := newRemote(conn, t)
+	if err != nil {
+		return nil, err
+	}
+	bl.addr = address
+	bl.wrap, bl.raw = opts.ConnWrapper, raw
+	bl.redial = func() (*Remote, error) {
+		return DialWithOptions(network, address, opts)
+	}
+	if opts.TLSConfig != nil {
+		if !bl.IsSSLSupported() {
+			_ = bl.Close()
+			return nil, fmt.Errorf("binlog.Dial: server does not support SSL")
+		}
+		if err := bl.UpgradeSSL(opts.TLSConfig); err != nil {
+			_ = bl.Close()
+			return nil, err
+		}
+	}
+	return bl, nil
+}
+
+// newRemote reads handshake from server.
+func newRemote(conn net.Conn, t *tracer) (*Remote, error) {
+	bl := &Remote{conn: conn, raw: conn, tracer: t}
+	r := newReader(bl.rw(), &bl.seq)
 	hs := handshake{}
-	if err = hs.decode(r); err != nil {
+	if err := hs.decode(r); err != nil {
 		_ = conn.Close()
 		return nil, err
 	}
 	// unset the features we dont support
 	hs.capabilityFlags &= ^uint32(capSessionTrack)
-	return &Remote{
-		conn: conn,
-		seq:  seq,
-		hs:   hs,
-	}, nil
+	bl.hs = hs
+	return bl, nil
 }
 
 // IsSSLSupported tells whether MySQL server supports SSL.
@@ -65,6 +282,91 @@ func (bl *Remote) IsSSLSupported() bool {
 	return bl.hs.capabilityFlags&capSSL != 0
 }
 
+// ConnectionID returns id of this connection, as assigned by server.
+// It is same as Id column in `SHOW PROCESSLIST`, and can be used
+// with `KILL` statement.
+func (bl *Remote) ConnectionID() uint32 {
+	return bl.hs.connectionID
+}
+
+// ServerUUID returns server_uuid of the server, read in Authenticate.
+// Returns empty string if server does not have it, as in MariaDB.
+func (bl *Remote) ServerUUID() string {
+	return bl.serverUUID
+}
+
+// SetExpectedServerUUID makes Authenticate fail with *ServerUUIDError,
+// if server_uuid of the server is not uuid. When reconnecting to a
+// server by hostname, this detects that the hostname resolves to a
+// different server, whose binlog files are different, rather than
+// silently streaming wrong events. Must be called before Authenticate.
+func (bl *Remote) SetExpectedServerUUID(uuid string) {
+	bl.expectedServerUUID = uuid
+}
+
+// ServerUUIDError is returned by Authenticate, if server_uuid of the
+// server is not as expected. see Remote.SetExpectedServerUUID.
+type ServerUUIDError struct {
+	Expected, Actual string
+}
+
+func (e *ServerUUIDError) Error() string {
+	return fmt.Sprintf("binlog: server_uuid is %q, want %q", e.Actual, e.Expected)
+}
+
+// SourceInfo returns identity of this connection, as set in EventMeta.
+func (bl *Remote) SourceInfo() *SourceInfo {
+	addr := bl.addr
+	if addr == "" && bl.conn != nil {
+		addr = bl.conn.RemoteAddr().String()
+	}
+	return &SourceInfo{
+		Addr:         addr,
+		ServerUUID:   bl.serverUUID,
+		ConnectionID: bl.hs.connectionID,
+		Flavor:       flavor(bl.hs.serverVersion),
+	}
+}
+
+// ServerVersion returns version of MySQL server, such as "8.0.23".
+// It is version reported in handshake, till Authenticate replaces it
+// with result of `SELECT VERSION()`.
+func (bl *Remote) ServerVersion() string {
+	return bl.hs.serverVersion
+}
+
+// Supports tells whether server supports feature f, as per its flavor
+// and version. see ServerVersion.
+func (bl *Remote) Supports(f Feature) bool {
+	return supports(bl.hs.serverVersion, f)
+}
+
+// Features returns features supported by server.
+func (bl *Remote) Features() []Feature {
+	var features []Feature
+	for f := range featureNames {
+		if bl.Supports(Feature(f)) {
+			features = append(features, Feature(f))
+		}
+	}
+	return features
+}
+
+// Capabilities returns capability flags negotiated with server in
+// Authenticate. Before Authenticate, returns capability flags of server.
+//
+// https://dev.mysql.com/doc/internals/en/capability-flags.html
+func (bl *Remote) Capabilities() uint32 {
+	if bl.capabilities == 0 {
+		return bl.hs.capabilityFlags
+	}
+	caps := bl.capabilities
+	if _, ok := bl.raw.(*tls.Conn); ok {
+		caps |= capSSL
+	}
+	return caps
+}
+
 // UpgradeSSL upgrades current connection to SSL. If tlsConfig is nil
 // it will use InsecureSkipVerify true value. This should be called
 // before Authenticate call.
@@ -80,8 +382,15 @@ func (bl *Remote) UpgradeSSL(tlsConfig *tls.Config) error {
 	if tlsConfig == nil {
 		tlsConfig = &tls.Config{InsecureSkipVerify: true}
 	}
-	bl.conn = tls.Client(bl.conn, tlsConfig)
-	return bl.conn.(*tls.Conn).Handshake()
+	tc := tls.Client(bl.conn, tlsConfig)
+	if err := tc.Handshake(); err != nil {
+		return err
+	}
+	bl.conn, bl.raw = tc, tc
+	if bl.wrap != nil {
+		bl.conn = bl.wrap(tc)
+	}
+	return nil
 }
 
 // ListFiles lists the binary log files on the server,
@@ -100,9 +409,14 @@ func (bl *Remote) ListFiles() ([]string, error) {
 }
 
 // MasterStatus provides status information about the binary log files of the server.
-// It is equivalent to `SHOW MASTER STATUS` statement.
+// It is equivalent to `SHOW MASTER STATUS` statement, or to
+// `SHOW BINARY LOG STATUS` on servers with FeatureBinaryLogStatus.
 func (bl *Remote) MasterStatus() (file string, pos uint32, err error) {
-	rows, err := bl.queryRows(`show master status`)
+	q := `show master status`
+	if bl.Supports(FeatureBinaryLogStatus) {
+		q = `show binary log status`
+	}
+	rows, err := bl.queryRows(q)
 	if err != nil {
 		return "", 0, err
 	}
@@ -118,25 +432,94 @@ func (bl *Remote) MasterStatus() (file string, pos uint32, err error) {
 // disables heartbeats altogether.
 //
 // Use this, if you are using non-zero serverID to Seek method. In this case, server sends
-// heartbeatEvents when there are no more events.
+// heartbeatEvents when there are no more events. Sub-second periods are supported,
+// to keep lag measurements fresh. see SetHeartbeatFunc.
 func (bl *Remote) SetHeartbeatPeriod(d time.Duration) error {
-	_, err := bl.query(fmt.Sprintf("SET @master_heartbeat_period=%d", d))
+	_, err := bl.sessionQuery(fmt.Sprintf("SET @master_heartbeat_period=%d", d))
 	return err
 }
 
-func (bl *Remote) fetchBinlogChecksum() (string, error) {
-	rows, err := bl.queryRows(`show global variables like 'binlog_checksum'`)
+// Flags of binlog dump request. see SetDumpFlags.
+const (
+	BINLOG_DUMP_NON_BLOCK   = 0x01 // server sends EOF when there are no more events
+	BINLOG_THROUGH_POSITION = 0x02 // gtid dump request starts at file and position
+	BINLOG_THROUGH_GTID     = 0x04 // gtid dump request is by gtid set. set by SeekGTID
+)
+
+// SetDumpFlags sets the flags sent in binlog dump request by Seek.
+// for example BINLOG_DUMP_NON_BLOCK makes server to send EOF when
+// there are no more events, even with non-zero serverID.
+func (bl *Remote) SetDumpFlags(flags uint16) {
+	bl.dumpFlags = flags
+}
+
+// SetDumpRateLimit limits rate at which Dump reads from network, to
+// bytesPerSec bytes per second, so that dumping archives over network
+// link of production server does not starve application traffic.
+// Reads are limited using token bucket of burst bytes. burst <= 0
+// means bytesPerSec. bytesPerSec <= 0 means unlimited, which is the
+// default.
+func (bl *Remote) SetDumpRateLimit(bytesPerSec, burst int) {
+	bl.dumpRate, bl.dumpBurst = bytesPerSec, burst
+}
+
+// SetSessionVariable sets user variable @name for current connection.
+// It should be called before Seek.
+//
+// MySQL has no server side filtering of binlog stream by table, but
+// dump thread of the server honors some user variables set by replicas.
+// for example:
+//
+//	@master_heartbeat_period       see SetHeartbeatPeriod
+//	@mariadb_slave_capability      MariaDB: capabilities of this replica
+//	@slave_connect_state           MariaDB: GTID position to start from
+//	@slave_gtid_ignore_duplicates  MariaDB: skip already seen GTIDs
+//
+// name may have only letters, digits, '_', '.' and '@'. value is sent as
+// quoted SQL string; string, []byte, bool and numbers are supported.
+func (bl *Remote) SetSessionVariable(name string, value interface{}) error {
+	q, err := setVariableQuery(name, value)
 	if err != nil {
-		return "", err
+		return err
 	}
-	if len(rows) > 0 {
-		return rows[0][1].(string), nil
+	_, err = bl.sessionQuery(q)
+	return err
+}
+
+// setVariableQuery returns SET statement for user variable @name.
+func setVariableQuery(name string, value interface{}) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("binlog: invalid session variable name %q", name)
 	}
-	return "", nil
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '_', c == '.', c == '@':
+		default:
+			return "", fmt.Errorf("binlog: invalid session variable name %q", name)
+		}
+	}
+	var lit string
+	switch v := value.(type) {
+	case bool:
+		lit = sqlQuote("0")
+		if v {
+			lit = sqlQuote("1")
+		}
+	case string, []byte:
+		lit = Column{}.SQLLiteral(v)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		lit = sqlQuote(Column{}.numberLiteral(v))
+	default:
+		return "", fmt.Errorf("binlog: unsupported session variable value %T", value)
+	}
+	return fmt.Sprintf("SET @%s = %s", name, lit), nil
 }
 
+// confirmChecksumSupport tells server that we can handle checksums.
+// otherwise server refuses to send events with checksum.
 func (bl *Remote) confirmChecksumSupport() error {
-	_, err := bl.query(`set @master_binlog_checksum = @@global.binlog_checksum`)
+	_, err := bl.sessionQuery(`set @master_binlog_checksum = @@global.binlog_checksum`)
 	return err
 }
 
@@ -144,30 +527,76 @@ func (bl *Remote) confirmChecksumSupport() error {
 //
 // if serverID is zero, NextEvent return io.EOF when there are no more events.
 // if serverID is non-zero, NextEvent waits for new events.
+//
+// The checksum algorithm is detected from the binlog stream, so
+// it does not require privileges to query binlog_checksum.
+//
+// Returns ErrStreaming if previous Seek's stream has not ended.
 func (bl *Remote) Seek(serverID uint32, fileName string, position uint32) error {
-	checksum, err := bl.fetchBinlogChecksum()
-	if err != nil {
-		return err
+	span := startSpan(bl.spanCtx, bl.spans, SpanSeek)
+	span.SetAttribute(AttrFile, fileName)
+	span.SetAttribute(AttrPos, int64(position))
+	err := bl.seek(serverID, fileName, position)
+	endSpan(span, err)
+	return err
+}
+
+func (bl *Remote) seek(serverID uint32, fileName string, position uint32) error {
+	if bl.isStreaming() {
+		return ErrStreaming
 	}
-	if checksum != "" && checksum != "NONE" {
-		if err := bl.confirmChecksumSupport(); err != nil {
+	if bl.validateSeek && fileName != "" {
+		if err := bl.ValidatePosition(fileName, int64(position)); err != nil {
 			return err
 		}
-		bl.checksum = 4
-	} else {
-		bl.checksum = 0
 	}
-	bl.seq = 0
-	err = bl.write(comBinlogDump{
+	return bl.requestDump(comBinlogDump{
 		binlogPos:      position,
-		flags:          0,
+		flags:          bl.dumpFlags,
 		serverID:       serverID,
 		binlogFilename: fileName,
-	})
+	}, fileName, position)
+}
+
+// requestDump sends cmd requesting binlog stream, which starts at given
+// file and position, and resets state of previous stream. It is used by
+// both Seek and SeekGTID.
+func (bl *Remote) requestDump(cmd interface{ encode(w *writer) error }, fileName string, position uint32) error {
+	bl.checkRowMetadata()
+	if bl.Supports(FeatureChecksum) {
+		// error is ignored, as checksums are detected from stream
+		_ = bl.confirmChecksumSupport()
+	}
+	bl.checksum = -1 // detected from RotateEvent and FormatDescriptionEvent
+	bl.binlogReader = nil
+	bl.seq = 0
+	err := bl.write(cmd)
 	bl.requestFile, bl.requestPos = fileName, position
+	bl.stop.advance(fileName, position)
+	bl.setStreaming(err == nil)
+	bl.startPrefetch()
 	return err
 }
 
+func (bl *Remote) isStreaming() bool {
+	bl.mu.Lock()
+	defer bl.mu.Unlock()
+	return bl.streaming
+}
+
+func (bl *Remote) setStreaming(streaming bool) {
+	bl.mu.Lock()
+	bl.streaming = streaming
+	bl.mu.Unlock()
+}
+
+// startPrefetch starts prefetcher, if enabled and streaming.
+func (bl *Remote) startPrefetch() {
+	if bl.isStreaming() && bl.prefetchSize > 0 {
+		bl.prefetch = startPrefetch(bl.rw(), bl.prefetchSize)
+	}
+}
+
 func (bl *Remote) binlogVersion() (uint16, error) {
 	sv, err := newServerVersion(bl.hs.serverVersion)
 	if err != nil {
@@ -180,35 +609,84 @@ func (bl *Remote) binlogVersion() (uint16, error) {
 //
 // return io.EOF when there are no more Events
 func (bl *Remote) NextEvent() (Event, error) {
+	if !bl.stop.begin() {
+		return Event{}, ErrStopped
+	}
+	defer bl.stop.end()
+	span := startSpan(bl.spanCtx, bl.spans, SpanNextEvent)
+	e, err := bl.nextEvent()
+	if err == nil && bl.spans != nil {
+		eventSpanAttributes(span, e)
+	}
+	endSpan(span, err)
+	if err != nil {
+		if bl.stop.isStopped() {
+			err = ErrStopped
+		}
+		return e, err
+	}
+	if e.Header.LogFile != "" && e.Header.NextPos != 0 {
+		bl.stop.advance(e.Header.LogFile, e.Header.NextPos)
+	}
+	return e, nil
+}
+
+func (bl *Remote) nextEvent() (Event, error) {
+	if e, ok := bl.binlogReader.nextPending(); ok {
+		return e, nil
+	}
+	if bl.idleFunc != nil && bl.idleTimeout > 0 {
+		bl.watchIdle()
+		defer bl.idle.stop()
+	}
+	e, err := bl.readEvent()
+	if err != nil || !bl.opts.coalesceRows {
+		return e, err
+	}
+	return coalesceRows(e, bl.binlogReader, bl.readEvent)
+}
+
+func (bl *Remote) readEvent() (e Event, err error) {
+	defer func() {
+		// stream ends, if reading from connection failed
+		if r := bl.binlogReader; err != nil && r != nil {
+			if pr, ok := r.rd.(*packetReader); ok && pr.err != nil {
+				bl.setStreaming(false)
+			}
+		}
+	}()
 	// checksum: https://dev.mysql.com/worklog/task/?id=2540#tabs-2540-4
 	r := bl.binlogReader
+	var corrupt *CorruptionEvent
 	if r == nil {
-		r = newReader(bl.conn, &bl.seq)
+		r = newReader(bl.rw(), &bl.seq)
 		v, err := bl.binlogVersion()
 		if err != nil {
 			return Event{}, err
 		}
-		r.checksum = bl.checksum
-		r.hash = crc32.NewIEEE()
+		if bl.checksum > 0 {
+			r.checksum = bl.checksum
+		}
+		if bl.opts.checksumPolicy != ChecksumIgnore || bl.checksum < 0 {
+			r.hash = crc32.NewIEEE() // needed to detect checksum of RotateEvent
+		}
 		r.fde = FormatDescriptionEvent{BinlogVersion: v}
+		r.opts = &bl.opts
+		r.source = bl.SourceInfo()
 		bl.binlogReader = r
 	} else {
 		if err := r.drain(); err != nil {
 			return Event{}, fmt.Errorf("binlog.NextEvent: error in draining event: %v", err)
 		}
 		if r.checksum > 0 {
-			got := r.hash.Sum32()
 			r.limit = -1
-			want := r.int4()
-			if r.err != nil {
-				return Event{}, r.err
-			}
-			if got != want {
-				return Event{}, fmt.Errorf("binlog.NextEvent: checksum failed got=%d want=%d", got, want)
+			var err error
+			if corrupt, err = r.verifyChecksum(); err != nil {
+				return Event{}, err
 			}
 		}
 		r.limit = -1
-		r.rd = &packetReader{rd: bl.conn, seq: &bl.seq}
+		r.rd = &packetReader{rd: bl.rw(), seq: &bl.seq}
 	}
 	// Check first byte.
 	b, err := r.peek()
@@ -223,32 +701,85 @@ func (bl *Remote) NextEvent() (Event, error) {
 		if err := eof.decode(r, bl.hs.capabilityFlags); err != nil {
 			return Event{}, err
 		}
+		bl.setStreaming(false)
 		return Event{}, io.EOF
 	case errMarker:
 		ep := errPacket{}
 		if err := ep.decode(r, bl.hs.capabilityFlags); err != nil {
 			return Event{}, err
 		}
-		return Event{}, errors.New(ep.errorMessage)
+		bl.setStreaming(false)
+		return Event{}, ep.error("COM_BINLOG_DUMP")
 	default:
+		bl.setStreaming(false)
 		return Event{}, fmt.Errorf("binlogStream: got %0x want OK-byte", b)
 	}
-	return nextEvent(r, bl.checksum)
+	e, err = nextEvent(r, bl.checksum)
+	if err == nil && bl.checksum < 0 {
+		switch d := e.Data.(type) {
+		case RotateEvent:
+			bl.checksum = int(e.Header.EventSize) - 19 - 8 - len(d.NextBinlog)
+		case FormatDescriptionEvent:
+			bl.checksum = r.checksum
+		}
+	}
+	if bl.checksum >= 0 && bl.opts.checksumPolicy == ChecksumIgnore {
+		r.hash = nil // was needed only to detect checksum
+	}
+	if err == nil && e.Header.EventType == HEARTBEAT_EVENT && bl.heartbeatFunc != nil {
+		bl.heartbeatFunc(e.Header.LogFile, e.Header.NextPos)
+	}
+	return corruptionFirst(r, corrupt, e, err)
+}
+
+// SetAzureCompat enables workarounds for known deviations of
+// Azure Database for MySQL:
+//
+//   - version in handshake is wrong, for example "5.6.26.0" for 5.7.
+//     version is queried after Authenticate. with this option, failure
+//     of that query is ignored.
+//
+// Packets coalesced or split by Azure gateway need no workaround, as
+// packets are read independent of the underlying network reads.
+//
+// Call this before Authenticate.
+func (bl *Remote) SetAzureCompat(enable bool) {
+	bl.azureCompat = enable
+}
+
+// SetClock sets source of time for EventMeta and SetIdleFunc timers.
+// nil means SystemClock.
+func (bl *Remote) SetClock(c Clock) {
+	bl.opts.clock = c
 }
 
 // NextRow returns next row for RowsEvent. Returns io.EOF when there are no more rows.
-// valuesBeforeUpdate should be used only for events UPDATE_ROWS_EVENTv1, UPDATE_ROWS_EVENTv2.
+// values is the row inserted or deleted, or the row after update. valuesBeforeUpdate
+// is the row before update, and is nil for events other than UPDATE_ROWS_EVENTv1,
+// UPDATE_ROWS_EVENTv2.
 func (bl *Remote) NextRow() (values []interface{}, valuesBeforeUpdate []interface{}, err error) {
 	return nextRow(bl.binlogReader)
 }
 
+// NextRows is like NextRow, but returns upto n rows at once, to amortize
+// per call overhead for RowsEvent with many rows. rowsBeforeUpdate is nil
+// unless rows were updated. Returns io.EOF when there are no more rows.
+func (bl *Remote) NextRows(n int) (rows [][]interface{}, rowsBeforeUpdate [][]interface{}, err error) {
+	return nextRows(bl.binlogReader, n)
+}
+
 // Close closes connection.
 func (bl *Remote) Close() error {
+	bl.closeControl()
+	if bl.prefetch != nil {
+		bl.prefetch.stop()
+		bl.prefetch = nil
+	}
 	return bl.conn.Close()
 }
 
 func (bl *Remote) write(event interface{ encode(w *writer) error }) error {
-	w := newWriter(bl.conn, &bl.seq)
+	w := newWriter(bl.rw(), &bl.seq)
 	if err := event.encode(w); err != nil {
 		return err
 	}
@@ -260,7 +791,7 @@ func (bl *Remote) write(event interface{ encode(w *writer) error }) error {
 // https://dev.mysql.com/doc/internals/en/com-binlog-dump.html
 type comBinlogDump struct {
 	binlogPos      uint32 // position in the binlog-file to start the stream with
-	flags          uint16 // if BINLOG_DUMP_NON_BLOCK(0x01) set, sends eofPacket when there are no more events
+	flags          uint16 // if BINLOG_DUMP_NON_BLOCK set, sends eofPacket when there are no more events
 	serverID       uint32 // server id of this slave
 	binlogFilename string // filename of the binlog. if empty, from first known binlog
 }
@@ -0,0 +1,999 @@
+package binlog
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type dialerFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+func (f dialerFunc) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return f(ctx, network, address)
+}
+
+func TestDialWithOptions(t *testing.T) {
+	errDial := errors.New("dial failed")
+	var gotAddr string
+	var gotDeadline bool
+	dialer := dialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+		gotAddr = network + ":" + address
+		_, gotDeadline = ctx.Deadline()
+		return nil, errDial
+	})
+	if _, err := DialWith(dialer, "tcp", "db:3306"); err != errDial {
+		t.Fatal("got", err, "want", errDial)
+	}
+	if gotAddr != "tcp:db:3306" || gotDeadline {
+		t.Fatal("got", gotAddr, gotDeadline)
+	}
+	opts := DialOptions{Dialer: dialer, Timeout: time.Second}
+	if _, err := DialWithOptions("unix", "/tmp/mysql.sock", opts); err != errDial {
+		t.Fatal("got", err, "want", errDial)
+	}
+	if gotAddr != "unix:/tmp/mysql.sock" || !gotDeadline {
+		t.Fatal("got", gotAddr, gotDeadline)
+	}
+}
+
+func TestDialWithOptions_socket(t *testing.T) {
+	s := newFakeServer()
+	f := newBinlogStream()
+	f.XID(7)
+	s.addFile("binlog.000001", f)
+	addr := s.listen(t)
+	bl, err := DialWithOptions("tcp", addr, DialOptions{ReadBuffer: 1 << 20, Nagle: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bl.Close()
+	if err := bl.Authenticate(s.user, s.password); err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.Seek(0, "binlog.000001", 4); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if _, err := bl.NextEvent(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// countConn counts bytes read.
+type countConn struct {
+	net.Conn
+	n int
+}
+
+func (c *countConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.n += n
+	return n, err
+}
+
+func TestDialWithOptions_ConnWrapper(t *testing.T) {
+	s := newFakeServer()
+	var cc *countConn
+	opts := DialOptions{
+		Dialer: s,
+		ConnWrapper: func(conn net.Conn) net.Conn {
+			cc = &countConn{Conn: conn}
+			return cc
+		},
+	}
+	bl, err := DialWithOptions("tcp", "fake:3306", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bl.Close()
+	if cc == nil || cc.n == 0 {
+		t.Fatal("handshake not read through wrapper")
+	}
+	n := cc.n
+	if err := bl.Authenticate(s.user, s.password); err != nil {
+		t.Fatal(err)
+	}
+	if cc.n == n {
+		t.Fatal("auth response not read through wrapper")
+	}
+}
+
+// BenchmarkRemote_ReadBuffer measures streaming of large transactions
+// over loopback, with default and larger receive buffer. The effect is
+// pronounced on links with high latency, which loopback does not have.
+func BenchmarkRemote_ReadBuffer(b *testing.B) {
+	s := newFakeServer()
+	f := newBinlogStream()
+	for i := uint64(1); i <= 2000; i++ {
+		f.Event(ROWS_QUERY_EVENT, append([]byte{0}, strings.Repeat("x", 4096)...))
+		f.XID(i)
+	}
+	s.addFile("binlog.000001", f)
+	addr := s.listen(b)
+	for _, size := range []int{0, 4 << 20} {
+		b.Run(fmt.Sprint("ReadBuffer=", size), func(b *testing.B) {
+			b.SetBytes(int64(f.Len()))
+			for i := 0; i < b.N; i++ {
+				bl, err := DialWithOptions("tcp", addr, DialOptions{ReadBuffer: size})
+				if err != nil {
+					b.Fatal(err)
+				}
+				if err := bl.Authenticate(s.user, s.password); err != nil {
+					b.Fatal(err)
+				}
+				if err := bl.Seek(0, "binlog.000001", 4); err != nil {
+					b.Fatal(err)
+				}
+				for {
+					if _, err := bl.NextEvent(); err == io.EOF {
+						break
+					} else if err != nil {
+						b.Fatal(err)
+					}
+				}
+				_ = bl.Close()
+			}
+		})
+	}
+}
+
+func TestRemote_trace(t *testing.T) {
+	s := newFakeServer()
+	buf := &bytes.Buffer{}
+	bl, err := DialWithOptions("tcp", "fake:3306", DialOptions{Dialer: s, Trace: buf})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bl.Close()
+	if err := bl.Authenticate(s.user, s.password); err != nil {
+		t.Fatal(err)
+	}
+	trace := buf.String()
+	for _, want := range []string{" < ", " > ", "|.8.0.23", "select vers"} {
+		if !strings.Contains(trace, want) {
+			t.Fatalf("trace does not contain %q:\n%s", want, trace)
+		}
+	}
+	bl.SetTrace(nil)
+	if _, err := bl.ListFiles(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != trace {
+		t.Fatal("trace must be disabled")
+	}
+}
+
+func TestRemote_azureCompat(t *testing.T) {
+	for _, checksum := range []bool{true, false} {
+		s := newFakeServer()
+		s.version = "5.6.26.0"
+		s.checksum = checksum
+		s.denied["select version()"] = true
+		f := newBinlogStream()
+		f.XID(7)
+		s.addFile("binlog.000001", f)
+
+		if _, err := s.dial(); err == nil {
+			t.Fatal("Authenticate must fail without azure compat")
+		}
+		bl, err := DialWith(s, "tcp", "fake:3306")
+		if err != nil {
+			t.Fatal(err)
+		}
+		bl.SetAzureCompat(true)
+		if err := bl.Authenticate(s.user, s.password); err != nil {
+			t.Fatal(err)
+		}
+		if err := bl.Seek(0, "binlog.000001", 4); err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for {
+			e, err := bl.NextEvent()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch d := e.Data.(type) {
+			case RotateEvent:
+				got = append(got, "rotate "+d.NextBinlog)
+			case FormatDescriptionEvent:
+				got = append(got, "fde")
+			case XIDEvent:
+				got = append(got, "xid")
+			}
+		}
+		_ = bl.Close()
+		if want := []string{"rotate binlog.000001", "fde", "xid"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("checksum=%v: got %q, want %q", checksum, got, want)
+		}
+	}
+}
+
+func TestRemote_Dump(t *testing.T) {
+	for _, checksum := range []bool{true, false} {
+		s := newFakeServer()
+		s.checksum = checksum
+		s.denied["show global variables like 'binlog_checksum'"] = true
+		f := newBinlogStream()
+		f.XID(7)
+		s.addFile("binlog.000001", f)
+		bl, err := s.dial()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := bl.Seek(0, "binlog.000001", 4); err != nil {
+			t.Fatal(err)
+		}
+		dir, err := ioutil.TempDir("", "dump")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		if err := bl.Dump(dir); err != io.EOF {
+			t.Fatal("got", err, "want", io.EOF)
+		}
+		_ = bl.Close()
+		got, err := ioutil.ReadFile(filepath.Join(dir, "binlog.000001"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, f.Bytes()) {
+			t.Fatalf("checksum=%v: dumped file does not match", checksum)
+		}
+	}
+}
+
+func TestRemote_ResumeDump(t *testing.T) {
+	s := newFakeServer()
+	f := newBinlogStream()
+	f.XID(7)
+	first := len(f.Bytes()) // end of first xid event
+	f.XID(8)
+	second := len(f.Bytes()) // last complete event, after interruption
+	f.XID(9)
+	s.addFile("binlog.000001", f)
+	dir, err := ioutil.TempDir("", "dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "binlog.000001")
+
+	bl, err := s.dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.ResumeDump(0, dir); err == nil {
+		t.Fatal("resume of empty dump directory: error expected")
+	}
+	if err := bl.Seek(0, "binlog.000001", 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.Dump(dir); err != io.EOF {
+		t.Fatal("got", err, "want", io.EOF)
+	}
+	_ = bl.Close()
+
+	// interrupted in the middle of last event, after progress was
+	// recorded at first xid event.
+	if err := os.Truncate(name, int64(len(f.Bytes())-3)); err != nil {
+		t.Fatal(err)
+	}
+	progress := fmt.Sprintf("binlog.000001 %d\n", first)
+	if err := ioutil.WriteFile(filepath.Join(dir, ".progress"), []byte(progress), 0666); err != nil {
+		t.Fatal(err)
+	}
+	bl, err = s.dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bl.Close()
+	if err := bl.ResumeDump(0, dir); err != io.EOF {
+		t.Fatal("got", err, "want", io.EOF)
+	}
+	if bl.requestPos != uint32(second) {
+		t.Fatalf("resumed from %d, want %d", bl.requestPos, second)
+	}
+	got, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, f.Bytes()) {
+		t.Fatal("resumed dump does not match")
+	}
+	got, err = ioutil.ReadFile(filepath.Join(dir, ".progress"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprintf("binlog.000001 %d\n", len(f.Bytes())); string(got) != want {
+		t.Fatalf("got progress %q, want %q", got, want)
+	}
+
+	// progress beyond end of file, as file was not synced before crash
+	if err := os.Truncate(name, int64(first)); err != nil {
+		t.Fatal(err)
+	}
+	progress = fmt.Sprintf("binlog.000001 %d\n", second)
+	if err := ioutil.WriteFile(filepath.Join(dir, ".progress"), []byte(progress), 0666); err != nil {
+		t.Fatal(err)
+	}
+	file, pos, serverPos, err := dumpProgress(OSFS, filepath.ToSlash(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file != "binlog.000001" || pos != uint32(first) || serverPos != uint32(first) {
+		t.Fatal("got", file, pos, serverPos, "want", "binlog.000001", first, first)
+	}
+	if fi, err := os.Stat(name); err != nil {
+		t.Fatal(err)
+	} else if fi.Size() != int64(first) {
+		t.Fatal("file size changed to", fi.Size())
+	}
+}
+
+func TestRemote_heartbeatAndIdle(t *testing.T) {
+	s := newFakeServer()
+	s.idle = make(chan struct{})
+	f := newBinlogStream()
+	f.Event(HEARTBEAT_EVENT, []byte("binlog.000001"))
+	s.addFile("binlog.000001", f)
+	bl, err := s.dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bl.Close()
+
+	type position struct {
+		file string
+		pos  uint32
+	}
+	var heartbeat position
+	bl.SetHeartbeatFunc(func(file string, pos uint32) {
+		heartbeat = position{file, pos}
+	})
+	idle := make(chan position, 10)
+	bl.SetIdleFunc(10*time.Millisecond, func(file string, pos uint32) {
+		idle <- position{file, pos}
+	})
+	clock := &timerCounter{Clock: SystemClock}
+	bl.SetClock(clock)
+	if err := bl.Seek(1, "binlog.000001", 4); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		e, err := bl.NextEvent()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Header.EventType == HEARTBEAT_EVENT {
+			break
+		}
+	}
+	want := position{"binlog.000001", uint32(f.Len())}
+	if heartbeat != want {
+		t.Fatalf("heartbeat: got %v, want %v", heartbeat, want)
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := bl.NextEvent()
+		done <- err
+	}()
+	for i := 0; i < 2; i++ {
+		if got := <-idle; got != want {
+			t.Fatalf("idle: got %v, want %v", got, want)
+		}
+	}
+	close(s.idle)
+	if err := <-done; err != io.EOF {
+		t.Fatal("got", err, "want", io.EOF)
+	}
+	if n := atomic.LoadInt32(&clock.timers); n != 1 {
+		t.Fatal("got", n, "idle timers, want 1")
+	}
+}
+
+// timerCounter is Clock counting timers created.
+type timerCounter struct {
+	Clock
+	timers int32
+}
+
+func (c *timerCounter) AfterFunc(d time.Duration, f func()) Timer {
+	atomic.AddInt32(&c.timers, 1)
+	return c.Clock.AfterFunc(d, f)
+}
+
+func TestRemote_connectionInfo(t *testing.T) {
+	s := newFakeServer()
+	s.queries["select version()"] = [][]string{{"version()"}, {"8.0.23-log"}}
+	bl, err := s.dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bl.Close()
+	if got := bl.ConnectionID(); got != 1 {
+		t.Errorf("ConnectionID: got %d, want 1", got)
+	}
+	if got := bl.ServerVersion(); got != "8.0.23-log" {
+		t.Errorf("ServerVersion: got %q", got)
+	}
+	caps := bl.Capabilities()
+	if caps&capProtocol41 == 0 || caps&capPluginAuth == 0 {
+		t.Errorf("Capabilities: got %#x, want protocol41 and pluginAuth", caps)
+	}
+	if caps&capTransactions != 0 {
+		t.Errorf("Capabilities: got %#x, transactions not requested by client", caps)
+	}
+}
+
+func TestRemote_SourceInfo(t *testing.T) {
+	s := newFakeServer()
+	s.queries["select version()"] = [][]string{{"version()"}, {"10.5.8-MariaDB"}}
+	f := newBinlogStream()
+	f.XID(7)
+	s.addFile("binlog.000001", f)
+	bl, err := s.dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bl.Close()
+	if err := bl.Seek(0, "binlog.000001", 4); err != nil {
+		t.Fatal(err)
+	}
+	e, err := bl.NextEvent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := SourceInfo{Addr: "fake:3306", ConnectionID: 1, Flavor: "mariadb"}
+	if e.Meta().Source == nil || *e.Meta().Source != want {
+		t.Fatalf("got %+v, want %+v", e.Meta().Source, want)
+	}
+}
+
+func TestRemote_SetExpectedServerUUID(t *testing.T) {
+	s := newFakeServer()
+	s.queries["select @@server_uuid"] = [][]string{{"@@server_uuid"}, {uuid1}}
+	bl, err := s.dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = bl.Close()
+	if got := bl.ServerUUID(); got != uuid1 {
+		t.Fatalf("ServerUUID: got %q, want %q", got, uuid1)
+	}
+	for _, expected := range []string{uuid1, uuid2} {
+		bl, err := DialWith(s, "tcp", "fake:3306")
+		if err != nil {
+			t.Fatal(err)
+		}
+		bl.SetExpectedServerUUID(expected)
+		err = bl.Authenticate(s.user, s.password)
+		_ = bl.Close()
+		if expected == uuid1 && err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := err.(*ServerUUIDError); expected == uuid2 && !ok {
+			t.Fatalf("got %v, want ServerUUIDError", err)
+		}
+	}
+}
+
+func TestRemote_ErrStreaming(t *testing.T) {
+	s := newFakeServer()
+	f := newBinlogStream()
+	f.XID(7)
+	s.addFile("binlog.000001", f)
+	bl, err := s.dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bl.Close()
+	if err := bl.Seek(0, "binlog.000001", 4); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := bl.MasterStatus(); err != ErrStreaming {
+		t.Fatal("MasterStatus: got", err, "want", ErrStreaming)
+	}
+	if err := bl.Seek(0, "binlog.000001", 4); err != ErrStreaming {
+		t.Fatal("Seek: got", err, "want", ErrStreaming)
+	}
+	for {
+		if _, err := bl.NextEvent(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, err := bl.MasterStatus(); err != nil {
+		t.Fatal("MasterStatus after stream ended:", err)
+	}
+}
+
+func TestRemote_ErrStreaming_terminal(t *testing.T) {
+	s := newFakeServer()
+	s.drop = true
+	f := newBinlogStream()
+	f.XID(7)
+	s.addFile("binlog.000001", f)
+	bl, err := s.dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bl.Close()
+	if err := bl.Seek(0, "binlog.000001", 4); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if _, err = bl.NextEvent(); err != nil {
+			break
+		}
+	}
+	if err == io.EOF {
+		t.Fatal("connection drop not detected")
+	}
+	if bl.isStreaming() {
+		t.Fatal("streaming not cleared on", err)
+	}
+	if bl.binlogReader == nil {
+		t.Fatal("binlogReader not used")
+	}
+	if err := bl.Seek(0, "binlog.000001", 4); err == ErrStreaming {
+		t.Fatal("Seek after connection drop:", err)
+	}
+	if bl.binlogReader != nil {
+		t.Fatal("binlogReader not reset by Seek")
+	}
+}
+
+func TestRemote_SetPrefetch(t *testing.T) {
+	s := newFakeServer()
+	f := newBinlogStream()
+	for i := uint64(1); i <= 5; i++ {
+		f.XID(i)
+	}
+	s.addFile("binlog.000001", f)
+	bl, err := s.dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bl.Close()
+	bl.SetPrefetch(2)
+	if err := bl.Seek(0, "binlog.000001", 4); err != nil {
+		t.Fatal(err)
+	}
+	var xids []uint64
+	for {
+		e, err := bl.NextEvent()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if d, ok := e.Data.(XIDEvent); ok {
+			xids = append(xids, d.XID)
+		}
+	}
+	if want := []uint64{1, 2, 3, 4, 5}; !reflect.DeepEqual(xids, want) {
+		t.Fatalf("got %v, want %v", xids, want)
+	}
+	if _, _, err := bl.MasterStatus(); err != nil {
+		t.Fatal("MasterStatus after stream ended:", err)
+	}
+	if bl.prefetch != nil {
+		t.Fatal("prefetcher not stopped")
+	}
+}
+
+func TestRemote_SetControlConn(t *testing.T) {
+	s := newFakeServer()
+	s.idle = make(chan struct{})
+	defer close(s.idle)
+	f := newBinlogStream()
+	f.XID(7)
+	s.addFile("binlog.000001", f)
+	bl, err := DialWith(s, "tcp", "fake:3306")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bl.Close()
+	bl.SetControlConn(true)
+	if err := bl.Authenticate(s.user, s.password); err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.Seek(1, "binlog.000001", 4); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bl.NextEvent(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := bl.MasterStatus(); err != nil {
+		t.Fatal("MasterStatus:", err)
+	}
+	if bl.control == nil {
+		t.Fatal("control connection not used")
+	}
+	if err := bl.SetHeartbeatPeriod(time.Second); err != ErrStreaming {
+		t.Fatal("SetHeartbeatPeriod: got", err, "want", ErrStreaming)
+	}
+	if _, err := bl.query("SET GLOBAL binlog_row_metadata = FULL"); err != nil {
+		t.Fatal("query over control connection:", err)
+	}
+
+	// broken control connection is redialed
+	_ = bl.control.conn.Close()
+	if _, err := bl.ListFiles(); err != nil {
+		t.Fatal("ListFiles after control connection broken:", err)
+	}
+
+	// stream is not disturbed
+	for {
+		e, err := bl.NextEvent()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Header.EventType == XID_EVENT {
+			break
+		}
+	}
+}
+
+// TestRemote_SetControlConn_concurrent queries from another goroutine,
+// while NextEvent blocks. Run with -race.
+func TestRemote_SetControlConn_concurrent(t *testing.T) {
+	s := newFakeServer()
+	s.idle = make(chan struct{})
+	f := newBinlogStream()
+	f.XID(7)
+	s.addFile("binlog.000001", f)
+	bl, err := DialWith(s, "tcp", "fake:3306")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bl.Close()
+	bl.SetControlConn(true)
+	if err := bl.Authenticate(s.user, s.password); err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.Seek(1, "binlog.000001", 4); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		for {
+			if _, err := bl.NextEvent(); err != nil {
+				done <- err
+				return
+			}
+		}
+	}()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := bl.ListFiles(); err != nil {
+				t.Error("ListFiles:", err)
+			}
+			if _, _, err := bl.MasterStatus(); err != nil {
+				t.Error("MasterStatus:", err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(s.idle)
+	if err := <-done; err != io.EOF {
+		t.Fatal("got", err, "want", io.EOF)
+	}
+}
+
+func TestRemote_SetDumpFileFuncs(t *testing.T) {
+	s := newFakeServer()
+	f1 := newBinlogStream()
+	f1.XID(7)
+	s.addFile("binlog.000001", f1)
+	f2 := newBinlogStream()
+	f2.XID(9)
+	s.addFile("binlog.000002", f2)
+
+	bl, err := s.dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bl.Close()
+	clock := &fakeClock{now: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}
+	bl.SetClock(clock)
+	var started []string
+	var completed []DumpedFile
+	bl.SetDumpFileFuncs(func(file string) {
+		started = append(started, file)
+		clock.now = clock.now.Add(time.Second)
+	}, func(f DumpedFile) error {
+		completed = append(completed, f)
+		return nil
+	})
+	if err := bl.Seek(0, "binlog.000001", 4); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := bl.Dump(dir); err != io.EOF {
+		t.Fatal("got", err, "want", io.EOF)
+	}
+
+	if want := []string{"binlog.000001", "binlog.000002"}; !reflect.DeepEqual(started, want) {
+		t.Fatalf("started: got %v, want %v", started, want)
+	}
+	sum := sha256.Sum256(f1.Bytes())
+	want := []DumpedFile{{"binlog.000001", int64(f1.Len()), hex.EncodeToString(sum[:]), time.Second}}
+	if !reflect.DeepEqual(completed, want) {
+		t.Fatalf("completed: got %+v, want %+v", completed, want)
+	}
+}
+
+func TestRemote_SetDumpIgnoredEvents(t *testing.T) {
+	s := newFakeServer()
+	f := newBinlogStream()
+	f.XID(7)
+	f.Event(ROWS_QUERY_EVENT, []byte{1, 'q'})
+	serverPos := f.Len() // after ignored event
+	f.XID(8)
+	f.XID(9)
+	s.addFile("binlog.000001", f)
+
+	// expected dump, without ROWS_QUERY_EVENT
+	want := newBinlogStream()
+	want.XID(7)
+	localPos := want.Len()
+	want.XID(8)
+	want.XID(9)
+
+	dir, err := ioutil.TempDir("", "dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "binlog.000001")
+
+	bl, err := s.dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, typ := range []EventType{ROTATE_EVENT, TABLE_MAP_EVENT, XID_EVENT} {
+		if err := bl.SetDumpIgnoredEvents([]EventType{typ}); err == nil {
+			t.Fatalf("ignoring %s: error expected", typ)
+		}
+	}
+	if err := bl.SetDumpIgnoredEvents([]EventType{ROWS_QUERY_EVENT}); err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.Seek(0, "binlog.000001", 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.Dump(dir); err != io.EOF {
+		t.Fatal("got", err, "want", io.EOF)
+	}
+	_ = bl.Close()
+	got, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want.Bytes()) {
+		t.Fatal("dumped file does not match")
+	}
+	progress, err := ioutil.ReadFile(filepath.Join(dir, ".progress"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprintf("binlog.000001 %d %d\n", want.Len(), f.Len()); string(progress) != want {
+		t.Fatalf("got progress %q, want %q", progress, want)
+	}
+
+	// resume after ignored event
+	p := fmt.Sprintf("binlog.000001 %d %d\n", localPos, serverPos)
+	if err := ioutil.WriteFile(filepath.Join(dir, ".progress"), []byte(p), 0666); err != nil {
+		t.Fatal(err)
+	}
+	bl, err = s.dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bl.Close()
+	if err := bl.SetDumpIgnoredEvents([]EventType{ROWS_QUERY_EVENT}); err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.ResumeDump(0, dir); err != io.EOF {
+		t.Fatal("got", err, "want", io.EOF)
+	}
+	if bl.requestPos != uint32(serverPos) {
+		t.Fatalf("resumed from %d, want %d", bl.requestPos, serverPos)
+	}
+	if got, err = ioutil.ReadFile(name); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want.Bytes()) {
+		t.Fatal("resumed dump does not match")
+	}
+}
+
+func TestRemote_SetDumpRedaction(t *testing.T) {
+	insert := func(s *binlogStream, tableID uint64, table string) {
+		s.TableMap(tableID, "test", table, []byte{byte(TypeLong)}, nil, []byte{0})
+		s.Rows(WRITE_ROWS_EVENTv2, tableID, true, 1, []byte{0, 1, 0, 0, 0})
+	}
+	s := newFakeServer()
+	f := newBinlogStream()
+	f.Query("test", "create table secret(id int)")
+	f.Query("test", "BEGIN")
+	f.Event(ROWS_QUERY_EVENT, []byte{1, 'q'})
+	insert(f, 1, "secret")
+	insert(f, 2, "t")
+	f.XID(7)
+	s.addFile("binlog.000001", f)
+
+	// expected dump, with query masked and table secret removed
+	want := newBinlogStream()
+	want.Query("test", "/* redacted */")
+	want.Query("test", "BEGIN")
+	insert(want, 2, "t")
+	want.XID(7)
+
+	dir, err := ioutil.TempDir("", "dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	bl, err := s.dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bl.Close()
+	bl.SetDumpRedaction(&DumpRedaction{
+		Tables: regexp.MustCompile(`^test\.secret$`),
+		Query:  func(schema, query string) string { return "/* redacted */" },
+	})
+	if err := bl.Seek(0, "binlog.000001", 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.Dump(dir); err != io.EOF {
+		t.Fatal("got", err, "want", io.EOF)
+	}
+	got, err := ioutil.ReadFile(filepath.Join(dir, "binlog.000001"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want.Bytes()) {
+		t.Fatal("dumped file does not match")
+	}
+	progress, err := ioutil.ReadFile(filepath.Join(dir, ".progress"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprintf("binlog.000001 %d %d\n", want.Len(), f.Len()); string(progress) != want {
+		t.Fatalf("got progress %q, want %q", progress, want)
+	}
+}
+
+func TestRemote_SetDumpRedaction_tables(t *testing.T) {
+	tableMap := func(s *binlogStream, tableID uint64, table string) {
+		s.TableMap(tableID, "test", table, []byte{byte(TypeLong)}, nil, []byte{0})
+	}
+	insert := func(s *binlogStream, tableID uint64, stmtEnd bool) {
+		s.Rows(WRITE_ROWS_EVENTv2, tableID, stmtEnd, 1, []byte{0, 1, 0, 0, 0})
+	}
+	s := newFakeServer()
+	f := newBinlogStream()
+	f.Query("test", "BEGIN")
+	f.Event(ROWS_QUERY_EVENT, []byte{1, 'q'})
+	f.Event(annotateRowsEvent, []byte("q"))
+	tableMap(f, 1, "t")
+	tableMap(f, 2, "secret")
+	insert(f, 1, false)
+	insert(f, 2, true) // end of statement
+	f.XID(7)
+	s.addFile("binlog.000001", f)
+
+	// expected dump, without query of rows events, and end of statement
+	// moved to rows event of table t
+	want := newBinlogStream()
+	want.Query("test", "BEGIN")
+	tableMap(want, 1, "t")
+	insert(want, 1, true)
+	want.XID(7)
+
+	dir, err := ioutil.TempDir("", "dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	bl, err := s.dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bl.Close()
+	bl.SetDumpRedaction(&DumpRedaction{Tables: regexp.MustCompile(`^test\.secret$`)})
+	if err := bl.Seek(0, "binlog.000001", 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.Dump(dir); err != io.EOF {
+		t.Fatal("got", err, "want", io.EOF)
+	}
+	got, err := ioutil.ReadFile(filepath.Join(dir, "binlog.000001"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want.Bytes()) {
+		t.Fatal("dumped file does not match")
+	}
+}
+
+func TestRemote_SetSessionVariable(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{"mariadb_slave_capability", 4, "SET @mariadb_slave_capability = '4'"},
+		{"slave_connect_state", "0-1-2", "SET @slave_connect_state = '0-1-2'"},
+		{"x", "'; DROP TABLE t; --", `SET @x = '\'; DROP TABLE t; --'`},
+		{"x", true, "SET @x = '1'"},
+		{"x", []byte{1, 2}, "SET @x = X'0102'"},
+		{"x=(select(sleep(1)))", 1, ""},
+		{"x y", 1, ""},
+		{"", 1, ""},
+		{"x", struct{}{}, ""},
+	}
+	for _, test := range tests {
+		got, err := setVariableQuery(test.name, test.value)
+		if test.want == "" {
+			if err == nil {
+				t.Errorf("%q = %v: got %q, want error", test.name, test.value, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q = %v: %v", test.name, test.value, err)
+		} else if got != test.want {
+			t.Errorf("%q = %v: got %q, want %q", test.name, test.value, got, test.want)
+		}
+	}
+
+	s := newFakeServer()
+	bl, err := s.dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bl.Close()
+	if err := bl.SetSessionVariable("slave_connect_state", "0-1-2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.SetSessionVariable("x;y", 1); err == nil {
+		t.Fatal("invalid name not rejected")
+	}
+}
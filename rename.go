@@ -0,0 +1,31 @@
+package binlog
+
+import "regexp"
+
+// TableRenameRule renames tables whose name matches Pattern,
+// to the expansion of Replacement, as in regexp.ReplaceAllString.
+//
+// Online schema change tools write changes into shadow table and then
+// rename it to the original table at cutover. Rename rules help
+// consumers see a stable logical table name across such cutovers.
+type TableRenameRule struct {
+	Pattern     *regexp.Regexp // matched against table name
+	Replacement string
+}
+
+// OSCRenameRules maps shadow tables of gh-ost(_tbl_gho) and
+// pt-online-schema-change(_tbl_new) to the original table tbl.
+var OSCRenameRules = []TableRenameRule{
+	{regexp.MustCompile(`^_(.+)_(gho|new)$`), "$1"},
+}
+
+// renameTable returns logical name of given table, by applying
+// first matching rule.
+func renameTable(rules []TableRenameRule, table string) string {
+	for _, rule := range rules {
+		if rule.Pattern.MatchString(table) {
+			return rule.Pattern.ReplaceAllString(table, rule.Replacement)
+		}
+	}
+	return table
+}
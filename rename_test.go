@@ -0,0 +1,56 @@
+package binlog
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestReader_SetTableRenameRules(t *testing.T) {
+	custom := []TableRenameRule{
+		{regexp.MustCompile(`^orders_\d+$`), "orders"},
+		{regexp.MustCompile(`^(.+)_v\d+$`), "$1"},
+	}
+	tests := []struct {
+		rules    []TableRenameRule
+		table    string
+		wantName string
+		wantOrig string
+	}{
+		{nil, "_users_gho", "_users_gho", ""},
+		{OSCRenameRules, "_users_gho", "users", "_users_gho"},
+		{OSCRenameRules, "_users_new", "users", "_users_new"},
+		{OSCRenameRules, "_users_del", "_users_del", ""},
+		{OSCRenameRules, "users", "users", ""},
+		{custom, "orders_2021", "orders", "orders_2021"},
+		{custom, "orders_v2", "orders", "orders_v2"}, // first matching rule wins
+		{custom, "items_v3", "items", "items_v3"},
+		{custom, "items", "items", ""},
+	}
+	for _, test := range tests {
+		s := newBinlogStream()
+		s.TableMap(101, "test", test.table, []byte{byte(TypeLong)}, nil, []byte{0x00})
+		s.Rows(WRITE_ROWS_EVENTv2, 101, true, 1, []byte{0, 1, 0, 0, 0})
+		r := NewReader(s)
+		r.SetTableRenameRules(test.rules)
+		check := func(kind string, tme *TableMapEvent) {
+			t.Helper()
+			if tme.SchemaName != "test" || tme.TableName != test.wantName || tme.OrigTableName != test.wantOrig {
+				t.Errorf("%s %s: got %s.%s orig %q, want test.%s orig %q", kind, test.table,
+					tme.SchemaName, tme.TableName, tme.OrigTableName, test.wantName, test.wantOrig)
+			}
+		}
+		for {
+			e, err := r.NextEvent()
+			if err != nil {
+				t.Fatalf("%s: %v", test.table, err)
+			}
+			if d, ok := e.Data.(TableMapEvent); ok {
+				check("TableMapEvent", &d)
+			}
+			if d, ok := e.Data.(RowsEvent); ok {
+				check("RowsEvent", d.TableMap)
+				break
+			}
+		}
+	}
+}
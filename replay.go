@@ -0,0 +1,42 @@
+package binlog
+
+import "time"
+
+// SetReplaySpeed paces NextEvent according to timestamps of events, to
+// replay the binlog with its original temporal pattern, for example to
+// load test consumers. speed 1 replays in real time, 10 replays ten
+// times faster. Zero, the default, returns events as fast as possible.
+//
+// The first event is returned immediately, and each subsequent event
+// no earlier than its time offset from the first event, divided by
+// speed. Events with earlier timestamp than previous are not delayed.
+// Delays use the Clock set by SetClock.
+func (bl *Local) SetReplaySpeed(speed float64) {
+	bl.pacer = pacer{speed: speed}
+}
+
+// pacer delays events according to their timestamps.
+type pacer struct {
+	speed float64
+	first uint32    // timestamp of first event. zero if not started
+	start time.Time // when first event was returned
+}
+
+func (p *pacer) wait(clock Clock, e Event) {
+	ts := e.Header.Timestamp
+	if p.speed <= 0 || ts == 0 {
+		return
+	}
+	clock = clockOrSystem(clock)
+	if p.first == 0 {
+		p.first, p.start = ts, clock.Now()
+		return
+	}
+	if ts <= p.first {
+		return
+	}
+	offset := time.Duration(float64(time.Duration(ts-p.first)*time.Second) / p.speed)
+	if d := p.start.Add(offset).Sub(clock.Now()); d > 0 {
+		clock.Sleep(d)
+	}
+}
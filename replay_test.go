@@ -0,0 +1,21 @@
+package binlog
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLocal_SetReplaySpeed(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}
+	bl := &Local{}
+	bl.SetClock(clock)
+	bl.SetReplaySpeed(2)
+	for _, ts := range []uint32{100, 0, 101, 101, 99, 105} {
+		bl.pacer.wait(bl.opts.clock, Event{Header: EventHeader{Timestamp: ts}})
+	}
+	want := []time.Duration{500 * time.Millisecond, 2 * time.Second}
+	if !reflect.DeepEqual(clock.sleeps, want) {
+		t.Fatalf("got %v, want %v", clock.sleeps, want)
+	}
+}
@@ -0,0 +1,107 @@
+package binlog
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RetentionWatchdog periodically compares checkpoint of a consumer
+// with the oldest binary log file available on the server, and alerts
+// when the margin drops below threshold, so that operators can act
+// before the server purges binlog needed to resume the consumer. The
+// margin is the files preceding checkpoint file on the server, as the
+// server purges whole files.
+//
+// Conn must be a connection used only by the watchdog, as it is used
+// from a separate goroutine. It must not be the streaming connection.
+type RetentionWatchdog struct {
+	Conn       *Remote
+	Checkpoint func() (file string, pos uint32) // position consumer resumes from
+	Interval   time.Duration                    // of checks. defaults to a minute
+
+	// Alert is called with status, if checkpoint is purged or margin is
+	// less than MinFiles or MinBytes, or with error if check failed.
+	// It is called from a separate goroutine.
+	Alert    func(status RetentionStatus, err error)
+	MinFiles int   // zero means not checked
+	MinBytes int64 // zero means not checked
+
+	Clock Clock // nil means SystemClock
+}
+
+// RetentionStatus is result of a check by RetentionWatchdog.
+type RetentionStatus struct {
+	File   string // checkpoint
+	Pos    uint32
+	Oldest string // oldest binary log file on server
+	Files  int    // number of files on server, before checkpoint file
+	Bytes  int64  // size of files on server, before checkpoint file
+	Purged bool   // checkpoint file is no longer on server
+}
+
+// Low tells whether margin of status is less than thresholds of w.
+func (w *RetentionWatchdog) Low(s RetentionStatus) bool {
+	return s.Purged || s.Files < w.MinFiles || s.Bytes < w.MinBytes
+}
+
+// Check returns current retention status of checkpoint.
+func (w *RetentionWatchdog) Check() (RetentionStatus, error) {
+	s := RetentionStatus{}
+	s.File, s.Pos = w.Checkpoint()
+	rows, err := w.Conn.queryRows(`show binary logs`)
+	if err != nil {
+		return s, err
+	}
+	if len(rows) > 0 {
+		s.Oldest, _ = rows[0][0].(string)
+	}
+	for _, row := range rows {
+		name, _ := row[0].(string)
+		if name == s.File {
+			return s, nil
+		}
+		size, err := strconv.ParseInt(fmt.Sprint(row[1]), 10, 64)
+		if err != nil {
+			return s, fmt.Errorf("binlog: invalid size of binary log %s: %v", name, err)
+		}
+		s.Files++
+		s.Bytes += size
+	}
+	s.Files, s.Bytes, s.Purged = 0, 0, true
+	return s, nil
+}
+
+// Start starts checking every Interval, in a separate goroutine.
+// The returned function stops the checks.
+func (w *RetentionWatchdog) Start() (stop func()) {
+	d := w.Interval
+	if d <= 0 {
+		d = time.Minute
+	}
+	var (
+		mu      sync.Mutex
+		stopped bool
+		t       Timer
+	)
+	mu.Lock()
+	defer mu.Unlock()
+	t = clockOrSystem(w.Clock).AfterFunc(d, func() {
+		s, err := w.Check()
+		if err != nil || w.Low(s) {
+			w.Alert(s, err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if !stopped {
+			t.Reset(d)
+		}
+	})
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		stopped = true
+		t.Stop()
+	}
+}
@@ -0,0 +1,61 @@
+package binlog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetentionWatchdog(t *testing.T) {
+	s := newFakeServer()
+	f1 := newBinlogStream()
+	f1.XID(7)
+	s.addFile("binlog.000002", f1)
+	f2 := newBinlogStream()
+	s.addFile("binlog.000003", f2)
+	bl, err := s.dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bl.Close()
+
+	checkpoint := "binlog.000003"
+	w := &RetentionWatchdog{
+		Conn:       bl,
+		Checkpoint: func() (string, uint32) { return checkpoint, 4 },
+		Interval:   10 * time.Millisecond,
+		MinFiles:   2,
+	}
+	got, err := w.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := RetentionStatus{File: "binlog.000003", Pos: 4, Oldest: "binlog.000002", Files: 1, Bytes: int64(f1.Len())}
+	if got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	if !w.Low(got) {
+		t.Fatal("margin of one file must be low")
+	}
+
+	checkpoint = "binlog.000001"
+	alerts := make(chan RetentionStatus, 10)
+	w.Alert = func(s RetentionStatus, err error) {
+		if err != nil {
+			t.Error(err)
+		}
+		select {
+		case alerts <- s:
+		default:
+		}
+	}
+	stop := w.Start()
+	defer stop()
+	select {
+	case s := <-alerts:
+		if !s.Purged || s.Oldest != "binlog.000002" {
+			t.Fatalf("got %+v, want purged", s)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no alert")
+	}
+}
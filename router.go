@@ -0,0 +1,184 @@
+package binlog
+
+import "io"
+
+// Position is a position in binlog files.
+type Position struct {
+	File string
+	Pos  uint32
+}
+
+// Less tells whether p is before q. File names are compared, assuming
+// they have same base name, as in "binlog.000012".
+func (p Position) Less(q Position) bool {
+	if len(p.File) != len(q.File) {
+		return len(p.File) < len(q.File)
+	}
+	if p.File != q.File {
+		return p.File < q.File
+	}
+	return p.Pos < q.Pos
+}
+
+// RoutedEvent is an event dispatched by Router, along with its rows.
+type RoutedEvent struct {
+	Event
+	Schema           string
+	Rows             [][]interface{} // rows of RowsEvent
+	RowsBeforeUpdate [][]interface{} // rows before update of RowsEvent
+}
+
+// SchemaHandler handles events of a schema, dispatched by Router.
+type SchemaHandler func(e RoutedEvent) error
+
+// Router splits a single stream into per-schema streams, so that
+// multi-tenant databases can be fanned out to per-tenant consumers,
+// without a source connection per tenant.
+//
+// RowsEvent and statements are dispatched to the handler of their
+// schema. DDL is dispatched to handler of each schema it affects.
+// The event ending a transaction, such as XIDEvent, is dispatched to
+// handler of each schema with changes in the transaction, so that
+// the handler can commit and checkpoint independently.
+//
+// To resume, Seek the source to the earliest of the checkpoints, and
+// set Checkpoints, so that events already handled are skipped for
+// each schema.
+type Router struct {
+	Src      EventSource
+	Handlers map[string]SchemaHandler // by schema name
+	Default  SchemaHandler            // for other schemas. nil drops them
+
+	// Checkpoints has position after last transaction handled, by
+	// schema name. Router updates it after transaction end is handled.
+	Checkpoints map[string]Position
+
+	touched map[string]bool // schemas with changes in transaction
+}
+
+// Run dispatches events, till Src or a handler returns error. io.EOF
+// from Src is returned as nil.
+func (r *Router) Run() error {
+	if r.Checkpoints == nil {
+		r.Checkpoints = make(map[string]Position)
+	}
+	r.touched = make(map[string]bool)
+	for {
+		e, err := r.Src.NextEvent()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := r.route(e); err != nil {
+			return err
+		}
+	}
+}
+
+func (r *Router) route(e Event) error {
+	if _, end := txBoundary(e); end {
+		for schema := range r.touched {
+			if err := r.dispatch(RoutedEvent{Event: e, Schema: schema}); err != nil {
+				return err
+			}
+			r.checkpoint(schema, e)
+		}
+		r.touched = make(map[string]bool)
+		return nil
+	}
+	var schemas []string
+	isDDL := false
+	switch d := e.Data.(type) {
+	case RowsEvent:
+		if d.TableMap != nil {
+			schemas = []string{d.TableMap.SchemaName}
+		}
+	case QueryEvent:
+		if begin, _ := txBoundary(e); begin {
+			return nil
+		}
+		if ddl, ok := d.DDL(); ok {
+			isDDL = true
+			for _, t := range ddl.Targets {
+				if !containsString(schemas, t.Schema) {
+					schemas = append(schemas, t.Schema)
+				}
+			}
+		} else {
+			schemas = []string{d.Schema}
+		}
+	}
+	if len(schemas) == 0 {
+		return nil
+	}
+	re := RoutedEvent{Event: e}
+	if _, ok := e.Data.(RowsEvent); ok {
+		for {
+			values, before, err := r.Src.NextRow()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			re.Rows = append(re.Rows, values)
+			re.RowsBeforeUpdate = append(re.RowsBeforeUpdate, before)
+		}
+	}
+	for _, schema := range schemas {
+		re.Schema = schema
+		if err := r.dispatch(re); err != nil {
+			return err
+		}
+		if isDDL {
+			// ddl is transaction by itself
+			r.checkpoint(schema, e)
+		} else {
+			r.touched[schema] = true
+		}
+	}
+	return nil
+}
+
+// dispatch calls handler of schema, unless the event is already
+// handled as per Checkpoints.
+func (r *Router) dispatch(e RoutedEvent) error {
+	h := r.handler(e.Schema)
+	if h == nil {
+		return nil
+	}
+	if cp, ok := r.Checkpoints[e.Schema]; ok && e.Header.LogFile != "" {
+		if !cp.Less(Position{e.Header.LogFile, e.Header.NextPos}) {
+			return nil
+		}
+	}
+	return h(e)
+}
+
+func (r *Router) handler(schema string) SchemaHandler {
+	if h, ok := r.Handlers[schema]; ok {
+		return h
+	}
+	return r.Default
+}
+
+func (r *Router) checkpoint(schema string, e Event) {
+	if e.Header.LogFile == "" || r.handler(schema) == nil {
+		return
+	}
+	p := Position{e.Header.LogFile, e.Header.NextPos}
+	if cp, ok := r.Checkpoints[schema]; !ok || cp.Less(p) {
+		r.Checkpoints[schema] = p
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
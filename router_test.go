@@ -0,0 +1,113 @@
+package binlog
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestRouter(t *testing.T) {
+	newSource := func() *fakeSource {
+		src := &fakeSource{}
+		pos := uint32(0)
+		h := func(typ EventType) EventHeader {
+			pos += 100
+			return EventHeader{EventType: typ, LogFile: "binlog.000001", NextPos: pos}
+		}
+		query := func(schema, q string) {
+			src.push(Event{Header: h(QUERY_EVENT), Data: QueryEvent{Schema: schema, Query: q}})
+		}
+		rows := func(schema, v string) {
+			cols := []Column{{Name: "v"}}
+			re := RowsEvent{
+				eventType: WRITE_ROWS_EVENTv2,
+				TableMap:  &TableMapEvent{SchemaName: schema, TableName: "t", Columns: cols},
+				columns:   [][]Column{cols, nil},
+			}
+			src.push(Event{Header: h(WRITE_ROWS_EVENTv2), Data: re}, [2][]interface{}{{v}})
+		}
+		xid := func() {
+			src.push(Event{Header: h(XID_EVENT), Data: XIDEvent{}})
+		}
+		query("t1", "BEGIN")
+		rows("t1", "a")
+		rows("t2", "b")
+		xid()
+		query("t1", "create table t3.x (id int)")
+		query("t1", "alter table t2.y add column c int")
+		query("t1", "BEGIN")
+		rows("t1", "c")
+		xid()
+		return src
+	}
+
+	var got []string
+	handler := func(e RoutedEvent) error {
+		s := fmt.Sprintf("%s:%d:", e.Schema, e.Header.NextPos)
+		switch d := e.Data.(type) {
+		case RowsEvent:
+			s += fmt.Sprint(e.Rows)
+		case QueryEvent:
+			s += d.Query
+		case XIDEvent:
+			s += "xid"
+		}
+		got = append(got, s)
+		return nil
+	}
+	r := &Router{
+		Src:      newSource(),
+		Handlers: map[string]SchemaHandler{"t1": handler, "t2": handler},
+	}
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"t1:200:[[a]]", "t2:300:[[b]]", "t1:400:xid", "t2:400:xid",
+		"t2:600:alter table t2.y add column c int",
+		"t1:800:[[c]]", "t1:900:xid",
+	}
+	sortTx := func(s []string) {
+		if len(s) > 3 && s[2] > s[3] { // order of schemas at commit is not defined
+			s[2], s[3] = s[3], s[2]
+		}
+	}
+	sortTx(got)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	wantCP := map[string]Position{"t1": {"binlog.000001", 900}, "t2": {"binlog.000001", 600}}
+	if !reflect.DeepEqual(r.Checkpoints, wantCP) {
+		t.Fatalf("got %v, want %v", r.Checkpoints, wantCP)
+	}
+
+	// resume
+	got = nil
+	r = &Router{
+		Src:         newSource(),
+		Handlers:    map[string]SchemaHandler{"t1": handler, "t2": handler},
+		Checkpoints: map[string]Position{"t1": {"binlog.000001", 400}, "t2": {"binlog.000001", 600}},
+	}
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"t1:800:[[c]]", "t1:900:xid"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestPosition_Less(t *testing.T) {
+	for _, tc := range []struct {
+		p, q Position
+		want bool
+	}{
+		{Position{"binlog.000001", 4}, Position{"binlog.000001", 5}, true},
+		{Position{"binlog.000001", 5}, Position{"binlog.000001", 5}, false},
+		{Position{"binlog.000002", 4}, Position{"binlog.000001", 500}, false},
+		{Position{"binlog.999999", 4}, Position{"binlog.1000000", 4}, true},
+	} {
+		if got := tc.p.Less(tc.q); got != tc.want {
+			t.Errorf("%v.Less(%v) = %v, want %v", tc.p, tc.q, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package binlog
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Hash returns sha256 of canonical encoding of the row, for use as
+// dedupe or idempotency key, and to compare rows across replicas.
+//
+// It is independent of decoding quirks: integers
+// are hashed by their bits in column width, so that signedness unknown
+// without binlog_row_metadata=FULL does not matter; string and []byte
+// values are hashed alike; ENUM and SET values are hashed by their
+// numeric values; and TIMESTAMP values are hashed in UTC, regardless
+// of local time zone. Columns are identified by ordinal, so names are
+// not required.
+func (r Row) Hash() [sha256.Size]byte {
+	h := sha256.New()
+	var buf []byte
+	for i, v := range r.Values {
+		ordinal := i
+		var col Column
+		if i < len(r.Columns) {
+			col = r.Columns[i]
+			ordinal = col.Ordinal
+		}
+		buf = appendUvarint(buf[:0], uint64(ordinal))
+		buf = appendCanonical(buf, col, v)
+		h.Write(buf)
+	}
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+// appendCanonical appends tagged, length prefixed encoding of v.
+func appendCanonical(buf []byte, col Column, v interface{}) []byte {
+	appendBytes := func(tag byte, b []byte) []byte {
+		buf = append(buf, tag)
+		buf = appendUvarint(buf, uint64(len(b)))
+		return append(buf, b...)
+	}
+	appendInt := func(tag byte, v uint64) []byte {
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], v)
+		return appendBytes(tag, b[:])
+	}
+	switch v := v.(type) {
+	case nil:
+		return append(buf, 'N')
+	case string:
+		return appendBytes('S', []byte(v))
+	case []byte:
+		return appendBytes('S', v)
+	case int8:
+		return appendInt('I', uint64(v)&intMask(col, 1))
+	case int16:
+		return appendInt('I', uint64(v)&intMask(col, 2))
+	case int32:
+		return appendInt('I', uint64(v)&intMask(col, 4))
+	case int64:
+		return appendInt('I', uint64(v))
+	case int:
+		return appendInt('I', uint64(v))
+	case uint8:
+		return appendInt('I', uint64(v))
+	case uint16:
+		return appendInt('I', uint64(v))
+	case uint32:
+		return appendInt('I', uint64(v))
+	case uint64:
+		return appendInt('I', v)
+	case float32:
+		return appendInt('F', math.Float64bits(float64(v)))
+	case float64:
+		return appendInt('F', math.Float64bits(v))
+	case Decimal:
+		return appendBytes('M', []byte(v))
+	case time.Time:
+		return appendBytes('T', []byte(v.UTC().Format(time.RFC3339Nano)))
+	case time.Duration:
+		return appendInt('D', uint64(v))
+	case ZeroDate:
+		return appendBytes('Z', []byte(v))
+	case Enum:
+		return appendInt('E', uint64(v.Val))
+	case Set:
+		return appendInt('E', v.Val)
+	case JSON:
+		return appendBytes('J', []byte(v.Raw()))
+	}
+	return appendBytes('?', []byte(fmt.Sprint(v)))
+}
+
+// intMask returns mask of column width, for integer decoded as signed
+// go type of n bytes.
+func intMask(col Column, n int) uint64 {
+	if col.Type == TypeInt24 {
+		n = 3
+	}
+	return 1<<(8*uint(n)) - 1
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	return append(buf, b[:binary.PutUvarint(b[:], v)]...)
+}
@@ -0,0 +1,43 @@
+package binlog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRow_Hash(t *testing.T) {
+	full := []Column{
+		{Ordinal: 0, Type: TypeTiny, Unsigned: true},
+		{Ordinal: 1, Type: TypeBlob, Charset: 255},
+		{Ordinal: 2, Type: TypeEnum, Values: []string{"a", "b"}},
+		{Ordinal: 3, Type: TypeTimestamp2},
+		{Ordinal: 4, Type: TypeInt24, Unsigned: true},
+	}
+	minimal := []Column{
+		{Ordinal: 0, Type: TypeTiny},
+		{Ordinal: 1, Type: TypeBlob},
+		{Ordinal: 2, Type: TypeEnum},
+		{Ordinal: 3, Type: TypeTimestamp2},
+		{Ordinal: 4, Type: TypeInt24},
+	}
+	ts := time.Unix(1600000000, 0)
+	a := Row{full, []interface{}{uint8(250), "text", Enum{2, full[2].Values}, ts.In(time.FixedZone("IST", 19800)), uint32(0xfffffe)}}
+	b := Row{minimal, []interface{}{int8(-6), []byte("text"), Enum{2, nil}, ts.UTC(), int32(-2)}}
+	if a.Hash() != b.Hash() {
+		t.Fatal("hash must be independent of decoding quirks")
+	}
+	for i, v := range []interface{}{int8(-5), "text2", Enum{1, nil}, ts.Add(time.Second), int32(-3)} {
+		c := Row{minimal, append([]interface{}(nil), b.Values...)}
+		c.Values[i] = v
+		if c.Hash() == b.Hash() {
+			t.Errorf("column %d: hash must change with value", i)
+		}
+	}
+	// null differs from empty string, and ordinals matter
+	if (Row{minimal[1:2], []interface{}{nil}}).Hash() == (Row{minimal[1:2], []interface{}{""}}).Hash() {
+		t.Fatal("null and empty string must differ")
+	}
+	if (Row{minimal[:1], []interface{}{int8(1)}}).Hash() == (Row{minimal[1:2], []interface{}{int8(1)}}).Hash() {
+		t.Fatal("ordinal must matter")
+	}
+}
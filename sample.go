@@ -0,0 +1,177 @@
+package binlog
+
+import (
+	"io"
+	"math/rand"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// SamplingRule samples rows of tables whose qualified name, such as
+// "db.tbl", matches Pattern. Rows not sampled are skipped without
+// decoding their values, reducing downstream volume for analytics
+// use cases.
+//
+// If Every is positive, first row and then every Nth row of each
+// table is kept. Otherwise each row is kept with probability Rate,
+// in range [0, 1]. Rate zero drops all rows of the table.
+type SamplingRule struct {
+	Pattern *regexp.Regexp
+	Rate    float64
+	Every   int
+}
+
+// sampler decides which rows are kept, by applying first matching
+// rule to the table of current RowsEvent.
+type sampler struct {
+	rules []SamplingRule
+
+	mu    sync.Mutex
+	rand  *rand.Rand
+	count map[string]int // rows seen so far, for rules with Every
+}
+
+func newSampler(rules []SamplingRule) *sampler {
+	if len(rules) == 0 {
+		return nil
+	}
+	return &sampler{
+		rules: rules,
+		rand:  rand.New(rand.NewSource(time.Now().UnixNano())),
+		count: make(map[string]int),
+	}
+}
+
+// rule returns the rule matching given table, or nil.
+func (s *sampler) rule(tme *TableMapEvent) *SamplingRule {
+	name := tme.SchemaName + "." + tme.TableName
+	for i := range s.rules {
+		if s.rules[i].Pattern.MatchString(name) {
+			return &s.rules[i]
+		}
+	}
+	return nil
+}
+
+// keep tells whether next row of the table is to be sampled.
+func (s *sampler) keep(tme *TableMapEvent, rule *SamplingRule) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if rule.Every > 0 {
+		name := tme.SchemaName + "." + tme.TableName
+		n := s.count[name]
+		s.count[name] = n + 1
+		return n%rule.Every == 0
+	}
+	return rule.Rate > 0 && (rule.Rate >= 1 || s.rand.Float64() < rule.Rate)
+}
+
+// skipUnsampled skips rows of current RowsEvent, that are not sampled.
+// Returns io.EOF if no rows remain.
+func skipUnsampled(r *reader) error {
+	if err := rowsRemaining(r); err != nil {
+		return err
+	}
+	s := r.opts.sampling
+	if s == nil {
+		return nil
+	}
+	rule := s.rule(r.tme)
+	if rule == nil {
+		return nil
+	}
+	if rule.Every <= 0 && rule.Rate <= 0 {
+		// remaining bytes are drained by next NextEvent
+		return io.EOF
+	}
+	n := 1
+	switch r.re.eventType {
+	case UPDATE_ROWS_EVENTv1, UPDATE_ROWS_EVENTv2:
+		n = 2
+	}
+	for !s.keep(r.tme, rule) {
+		for m := 0; m < n; m++ {
+			if err := skipRow(r, m); err != nil {
+				return err
+			}
+		}
+		if err := rowsRemaining(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skipRow skips values of row image m, without decoding them.
+func skipRow(r *reader, m int) error {
+	nullValue := r.nullBitmap(uint64(len(r.re.columns[m])))
+	if r.err != nil {
+		return r.err
+	}
+	for i, col := range r.re.columns[m] {
+		if !nullValue.isTrue(i) {
+			if err := col.skipValue(r); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// skipValue skips value of the column. It computes size of value from
+// type and meta, falling back to decodeValue for other types.
+func (col Column) skipValue(r *reader) error {
+	var size int
+	switch col.Type {
+	case TypeVarchar, TypeVarString, TypeString:
+		if col.Meta < 256 {
+			size = int(r.int1())
+		} else {
+			size = int(r.int2())
+		}
+	case TypeBlob, TypeGeometry, TypeJSON:
+		size = int(r.intFixed(int(col.Meta)))
+	default:
+		var ok bool
+		if size, ok = col.fixedSize(); !ok {
+			_, err := col.decodeValue(r)
+			return err
+		}
+	}
+	if r.err != nil {
+		return r.err
+	}
+	return r.skip(size)
+}
+
+// fixedSize returns size of value of the column, if it is determined
+// by type and meta alone.
+func (col Column) fixedSize() (int, bool) {
+	switch col.Type {
+	case TypeTiny, TypeYear:
+		return 1, true
+	case TypeShort:
+		return 2, true
+	case TypeInt24, TypeDate, TypeNewDate, TypeTime:
+		return 3, true
+	case TypeLong, TypeFloat, TypeTimestamp:
+		return 4, true
+	case TypeLongLong, TypeDouble, TypeDateTime:
+		return 8, true
+	case TypeNewDecimal:
+		return decimalSize(int(byte(col.Meta)), int(byte(col.Meta>>8))), true
+	case TypeEnum, TypeSet:
+		return int(col.Meta), true
+	case TypeBit:
+		nbits := ((col.Meta >> 8) * 8) + (col.Meta & 0xFF)
+		return int(nbits+7) / 8, true
+	case TypeDateTime2:
+		return 5 + int(col.Meta+1)/2, true
+	case TypeTimestamp2:
+		return 4 + int(col.Meta+1)/2, true
+	case TypeTime2:
+		return 3 + int(col.Meta+1)/2, true
+	}
+	return 0, false
+}
@@ -0,0 +1,85 @@
+package binlog
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"testing"
+)
+
+func TestReader_SetSampling(t *testing.T) {
+	w := newEncodedStream(4, true)
+	w.FormatDescription("8.0.23", 40, 1)
+	w.Query("test", "BEGIN")
+	w.TableMap(100, "test", "all_types", allTypesColumns, allTypesMeta, []byte{0xff, 0xff, 0x3f}, allTypesExtMeta()...)
+	w.Rows(WRITE_ROWS_EVENTv2, 100, true, len(allTypesColumns), allTypesRow, allTypesRow, allTypesNullRow, allTypesRow)
+	w.TableMap(101, "test", "t", []byte{byte(TypeLong)}, nil, []byte{0x01})
+	w.Rows(WRITE_ROWS_EVENTv2, 101, true, 1, []byte{0, 1, 0, 0, 0}, []byte{0, 2, 0, 0, 0})
+	w.XID(10)
+	stream := w.Bytes()
+
+	// read returns rows read, per table.
+	read := func(rules []SamplingRule, batch int) map[string][][]interface{} {
+		t.Helper()
+		bl := NewReader(bytes.NewReader(stream))
+		bl.SetSampling(rules)
+		got := map[string][][]interface{}{}
+		for {
+			e, err := bl.NextEvent()
+			if err == io.EOF {
+				return got
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			re, ok := e.Data.(RowsEvent)
+			if !ok {
+				continue
+			}
+			for {
+				var rows [][]interface{}
+				if batch > 0 {
+					rows, _, err = bl.NextRows(batch)
+				} else {
+					var values []interface{}
+					values, _, err = bl.NextRow()
+					rows = [][]interface{}{values}
+				}
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				got[re.TableMap.TableName] = append(got[re.TableMap.TableName], rows...)
+			}
+		}
+	}
+
+	all := read(nil, 0)
+	if len(all["all_types"]) != 4 || len(all["t"]) != 2 {
+		t.Fatalf("got %v", all)
+	}
+	for _, batch := range []int{0, 3} {
+		// second row with all types is skipped
+		got := read([]SamplingRule{{Pattern: regexp.MustCompile(`^test\.all_types$`), Every: 2}}, batch)
+		if n := len(got["all_types"]); n != 2 {
+			t.Fatalf("batch %d: got %d rows, want 2", batch, n)
+		}
+		if got["all_types"][1][0] != nil {
+			t.Fatalf("batch %d: got %v, want null row", batch, got["all_types"][1])
+		}
+		if n := len(got["t"]); n != 2 {
+			t.Fatalf("batch %d: got %d rows of unmatched table, want 2", batch, n)
+		}
+
+		got = read([]SamplingRule{{Pattern: regexp.MustCompile(`^test\.`), Rate: 0}}, batch)
+		if len(got) != 0 {
+			t.Fatalf("batch %d: got %v, want no rows", batch, got)
+		}
+		got = read([]SamplingRule{{Pattern: regexp.MustCompile(`^test\.t$`), Rate: 1}}, batch)
+		if len(got["all_types"]) != 4 || len(got["t"]) != 2 {
+			t.Fatalf("batch %d: got %v", batch, got)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package binlog
+
+import "strings"
+
+// SavepointOp is savepoint statement within a transaction.
+type SavepointOp uint8
+
+// SavepointOp constants.
+const (
+	SavepointSet      SavepointOp = iota + 1 // SAVEPOINT name
+	SavepointRollback                        // ROLLBACK TO SAVEPOINT name
+	SavepointRelease                         // RELEASE SAVEPOINT name
+)
+
+func (op SavepointOp) String() string {
+	switch op {
+	case SavepointSet:
+		return "set"
+	case SavepointRollback:
+		return "rollback"
+	case SavepointRelease:
+		return "release"
+	}
+	return "unknown"
+}
+
+// SavepointEvent is a synthetic event, returned along with boundary
+// events, right after QueryEvent with savepoint statement. It is never
+// written to binlog.
+//
+// ROLLBACK TO SAVEPOINT is logged only if the transaction changed
+// non-transactional tables. The rolled back changes to transactional
+// tables are discarded by the server, before writing to binlog, and
+// the logged changes to non-transactional tables are not undone. So
+// events before ROLLBACK TO SAVEPOINT are committed changes, and must
+// not be trimmed.
+type SavepointEvent struct {
+	Op   SavepointOp
+	Name string
+}
+
+// savepointEvent returns SavepointEvent for e, if it is QueryEvent
+// with savepoint statement.
+func savepointEvent(e Event) (SavepointEvent, bool) {
+	qe, ok := e.Data.(QueryEvent)
+	if !ok {
+		return SavepointEvent{}, false
+	}
+	toks := sqlTokens(qe.Query)
+	word := func(i int, s string) bool {
+		return i < len(toks) && !toks[i].quoted && strings.EqualFold(toks[i].text, s)
+	}
+	name := func(i int, op SavepointOp) (SavepointEvent, bool) {
+		if i != len(toks)-1 {
+			return SavepointEvent{}, false
+		}
+		return SavepointEvent{op, toks[i].text}, true
+	}
+	switch {
+	case word(0, "SAVEPOINT"):
+		return name(1, SavepointSet)
+	case word(0, "RELEASE") && word(1, "SAVEPOINT"):
+		return name(2, SavepointRelease)
+	case word(0, "ROLLBACK"):
+		i := 1
+		if word(i, "WORK") {
+			i++
+		}
+		if !word(i, "TO") {
+			return SavepointEvent{}, false
+		}
+		i++
+		if word(i, "SAVEPOINT") {
+			i++
+		}
+		return name(i, SavepointRollback)
+	}
+	return SavepointEvent{}, false
+}
+
+// queueSavepointEvent queues SavepointEvent for e, if boundary
+// events are enabled.
+func queueSavepointEvent(r *reader, e Event) {
+	if !r.opts.boundaryEvents {
+		return
+	}
+	if se, ok := savepointEvent(e); ok {
+		r.pending = append(r.pending, synthetic(e, se))
+	}
+}
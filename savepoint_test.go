@@ -0,0 +1,26 @@
+package binlog
+
+import "testing"
+
+func TestSavepointEvent(t *testing.T) {
+	tests := []struct {
+		q    string
+		want SavepointEvent
+		ok   bool
+	}{
+		{"SAVEPOINT s1", SavepointEvent{SavepointSet, "s1"}, true},
+		{"savepoint `my sp`", SavepointEvent{SavepointSet, "my sp"}, true},
+		{"ROLLBACK TO s1", SavepointEvent{SavepointRollback, "s1"}, true},
+		{"rollback work to savepoint s1", SavepointEvent{SavepointRollback, "s1"}, true},
+		{"RELEASE SAVEPOINT s1", SavepointEvent{SavepointRelease, "s1"}, true},
+		{"ROLLBACK", SavepointEvent{}, false},
+		{"SAVEPOINT", SavepointEvent{}, false},
+		{"insert into savepoint values(1)", SavepointEvent{}, false},
+	}
+	for _, tc := range tests {
+		got, ok := savepointEvent(Event{Data: QueryEvent{Query: tc.q}})
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("%q: got %v %v, want %v %v", tc.q, got, ok, tc.want, tc.ok)
+		}
+	}
+}
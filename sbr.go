@@ -0,0 +1,348 @@
+package binlog
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// QueryStatus has status variables of QueryEvent, which capture
+// the session state in which the query was executed.
+//
+// https://dev.mysql.com/doc/internals/en/query-event.html#q-flags2-code
+type QueryStatus struct {
+	Flags2  *uint32 // nil if absent
+	SQLMode *uint64 // nil if absent
+
+	// following are zero if absent
+	AutoIncrementIncrement uint16
+	AutoIncrementOffset    uint16
+	CharsetClient          uint16
+	CollationConnection    uint16
+	CollationServer        uint16
+	TimeZone               string
+	LCTimeNames            uint16
+	CollationDatabase      uint16
+	Microseconds           uint32 // fractional part of query start time
+}
+
+// status variable codes.
+const (
+	qFlags2Code                   = 0
+	qSQLModeCode                  = 1
+	qCatalogCode                  = 2
+	qAutoIncrement                = 3
+	qCharsetCode                  = 4
+	qTimeZoneCode                 = 5
+	qCatalogNZCode                = 6
+	qLCTimeNamesCode              = 7
+	qCharsetDatabaseCode          = 8
+	qTableMapForUpdateCode        = 9
+	qMasterDataWrittenCode        = 10
+	qInvoker                      = 11
+	qUpdatedDBNames               = 12
+	qMicroseconds                 = 13
+	qExplicitDefaultsForTimestamp = 16
+	qDDLLoggedWithXID             = 17
+	qDefaultCollationForUTF8MB4   = 18
+	qSQLRequirePrimaryKey         = 19
+	qDefaultTableEncryption       = 20
+	qHRNow                        = 128 // MariaDB
+	qXID                          = 129 // MariaDB
+
+	overMaxDBsInEventMTS = 254 // in qUpdatedDBNames
+)
+
+// bits in Flags2.
+const (
+	optionAutoIsNull          = 1 << 14
+	optionNotAutocommit       = 1 << 19
+	optionNoForeignKeyChecks  = 1 << 26
+	optionRelaxedUniqueChecks = 1 << 27
+)
+
+// UserVarEvent.Type values.
+const (
+	userVarStringResult  = 0
+	userVarRealResult    = 1
+	userVarIntResult     = 2
+	userVarDecimalResult = 4
+)
+
+// IntVarEvent.Type values.
+const (
+	intVarLastInsertID = 1
+	intVarInsertID     = 2
+)
+
+// Status decodes status variables of this query. Decoding stops
+// at the first unknown status variable.
+func (e QueryEvent) Status() (QueryStatus, error) {
+	var s QueryStatus
+	b := e.StatusVars
+	need := func(n int) bool { return len(b) >= n }
+	for len(b) > 0 {
+		code := b[0]
+		b = b[1:]
+		switch code {
+		case qFlags2Code:
+			if !need(4) {
+				return s, ErrMalformedPacket
+			}
+			v := binary.LittleEndian.Uint32(b)
+			s.Flags2, b = &v, b[4:]
+		case qSQLModeCode:
+			if !need(8) {
+				return s, ErrMalformedPacket
+			}
+			v := binary.LittleEndian.Uint64(b)
+			s.SQLMode, b = &v, b[8:]
+		case qCatalogCode:
+			if !need(1) || !need(int(b[0])+2) {
+				return s, ErrMalformedPacket
+			}
+			b = b[int(b[0])+2:]
+		case qAutoIncrement:
+			if !need(4) {
+				return s, ErrMalformedPacket
+			}
+			s.AutoIncrementIncrement = binary.LittleEndian.Uint16(b)
+			s.AutoIncrementOffset = binary.LittleEndian.Uint16(b[2:])
+			b = b[4:]
+		case qCharsetCode:
+			if !need(6) {
+				return s, ErrMalformedPacket
+			}
+			s.CharsetClient = binary.LittleEndian.Uint16(b)
+			s.CollationConnection = binary.LittleEndian.Uint16(b[2:])
+			s.CollationServer = binary.LittleEndian.Uint16(b[4:])
+			b = b[6:]
+		case qTimeZoneCode, qCatalogNZCode:
+			if !need(1) || !need(int(b[0])+1) {
+				return s, ErrMalformedPacket
+			}
+			if code == qTimeZoneCode {
+				s.TimeZone = string(b[1 : 1+b[0]])
+			}
+			b = b[int(b[0])+1:]
+		case qLCTimeNamesCode, qCharsetDatabaseCode, qDefaultCollationForUTF8MB4:
+			if !need(2) {
+				return s, ErrMalformedPacket
+			}
+			switch code {
+			case qLCTimeNamesCode:
+				s.LCTimeNames = binary.LittleEndian.Uint16(b)
+			case qCharsetDatabaseCode:
+				s.CollationDatabase = binary.LittleEndian.Uint16(b)
+			}
+			b = b[2:]
+		case qTableMapForUpdateCode, qDDLLoggedWithXID, qXID:
+			if !need(8) {
+				return s, ErrMalformedPacket
+			}
+			b = b[8:]
+		case qMasterDataWrittenCode:
+			if !need(4) {
+				return s, ErrMalformedPacket
+			}
+			b = b[4:]
+		case qInvoker:
+			for i := 0; i < 2; i++ { // user, host
+				if !need(1) || !need(int(b[0])+1) {
+					return s, ErrMalformedPacket
+				}
+				b = b[int(b[0])+1:]
+			}
+		case qUpdatedDBNames:
+			if !need(1) {
+				return s, ErrMalformedPacket
+			}
+			n := int(b[0])
+			b = b[1:]
+			if n == overMaxDBsInEventMTS {
+				n = 0
+			}
+			for i := 0; i < n; i++ {
+				j := strings.IndexByte(string(b), 0)
+				if j == -1 {
+					return s, ErrMalformedPacket
+				}
+				b = b[j+1:]
+			}
+		case qMicroseconds, qHRNow:
+			if !need(3) {
+				return s, ErrMalformedPacket
+			}
+			s.Microseconds = uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16
+			b = b[3:]
+		case qExplicitDefaultsForTimestamp, qSQLRequirePrimaryKey, qDefaultTableEncryption:
+			if !need(1) {
+				return s, ErrMalformedPacket
+			}
+			b = b[1:]
+		default:
+			return s, nil
+		}
+	}
+	return s, nil
+}
+
+// SQLStatementEvent is a synthetic event, returned right after QueryEvent,
+// when statement mode is enabled. It is never written to binlog.
+//
+// Statements has the query, preceded by statements to recreate the
+// session context in which the query was executed, i.e. timestamp,
+// session variables, default schema, and the values of INSERT_ID,
+// LAST_INSERT_ID, RAND seeds and user variables from IntVarEvent,
+// RandEvent and UserVarEvent that preceded the query. Executing them
+// in order on another server, replays the statement.
+type SQLStatementEvent struct {
+	Statements []string
+}
+
+// stmtContext collects context events preceding QueryEvent.
+type stmtContext struct {
+	vars []string
+}
+
+// track updates statement context with event e, and queues
+// SQLStatementEvent into r.pending for QueryEvent.
+func (c *stmtContext) track(r *reader, e Event) error {
+	switch d := e.Data.(type) {
+	case IntVarEvent:
+		if stmt := d.SQL(); stmt != "" {
+			c.vars = append(c.vars, stmt)
+		}
+	case RandEvent:
+		c.vars = append(c.vars, d.SQL())
+	case UserVarEvent:
+		v, err := d.literal()
+		if err != nil {
+			return err
+		}
+		c.vars = append(c.vars, fmt.Sprintf("SET @%s:=%s", quoteIdent(d.Name), v))
+	case QueryEvent:
+		status, err := d.Status()
+		if err != nil {
+			return err
+		}
+		stmts := []string{fmt.Sprintf("SET TIMESTAMP=%d", e.Header.Timestamp)}
+		if status.Microseconds > 0 {
+			stmts[0] = fmt.Sprintf("SET TIMESTAMP=%d.%06d", e.Header.Timestamp, status.Microseconds)
+		}
+		stmts = append(stmts, status.sessionStatements()...)
+		if d.Schema != "" {
+			stmts = append(stmts, "USE "+quoteIdent(d.Schema))
+		}
+		stmts = append(stmts, c.vars...)
+		stmts = append(stmts, d.Query)
+		c.vars = nil
+		r.pending = append(r.pending, synthetic(e, SQLStatementEvent{stmts}))
+	}
+	return nil
+}
+
+// SQL returns statement setting the value for next statement, i.e.
+// "SET INSERT_ID=n" or "SET LAST_INSERT_ID=n". Executing it before
+// the next QueryEvent on another server, reproduces AUTO_INCREMENT
+// values and LAST_INSERT_ID(). It returns empty string for unknown
+// Type.
+func (e IntVarEvent) SQL() string {
+	switch e.Type {
+	case intVarLastInsertID:
+		return fmt.Sprintf("SET LAST_INSERT_ID=%d", e.Value)
+	case intVarInsertID:
+		return fmt.Sprintf("SET INSERT_ID=%d", e.Value)
+	}
+	return ""
+}
+
+// SQL returns statement setting the seeds for next statement, i.e.
+// "SET @@RAND_SEED1=s1, @@RAND_SEED2=s2". Executing it before the
+// next QueryEvent on another server, reproduces results of RAND().
+func (e RandEvent) SQL() string {
+	return fmt.Sprintf("SET @@RAND_SEED1=%d, @@RAND_SEED2=%d", e.Seed1, e.Seed2)
+}
+
+// sessionStatements returns SET statements to recreate session state.
+func (s QueryStatus) sessionStatements() []string {
+	var stmts []string
+	if s.Flags2 != nil {
+		bit := func(mask uint32) int {
+			if *s.Flags2&mask != 0 {
+				return 1
+			}
+			return 0
+		}
+		stmts = append(stmts, fmt.Sprintf("SET @@session.foreign_key_checks=%d, @@session.sql_auto_is_null=%d, @@session.unique_checks=%d, @@session.autocommit=%d",
+			1-bit(optionNoForeignKeyChecks), bit(optionAutoIsNull), 1-bit(optionRelaxedUniqueChecks), 1-bit(optionNotAutocommit)))
+	}
+	if s.SQLMode != nil {
+		stmts = append(stmts, fmt.Sprintf("SET @@session.sql_mode=%d", *s.SQLMode))
+	}
+	if s.AutoIncrementIncrement != 0 {
+		stmts = append(stmts, fmt.Sprintf("SET @@session.auto_increment_increment=%d, @@session.auto_increment_offset=%d", s.AutoIncrementIncrement, s.AutoIncrementOffset))
+	}
+	if s.CharsetClient != 0 {
+		stmts = append(stmts, fmt.Sprintf("SET @@session.character_set_client=%d, @@session.collation_connection=%d, @@session.collation_server=%d", s.CharsetClient, s.CollationConnection, s.CollationServer))
+	}
+	if s.TimeZone != "" {
+		stmts = append(stmts, "SET @@session.time_zone="+sqlQuote(s.TimeZone))
+	}
+	if s.LCTimeNames != 0 {
+		stmts = append(stmts, fmt.Sprintf("SET @@session.lc_time_names=%d", s.LCTimeNames))
+	}
+	if s.CollationDatabase != 0 {
+		stmts = append(stmts, fmt.Sprintf("SET @@session.collation_database=%d", s.CollationDatabase))
+	}
+	return stmts
+}
+
+// literal returns value of user variable as SQL literal.
+// string values are written as hex literals.
+func (e UserVarEvent) literal() (string, error) {
+	if e.Null {
+		return "NULL", nil
+	}
+	switch e.Type {
+	case userVarStringResult:
+		if len(e.Value) == 0 {
+			return "''", nil
+		}
+		return "X'" + strings.ToUpper(hex.EncodeToString(e.Value)) + "'", nil
+	case userVarRealResult:
+		if len(e.Value) < 8 {
+			return "", ErrMalformedPacket
+		}
+		f := math.Float64frombits(binary.LittleEndian.Uint64(e.Value))
+		return strconv.FormatFloat(f, 'g', -1, 64), nil
+	case userVarIntResult:
+		if len(e.Value) < 8 {
+			return "", ErrMalformedPacket
+		}
+		v := binary.LittleEndian.Uint64(e.Value)
+		if e.Unsigned {
+			return strconv.FormatUint(v, 10), nil
+		}
+		return strconv.FormatInt(int64(v), 10), nil
+	case userVarDecimalResult:
+		if len(e.Value) < 2 {
+			return "", ErrMalformedPacket
+		}
+		precision, scale := int(e.Value[0]), int(e.Value[1])
+		if len(e.Value)-2 < decimalSize(precision, scale) {
+			return "", ErrMalformedPacket
+		}
+		d, err := decodeDecimal(e.Value[2:], precision, scale)
+		return string(d), err
+	}
+	return "", fmt.Errorf("binlog: unsupported user variable type %d", e.Type)
+}
+
+// quoteIdent quotes identifier with backticks.
+func quoteIdent(s string) string {
+	return "`" + strings.Replace(s, "`", "``", -1) + "`"
+}
@@ -0,0 +1,77 @@
+package binlog
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStmtContext(t *testing.T) {
+	status := []byte{
+		qFlags2Code, 0x00, 0x00, 0x00, 0x04, // NO_FOREIGN_KEY_CHECKS
+		qSQLModeCode, 0x20, 0x00, 0xa0, 0x55, 0x00, 0x00, 0x00, 0x00,
+		qCatalogNZCode, 3, 's', 't', 'd',
+		qCharsetCode, 0xff, 0x00, 0xff, 0x00, 0x08, 0x00,
+		qTimeZoneCode, 6, 'S', 'Y', 'S', 'T', 'E', 'M',
+		qUpdatedDBNames, 1, 'd', 'b', 0,
+		qMicroseconds, 0x40, 0xe2, 0x01,
+	}
+	r := &reader{opts: &decodeOptions{}}
+	var c stmtContext
+	events := []Event{
+		{Data: IntVarEvent{Type: intVarInsertID, Value: 5}},
+		{Data: RandEvent{Seed1: 1, Seed2: 2}},
+		{Data: UserVarEvent{Name: "a", Type: userVarIntResult, Value: []byte{0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}}},
+		{Data: UserVarEvent{Name: "b", Type: userVarStringResult, Value: []byte("x'")}},
+		{Data: UserVarEvent{Name: "c", Null: true}},
+		{Header: EventHeader{Timestamp: 1613335032}, Data: QueryEvent{Schema: "db", StatusVars: status, Query: "insert into t values(@a, @b, @c, rand())"}},
+	}
+	for _, e := range events {
+		if err := c.track(r, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(r.pending) != 1 {
+		t.Fatal("got", len(r.pending), "pending events, want", 1)
+	}
+	got := r.pending[0].Data.(SQLStatementEvent).Statements
+	want := []string{
+		"SET TIMESTAMP=1613335032.123456",
+		"SET @@session.foreign_key_checks=0, @@session.sql_auto_is_null=0, @@session.unique_checks=1, @@session.autocommit=1",
+		"SET @@session.sql_mode=1436549152",
+		"SET @@session.character_set_client=255, @@session.collation_connection=255, @@session.collation_server=8",
+		"SET @@session.time_zone='SYSTEM'",
+		"USE `db`",
+		"SET INSERT_ID=5",
+		"SET @@RAND_SEED1=1, @@RAND_SEED2=2",
+		"SET @`a`:=-2",
+		"SET @`b`:=X'7827'",
+		"SET @`c`:=NULL",
+		"insert into t values(@a, @b, @c, rand())",
+	}
+	if !reflect.DeepEqual(got, want) {
+		for i := range got {
+			t.Log(got[i])
+		}
+		t.Fatal("statements did not match")
+	}
+	if len(c.vars) != 0 {
+		t.Fatal("context must be reset after QueryEvent")
+	}
+}
+
+func TestIntVarEvent_SQL(t *testing.T) {
+	testCases := []struct {
+		e    interface{ SQL() string }
+		want string
+	}{
+		{IntVarEvent{Type: intVarInsertID, Value: 10}, "SET INSERT_ID=10"},
+		{IntVarEvent{Type: intVarLastInsertID, Value: 7}, "SET LAST_INSERT_ID=7"},
+		{IntVarEvent{Type: 9, Value: 7}, ""},
+		{RandEvent{Seed1: 123, Seed2: 456}, "SET @@RAND_SEED1=123, @@RAND_SEED2=456"},
+	}
+	for _, tc := range testCases {
+		if got := tc.e.SQL(); got != tc.want {
+			t.Errorf("%#v: got %q, want %q", tc.e, got, tc.want)
+		}
+	}
+}
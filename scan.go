@@ -0,0 +1,278 @@
+package binlog
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Row is a row of RowsEvent, along with its columns.
+//
+//	row := binlog.Row{Columns: re.Columns(), Values: values}
+//	before := binlog.Row{Columns: re.ColumnsBeforeUpdate(), Values: valuesBeforeUpdate}
+type Row struct {
+	Columns []Column
+	Values  []interface{}
+}
+
+// Scan copies the values in the row into the values pointed at by dest,
+// like sql.Rows.Scan. The number of values in dest must be the same
+// as the number of values in row. nil in dest skips the value.
+//
+// Besides the type of value, dest can be a pointer to any integer,
+// float, string, []byte or bool type, if the value can be converted
+// to it without loss. Pointer to pointer is set to nil for NULL value.
+// sql.Scanner is called with value converted to one of int64, float64,
+// bool, []byte, string, time.Time or nil.
+func (r Row) Scan(dest ...interface{}) error {
+	if len(dest) != len(r.Values) {
+		return fmt.Errorf("binlog: expected %d destination arguments in Scan, not %d", len(r.Values), len(dest))
+	}
+	for i, d := range dest {
+		if d == nil {
+			continue
+		}
+		if err := convertAssign(d, r.Values[i]); err != nil {
+			return r.scanError(i, err)
+		}
+	}
+	return nil
+}
+
+// ScanStruct copies the values in the row into the fields of the struct
+// pointed at by dest. Fields are matched with column names, using tag
+// `binlog:"col_name"` if present, otherwise field name. Tag "-" skips
+// the field. Names are matched case insensitively. Columns without
+// matching field and fields without matching column are ignored.
+//
+// Column names are available only if binlog_row_metadata=FULL.
+func (r Row) ScanStruct(dest interface{}) error {
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("binlog: ScanStruct expects pointer to struct, not %T", dest)
+	}
+	if len(r.Columns) != len(r.Values) {
+		return fmt.Errorf("binlog: row has %d columns and %d values", len(r.Columns), len(r.Values))
+	}
+	for _, col := range r.Columns {
+		if col.Name == "" {
+			return errNoColumnNames
+		}
+	}
+	sv := rv.Elem()
+	st := sv.Type()
+	for i := 0; i < st.NumField(); i++ {
+		f := st.Field(i)
+		if f.PkgPath != "" { // unexported
+			continue
+		}
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("binlog"); ok {
+			if tag == "-" {
+				continue
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+		for j, col := range r.Columns {
+			if strings.EqualFold(col.Name, name) {
+				if err := convertAssign(sv.Field(i).Addr().Interface(), r.Values[j]); err != nil {
+					return r.scanError(j, err)
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func (r Row) scanError(i int, err error) error {
+	if i < len(r.Columns) && r.Columns[i].Name != "" {
+		return fmt.Errorf("binlog: scan column %q: %v", r.Columns[i].Name, err)
+	}
+	return fmt.Errorf("binlog: scan column %d: %v", i, err)
+}
+
+var errNoColumnNames = errors.New("binlog: column names not available. set binlog_row_metadata=FULL")
+
+var (
+	scannerType  = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// convertAssign assigns src to value pointed by dest.
+func convertAssign(dest, src interface{}) error {
+	if s, ok := dest.(sql.Scanner); ok {
+		return s.Scan(driverValue(src))
+	}
+	dv := reflect.ValueOf(dest)
+	if dv.Kind() != reflect.Ptr || dv.IsNil() {
+		return fmt.Errorf("destination %T is not a non-nil pointer", dest)
+	}
+	return assign(dv.Elem(), src)
+}
+
+func assign(dv reflect.Value, src interface{}) error {
+	if src == nil {
+		switch dv.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+			dv.Set(reflect.Zero(dv.Type()))
+			return nil
+		}
+		return fmt.Errorf("cannot scan NULL into %s", dv.Type())
+	}
+	if reflect.PtrTo(dv.Type()).Implements(scannerType) {
+		return dv.Addr().Interface().(sql.Scanner).Scan(driverValue(src))
+	}
+	sv := reflect.ValueOf(src)
+	if sv.Type().AssignableTo(dv.Type()) {
+		if b, ok := src.([]byte); ok {
+			src = append([]byte(nil), b...)
+		}
+		dv.Set(reflect.ValueOf(src))
+		return nil
+	}
+	if dv.Kind() == reflect.Ptr {
+		v := reflect.New(dv.Type().Elem())
+		if err := assign(v.Elem(), src); err != nil {
+			return err
+		}
+		dv.Set(v)
+		return nil
+	}
+	if dv.Type() == durationType {
+		return fmt.Errorf("cannot scan %T into %s", src, dv.Type())
+	}
+
+	switch dv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch sv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if !dv.OverflowInt(sv.Int()) {
+				dv.SetInt(sv.Int())
+				return nil
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if u := sv.Uint(); int64(u) >= 0 && !dv.OverflowInt(int64(u)) {
+				dv.SetInt(int64(u))
+				return nil
+			}
+		}
+		return fmt.Errorf("cannot scan %T(%v) into %s", src, src, dv.Type())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		switch sv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if i := sv.Int(); i >= 0 && !dv.OverflowUint(uint64(i)) {
+				dv.SetUint(uint64(i))
+				return nil
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if !dv.OverflowUint(sv.Uint()) {
+				dv.SetUint(sv.Uint())
+				return nil
+			}
+		}
+		return fmt.Errorf("cannot scan %T(%v) into %s", src, src, dv.Type())
+	case reflect.Float32, reflect.Float64:
+		switch sv.Kind() {
+		case reflect.Float32, reflect.Float64:
+			dv.SetFloat(sv.Float())
+			return nil
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			dv.SetFloat(float64(sv.Int()))
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			dv.SetFloat(float64(sv.Uint()))
+			return nil
+		}
+		if d, ok := src.(Decimal); ok {
+			f, err := d.Float64()
+			if err != nil {
+				return err
+			}
+			dv.SetFloat(f)
+			return nil
+		}
+	case reflect.Bool:
+		switch sv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			dv.SetBool(sv.Int() != 0)
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			dv.SetBool(sv.Uint() != 0)
+			return nil
+		}
+	case reflect.String:
+		switch src := src.(type) {
+		case []byte:
+			dv.SetString(string(src))
+			return nil
+		case JSON:
+			dv.SetString(src.Raw())
+			return nil
+		case fmt.Stringer:
+			dv.SetString(src.String())
+			return nil
+		}
+		switch sv.Kind() {
+		case reflect.String:
+			dv.SetString(sv.String())
+			return nil
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			dv.SetString(fmt.Sprint(src))
+			return nil
+		}
+	case reflect.Slice:
+		if dv.Type().Elem().Kind() == reflect.Uint8 {
+			switch src := src.(type) {
+			case string:
+				dv.SetBytes([]byte(src))
+				return nil
+			case JSON:
+				dv.SetBytes([]byte(src.Raw()))
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("cannot scan %T into %s", src, dv.Type())
+}
+
+// driverValue converts v to a value accepted by sql.Scanner.
+func driverValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil, int64, float64, bool, []byte, string, time.Time:
+		return v
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case int:
+		return int64(v)
+	case uint8:
+		return int64(v)
+	case uint16:
+		return int64(v)
+	case uint32:
+		return int64(v)
+	case uint64:
+		if int64(v) >= 0 {
+			return int64(v)
+		}
+		return fmt.Sprint(v)
+	case float32:
+		return float64(v)
+	case JSON:
+		return []byte(v.Raw())
+	case fmt.Stringer: // Decimal, Enum, Set, time.Duration
+		return v.String()
+	}
+	return fmt.Sprint(v)
+}
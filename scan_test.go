@@ -0,0 +1,90 @@
+package binlog
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRow_Scan(t *testing.T) {
+	dt := time.Date(2021, 2, 14, 20, 37, 12, 0, time.UTC)
+	row := Row{
+		Values: []interface{}{
+			int32(-23), uint64(7), Decimal("12.50"), "alice", []byte("hi"),
+			dt, nil, Enum{2, []string{"a", "b"}}, nil, int8(1),
+		},
+	}
+	var (
+		i    int64
+		u    uint8
+		f    float64
+		s    string
+		b    []byte
+		tm   time.Time
+		np   *int
+		e    string
+		ns   sql.NullString
+		flag bool
+	)
+	if err := row.Scan(&i, &u, &f, &s, &b, &tm, &np, &e, &ns, &flag); err != nil {
+		t.Fatal(err)
+	}
+	if i != -23 || u != 7 || f != 12.5 || s != "alice" || string(b) != "hi" || !tm.Equal(dt) || np != nil || e != "b" || ns.Valid || !flag {
+		t.Fatalf("got %v %v %v %v %q %v %v %v %v %v", i, u, f, s, b, tm, np, e, ns, flag)
+	}
+	row.Values[4].([]byte)[0] = 'X'
+	if string(b) != "hi" {
+		t.Fatal("[]byte not copied")
+	}
+
+	// errors
+	if err := row.Scan(&i); err == nil {
+		t.Fatal("error expected for wrong number of args")
+	}
+	var i8 int8
+	if err := (Row{Values: []interface{}{int32(300)}}).Scan(&i8); err == nil {
+		t.Fatal("error expected for overflow")
+	}
+	if err := (Row{Values: []interface{}{int32(-1)}}).Scan(&u); err == nil {
+		t.Fatal("error expected for negative into unsigned")
+	}
+	if err := (Row{Values: []interface{}{nil}}).Scan(&i); err == nil {
+		t.Fatal("error expected for NULL into int64")
+	}
+}
+
+func TestRow_ScanStruct(t *testing.T) {
+	type user struct {
+		ID      int64  `binlog:"id"`
+		Name    string `binlog:"user_name"`
+		Email   *string
+		Ignored string `binlog:"-"`
+		Missing int
+		private int
+	}
+	row := Row{
+		Columns: []Column{{Name: "id"}, {Name: "user_name"}, {Name: "EMAIL"}, {Name: "ignored"}, {Name: "extra"}},
+		Values:  []interface{}{int32(5), "bob", "bob@x.com", "zzz", 1.5},
+	}
+	var got user
+	if err := row.ScanStruct(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != 5 || got.Name != "bob" || got.Email == nil || *got.Email != "bob@x.com" || got.Ignored != "" || got.Missing != 0 || got.private != 0 {
+		t.Fatalf("got %+v", got)
+	}
+
+	if err := row.ScanStruct(got); err == nil {
+		t.Fatal("error expected for non-pointer")
+	}
+	row.Columns[0].Name = ""
+	if err := row.ScanStruct(&got); err == nil || !strings.Contains(err.Error(), "binlog_row_metadata") {
+		t.Fatalf("got %v, want column names error", err)
+	}
+	row.Columns[0].Name = "id"
+	row.Values[0] = "x"
+	if err := row.ScanStruct(&got); err == nil || !strings.Contains(err.Error(), `"id"`) {
+		t.Fatalf("got %v, want error for column id", err)
+	}
+}
@@ -0,0 +1,120 @@
+package binlog
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// TableSchema is machine readable schema of a table, as described by
+// TableMapEvent. It is meant to be marshaled to JSON for downstream
+// systems tracking schema evolution.
+//
+// Column names, charsets and primary key are available only if
+// binlog_row_metadata=FULL.
+type TableSchema struct {
+	Schema     string         `json:"schema"`
+	Table      string         `json:"table"`
+	Columns    []ColumnSchema `json:"columns"`
+	PrimaryKey []string       `json:"primaryKey,omitempty"`
+}
+
+// ColumnSchema is schema of a column in TableSchema.
+type ColumnSchema struct {
+	Ordinal   int    `json:"ordinal"`
+	Name      string `json:"name,omitempty"`
+	Type      string `json:"type"` // as returned by Column.SQLType
+	Nullable  bool   `json:"nullable"`
+	Charset   string `json:"charset,omitempty"`
+	Collation string `json:"collation,omitempty"`
+	Generated bool   `json:"generated,omitempty"`
+}
+
+// Schema returns schema of the table.
+func (e TableMapEvent) Schema() TableSchema {
+	s := TableSchema{
+		Schema:  e.SchemaName,
+		Table:   e.TableName,
+		Columns: make([]ColumnSchema, len(e.Columns)),
+	}
+	for i, col := range e.Columns {
+		s.Columns[i] = ColumnSchema{
+			Ordinal:   col.Ordinal,
+			Name:      col.Name,
+			Type:      col.SQLType(),
+			Nullable:  col.Nullable,
+			Charset:   col.CharsetName(),
+			Collation: col.CollationName(),
+			Generated: col.Generated,
+		}
+	}
+	for _, ord := range e.PrimaryKey {
+		s.PrimaryKey = append(s.PrimaryKey, e.Columns[ord].Name)
+	}
+	return s
+}
+
+// SchemaRegistry tracks schema of each table, from TableMapEvents.
+// TableMapEvent precedes each RowsEvent, so Track reports only the
+// schemas that are new or changed since last seen.
+//
+//	var reg binlog.SchemaRegistry
+//	for {
+//	    e, err := bl.NextEvent()
+//	    ...
+//	    if tme, ok := e.Data.(binlog.TableMapEvent); ok {
+//	        if s, changed := reg.Track(tme); changed {
+//	            publish(s)
+//	        }
+//	    }
+//	}
+//
+// It is safe for concurrent use.
+type SchemaRegistry struct {
+	mu      sync.Mutex
+	schemas map[string]TableSchema
+}
+
+// Track records schema of table in e. It returns the schema and true,
+// if the table is seen first time or its schema has changed.
+func (reg *SchemaRegistry) Track(e TableMapEvent) (TableSchema, bool) {
+	key := e.SchemaName + "." + e.TableName
+	s := e.Schema()
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if reg.schemas == nil {
+		reg.schemas = make(map[string]TableSchema)
+	}
+	if old, ok := reg.schemas[key]; ok && reflect.DeepEqual(old, s) {
+		return s, false
+	}
+	reg.schemas[key] = s
+	return s, true
+}
+
+// Lookup returns last tracked schema of given table.
+func (reg *SchemaRegistry) Lookup(schema, table string) (TableSchema, bool) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	s, ok := reg.schemas[schema+"."+table]
+	return s, ok
+}
+
+// MarshalJSON returns all tracked schemas as JSON array, sorted by
+// schema and table.
+func (reg *SchemaRegistry) MarshalJSON() ([]byte, error) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	schemas := make([]TableSchema, 0, len(reg.schemas))
+	for _, s := range reg.schemas {
+		schemas = append(schemas, s)
+	}
+	sort.Slice(schemas, func(i, j int) bool {
+		if schemas[i].Schema != schemas[j].Schema {
+			return schemas[i].Schema < schemas[j].Schema
+		}
+		return schemas[i].Table < schemas[j].Table
+	})
+	return json.Marshal(schemas)
+}
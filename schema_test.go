@@ -0,0 +1,47 @@
+package binlog
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSchemaRegistry(t *testing.T) {
+	tme := TableMapEvent{
+		SchemaName: "test",
+		TableName:  "t",
+		Columns: []Column{
+			{Ordinal: 0, Name: "id", Type: TypeLong},
+			{Ordinal: 1, Name: "name", Type: TypeVarchar, Meta: 80, Charset: 255, Nullable: true},
+		},
+		PrimaryKey:       []int{0},
+		PrimaryKeyPrefix: []int{0},
+	}
+	var reg SchemaRegistry
+	if _, changed := reg.Track(tme); !changed {
+		t.Fatal("new table must be reported")
+	}
+	if _, changed := reg.Track(tme); changed {
+		t.Fatal("unchanged table must not be reported")
+	}
+	tme.Columns = append(tme.Columns, Column{Ordinal: 2, Name: "age", Type: TypeTiny, Unsigned: true})
+	s, changed := reg.Track(tme)
+	if !changed || len(s.Columns) != 3 {
+		t.Fatalf("changed table must be reported: got %v %+v", changed, s)
+	}
+	if got, ok := reg.Lookup("test", "t"); !ok || len(got.Columns) != 3 {
+		t.Fatalf("Lookup: got %v %+v", ok, got)
+	}
+
+	b, err := json.Marshal(&reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"schema":"test","table":"t","columns":[` +
+		`{"ordinal":0,"name":"id","type":"int","nullable":false},` +
+		`{"ordinal":1,"name":"name","type":"varchar(20)","nullable":true,"charset":"utf8mb4","collation":"utf8mb4_0900_ai_ci"},` +
+		`{"ordinal":2,"name":"age","type":"tinyint unsigned","nullable":false}],` +
+		`"primaryKey":["id"]}]`
+	if string(b) != want {
+		t.Fatalf("got %s\nwant %s", b, want)
+	}
+}
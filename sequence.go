@@ -0,0 +1,135 @@
+package binlog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Anomaly is violation of an invariant of event ordering, detected by
+// SequenceValidator.
+type Anomaly struct {
+	Header EventHeader // of the event violating the invariant
+	Msg    string
+}
+
+func (a *Anomaly) Error() string {
+	return fmt.Sprintf("binlog: %s at %s:%d", a.Msg, a.Header.LogFile, a.Header.NextPos)
+}
+
+// SequenceValidator checks invariants of events from Src, as they flow,
+// to catch stream corruption or bugs in decoding early:
+//
+//   - positions are increasing within a binlog file
+//   - RowsEvent is preceded by TableMapEvent of its table, in the
+//     same transaction
+//   - GTIDEvent is followed by a transaction or a DDL
+//   - transactions begin with BEGIN, and end with XID or COMMIT,
+//     without nesting
+//
+// Event ordering is as logged by MySQL. Synthetic events, heartbeats
+// and artificial events are not checked. Src must be positioned at
+// transaction boundary.
+type SequenceValidator struct {
+	Src EventSource
+
+	// OnAnomaly, if non-nil, is called with each anomaly detected.
+	// Error returned is returned by NextEvent. If nil, anomaly is
+	// returned by NextEvent as *Anomaly.
+	OnAnomaly func(a *Anomaly) error
+
+	file   string
+	pos    uint32
+	gtid   bool // GTIDEvent seen, and waiting for transaction
+	inTx   bool
+	tables map[uint64]bool // table ids mapped in current transaction
+}
+
+// NextEvent returns next event from Src, after validating it.
+func (v *SequenceValidator) NextEvent() (Event, error) {
+	e, err := v.Src.NextEvent()
+	if err != nil {
+		return e, err
+	}
+	if msg := v.check(e); msg != "" {
+		a := &Anomaly{Header: e.Header, Msg: msg}
+		if v.OnAnomaly == nil {
+			return e, a
+		}
+		if err := v.OnAnomaly(a); err != nil {
+			return e, err
+		}
+	}
+	return e, nil
+}
+
+// NextRow returns next row of current RowsEvent from Src.
+func (v *SequenceValidator) NextRow() (values []interface{}, valuesBeforeUpdate []interface{}, err error) {
+	return v.Src.NextRow()
+}
+
+// check updates state with event e, and returns description of
+// anomaly if any.
+func (v *SequenceValidator) check(e Event) string {
+	h := e.Header
+	if h.EventSize == 0 || h.Artificial() || h.EventType == HEARTBEAT_EVENT {
+		return ""
+	}
+	if h.LogFile != v.file {
+		v.file, v.pos = h.LogFile, 0
+	}
+	if h.NextPos != 0 {
+		if h.NextPos <= v.pos {
+			return fmt.Sprintf("position %d not after %d", h.NextPos, v.pos)
+		}
+		v.pos = h.NextPos
+	}
+
+	begin, end := txBoundary(e)
+	switch d := e.Data.(type) {
+	case GTIDEvent:
+		switch {
+		case v.inTx:
+			return "gtid event inside transaction"
+		case v.gtid:
+			return "gtid event not followed by transaction"
+		}
+		v.gtid = true
+		return ""
+	case TableMapEvent:
+		if !v.inTx {
+			return "table map event outside transaction"
+		}
+		v.tables[d.tableID] = true
+		return ""
+	case RowsEvent:
+		switch {
+		case !v.inTx:
+			return "rows event outside transaction"
+		case !v.tables[d.tableID]:
+			return fmt.Sprintf("rows event of table id %d without table map", d.tableID)
+		}
+		return ""
+	}
+	switch {
+	case begin:
+		if v.inTx {
+			return "transaction begins inside transaction"
+		}
+		v.gtid, v.inTx, v.tables = false, true, make(map[uint64]bool)
+	case end:
+		if !v.inTx {
+			if qe, ok := e.Data.(QueryEvent); ok && hasPrefixFold(strings.TrimSpace(qe.Query), "XA ") {
+				// XA COMMIT and XA ROLLBACK are logged outside transaction
+				v.gtid = false
+				return ""
+			}
+			return "transaction ends outside transaction"
+		}
+		v.gtid, v.inTx, v.tables = false, false, nil
+	case h.EventType == QUERY_EVENT:
+		if v.gtid {
+			v.gtid = false // DDL
+		}
+	}
+	return ""
+}
@@ -0,0 +1,63 @@
+package binlog
+
+import (
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestSequenceValidator(t *testing.T) {
+	src := &fakeSource{}
+	pos := uint32(4)
+	push := func(typ EventType, data interface{}) {
+		pos += 10
+		src.push(Event{Header: EventHeader{EventType: typ, EventSize: 10, LogFile: "binlog.000001", NextPos: pos}, Data: data})
+	}
+	push(GTID_EVENT, GTIDEvent{})
+	push(QUERY_EVENT, QueryEvent{Query: "BEGIN"})
+	push(TABLE_MAP_EVENT, TableMapEvent{tableID: 1})
+	push(WRITE_ROWS_EVENTv2, RowsEvent{tableID: 1})
+	push(WRITE_ROWS_EVENTv2, RowsEvent{tableID: 2})
+	push(XID_EVENT, XIDEvent{})
+	push(GTID_EVENT, GTIDEvent{})
+	push(QUERY_EVENT, QueryEvent{Query: "create table t(id int)"})
+	push(GTID_EVENT, GTIDEvent{})
+	push(GTID_EVENT, GTIDEvent{})
+	push(XID_EVENT, XIDEvent{})
+	push(QUERY_EVENT, QueryEvent{Query: "XA COMMIT X'31',X'',1"})
+	pos -= 20
+	push(QUERY_EVENT, QueryEvent{Query: "BEGIN"})
+
+	var got []string
+	v := &SequenceValidator{Src: src, OnAnomaly: func(a *Anomaly) error {
+		got = append(got, a.Error())
+		return nil
+	}}
+	for {
+		if _, err := v.NextEvent(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{
+		"binlog: rows event of table id 2 without table map at binlog.000001:54",
+		"binlog: gtid event not followed by transaction at binlog.000001:104",
+		"binlog: transaction ends outside transaction at binlog.000001:114",
+		"binlog: position 114 not after 124 at binlog.000001:114",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	// anomaly is returned, without OnAnomaly
+	src = &fakeSource{}
+	pos = 4
+	push(XID_EVENT, XIDEvent{})
+	v = &SequenceValidator{Src: src}
+	if _, err := v.NextEvent(); err == nil {
+		t.Fatal("anomaly not returned")
+	} else if _, ok := err.(*Anomaly); !ok {
+		t.Fatalf("got %T, want *Anomaly", err)
+	}
+}
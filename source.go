@@ -0,0 +1,53 @@
+package binlog
+
+import "strings"
+
+// BinlogSource is implemented by Remote and Local, so that code can
+// switch between online and offline sources of binlog events.
+type BinlogSource interface {
+	ListFiles() ([]string, error)
+	MasterStatus() (file string, pos uint32, err error)
+	Seek(serverID uint32, fileName string, position uint32) error
+	NextEvent() (Event, error)
+	NextRow() (values []interface{}, valuesBeforeUpdate []interface{}, err error)
+	Close() error
+}
+
+// EventSource is source of events and their rows. It is implemented
+// by Remote, Local, Reader, Failover and Backfiller.
+//
+// NextRow returns values of row after update first, followed by values
+// before update, as in Remote.NextRow. Wrappers of EventSource must
+// preserve this order.
+type EventSource interface {
+	NextEvent() (Event, error)
+	NextRow() (values []interface{}, valuesBeforeUpdate []interface{}, err error)
+}
+
+// SourceInfo identifies where events are read from. It is set in
+// EventMeta of each event returned by Remote and Local, so that
+// multi-source pipelines and logs can attribute changes. It must
+// not be modified.
+type SourceInfo struct {
+	Addr         string // address of server, or dump directory of Local
+	ServerUUID   string // server_uuid of server. empty if unknown
+	ConnectionID uint32 // connection id of Remote
+	Flavor       string // "mysql" or "mariadb". empty if unknown
+}
+
+// flavor returns flavor of server with given version.
+func flavor(version string) string {
+	if strings.Contains(strings.ToLower(version), "mariadb") {
+		return "mariadb"
+	}
+	return "mysql"
+}
+
+var (
+	_ BinlogSource = (*Remote)(nil)
+	_ BinlogSource = (*Local)(nil)
+
+	_ EventSource = (*Reader)(nil)
+	_ EventSource = (*Failover)(nil)
+	_ EventSource = (*Backfiller)(nil)
+)
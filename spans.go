@@ -0,0 +1,96 @@
+package binlog
+
+import (
+	"context"
+	"io"
+)
+
+// SpanTracer starts spans of operations, so that latency of change
+// data capture can be traced end-to-end, alongside the rest of a
+// service. It is implemented by embedders over OpenTelemetry API, so
+// that this package does not depend on it:
+//
+//	type otelTracer struct{ trace.Tracer }
+//
+//	func (t otelTracer) Start(ctx context.Context, name string) (context.Context, binlog.Span) {
+//		ctx, span := t.Tracer.Start(ctx, name)
+//		return ctx, otelSpan{span}
+//	}
+//
+// see Remote.SetSpanTracer and DialOptions.SpanTracer.
+type SpanTracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is an operation started by SpanTracer. It is subset of Span
+// of OpenTelemetry API. value of attribute is string, int64 or bool.
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+// Span names, and keys of span attributes.
+const (
+	SpanDial         = "binlog.Dial"
+	SpanAuthenticate = "binlog.Authenticate"
+	SpanSeek         = "binlog.Seek"
+	SpanNextEvent    = "binlog.NextEvent"
+	SpanDump         = "binlog.Dump"
+
+	AttrAddr      = "net.peer.name"
+	AttrFile      = "binlog.file"
+	AttrPos       = "binlog.pos"
+	AttrGTIDs     = "binlog.gtids" // executed gtid set of SeekGTID
+	AttrEventType = "binlog.event_type"
+	AttrTable     = "binlog.table" // as "schema.table"
+)
+
+// SetSpanTracer enables spans of Authenticate, Seek, SeekGTID, NextEvent
+// and Dump, as children of span in ctx. Passing nil t disables them.
+func (bl *Remote) SetSpanTracer(ctx context.Context, t SpanTracer) {
+	bl.spans, bl.spanCtx = t, ctx
+}
+
+type nopSpan struct{}
+
+func (nopSpan) SetAttribute(string, interface{}) {}
+func (nopSpan) RecordError(error)                {}
+func (nopSpan) End()                             {}
+
+// startSpan starts span of operation name, if t is non-nil.
+func startSpan(ctx context.Context, t SpanTracer, name string) Span {
+	if t == nil {
+		return nopSpan{}
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	_, s := t.Start(ctx, name)
+	return s
+}
+
+// endSpan ends span s, recording err other than io.EOF.
+func endSpan(s Span, err error) {
+	if err != nil && err != io.EOF {
+		s.RecordError(err)
+	}
+	s.End()
+}
+
+// eventSpanAttributes sets attributes of span, describing event e.
+func eventSpanAttributes(s Span, e Event) {
+	s.SetAttribute(AttrFile, e.Header.LogFile)
+	s.SetAttribute(AttrPos, int64(e.Header.NextPos))
+	s.SetAttribute(AttrEventType, e.Header.EventType.String())
+	var tme *TableMapEvent
+	switch d := e.Data.(type) {
+	case TableMapEvent:
+		tme = &d
+	case RowsEvent:
+		tme = d.TableMap
+	}
+	if tme != nil {
+		s.SetAttribute(AttrTable, tme.SchemaName+"."+tme.TableName)
+	}
+}
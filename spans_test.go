@@ -0,0 +1,91 @@
+package binlog
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// fakeSpanTracer records spans ended, as name followed by attributes.
+type fakeSpanTracer struct {
+	mu    sync.Mutex
+	spans []string
+}
+
+func (t *fakeSpanTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	return ctx, &fakeSpan{t: t, name: name}
+}
+
+type fakeSpan struct {
+	t     *fakeSpanTracer
+	name  string
+	attrs []string
+}
+
+func (s *fakeSpan) SetAttribute(key string, value interface{}) {
+	s.attrs = append(s.attrs, fmt.Sprintf("%s=%v", key, value))
+}
+
+func (s *fakeSpan) RecordError(err error) {
+	s.attrs = append(s.attrs, "error="+err.Error())
+}
+
+func (s *fakeSpan) End() {
+	s.t.mu.Lock()
+	defer s.t.mu.Unlock()
+	s.t.spans = append(s.t.spans, s.name+" "+fmt.Sprint(s.attrs))
+}
+
+func TestRemote_SetSpanTracer(t *testing.T) {
+	s := newFakeServer()
+	f := newBinlogStream()
+	f.XID(7)
+	s.addFile("binlog.000001", f)
+	tracer := &fakeSpanTracer{}
+	bl, err := DialWithOptions("tcp", "fake:3306", DialOptions{Dialer: s, SpanTracer: tracer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bl.Close()
+	if err := bl.Authenticate(s.user, s.password); err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.Seek(0, "binlog.000001", 4); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if _, err := bl.NextEvent(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{
+		"binlog.Dial [net.peer.name=fake:3306]",
+		"binlog.Authenticate [net.peer.name=fake:3306]",
+		"binlog.Seek [binlog.file=binlog.000001 binlog.pos=4]",
+		"binlog.NextEvent [binlog.file=binlog.000001 binlog.pos=4 binlog.event_type=rotate]",
+		fmt.Sprintf("binlog.NextEvent [binlog.file=binlog.000001 binlog.pos=%d binlog.event_type=formatDescription]", f.Len()-31),
+		fmt.Sprintf("binlog.NextEvent [binlog.file=binlog.000001 binlog.pos=%d binlog.event_type=xid]", f.Len()),
+		"binlog.NextEvent []",
+	}
+	if !reflect.DeepEqual(tracer.spans, want) {
+		t.Fatalf("got %q, want %q", tracer.spans, want)
+	}
+
+	executed, err := ParseGTIDSet("3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracer.spans = nil
+	if err := bl.SeekGTID(0, executed); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"binlog.Seek [binlog.gtids=" + executed.String() + "]"}
+	if !reflect.DeepEqual(tracer.spans, want) {
+		t.Fatalf("got %q, want %q", tracer.spans, want)
+	}
+}
@@ -0,0 +1,55 @@
+//go:build ssh
+// +build ssh
+
+package binlog
+
+// this file is compiled only with `-tags ssh`.
+
+import (
+	"context"
+	"net"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// DialSSH connects to the MySQL server at mysqlAddr, through
+// SSH bastion host at sshAddr. The SSH connection is closed
+// when the returned Remote is closed.
+func DialSSH(sshAddr string, sshConfig *ssh.ClientConfig, mysqlAddr string) (*Remote, error) {
+	client, err := ssh.Dial("tcp", sshAddr, sshConfig)
+	if err != nil {
+		return nil, err
+	}
+	bl, err := DialWith(sshDialer{client}, "tcp", mysqlAddr)
+	if err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+	return bl, nil
+}
+
+type sshDialer struct {
+	client *ssh.Client
+}
+
+func (d sshDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	conn, err := d.client.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return sshConn{conn, d.client}, nil
+}
+
+// sshConn closes ssh client along with the connection.
+type sshConn struct {
+	net.Conn
+	client *ssh.Client
+}
+
+func (c sshConn) Close() error {
+	err := c.Conn.Close()
+	if err1 := c.client.Close(); err == nil {
+		err = err1
+	}
+	return err
+}
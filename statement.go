@@ -0,0 +1,70 @@
+package binlog
+
+import "io"
+
+// StatementEvent is a synthetic event, which coalesces RowsEvents of
+// single statement along with their rows. MySQL splits changes of a
+// statement into multiple RowsEvents as per binlog_row_event_max_size,
+// and sets STMT_END_F flag only on the last one. It is never written
+// to binlog, and returned by NextEvent only if coalescing is enabled.
+//
+// Its Header is the header of last RowsEvent of the statement, with
+// EventSize being sum of EventSize of all RowsEvents.
+type StatementEvent struct {
+	Changes []RowsChange // in the order they appeared in binlog
+}
+
+// RowsChange is a RowsEvent along with its rows.
+type RowsChange struct {
+	Header EventHeader
+	RowsEvent
+	Rows             [][]interface{}
+	RowsBeforeUpdate [][]interface{} // populated only for update events
+}
+
+// coalesceRows reads remaining RowsEvents of the statement, if e is RowsEvent.
+// The first non RowsEvent, if read before statement end, is queued into
+// r.pending, so that it is returned by next call to NextEvent, ahead of
+// synthetic events queued while reading it.
+func coalesceRows(e Event, r *reader, next func() (Event, error)) (Event, error) {
+	if _, ok := e.Data.(RowsEvent); !ok {
+		return e, nil
+	}
+	var stmt StatementEvent
+	size := uint32(0)
+	for {
+		re := e.Data.(RowsEvent)
+		change := RowsChange{Header: e.Header, RowsEvent: re}
+		for {
+			values, before, err := nextRow(r)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return Event{}, err
+			}
+			change.Rows = append(change.Rows, values)
+			if before != nil {
+				change.RowsBeforeUpdate = append(change.RowsBeforeUpdate, before)
+			}
+		}
+		stmt.Changes = append(stmt.Changes, change)
+		size += e.Header.EventSize
+		if re.StmtEnd() {
+			break
+		}
+		n := len(r.pending)
+		ne, err := next()
+		if err != nil {
+			return Event{}, err
+		}
+		if _, ok := ne.Data.(RowsEvent); !ok {
+			r.pending = append(r.pending[:n], append([]Event{ne}, r.pending[n:]...)...)
+			break
+		}
+		e = ne
+	}
+	h := e.Header
+	h.EventSize = size
+	return Event{Header: h, Data: stmt, meta: e.meta}, nil
+}
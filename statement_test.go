@@ -0,0 +1,66 @@
+package binlog
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestReader_SetCoalesceRows(t *testing.T) {
+	row := []byte{0, 1, 0, 0, 0}
+	tests := []struct {
+		name    string
+		stmtEnd bool
+		want    []string
+	}{
+		{"stmtEnd", true, []string{"BEGIN", "begin", "tableMap", "stmt(2 changes, 3 rows)", "endStmt", "xid", "endTx"}},
+		{"pushedBack", false, []string{"BEGIN", "begin", "tableMap", "stmt(2 changes, 3 rows)", "xid", "endTx"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newBinlogStream()
+			s.Query("test", "BEGIN")
+			s.TableMap(1, "test", "t", []byte{byte(TypeLong)}, nil, []byte{0})
+			s.Rows(WRITE_ROWS_EVENTv2, 1, false, 1, row, row)
+			s.Rows(WRITE_ROWS_EVENTv2, 1, tc.stmtEnd, 1, row)
+			s.XID(7)
+			r := NewReader(s)
+			r.SetCoalesceRows(true)
+			r.SetBoundaryEvents(true)
+			var got []string
+			for {
+				e, err := r.NextEvent()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				switch d := e.Data.(type) {
+				case QueryEvent:
+					got = append(got, d.Query)
+				case TableMapEvent:
+					got = append(got, "tableMap")
+				case StatementEvent:
+					n := 0
+					for _, c := range d.Changes {
+						n += len(c.Rows)
+					}
+					got = append(got, fmt.Sprintf("stmt(%d changes, %d rows)", len(d.Changes), n))
+				case XIDEvent:
+					got = append(got, "xid")
+				case BeginTransactionEvent:
+					got = append(got, "begin")
+				case EndStatementEvent:
+					got = append(got, "endStmt")
+				case EndTransactionEvent:
+					got = append(got, "endTx")
+				}
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
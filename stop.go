@@ -0,0 +1,87 @@
+package binlog
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrStopped is returned by NextEvent and Dump, after Stop is called.
+var ErrStopped = errors.New("binlog: stopped")
+
+// Stop stops NextEvent or Dump, running in another goroutine, at event
+// boundary. It waits till the running call returns ErrStopped, and
+// returns binlog position after the last complete event, from where
+// the stream can be resumed using Seek. Dump syncs the dump files,
+// discarding the partially written event, if any, before returning.
+//
+// If ctx is done before that, the connection is closed, and ctx.Err()
+// is returned, along with the position. Further calls to NextEvent or
+// Dump return ErrStopped.
+func (bl *Remote) Stop(ctx context.Context) (file string, pos uint32, err error) {
+	s := &bl.stop
+	s.mu.Lock()
+	s.stopped = true
+	var done chan struct{}
+	if s.running {
+		done = make(chan struct{})
+		s.done = done
+		// interrupt the wait for next event
+		_ = bl.conn.SetReadDeadline(time.Now())
+	}
+	s.mu.Unlock()
+	if done != nil {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			_ = bl.conn.Close()
+			err = ctx.Err()
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file, s.pos, err
+}
+
+// stopper coordinates Stop with NextEvent or Dump.
+type stopper struct {
+	mu      sync.Mutex
+	stopped bool
+	running bool
+	done    chan struct{} // closed when running call returns, if stopped
+	file    string        // position after last complete event
+	pos     uint32
+}
+
+// begin marks start of NextEvent or Dump. returns false if stopped.
+func (s *stopper) begin() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = !s.stopped
+	return s.running
+}
+
+// end marks return of NextEvent or Dump.
+func (s *stopper) end() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = false
+	if s.done != nil {
+		close(s.done)
+		s.done = nil
+	}
+}
+
+func (s *stopper) isStopped() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stopped
+}
+
+// advance records position after complete event.
+func (s *stopper) advance(file string, pos uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.file, s.pos = file, pos
+}
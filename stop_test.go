@@ -0,0 +1,98 @@
+package binlog
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRemote_Stop(t *testing.T) {
+	s := newFakeServer()
+	s.idle = make(chan struct{})
+	defer close(s.idle)
+	f := newBinlogStream()
+	f.XID(7)
+	s.addFile("binlog.000001", f)
+	bl, err := s.dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bl.Close()
+	if err := bl.Seek(0, "binlog.000001", 4); err != nil {
+		t.Fatal(err)
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		for {
+			if _, err := bl.NextEvent(); err != nil {
+				errCh <- err
+				return
+			}
+		}
+	}()
+	// wait till NextEvent blocks for events after xid
+	time.Sleep(100 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	file, pos, err := bl.Stop(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file != "binlog.000001" || pos != uint32(f.Len()) {
+		t.Fatalf("got %s:%d, want binlog.000001:%d", file, pos, f.Len())
+	}
+	if err := <-errCh; err != ErrStopped {
+		t.Fatal("got", err, "want", ErrStopped)
+	}
+	if _, err := bl.NextEvent(); err != ErrStopped {
+		t.Fatal("got", err, "want", ErrStopped)
+	}
+}
+
+func TestRemote_StopDump(t *testing.T) {
+	s := newFakeServer()
+	s.idle = make(chan struct{})
+	defer close(s.idle)
+	f := newBinlogStream()
+	f.XID(7)
+	s.addFile("binlog.000001", f)
+	bl, err := s.dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bl.Close()
+	if err := bl.Seek(0, "binlog.000001", 4); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- bl.Dump(dir)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	file, pos, err := bl.Stop(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errCh; err != ErrStopped {
+		t.Fatal("got", err, "want", ErrStopped)
+	}
+	if file != "binlog.000001" || pos != uint32(f.Len()) {
+		t.Fatalf("got %s:%d, want binlog.000001:%d", file, pos, f.Len())
+	}
+	got, err := ioutil.ReadFile(filepath.Join(dir, file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, f.Bytes()) {
+		t.Fatal("dumped file does not match")
+	}
+}
@@ -0,0 +1,146 @@
+package binlog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Reader reads binlog events from raw binlog byte stream, i.e. contents
+// of a binlog file, such as from a pipe, an object in cloud storage or
+// output of mysqlbinlog --read-from-remote-server --raw.
+//
+// Event headers have empty LogFile until a RotateEvent is seen,
+// unless opened with OpenFile.
+type Reader struct {
+	rd           io.Reader
+	name         string    // binlog file name
+	closer       io.Closer // nil if not opened by OpenFile
+	binlogReader *reader
+	options      // decodeOptions, with setters shared by Remote, Local and Reader
+}
+
+// NewReader returns Reader that reads binlog byte stream from rd.
+// The stream must start with binlog magic header.
+func NewReader(rd io.Reader) *Reader {
+	return &Reader{rd: rd}
+}
+
+// OpenFile opens standalone binlog file for reading. Unlike Open,
+// it does not require dump directory. Binlog version and checksum
+// are detected from its FormatDescriptionEvent.
+//
+// Events are read till the end of file; the next file, if any, is
+// not followed.
+func OpenFile(name string) (*Reader, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{rd: f, name: filepath.Base(name), closer: f}, nil
+}
+
+// Close closes the file, if opened with OpenFile.
+func (bl *Reader) Close() error {
+	if bl.closer == nil {
+		return nil
+	}
+	return bl.closer.Close()
+}
+
+// NextEvent return next binlog event.
+//
+// return io.EOF when there are no more Events
+func (bl *Reader) NextEvent() (Event, error) {
+	if e, ok := bl.binlogReader.nextPending(); ok {
+		return e, nil
+	}
+	e, err := bl.readEvent()
+	if err != nil || !bl.opts.coalesceRows {
+		return e, err
+	}
+	return coalesceRows(e, bl.binlogReader, bl.readEvent)
+}
+
+func (bl *Reader) readEvent() (Event, error) {
+	r := bl.binlogReader
+	var corrupt *CorruptionEvent
+	if r == nil {
+		r = &reader{
+			rd:         bl.rd,
+			tmeCache:   make(map[uint64]*TableMapEvent),
+			binlogFile: bl.name,
+			limit:      -1,
+			scan:       true,
+			opts:       &bl.opts,
+		}
+		if err := r.ensure(4); err != nil {
+			return Event{}, err
+		}
+		if !bytes.Equal(r.buffer()[:4], fileHeader) {
+			return Event{}, fmt.Errorf("binlog.NextEvent: not binlog stream")
+		}
+		r.skip(4)
+		if !r.more() {
+			if r.err != nil {
+				return Event{}, r.err
+			}
+			return Event{}, io.EOF
+		}
+		if err := r.ensure(13); err != nil {
+			return Event{}, err
+		}
+		buf := r.buffer()
+		v := binlogVersion(EventType(buf[4]), binary.LittleEndian.Uint32(buf[9:]))
+		if bl.opts.checksumPolicy != ChecksumIgnore {
+			r.hash = crc32.NewIEEE()
+		}
+		r.fde = FormatDescriptionEvent{BinlogVersion: v}
+		bl.binlogReader = r
+	} else {
+		if err := r.drain(); err != nil {
+			return Event{}, fmt.Errorf("binlog.NextEvent: error in draining event: %v", err)
+		}
+		if r.checksum > 0 {
+			r.limit += r.checksum
+			var err error
+			if corrupt, err = r.verifyChecksum(); err != nil {
+				return Event{}, err
+			}
+		}
+		r.limit = -1
+	}
+	if !r.more() {
+		err := r.err
+		if err == nil {
+			err = io.EOF
+		}
+		return corruptionFirst(r, corrupt, Event{}, err)
+	}
+	e, err := nextEvent(r, 0)
+	return corruptionFirst(r, corrupt, e, err)
+}
+
+// SetClock sets source of time for EventMeta. nil means SystemClock.
+func (bl *Reader) SetClock(c Clock) {
+	bl.opts.clock = c
+}
+
+// NextRow returns next row for RowsEvent. Returns io.EOF when there are no more rows.
+// values is the row inserted or deleted, or the row after update. valuesBeforeUpdate
+// is the row before update, and is nil for events other than UPDATE_ROWS_EVENTv1,
+// UPDATE_ROWS_EVENTv2.
+func (bl *Reader) NextRow() (values []interface{}, valuesBeforeUpdate []interface{}, err error) {
+	return nextRow(bl.binlogReader)
+}
+
+// NextRows is like NextRow, but returns upto n rows at once, to amortize
+// per call overhead for RowsEvent with many rows. rowsBeforeUpdate is nil
+// unless rows were updated. Returns io.EOF when there are no more rows.
+func (bl *Reader) NextRows(n int) (rows [][]interface{}, rowsBeforeUpdate [][]interface{}, err error) {
+	return nextRows(bl.binlogReader, n)
+}
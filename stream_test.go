@@ -0,0 +1,218 @@
+package binlog
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// binlogStream is binlog stream in memory, with events written by its
+// EventEncoder.
+type binlogStream struct {
+	bytes.Buffer
+	*EventEncoder
+}
+
+// newEncodedStream returns binlogStream of given binlog version, having
+// only magic header.
+func newEncodedStream(version uint16, checksum bool) *binlogStream {
+	s := &binlogStream{}
+	s.EventEncoder = NewEventEncoder(&s.Buffer, version, checksum)
+	return s
+}
+
+// newBinlogStream returns binlogStream of MySQL 8.0, starting with
+// FormatDescriptionEvent, with crc32 checksum of each event.
+func newBinlogStream() *binlogStream {
+	s := newEncodedStream(4, true)
+	s.FormatDescription("8.0.23", 40, 1)
+	return s
+}
+
+func TestReader(t *testing.T) {
+	s := newBinlogStream()
+	s.XID(7)
+	s.XID(8)
+	r := NewReader(s)
+	e, err := r.NextEvent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fde, ok := e.Data.(FormatDescriptionEvent); !ok || fde.ServerVersion != "8.0.23" {
+		t.Fatalf("got %#v, want FormatDescriptionEvent", e.Data)
+	}
+	for _, want := range []uint64{7, 8} {
+		e, err := r.NextEvent()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if xe, ok := e.Data.(XIDEvent); !ok || xe.XID != want {
+			t.Fatalf("got %#v, want XIDEvent %d", e.Data, want)
+		}
+	}
+	if _, err := r.NextEvent(); err != io.EOF {
+		t.Fatal("got", err, "want", io.EOF)
+	}
+}
+
+func TestReader_corrupt(t *testing.T) {
+	s := newBinlogStream()
+	s.XID(7)
+	buf := s.Bytes()
+	buf[len(buf)-5] ^= 0xff
+	r := NewReader(bytes.NewReader(buf))
+	var err error
+	for err == nil {
+		_, err = r.NextEvent()
+	}
+	if err == io.EOF {
+		t.Fatal("checksum mismatch not detected")
+	}
+
+	if _, err := NewReader(bytes.NewReader([]byte("hello"))).NextEvent(); err == nil {
+		t.Fatal("invalid magic header not detected")
+	}
+}
+
+func TestOpenFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "binlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s := newBinlogStream()
+	s.XID(7)
+	file := filepath.Join(dir, "binlog.000001")
+	if err := ioutil.WriteFile(file, s.Bytes(), 0666); err != nil {
+		t.Fatal(err)
+	}
+	r, err := OpenFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var events []Event
+	for {
+		e, err := r.NextEvent()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, e)
+	}
+	if len(events) != 2 {
+		t.Fatal("got", len(events), "events, want", 2)
+	}
+	if h := events[1].Header; h.LogFile != "binlog.000001" || h.NextPos != uint32(s.Len()) {
+		t.Fatal("got", h.LogFile, h.NextPos, "want", "binlog.000001", s.Len())
+	}
+}
+
+func TestReader_unknownEvent(t *testing.T) {
+	s := newBinlogStream()
+	body := []byte{1, 2, 3, 4, 5}
+	s.Event(IGNORABLE_EVENT, body)
+	r := NewReader(s)
+	if _, err := r.NextEvent(); err != nil {
+		t.Fatal(err)
+	}
+	e, err := r.NextEvent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ue, ok := e.Data.(UnknownEvent)
+	if !ok || ue.Type != IGNORABLE_EVENT || !bytes.Equal(ue.Body, body) {
+		t.Fatalf("got %#v, want UnknownEvent with body", e.Data)
+	}
+}
+
+func TestReader_NextRows(t *testing.T) {
+	w := newEncodedStream(4, true)
+	w.FormatDescription("8.0.23", 40, 1)
+	w.TableMap(101, "test", "t", []byte{byte(TypeLong)}, nil, []byte{0x01})
+	w.Rows(WRITE_ROWS_EVENTv2, 101, true, 1, []byte{0, 1, 0, 0, 0}, []byte{0, 2, 0, 0, 0}, []byte{0, 3, 0, 0, 0})
+	w.Event(UPDATE_ROWS_EVENTv2, []byte{101, 0, 0, 0, 0, 0, rowsEventStmtEnd, 0, 2, 0, 1, 0x01, 0x01},
+		[]byte{0, 1, 0, 0, 0}, []byte{0, 10, 0, 0, 0},
+		[]byte{0, 2, 0, 0, 0}, []byte{0, 20, 0, 0, 0})
+
+	r := NewReader(w)
+	next := func(typ EventType) {
+		t.Helper()
+		for {
+			e, err := r.NextEvent()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e.Header.EventType == typ {
+				return
+			}
+		}
+	}
+	nextRows := func(n int, want, wantBefore [][]interface{}) {
+		t.Helper()
+		rows, before, err := r.NextRows(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(rows, want) || !reflect.DeepEqual(before, wantBefore) {
+			t.Fatalf("got %v %v, want %v %v", rows, before, want, wantBefore)
+		}
+	}
+
+	next(WRITE_ROWS_EVENTv2)
+	nextRows(2, [][]interface{}{{int32(1)}, {int32(2)}}, nil)
+	nextRows(2, [][]interface{}{{int32(3)}}, nil)
+	if _, _, err := r.NextRows(2); err != io.EOF {
+		t.Fatal("got", err, "want", io.EOF)
+	}
+
+	next(UPDATE_ROWS_EVENTv2)
+	nextRows(5, [][]interface{}{{int32(10)}, {int32(20)}}, [][]interface{}{{int32(1)}, {int32(2)}})
+	if _, _, err := r.NextRows(5); err != io.EOF {
+		t.Fatal("got", err, "want", io.EOF)
+	}
+}
+
+func TestReader_SetIsolateColumnErrors(t *testing.T) {
+	w := newEncodedStream(4, true)
+	w.FormatDescription("8.0.23", 40, 1)
+	// enum of invalid length 3, followed by int column
+	w.TableMap(101, "test", "t", []byte{byte(TypeEnum), byte(TypeLong)}, []byte{3, 0}, []byte{0x00})
+	w.Rows(WRITE_ROWS_EVENTv2, 101, true, 2, []byte{0, 1, 0, 0, 7, 0, 0, 0})
+
+	for _, isolate := range []bool{false, true} {
+		r := NewReader(bytes.NewReader(w.Bytes()))
+		r.SetIsolateColumnErrors(isolate)
+		for {
+			e, err := r.NextEvent()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e.Header.EventType == WRITE_ROWS_EVENTv2 {
+				break
+			}
+		}
+		values, _, err := r.NextRow()
+		if !isolate {
+			if err == nil {
+				t.Fatal("error expected")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cerr, ok := values[0].(*ColumnDecodeError); !ok || cerr.Column.Ordinal != 0 {
+			t.Fatalf("got %#v, want *ColumnDecodeError", values[0])
+		}
+		if values[1] != int32(7) {
+			t.Fatalf("got %#v, want 7", values[1])
+		}
+	}
+}
@@ -0,0 +1,117 @@
+//go:build go1.18
+// +build go1.18
+
+package binlog
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Change is a row change of table, decoded into T.
+type Change[T any] struct {
+	Header EventHeader // header of RowsEvent
+	Before *T          // row before update or delete. nil for insert
+	After  *T          // row after insert or update. nil for delete
+	Err    error       // error reading src. last value sent on channel
+}
+
+// Subscribe returns channel of changes to table, given as "db.table",
+// with rows decoded into struct T using Row.ScanStruct. This needs
+// binlog_row_metadata=FULL for column names.
+//
+// src must be positioned using Seek. Subscribe consumes all events from
+// src in a separate goroutine, so src must not be used by caller, except
+// to Close it. The channel is closed after sending Change with Err set,
+// which is io.EOF if Local reached end of binlogs. Caller must drain
+// the channel, until it is closed.
+//
+// Requires go1.18 or later.
+func Subscribe[T any](src BinlogSource, table string) (<-chan Change[T], error) {
+	return subscribe[T](src, table, nil)
+}
+
+// SubscribeColumns is like Subscribe, but updates are sent only if any
+// of given columns changed, as per ColumnsChanged. Inserts and deletes
+// are always sent. This reduces noise for wide tables, where consumer
+// is interested in few columns, such as status or price.
+//
+// Requires go1.18 or later.
+func SubscribeColumns[T any](src BinlogSource, table string, columns ...string) (<-chan Change[T], error) {
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("binlog: SubscribeColumns expects columns")
+	}
+	return subscribe[T](src, table, columns)
+}
+
+// subscribe implements Subscribe. non-nil columns skips updates
+// which do not change them.
+func subscribe[T any](src BinlogSource, table string, columns []string) (<-chan Change[T], error) {
+	var zero T
+	if reflect.TypeOf(zero) == nil || reflect.TypeOf(zero).Kind() != reflect.Struct {
+		return nil, fmt.Errorf("binlog: Subscribe expects struct type, not %T", zero)
+	}
+	dot := strings.IndexByte(table, '.')
+	if dot <= 0 || dot == len(table)-1 {
+		return nil, fmt.Errorf("binlog: invalid table %q, expected db.table", table)
+	}
+	schema, name := table[:dot], table[dot+1:]
+	ch := make(chan Change[T])
+	go func() {
+		defer close(ch)
+		for {
+			e, err := src.NextEvent()
+			if err != nil {
+				ch <- Change[T]{Err: err}
+				return
+			}
+			re, ok := e.Data.(RowsEvent)
+			if !ok || re.TableMap.SchemaName != schema || re.TableMap.TableName != name {
+				continue
+			}
+			for {
+				values, valuesBeforeUpdate, err := src.NextRow()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					ch <- Change[T]{Err: err}
+					return
+				}
+				if columns != nil && e.Header.EventType.IsUpdateRows() {
+					before := Row{Columns: re.ColumnsBeforeUpdate(), Values: valuesBeforeUpdate}
+					if !ColumnsChanged(before, Row{Columns: re.Columns(), Values: values}, columns...) {
+						continue
+					}
+				}
+				c := Change[T]{Header: e.Header}
+				switch {
+				case e.Header.EventType.IsDeleteRows():
+					c.Before, err = scanStruct[T](re.Columns(), values)
+				case e.Header.EventType.IsUpdateRows():
+					if c.Before, err = scanStruct[T](re.ColumnsBeforeUpdate(), valuesBeforeUpdate); err == nil {
+						c.After, err = scanStruct[T](re.Columns(), values)
+					}
+				default:
+					c.After, err = scanStruct[T](re.Columns(), values)
+				}
+				if err != nil {
+					ch <- Change[T]{Header: e.Header, Err: err}
+					return
+				}
+				ch <- c
+			}
+		}
+	}()
+	return ch, nil
+}
+
+func scanStruct[T any](cols []Column, values []interface{}) (*T, error) {
+	v := new(T)
+	if err := (Row{Columns: cols, Values: values}).ScanStruct(v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
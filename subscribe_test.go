@@ -0,0 +1,89 @@
+//go:build go1.18
+// +build go1.18
+
+package binlog
+
+import (
+	"database/sql"
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+func TestSubscribe(t *testing.T) {
+	type allTypes struct {
+		Tiny    sql.NullInt64 `binlog:"c_tiny"`
+		Varchar *string       `binlog:"c_varchar"`
+		Decimal *float64      `binlog:"c_decimal"`
+		Enum    *string       `binlog:"c_enum"`
+		Year    *int          `binlog:"c_year"`
+	}
+	bl, err := Open(filepath.Join("testdata", "fixtures", "mysql80_full"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bl.Close()
+	if err := bl.Seek(0, "binlog.000001", 4); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Subscribe[allTypes](bl, "test"); err == nil {
+		t.Fatal("error expected for invalid table")
+	}
+	if _, err := Subscribe[int](bl, "test.all_types"); err == nil {
+		t.Fatal("error expected for non-struct type")
+	}
+	ch, err := Subscribe[allTypes](bl, "test.all_types")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var changes []Change[allTypes]
+	for c := range ch {
+		changes = append(changes, c)
+	}
+	if len(changes) != 3 {
+		t.Fatalf("got %d changes, want 3", len(changes))
+	}
+	c := changes[0]
+	if c.Err != nil || c.Before != nil || c.After == nil || !c.Header.EventType.IsWriteRows() {
+		t.Fatalf("got %+v", c)
+	}
+	if got := *c.After; got.Tiny.Int64 != -5 || *got.Varchar != "héllo" || *got.Decimal != 1234.56 || *got.Enum != "b" || *got.Year != 2021 {
+		t.Fatalf("got %+v", got)
+	}
+	if got := *changes[1].After; got.Tiny.Valid || got.Varchar != nil || got.Year != nil {
+		t.Fatalf("got %+v, want nulls", got)
+	}
+	if changes[2].Err != io.EOF {
+		t.Fatalf("got %v, want io.EOF", changes[2].Err)
+	}
+}
+
+func TestSubscribeColumns(t *testing.T) {
+	type row struct {
+		Tiny *int `binlog:"c_tiny"`
+	}
+	bl, err := Open(filepath.Join("testdata", "fixtures", "mysql80_full"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bl.Close()
+	if err := bl.Seek(0, "binlog.000001", 4); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SubscribeColumns[row](bl, "test.all_types"); err == nil {
+		t.Fatal("error expected without columns")
+	}
+	ch, err := SubscribeColumns[row](bl, "test.all_types", "c_tiny")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for c := range ch {
+		if c.Err == nil {
+			n++
+		}
+	}
+	if n != 2 {
+		t.Fatalf("got %d inserts, want 2", n)
+	}
+}
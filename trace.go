@@ -0,0 +1,92 @@
+package binlog
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// tracer writes hex dump of bytes sent and received, with direction
+// and timestamp, for debugging protocol issues.
+//
+// sample output:
+//
+//	2021-03-04T05:06:07.123456Z < 4 bytes
+//	00000000  4a 00 00 00                                       |J...|
+//	2021-03-04T05:06:07.123481Z < 74 bytes
+//	00000000  0a 38 2e 30 2e 32 33 00  01 00 00 00 61 62 63 64  |.8.0.23.....abcd|
+//	...
+//
+// '<' is for bytes received from server, '>' is for bytes sent to server.
+// Received packet header and payload are usually logged separately.
+type tracer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (t *tracer) dump(dir byte, b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Fprintf(t.w, "%s %c %d bytes\n", time.Now().UTC().Format("2006-01-02T15:04:05.000000Z"), dir, len(b))
+	d := hex.Dumper(t.w)
+	_, _ = d.Write(b)
+	_ = d.Close()
+}
+
+// traceConn traces bytes read from and written to rw.
+type traceConn struct {
+	rw io.ReadWriter
+	t  *tracer
+}
+
+func (c traceConn) Read(p []byte) (int, error) {
+	n, err := c.rw.Read(p)
+	c.t.dump('<', p[:n])
+	return n, err
+}
+
+func (c traceConn) Write(p []byte) (int, error) {
+	n, err := c.rw.Write(p)
+	c.t.dump('>', p[:n])
+	return n, err
+}
+
+// SetTrace logs all bytes sent and received on this connection to w,
+// as hex dump with direction and timestamp. Data sent over SSL is
+// logged unencrypted. Passing nil disables tracing.
+//
+// Note that the dump includes the password scramble sent during
+// Authenticate. Use DialOptions.Trace to include the handshake.
+func (bl *Remote) SetTrace(w io.Writer) {
+	if w == nil {
+		bl.tracer = nil
+	} else {
+		bl.tracer = &tracer{w: w}
+	}
+}
+
+// rw returns reader/writer used for protocol packets.
+func (bl *Remote) rw() io.ReadWriter {
+	if bl.prefetch != nil {
+		if bl.isStreaming() {
+			return prefetchConn{bl.prefetch, bl.conn}
+		}
+		bl.prefetch.stop()
+		bl.prefetch = nil
+	}
+	if bl.tracer != nil {
+		return traceConn{bl.conn, bl.tracer}
+	}
+	return bl.conn
+}
+
+// prefetchConn reads from prefetcher, and writes to conn.
+type prefetchConn struct {
+	io.Reader
+	io.Writer
+}
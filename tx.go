@@ -0,0 +1,210 @@
+package binlog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LargeTxConfig configures detection of transactions exceeding
+// MaxEvents events or MaxBytes bytes. Zero value of a limit
+// means no limit on it.
+type LargeTxConfig struct {
+	MaxEvents int
+	MaxBytes  int64
+
+	// Chunk, if true, emits a TxChunkEvent after every MaxEvents
+	// events or MaxBytes bytes of a transaction. Consumers can use
+	// these continuation markers to flush partial transactions to sinks.
+	Chunk bool
+
+	// OnOverflow, if non-nil, is called once per transaction, when
+	// it first exceeds the limits. Error returned is returned by NextEvent.
+	OnOverflow func(tx TxStats) error
+}
+
+// TxStats captures statistics of a transaction seen so far.
+type TxStats struct {
+	StartFile string // binlog file, where transaction started
+	StartPos  uint32 // position of the event that started the transaction
+	Events    int    // number of events including begin event
+	Bytes     int64  // total size of events
+
+	// StatementChanges is number of data changes logged as statements
+	// instead of row images, as in MIXED or STATEMENT binlog_format.
+	// Such changes are not available through NextRow.
+	StatementChanges int
+}
+
+// TxChunkEvent is a synthetic event, which marks the end of a chunk of
+// a large transaction. It is never written to binlog. Its Header has
+// zero EventSize and carries the position of the last event in the chunk.
+//
+// see LargeTxConfig.
+type TxChunkEvent struct {
+	Chunk int // chunk number, starting from 1
+	TxStats
+}
+
+// BeginTransactionEvent is a synthetic event, returned right after
+// the event that begins a transaction, i.e QueryEvent with BEGIN.
+// It is never written to binlog.
+//
+// Synthetic events have zero EventSize. Their Header is copied from
+// the event which triggered it, with EventType UNKNOWN_EVENT.
+type BeginTransactionEvent struct{}
+
+// EndTransactionEvent is a synthetic event, returned right after the
+// event that ends a transaction, i.e XIDEvent or QueryEvent with COMMIT
+// or ROLLBACK. It is never written to binlog.
+type EndTransactionEvent struct {
+	Rollback bool // true if transaction is rolled back
+	Prepared bool // true if XA transaction is prepared, but not committed
+	TxStats
+}
+
+// EndStatementEvent is a synthetic event, returned right after the
+// last RowsEvent of a statement, i.e. with STMT_END_F flag. It is
+// never written to binlog.
+type EndStatementEvent struct{}
+
+// synthetic returns synthetic event triggered by e.
+func synthetic(e Event, data interface{}) Event {
+	h := e.Header
+	h.EventType, h.EventSize = UNKNOWN_EVENT, 0
+	return Event{Header: h, Data: data}
+}
+
+// txTracker tracks transaction boundaries in the event stream.
+type txTracker struct {
+	active      bool
+	stats       TxStats
+	chunk       int
+	chunkEvents int
+	chunkBytes  int64
+	overflowed  bool
+}
+
+// txBoundary tells whether e begins or ends a transaction.
+func txBoundary(e Event) (begin, end bool) {
+	switch e.Header.EventType {
+	case XID_EVENT, XA_PREPARE_LOG_EVENT:
+		return false, true
+	case QUERY_EVENT:
+		q := strings.TrimSpace(e.Data.(QueryEvent).Query)
+		switch {
+		case strings.EqualFold(q, "BEGIN"), hasPrefixFold(q, "XA START"):
+			return true, false
+		case strings.EqualFold(q, "COMMIT"), strings.EqualFold(q, "ROLLBACK"),
+			hasPrefixFold(q, "XA COMMIT"), hasPrefixFold(q, "XA ROLLBACK"):
+			return false, true
+		}
+	}
+	return false, false
+}
+
+func isRollback(e Event) bool {
+	if qe, ok := e.Data.(QueryEvent); ok {
+		q := strings.TrimSpace(qe.Query)
+		return strings.EqualFold(q, "ROLLBACK") || hasPrefixFold(q, "XA ROLLBACK")
+	}
+	return false
+}
+
+// isStatementChange tells whether e is a data change logged as statement.
+func isStatementChange(e Event) bool {
+	qe, ok := e.Data.(QueryEvent)
+	if !ok {
+		return false
+	}
+	toks := sqlTokens(qe.Query)
+	if len(toks) == 0 {
+		return false
+	}
+	switch strings.ToUpper(toks[0].text) {
+	case "INSERT", "UPDATE", "DELETE", "REPLACE", "LOAD", "WITH":
+		return true
+	}
+	return false
+}
+
+// StatementChangeError is returned by NextEvent in strict row format
+// mode, when a data change is logged as statement, instead of rows.
+type StatementChangeError struct {
+	Header EventHeader
+	Query  string
+}
+
+func (e *StatementChangeError) Error() string {
+	return fmt.Sprintf("binlog: data change logged as statement at %s:%d: %s", e.Header.LogFile, e.Header.NextPos, e.Query)
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// track updates transaction state with event e, and queues
+// synthetic events into r.pending if required.
+func (t *txTracker) track(r *reader, e Event) error {
+	begin, end := txBoundary(e)
+	stmtChange := isStatementChange(e)
+	if stmtChange && r.opts.strictRowFormat {
+		return &StatementChangeError{e.Header, e.Data.(QueryEvent).Query}
+	}
+	if r.opts.boundaryEvents {
+		if re, ok := e.Data.(RowsEvent); ok && re.StmtEnd() {
+			r.pending = append(r.pending, synthetic(e, EndStatementEvent{}))
+		}
+	}
+	if begin {
+		pos := e.Header.NextPos - e.Header.EventSize
+		*t = txTracker{active: true, stats: TxStats{StartFile: e.Header.LogFile, StartPos: pos}}
+	}
+	if !t.active {
+		// XA COMMIT and XA ROLLBACK are logged outside transaction
+		queueXAEvent(r, e)
+		return nil
+	}
+	size := int64(e.Header.EventSize)
+	t.stats.Events++
+	t.stats.Bytes += size
+	if stmtChange {
+		t.stats.StatementChanges++
+	}
+	t.chunkEvents++
+	t.chunkBytes += size
+	if end {
+		t.active = false
+	}
+	if r.opts.boundaryEvents {
+		switch {
+		case begin:
+			r.pending = append(r.pending, synthetic(e, BeginTransactionEvent{}))
+		case end:
+			prepared := false
+			if xpe, ok := e.Data.(XAPrepareEvent); ok {
+				prepared = !xpe.OnePhase
+			}
+			r.pending = append(r.pending, synthetic(e, EndTransactionEvent{isRollback(e), prepared, t.stats}))
+		}
+		queueXAEvent(r, e)
+		queueSavepointEvent(r, e)
+	}
+
+	cfg := r.opts.largeTx
+	if cfg == nil {
+		return nil
+	}
+	exceeds := func(events int, bytes int64) bool {
+		return (cfg.MaxEvents > 0 && events >= cfg.MaxEvents) || (cfg.MaxBytes > 0 && bytes >= cfg.MaxBytes)
+	}
+	if cfg.Chunk && !end && exceeds(t.chunkEvents, t.chunkBytes) {
+		t.chunk++
+		t.chunkEvents, t.chunkBytes = 0, 0
+		r.pending = append(r.pending, synthetic(e, TxChunkEvent{t.chunk, t.stats}))
+	}
+	if cfg.OnOverflow != nil && !t.overflowed && exceeds(t.stats.Events, t.stats.Bytes) {
+		t.overflowed = true
+		return cfg.OnOverflow(t.stats)
+	}
+	return nil
+}
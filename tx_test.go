@@ -0,0 +1,115 @@
+package binlog
+
+import "testing"
+
+func TestTxTracker_largeTx(t *testing.T) {
+	var overflow []TxStats
+	r := &reader{opts: &decodeOptions{largeTx: &LargeTxConfig{
+		MaxEvents: 3,
+		Chunk:     true,
+		OnOverflow: func(tx TxStats) error {
+			overflow = append(overflow, tx)
+			return nil
+		},
+	}}}
+	pos := uint32(100)
+	event := func(typ EventType, data interface{}) Event {
+		pos += 10
+		return Event{Header: EventHeader{EventType: typ, EventSize: 10, LogFile: "binlog.000001", NextPos: pos}, Data: data}
+	}
+	events := []Event{
+		event(QUERY_EVENT, QueryEvent{Query: "BEGIN"}),
+		event(TABLE_MAP_EVENT, TableMapEvent{}),
+		event(WRITE_ROWS_EVENTv2, RowsEvent{}),
+		event(TABLE_MAP_EVENT, TableMapEvent{}),
+		event(WRITE_ROWS_EVENTv2, RowsEvent{}),
+		event(XID_EVENT, XIDEvent{}),
+		event(QUERY_EVENT, QueryEvent{Query: "BEGIN"}),
+		event(XID_EVENT, XIDEvent{}),
+	}
+	var chunks []TxChunkEvent
+	for _, e := range events {
+		if err := r.tx.track(r, e); err != nil {
+			t.Fatal(err)
+		}
+		for {
+			pe, ok := r.nextPending()
+			if !ok {
+				break
+			}
+			chunks = append(chunks, pe.Data.(TxChunkEvent))
+		}
+	}
+	if len(chunks) != 1 || chunks[0].Chunk != 1 || chunks[0].Events != 3 {
+		t.Fatalf("chunks: got %+v", chunks)
+	}
+	want := TxStats{StartFile: "binlog.000001", StartPos: 100, Events: 3, Bytes: 30}
+	if len(overflow) != 1 || overflow[0] != want {
+		t.Fatalf("overflow: got %+v, want [%+v]", overflow, want)
+	}
+}
+
+func TestTxTracker_boundaryEvents(t *testing.T) {
+	r := &reader{opts: &decodeOptions{boundaryEvents: true}}
+	events := []Event{
+		{Header: EventHeader{EventType: QUERY_EVENT}, Data: QueryEvent{Query: "BEGIN"}},
+		{Header: EventHeader{EventType: TABLE_MAP_EVENT}, Data: TableMapEvent{}},
+		{Header: EventHeader{EventType: WRITE_ROWS_EVENTv2}, Data: RowsEvent{}},
+		{Header: EventHeader{EventType: WRITE_ROWS_EVENTv2}, Data: RowsEvent{flags: rowsEventStmtEnd}},
+		{Header: EventHeader{EventType: QUERY_EVENT}, Data: QueryEvent{Query: "rollback"}},
+	}
+	var got []interface{}
+	for _, e := range events {
+		if err := r.tx.track(r, e); err != nil {
+			t.Fatal(err)
+		}
+		for {
+			pe, ok := r.nextPending()
+			if !ok {
+				break
+			}
+			got = append(got, pe.Data)
+		}
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %d synthetic events, want 3", len(got))
+	}
+	if _, ok := got[0].(BeginTransactionEvent); !ok {
+		t.Fatalf("got %T, want BeginTransactionEvent", got[0])
+	}
+	if _, ok := got[1].(EndStatementEvent); !ok {
+		t.Fatalf("got %T, want EndStatementEvent", got[1])
+	}
+	if e, ok := got[2].(EndTransactionEvent); !ok || !e.Rollback || e.Events != 5 {
+		t.Fatalf("got %#v, want EndTransactionEvent with Rollback", got[2])
+	}
+}
+
+func TestTxTracker_statementChanges(t *testing.T) {
+	events := []Event{
+		{Header: EventHeader{EventType: QUERY_EVENT}, Data: QueryEvent{Query: "BEGIN"}},
+		{Header: EventHeader{EventType: QUERY_EVENT}, Data: QueryEvent{Query: "/* app */ insert into t values(uuid())"}},
+		{Header: EventHeader{EventType: WRITE_ROWS_EVENTv2}, Data: RowsEvent{}},
+		{Header: EventHeader{EventType: XID_EVENT}, Data: XIDEvent{}},
+	}
+	r := &reader{opts: &decodeOptions{}}
+	for _, e := range events {
+		if err := r.tx.track(r, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := r.tx.stats.StatementChanges; n != 1 {
+		t.Fatal("got", n, "want", 1)
+	}
+
+	r = &reader{opts: &decodeOptions{strictRowFormat: true}}
+	var err error
+	for _, e := range events {
+		if err = r.tx.track(r, e); err != nil {
+			break
+		}
+	}
+	if _, ok := err.(*StatementChangeError); !ok {
+		t.Fatalf("got %v, want StatementChangeError", err)
+	}
+}
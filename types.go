@@ -3,9 +3,10 @@ package binlog
 import (
 	"bytes"
 	"encoding/binary"
-	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"math"
 	"math/big"
 	"strconv"
@@ -27,14 +28,14 @@ const (
 	TypeFloat      ColumnType = 0x04 // float32. FLOAT
 	TypeDouble     ColumnType = 0x05 // float64. DOUBLE
 	TypeNull       ColumnType = 0x06
-	TypeTimestamp  ColumnType = 0x07
+	TypeTimestamp  ColumnType = 0x07 // time.Time(LOCAL). TIMESTAMP before MySQL 5.6
 	TypeLongLong   ColumnType = 0x08 // int64 or uint64. BIGINT
 	TypeInt24      ColumnType = 0x09 // int32 or uint32. MEDIUMINT
 	TypeDate       ColumnType = 0x0a // time.Time(UTC). DATE
-	TypeTime       ColumnType = 0x0b
-	TypeDateTime   ColumnType = 0x0c
+	TypeTime       ColumnType = 0x0b // time.Duration. TIME before MySQL 5.6
+	TypeDateTime   ColumnType = 0x0c // time.Time(UTC). DATETIME before MySQL 5.6
 	TypeYear       ColumnType = 0x0d // int. YEAR
-	TypeNewDate    ColumnType = 0x0e
+	TypeNewDate    ColumnType = 0x0e // time.Time(UTC). DATE
 	TypeVarchar    ColumnType = 0x0f // string. VARCHAR
 	TypeBit        ColumnType = 0x10 // uint64. BIT
 	TypeTimestamp2 ColumnType = 0x11 // time.Time(LOCAL). TIMESTAMP
@@ -48,9 +49,9 @@ const (
 	TypeMediumBlob ColumnType = 0xfa
 	TypeLongBlob   ColumnType = 0xfb
 	TypeBlob       ColumnType = 0xfc // []byte or string. TINYBLOB BLOB MEDIUMBLOB LONGBLOB TINYTEXT TEXT MEDIUMTEXT LONGTEXT
-	TypeVarString  ColumnType = 0xfd
+	TypeVarString  ColumnType = 0xfd // string. VARCHAR
 	TypeString     ColumnType = 0xfe // string. CHAR
-	TypeGeometry   ColumnType = 0xff
+	TypeGeometry   ColumnType = 0xff // []byte. GEOMETRY in WKB with SRID prefix
 )
 
 var typeNames = map[ColumnType]string{
@@ -115,6 +116,15 @@ func (t ColumnType) String() string {
 	return fmt.Sprintf("0x%02x", uint8(t))
 }
 
+// DecodeValue decodes a single non-NULL value of this column from
+// the raw row image in r, as encoded in rows events. It reads exactly
+// the bytes of the value, so successive calls can decode a row image
+// column by column. The type of returned value is as documented on
+// ColumnType constants.
+func (col Column) DecodeValue(r io.Reader) (interface{}, error) {
+	return col.decodeValue(&reader{rd: r, limit: -1, exact: true, opts: &decodeOptions{}})
+}
+
 func (col Column) decodeValue(r *reader) (interface{}, error) {
 	switch col.Type {
 	case TypeTiny:
@@ -159,7 +169,7 @@ func (col Column) decodeValue(r *reader) (interface{}, error) {
 		return math.Float32frombits(r.int4()), r.err
 	case TypeDouble:
 		return math.Float64frombits(r.int8()), r.err
-	case TypeVarchar, TypeString:
+	case TypeVarchar, TypeVarString, TypeString:
 		var size int
 		if col.Meta < 256 {
 			size = int(r.int1())
@@ -199,15 +209,31 @@ func (col Column) decodeValue(r *reader) (interface{}, error) {
 		if r.err != nil {
 			return nil, r.err
 		}
-		v, err := new(jsonDecoder).decodeValue(buf)
+		d := jsonDecoder{useNumber: r.opts.jsonNumber}
+		v, err := d.decodeValue(buf)
 		return JSON{v}, err
-	case TypeDate:
+	case TypeDate, TypeNewDate:
 		v := r.int3()
 		var year, month, day uint32
 		if v != 0 {
 			year, month, day = v/(16*32), v/32%16, v%32
 		}
-		return time.Date(int(year), time.Month(month), int(day), 0, 0, 0, 0, time.UTC), r.err
+		t := time.Date(int(year), time.Month(month), int(day), 0, 0, 0, 0, time.UTC)
+		if month == 0 || day == 0 {
+			return r.invalidTime(fmt.Sprintf("%04d-%02d-%02d", year, month, day), t)
+		}
+		return t, r.err
+	case TypeDateTime:
+		// YYYYMMDDhhmmss as integer
+		v := r.int8()
+		d, tm := v/1000000, v%1000000
+		year, month, day := int(d/10000), int(d/100%100), int(d%100)
+		hour, min, sec := int(tm/10000), int(tm/100%100), int(tm%100)
+		t := time.Date(year, time.Month(month), day, hour, min, sec, 0, time.UTC)
+		if month == 0 || day == 0 {
+			return r.invalidTime(fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d", year, month, day, hour, min, sec), t)
+		}
+		return t, r.err
 	case TypeDateTime2:
 		buf := r.bytesInternal(5)
 		if r.err != nil {
@@ -225,7 +251,19 @@ func (col Column) decodeValue(r *reader) (interface{}, error) {
 		if err != nil {
 			return nil, err
 		}
-		return time.Date(year, time.Month(month), day, hour, min, sec, frac*1000, time.UTC), r.err
+		t := time.Date(year, time.Month(month), day, hour, min, sec, frac*1000, time.UTC)
+		if month == 0 || day == 0 {
+			lit := fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d", year, month, day, hour, min, sec)
+			return r.invalidTime(lit+fracLiteral(frac, col.Meta), t)
+		}
+		return t, r.err
+	case TypeTimestamp:
+		sec := r.int4()
+		t := time.Unix(int64(sec), 0)
+		if sec == 0 {
+			return r.invalidTime("0000-00-00 00:00:00", t)
+		}
+		return t, r.err
 	case TypeTimestamp2:
 		buf := r.bytesInternal(4)
 		if r.err != nil {
@@ -237,7 +275,25 @@ func (col Column) decodeValue(r *reader) (interface{}, error) {
 		if err != nil {
 			return nil, err
 		}
-		return time.Unix(int64(sec), int64(frac)*1000), r.err
+		t := time.Unix(int64(sec), int64(frac)*1000)
+		if sec == 0 && frac == 0 {
+			return r.invalidTime("0000-00-00 00:00:00"+fracLiteral(0, col.Meta), t)
+		}
+		return t, r.err
+	case TypeTime:
+		// hhmmss as signed integer
+		v := int32(r.int3()<<8) >> 8
+		neg := v < 0
+		if neg {
+			v = -v
+		}
+		d := time.Duration(v/10000)*time.Hour +
+			time.Duration(v/100%100)*time.Minute +
+			time.Duration(v%100)*time.Second
+		if neg {
+			d = -d
+		}
+		return d, r.err
 	case TypeTime2:
 		// https://github.com/debezium/debezium/blob/master/debezium-connector-mysql/src/main/java/io/debezium/connector/mysql/RowDeserializers.java#L314
 		//
@@ -308,6 +364,67 @@ func bitSlice(v uint64, bits, off, len int) int {
 	return int(v & ((1 << len) - 1))
 }
 
+// InvalidTimePolicy tells how zero dates such as '0000-00-00' and dates
+// with zero month or day such as '2021-00-15' are decoded, for DATE,
+// DATETIME and TIMESTAMP columns. MySQL permits them unless NO_ZERO_DATE
+// and NO_ZERO_IN_DATE sql modes are enabled.
+type InvalidTimePolicy int
+
+const (
+	// InvalidTimeNormalize decodes as time.Time normalized by time.Date,
+	// for example '0000-00-00' becomes -0001-11-30. Zero TIMESTAMP becomes
+	// unix epoch. This is the default.
+	InvalidTimeNormalize InvalidTimePolicy = iota
+
+	// InvalidTimeNil decodes as nil, same as NULL.
+	InvalidTimeNil
+
+	// InvalidTimeZero decodes as zero time.Time.
+	InvalidTimeZero
+
+	// InvalidTimeZeroDate decodes as ZeroDate.
+	InvalidTimeZeroDate
+
+	// InvalidTimeError fails decoding with error wrapping ErrInvalidTime.
+	InvalidTimeError
+)
+
+// ErrInvalidTime is returned for zero dates with InvalidTimeError policy.
+var ErrInvalidTime = errors.New("binlog: invalid temporal value")
+
+// ZeroDate represents invalid DATE, DATETIME or TIMESTAMP value, with
+// InvalidTimeZeroDate policy. It is in MySQL format such as "0000-00-00"
+// or "2021-00-15 10:20:30.500".
+type ZeroDate string
+
+func (d ZeroDate) String() string { return string(d) }
+
+func (r *reader) invalidTime(lit string, t time.Time) (interface{}, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	switch r.opts.invalidTime {
+	case InvalidTimeNil:
+		return nil, nil
+	case InvalidTimeZero:
+		return time.Time{}, nil
+	case InvalidTimeZeroDate:
+		return ZeroDate(lit), nil
+	case InvalidTimeError:
+		return nil, fmt.Errorf("%w %q", ErrInvalidTime, lit)
+	}
+	return t, nil
+}
+
+// fracLiteral returns fractional seconds of given precision,
+// such as ".500". frac is in microseconds.
+func fracLiteral(frac int, fsp uint16) string {
+	if fsp == 0 || fsp > 6 {
+		return ""
+	}
+	return fmt.Sprintf(".%06d", frac)[:1+fsp]
+}
+
 func fractionalSeconds(meta uint16, r *reader) (int, error) {
 	n := (meta + 1) / 2
 	v := bigEndian(r.bytesInternal(int(n)))
@@ -333,38 +450,6 @@ func unsetSignMask(bits int) int {
 	return ^(1 << bits)
 }
 
-func (col Column) valueLiteral(v interface{}) string {
-	if v == nil {
-		return "NULL"
-	}
-	switch col.Type {
-	case TypeEnum:
-		v := v.(Enum)
-		if len(v.Values) > 0 {
-			return strconv.Quote(v.String())
-		}
-	case TypeSet:
-		v := v.(Set)
-		if len(v.Values) > 0 {
-			return strconv.Quote(v.String())
-		}
-	case TypeJSON:
-		var buf bytes.Buffer
-		_ = json.NewEncoder(&buf).Encode(v)
-		s := buf.String()
-		return strconv.Quote(s[:len(s)-1]) // remove trailing newline
-	case TypeBlob:
-		if v, ok := v.([]byte); ok { // 63 = binary charset
-			return fmt.Sprintf(`x"%s"`, hex.EncodeToString(v))
-		}
-	}
-	switch v := v.(type) {
-	case time.Time:
-		return strconv.Quote(v.String())
-	}
-	return fmt.Sprintf("%#v", v)
-}
-
 // Decimal ---
 
 const digitsPerInteger int = 9
@@ -576,6 +661,61 @@ func (d Decimal) BigFloat() (*big.Float, error) {
 	return f, err
 }
 
+// BigRat returns the number as an exact *big.Rat.
+func (d Decimal) BigRat() (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(string(d))
+	if !ok {
+		return nil, fmt.Errorf("binlog: invalid decimal %q", string(d))
+	}
+	return r, nil
+}
+
+// Cmp compares d and e exactly and returns:
+//
+//	-1 if d <  e
+//	 0 if d == e
+//	+1 if d >  e
+//
+// trailing zeros are not significant, so 1.50 and 1.5 are equal.
+func (d Decimal) Cmp(e Decimal) (int, error) {
+	x, err := d.BigRat()
+	if err != nil {
+		return 0, err
+	}
+	y, err := e.BigRat()
+	if err != nil {
+		return 0, err
+	}
+	return x.Cmp(y), nil
+}
+
+// Equal tells whether d and e represent the same number.
+// returns false if either of them is not a valid decimal.
+func (d Decimal) Equal(e Decimal) bool {
+	c, err := d.Cmp(e)
+	return err == nil && c == 0
+}
+
+// Scale returns the number of digits after the decimal point.
+// For values decoded from TypeNewDecimal column, this is the
+// scale of the column definition.
+func (d Decimal) Scale() int {
+	if i := strings.IndexByte(string(d), '.'); i != -1 {
+		return len(d) - i - 1
+	}
+	return 0
+}
+
+// Precision returns the number of significant digits, i.e. the
+// digits of integral part excluding leading zeros, plus Scale.
+func (d Decimal) Precision() int {
+	s := strings.TrimPrefix(string(d), "-")
+	if i := strings.IndexByte(s, '.'); i != -1 {
+		s = s[:i]
+	}
+	return len(strings.TrimLeft(s, "0")) + d.Scale()
+}
+
 func (d Decimal) MarshalJSON() ([]byte, error) {
 	return []byte(d), nil
 }
@@ -585,8 +725,7 @@ func (d Decimal) MarshalJSON() ([]byte, error) {
 // https://dev.mysql.com/doc/refman/8.0/en/json.html
 type JSON struct{ Val interface{} }
 
+// MarshalJSON returns JSON text. see Raw.
 func (j JSON) MarshalJSON() ([]byte, error) {
-	var buf bytes.Buffer
-	err := json.NewEncoder(&buf).Encode(j.Val)
-	return buf.Bytes(), err
+	return []byte(j.Raw()), nil
 }
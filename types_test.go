@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"reflect"
 	"strconv"
 	"testing"
@@ -352,3 +354,115 @@ func insertValue(t *testing.T, sqlType, value string) {
 		t.Fatalf("rowsAffected: got %d, want %d", got, 1)
 	}
 }
+
+func TestDecimal(t *testing.T) {
+	testCases := []struct {
+		d, e      Decimal
+		cmp       int
+		scale     int
+		precision int
+	}{
+		{"123.450", "123.45", 0, 3, 6},
+		{"-12.45", "12.45", -1, 2, 4},
+		{"123456", "123455.99", 1, 0, 6},
+		{"0.5", "0.50", 0, 1, 1},
+		{"-0.0001", "0", -1, 4, 4},
+	}
+	for _, tc := range testCases {
+		t.Run(string(tc.d), func(t *testing.T) {
+			got, err := tc.d.Cmp(tc.e)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.cmp {
+				t.Errorf("Cmp(%s): got %d, want %d", tc.e, got, tc.cmp)
+			}
+			if got := tc.d.Equal(tc.e); got != (tc.cmp == 0) {
+				t.Errorf("Equal(%s): got %v", tc.e, got)
+			}
+			if got := tc.d.Scale(); got != tc.scale {
+				t.Errorf("Scale: got %d, want %d", got, tc.scale)
+			}
+			if got := tc.d.Precision(); got != tc.precision {
+				t.Errorf("Precision: got %d, want %d", got, tc.precision)
+			}
+		})
+	}
+	if _, err := Decimal("1.2.3").BigRat(); err == nil {
+		t.Fatal("invalid decimal must return error")
+	}
+}
+
+func TestColumn_DecodeValue(t *testing.T) {
+	// row image of (int -23, varchar(10) 'hello', tinyint unsigned 200)
+	data := []byte{0xe9, 0xff, 0xff, 0xff, 5, 'h', 'e', 'l', 'l', 'o', 200, 'x'}
+	r := bytes.NewReader(data)
+	cols := []Column{
+		{Type: TypeLong},
+		{Type: TypeVarchar, Meta: 10},
+		{Type: TypeTiny, Unsigned: true},
+	}
+	want := []interface{}{int32(-23), "hello", uint8(200)}
+	for i, col := range cols {
+		v, err := col.DecodeValue(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != want[i] {
+			t.Fatalf("col %d: got %#v, want %#v", i, v, want[i])
+		}
+	}
+	if r.Len() != 1 {
+		t.Fatalf("DecodeValue must not read beyond value: unread %d bytes", r.Len())
+	}
+	if _, err := (Column{Type: TypeLongLong}).DecodeValue(r); err != io.ErrUnexpectedEOF {
+		t.Fatal("got", err, "want", io.ErrUnexpectedEOF)
+	}
+}
+
+func TestInvalidTimePolicy(t *testing.T) {
+	testCases := []struct {
+		col  Column
+		data []byte
+		lit  string
+	}{
+		{Column{Type: TypeDate}, []byte{0, 0, 0}, "0000-00-00"},
+		{Column{Type: TypeDate}, []byte{0x0f, 0xca, 0x0f}, "2021-00-15"},
+		{Column{Type: TypeDateTime2}, []byte{0x80, 0, 0, 0, 0}, "0000-00-00 00:00:00"},
+		{Column{Type: TypeTimestamp2, Meta: 3}, []byte{0, 0, 0, 0, 0, 0}, "0000-00-00 00:00:00.000"},
+	}
+	decode := func(col Column, data []byte, p InvalidTimePolicy) (interface{}, error) {
+		r := &reader{rd: bytes.NewReader(data), limit: -1, exact: true, opts: &decodeOptions{invalidTime: p}}
+		return col.decodeValue(r)
+	}
+	for _, tc := range testCases {
+		t.Run(tc.lit, func(t *testing.T) {
+			v, err := decode(tc.col, tc.data, InvalidTimeNormalize)
+			if _, ok := v.(time.Time); err != nil || !ok {
+				t.Errorf("normalize: got %#v, %v", v, err)
+			}
+			if v, err := decode(tc.col, tc.data, InvalidTimeNil); err != nil || v != nil {
+				t.Errorf("nil: got %#v, %v", v, err)
+			}
+			if v, err := decode(tc.col, tc.data, InvalidTimeZero); err != nil || v != (time.Time{}) {
+				t.Errorf("zero: got %#v, %v", v, err)
+			}
+			v, err = decode(tc.col, tc.data, InvalidTimeZeroDate)
+			if err != nil || v != ZeroDate(tc.lit) {
+				t.Errorf("zeroDate: got %#v, %v", v, err)
+			}
+			if got := tc.col.SQLLiteral(v); got != "'"+tc.lit+"'" {
+				t.Errorf("SQLLiteral: got %s", got)
+			}
+			if _, err := decode(tc.col, tc.data, InvalidTimeError); !errors.Is(err, ErrInvalidTime) {
+				t.Errorf("error: got %v, want ErrInvalidTime", err)
+			}
+		})
+	}
+
+	// valid date is not affected
+	v, err := decode(Column{Type: TypeDate}, []byte{0x2f, 0xca, 0x0f}, InvalidTimeError)
+	if err != nil || v != time.Date(2021, 1, 15, 0, 0, 0, 0, time.UTC) {
+		t.Fatalf("got %#v, %v", v, err)
+	}
+}
@@ -0,0 +1,171 @@
+package binlog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SourceReport is the result of Remote.ValidateSource.
+type SourceReport struct {
+	LogBin            string // ON or OFF
+	BinlogFormat      string // ROW, MIXED or STATEMENT
+	BinlogRowImage    string // FULL, MINIMAL or NOBLOB
+	BinlogRowMetadata string // FULL or MINIMAL. empty if not supported by server
+	ServerID          uint32
+
+	Errors   []string // problems that prevent reading row changes
+	Warnings []string // problems that may cause missing or partial data
+}
+
+// OK tells whether there are no errors in the report.
+func (r SourceReport) OK() bool {
+	return len(r.Errors) == 0
+}
+
+// ValidateSource checks server configuration required for reading row
+// changes from binlog. It does not modify anything on the server.
+// Call it before Seek, so that pipelines fail fast with actionable
+// messages, instead of missing row changes.
+//
+// The error returned is only for failure in querying the server.
+// Use SourceReport.OK to check the result.
+func (bl *Remote) ValidateSource() (SourceReport, error) {
+	rows, err := bl.queryRows(`show global variables where Variable_name in ('log_bin', 'binlog_format', 'binlog_row_image', 'binlog_row_metadata', 'server_id')`)
+	if err != nil {
+		return SourceReport{}, err
+	}
+	vars := make(map[string]string)
+	for _, row := range rows {
+		name, _ := row[0].(string)
+		value, _ := row[1].(string)
+		vars[strings.ToLower(name)] = value
+	}
+	return validateSource(vars), nil
+}
+
+func validateSource(vars map[string]string) SourceReport {
+	r := SourceReport{
+		LogBin:            strings.ToUpper(vars["log_bin"]),
+		BinlogFormat:      strings.ToUpper(vars["binlog_format"]),
+		BinlogRowImage:    strings.ToUpper(vars["binlog_row_image"]),
+		BinlogRowMetadata: strings.ToUpper(vars["binlog_row_metadata"]),
+	}
+	if id, err := strconv.ParseUint(vars["server_id"], 10, 32); err == nil {
+		r.ServerID = uint32(id)
+	}
+	if r.LogBin != "ON" && r.LogBin != "1" {
+		r.Errors = append(r.Errors, "log_bin is not ON: start server with --log-bin")
+	}
+	if r.ServerID == 0 {
+		r.Errors = append(r.Errors, "server_id is 0: server refuses replicas; set server_id to non-zero")
+	}
+	switch r.BinlogFormat {
+	case "ROW":
+	case "MIXED":
+		r.Warnings = append(r.Warnings, "binlog_format is MIXED: some changes are logged as statements without row images; set binlog_format=ROW")
+	default:
+		r.Errors = append(r.Errors, fmt.Sprintf("binlog_format is %s: row changes are not logged; set binlog_format=ROW", r.BinlogFormat))
+	}
+	switch r.BinlogRowImage {
+	case "FULL", "":
+	default:
+		r.Warnings = append(r.Warnings, fmt.Sprintf("binlog_row_image is %s: row images have only some columns; set binlog_row_image=FULL", r.BinlogRowImage))
+	}
+	if r.BinlogRowMetadata == "MINIMAL" {
+		r.Warnings = append(r.Warnings, "binlog_row_metadata is MINIMAL: column names are not available; set binlog_row_metadata=FULL")
+	}
+	return r
+}
+
+// RowMetadata returns the value of binlog_row_metadata on server, i.e.
+// FULL or MINIMAL. It returns empty string if server does not support
+// it (see FeatureRowMetadata), in which case metadata is always minimal.
+//
+// With MINIMAL, TableMapEvent has no column names, signedness, charsets
+// or enum/set values.
+func (bl *Remote) RowMetadata() (string, error) {
+	if !bl.Supports(FeatureRowMetadata) {
+		return "", nil
+	}
+	rows, err := bl.queryRows(`show global variables like 'binlog_row_metadata'`)
+	if err != nil {
+		return "", err
+	}
+	if len(rows) == 0 {
+		return "", nil
+	}
+	v, _ := rows[0][1].(string)
+	return strings.ToUpper(v), nil
+}
+
+// RowMetadataError is returned by EnsureFullRowMetadata, if
+// binlog_row_metadata is not FULL.
+type RowMetadataError struct {
+	Value string // detected value of binlog_row_metadata
+	Err   error  // error in changing it, if any
+}
+
+func (e *RowMetadataError) Error() string {
+	value := e.Value
+	if value == "" {
+		value = "not supported"
+	}
+	if e.Err != nil {
+		return fmt.Sprintf("binlog: binlog_row_metadata is %s: set to FULL failed: %v", value, e.Err)
+	}
+	return fmt.Sprintf("binlog: binlog_row_metadata is %s, want FULL", value)
+}
+
+// EnsureFullRowMetadata returns *RowMetadataError if binlog_row_metadata
+// is not FULL. If set is true, it tries to change it with SET PERSIST,
+// falling back to SET GLOBAL, which requires SYSTEM_VARIABLES_ADMIN
+// privilege. If the user lacks it, the change is not attempted and
+// RowMetadataError.Err is *PermissionError. The change applies only
+// to events logged after it.
+func (bl *Remote) EnsureFullRowMetadata(set bool) error {
+	v, err := bl.RowMetadata()
+	if err != nil {
+		return err
+	}
+	if v == "FULL" {
+		return nil
+	}
+	if !set || v == "" || bl.minimalPrivileges {
+		return &RowMetadataError{Value: v}
+	}
+	if ok, err := bl.hasPrivilege("SYSTEM_VARIABLES_ADMIN", "SUPER"); err == nil && !ok {
+		return &RowMetadataError{Value: v, Err: &PermissionError{
+			Query:   "SET GLOBAL binlog_row_metadata",
+			Code:    erSpecificAccessDenied,
+			Message: "SYSTEM_VARIABLES_ADMIN or SUPER privilege required",
+		}}
+	}
+	if _, err = bl.query(`SET PERSIST binlog_row_metadata = FULL`); err != nil {
+		if _, err = bl.query(`SET GLOBAL binlog_row_metadata = FULL`); err != nil {
+			return &RowMetadataError{Value: v, Err: err}
+		}
+	}
+	return nil
+}
+
+// SetRowMetadataFunc sets f to be called by Seek and SeekGTID, if
+// binlog_row_metadata is not FULL, so that callers can warn or refuse
+// to proceed. If set is true, it is first tried to change to FULL as in
+// EnsureFullRowMetadata, and f is called only if that fails. f is called
+// before the binlog is requested, so it may use methods of bl.
+//
+// Errors in querying the server are not reported to f. Pass nil f to
+// disable the check.
+func (bl *Remote) SetRowMetadataFunc(set bool, f func(err *RowMetadataError)) {
+	bl.rowMetadataFunc, bl.rowMetadataSet = f, set
+}
+
+func (bl *Remote) checkRowMetadata() {
+	if bl.rowMetadataFunc == nil {
+		return
+	}
+	if err, ok := bl.EnsureFullRowMetadata(bl.rowMetadataSet).(*RowMetadataError); ok {
+		bl.rowMetadataFunc(err)
+	}
+}
@@ -0,0 +1,63 @@
+package binlog
+
+import "testing"
+
+func TestValidateSource(t *testing.T) {
+	testCases := []struct {
+		vars     map[string]string
+		errors   int
+		warnings int
+	}{
+		{map[string]string{"log_bin": "ON", "binlog_format": "ROW", "binlog_row_image": "FULL", "binlog_row_metadata": "FULL", "server_id": "1"}, 0, 0},
+		{map[string]string{"log_bin": "ON", "binlog_format": "ROW", "binlog_row_image": "FULL", "server_id": "1"}, 0, 0},
+		{map[string]string{"log_bin": "ON", "binlog_format": "MIXED", "binlog_row_image": "MINIMAL", "binlog_row_metadata": "MINIMAL", "server_id": "1"}, 0, 3},
+		{map[string]string{"log_bin": "OFF", "binlog_format": "STATEMENT", "server_id": "0"}, 3, 0},
+	}
+	for i, tc := range testCases {
+		r := validateSource(tc.vars)
+		if len(r.Errors) != tc.errors || len(r.Warnings) != tc.warnings {
+			t.Errorf("#%d: got %q %q, want %d errors %d warnings", i, r.Errors, r.Warnings, tc.errors, tc.warnings)
+		}
+		if r.OK() != (tc.errors == 0) {
+			t.Errorf("#%d: OK() must be %v", i, tc.errors == 0)
+		}
+	}
+}
+
+func TestRemote_SetRowMetadataFunc(t *testing.T) {
+	tests := []struct {
+		value  string
+		grant  string
+		set    bool
+		called bool
+	}{
+		{"FULL", "GRANT REPLICATION SLAVE ON *.* TO `repl`@`%`", false, false},
+		{"MINIMAL", "GRANT REPLICATION SLAVE ON *.* TO `repl`@`%`", false, true},
+		{"MINIMAL", "GRANT REPLICATION SLAVE ON *.* TO `repl`@`%`", true, true},
+		{"MINIMAL", "GRANT REPLICATION SLAVE, SYSTEM_VARIABLES_ADMIN ON *.* TO `repl`@`%`", true, false},
+	}
+	for _, test := range tests {
+		s := newFakeServer()
+		s.addFile("binlog.000001", newBinlogStream())
+		s.queries["show grants"] = [][]string{{"Grants for repl@%"}, {test.grant}}
+		s.queries["show global variables like 'binlog_row_metadata'"] = [][]string{{"Variable_name", "Value"}, {"binlog_row_metadata", test.value}}
+		bl, err := s.dial()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got *RowMetadataError
+		bl.SetRowMetadataFunc(test.set, func(err *RowMetadataError) { got = err })
+		if err := bl.Seek(0, "binlog.000001", 4); err != nil {
+			t.Fatal(err)
+		}
+		if (got != nil) != test.called {
+			t.Errorf("%s set=%v: got %v, want called=%v", test.value, test.set, got, test.called)
+		} else if got != nil && got.Value != test.value {
+			t.Errorf("%s set=%v: got Value %q", test.value, test.set, got.Value)
+		}
+		if _, err := bl.NextEvent(); err != nil {
+			t.Errorf("%s set=%v: NextEvent: %v", test.value, test.set, err)
+		}
+		_ = bl.Close()
+	}
+}
@@ -24,10 +24,10 @@ func newServerVersion(str string) (serverVersion, error) {
 		}
 		sv = append(sv, n)
 	}
-	if len(sv) != 3 {
+	if len(sv) < 3 {
 		return nil, fmt.Errorf("binlog: invalid serverVersion %q", str)
 	}
-	return sv, nil
+	return sv[:3], nil // ignore extra components. azure reports "5.6.26.0"
 }
 
 func (sv serverVersion) eq(v serverVersion) bool {
@@ -62,3 +62,90 @@ func (sv serverVersion) binlogVersion() uint16 {
 		return 4 // the row based replication events were added
 	}
 }
+
+// Feature is a capability of server, that depends on its flavor and
+// version. see Remote.Supports.
+type Feature int
+
+// Features of server.
+const (
+	FeatureChecksum               Feature = iota // binlog_checksum
+	FeatureGTID                                  // GTID events
+	FeatureRowsEventV2                           // WRITE_ROWS_EVENTv2 and friends
+	FeatureRowMetadata                           // binlog_row_metadata=FULL
+	FeaturePartialJSON                           // PARTIAL_UPDATE_ROWS_EVENT, binlog_row_value_options=PARTIAL_JSON
+	FeatureTransactionCompression                // TRANSACTION_PAYLOAD_EVENT, binlog_transaction_compression
+	FeatureBinaryLogStatus                       // SHOW BINARY LOG STATUS, in place of SHOW MASTER STATUS
+	FeatureMasterStatus                          // SHOW MASTER STATUS, removed in MySQL 8.4
+)
+
+var featureNames = []string{
+	"checksum", "gtid", "rowsEventV2", "rowMetadata", "partialJSON",
+	"transactionCompression", "binaryLogStatus", "masterStatus",
+}
+
+func (f Feature) String() string {
+	if f >= 0 && int(f) < len(featureNames) {
+		return featureNames[f]
+	}
+	return fmt.Sprintf("Feature(%d)", int(f))
+}
+
+// featureVersions has, for each flavor, versions from which a feature
+// is supported, and until which it is supported. nil means no bound.
+// A missing flavor means the feature is never supported.
+var featureVersions = map[Feature]map[string][2]serverVersion{
+	FeatureChecksum: {
+		"mysql":   {{5, 6, 2}, nil},
+		"mariadb": {{5, 3, 0}, nil},
+	},
+	FeatureGTID: {
+		"mysql":   {{5, 6, 5}, nil},
+		"mariadb": {{10, 0, 2}, nil},
+	},
+	FeatureRowsEventV2: {
+		"mysql": {{5, 6, 2}, nil},
+	},
+	FeatureRowMetadata: {
+		"mysql":   {{8, 0, 1}, nil},
+		"mariadb": {{10, 5, 0}, nil},
+	},
+	FeaturePartialJSON: {
+		"mysql": {{8, 0, 3}, nil},
+	},
+	FeatureTransactionCompression: {
+		"mysql": {{8, 0, 20}, nil},
+	},
+	FeatureBinaryLogStatus: {
+		"mysql": {{8, 2, 0}, nil},
+	},
+	FeatureMasterStatus: {
+		"mysql":   {nil, {8, 4, 0}},
+		"mariadb": {nil, nil},
+	},
+}
+
+// Supports tells whether server that created the binlog supports
+// feature f.
+func (e FormatDescriptionEvent) Supports(f Feature) bool {
+	return supports(e.ServerVersion, f)
+}
+
+// supports tells whether server of given version supports f. Version
+// that cannot be parsed, is assumed to be of latest MySQL.
+func supports(version string, f Feature) bool {
+	fl := flavor(version)
+	if fl == "mariadb" {
+		// handshake of mariadb reports "5.5.5-10.6.12-MariaDB"
+		version = strings.TrimPrefix(version, "5.5.5-")
+	}
+	bounds, ok := featureVersions[f][fl]
+	if !ok {
+		return false
+	}
+	sv, err := newServerVersion(version)
+	if err != nil {
+		return bounds[1] == nil
+	}
+	return (bounds[0] == nil || !sv.lt(bounds[0])) && (bounds[1] == nil || sv.lt(bounds[1]))
+}
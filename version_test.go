@@ -0,0 +1,59 @@
+package binlog
+
+import "testing"
+
+func TestSupports(t *testing.T) {
+	tests := []struct {
+		version string
+		f       Feature
+		want    bool
+	}{
+		{"5.5.62-log", FeatureChecksum, false},
+		{"5.6.2", FeatureChecksum, true},
+		{"5.6.26.0", FeatureGTID, true},
+		{"5.5.5-10.6.12-MariaDB", FeatureGTID, true},
+		{"10.6.12-MariaDB-log", FeatureRowsEventV2, false},
+		{"8.0.0", FeatureRowMetadata, false},
+		{"8.0.23", FeatureRowMetadata, true},
+		{"10.4.1-MariaDB", FeatureRowMetadata, false},
+		{"10.5.8-MariaDB", FeatureRowMetadata, true},
+		{"8.0.19", FeatureTransactionCompression, false},
+		{"8.0.20", FeatureTransactionCompression, true},
+		{"8.0.23", FeatureMasterStatus, true},
+		{"8.0.23", FeatureBinaryLogStatus, false},
+		{"8.4.0", FeatureMasterStatus, false},
+		{"8.4.0", FeatureBinaryLogStatus, true},
+		{"11.4.2-MariaDB", FeatureMasterStatus, true},
+		{"unknown", FeatureBinaryLogStatus, true},
+		{"unknown", FeatureMasterStatus, false},
+	}
+	for _, test := range tests {
+		if got := supports(test.version, test.f); got != test.want {
+			t.Errorf("supports(%q, %v): got %v, want %v", test.version, test.f, got, test.want)
+		}
+	}
+}
+
+func TestRemote_Supports(t *testing.T) {
+	s := newFakeServer()
+	s.version = "8.4.0"
+	s.denied["show master status"] = true
+	f := newBinlogStream()
+	f.XID(7)
+	s.addFile("binlog.000001", f)
+	bl, err := s.dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bl.Close()
+	if bl.Supports(FeatureMasterStatus) || !bl.Supports(FeatureTransactionCompression) {
+		t.Fatalf("got features %v", bl.Features())
+	}
+	file, pos, err := bl.MasterStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file != "binlog.000001" || pos != uint32(len(f.Bytes())) {
+		t.Fatalf("got %s:%d", file, pos)
+	}
+}
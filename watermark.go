@@ -0,0 +1,102 @@
+package binlog
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"time"
+)
+
+// WatermarkWriter writes watermarks to a table on the source, so that
+// they can be recognized in the binlog stream by WatermarkDetector, to
+// measure end-to-end lag, or to fence backfill chunks. The table must
+// be created as:
+//
+//	create table watermark (id int primary key, value varchar(64), ts bigint)
+//
+// Each writer updates its own row, so that writers of multiple
+// consumers can share the table.
+type WatermarkWriter struct {
+	DB    *sql.DB // connection to the source
+	Table string  // as "db.table"
+	ID    int     // id of row to update
+	Clock Clock   // used for ts column. nil means SystemClock
+}
+
+// Write writes new unique watermark, and returns its value.
+func (w *WatermarkWriter) Write() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	v := hex.EncodeToString(b)
+	return v, w.WriteValue(v)
+}
+
+// WriteValue writes watermark with given value.
+func (w *WatermarkWriter) WriteValue(v string) error {
+	schema, table := splitTable(w.Table)
+	ts := clockOrSystem(w.Clock).Now().UnixNano()
+	q := fmt.Sprintf("insert into %s.%s (id, value, ts) values (?, ?, ?) on duplicate key update value = ?, ts = ?", quoteIdent(schema), quoteIdent(table))
+	_, err := w.DB.Exec(q, w.ID, v, ts, v, ts)
+	return err
+}
+
+// Watermark is watermark row found in binlog stream.
+type Watermark struct {
+	ID      int64
+	Value   string
+	Written time.Time     // when it was written
+	Lag     time.Duration // since written, when detected
+}
+
+// WatermarkDetector recognizes watermarks written by WatermarkWriter
+// in binlog stream. Requires binlog_row_image=FULL.
+type WatermarkDetector struct {
+	Table string // as "db.table"
+	Clock Clock  // used to compute lag. nil means SystemClock
+}
+
+// Detect returns watermarks in e, if it is RowsEvent of watermark
+// table, reading its rows from src. Deleted rows are ignored.
+func (d *WatermarkDetector) Detect(e Event, src EventSource) (marks []Watermark, ok bool, err error) {
+	re, ok := e.Data.(RowsEvent)
+	if !ok || re.TableMap == nil || re.TableMap.SchemaName+"."+re.TableMap.TableName != d.Table {
+		return nil, false, nil
+	}
+	now := clockOrSystem(d.Clock).Now()
+	for {
+		values, _, err := src.NextRow()
+		if err == io.EOF {
+			return marks, true, nil
+		}
+		if err != nil {
+			return nil, true, err
+		}
+		if e.Header.EventType.IsDeleteRows() {
+			continue
+		}
+		var m Watermark
+		var ts int64
+		for i, col := range re.Columns() {
+			if i >= len(values) || values[i] == nil {
+				continue
+			}
+			switch {
+			case col.Name == "id" || col.Name == "" && col.Ordinal == 0:
+				_ = convertAssign(&m.ID, values[i])
+			case col.Name == "value" || col.Name == "" && col.Ordinal == 1:
+				_ = convertAssign(&m.Value, values[i])
+			case col.Name == "ts" || col.Name == "" && col.Ordinal == 2:
+				_ = convertAssign(&ts, values[i])
+			}
+		}
+		if ts != 0 {
+			m.Written = time.Unix(0, ts)
+			m.Lag = now.Sub(m.Written)
+		}
+		marks = append(marks, m)
+	}
+}
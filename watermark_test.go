@@ -0,0 +1,54 @@
+package binlog
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWatermark(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}
+	src := &fakeSource{}
+	var query string
+	d := &fakeDriver{
+		exec: func(q string, args []driver.Value) error {
+			query = q
+			src.pushRows(WRITE_ROWS_EVENTv2, "test", "watermark", [2][]interface{}{{int32(args[0].(int64)), args[1], args[2]}})
+			return nil
+		},
+	}
+	w := &WatermarkWriter{DB: sql.OpenDB(d), Table: "test.watermark", ID: 7, Clock: clock}
+	v, err := w.Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "insert into `test`.`watermark` (id, value, ts) values (?, ?, ?) on duplicate key update value = ?, ts = ?"; query != want {
+		t.Fatalf("got %q, want %q", query, want)
+	}
+	src.pushRows(WRITE_ROWS_EVENTv2, "test", "users", [2][]interface{}{{int32(1), "a"}})
+
+	clock.Sleep(3 * time.Second)
+	det := &WatermarkDetector{Table: "test.watermark", Clock: clock}
+	var got []string
+	for {
+		e, err := src.NextEvent()
+		if err != nil {
+			break
+		}
+		marks, ok, err := det.Detect(e, src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fmt.Sprint(ok, marks))
+	}
+	want := []string{
+		fmt.Sprint(true, []Watermark{{ID: 7, Value: v, Written: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC).Local(), Lag: 3 * time.Second}}),
+		fmt.Sprint(false, []Watermark(nil)),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,151 @@
+package binlog
+
+import (
+	"io"
+	"sort"
+	"time"
+)
+
+// Window is a tumbling window of changes of a table, counted by
+// Windower.
+type Window struct {
+	Table      string    // as "schema.table"
+	Start, End time.Time // End is exclusive
+
+	Inserts, Updates, Deletes int64 // number of rows changed
+}
+
+// Windower groups row changes into tumbling windows of Size by event
+// timestamp, per table, for basic streaming aggregation, such as per
+// minute per table counts.
+//
+// A window is emitted once the watermark passes its end. Watermark is
+// the latest event timestamp seen, minus AllowedLateness. Changes older
+// than the watermark, whose window is already emitted, are counted in
+// Late and dropped.
+type Windower struct {
+	Src             EventSource // used by Run
+	Size            time.Duration
+	AllowedLateness time.Duration
+
+	// Emit is called with windows, in order of Start and Table, once
+	// they are complete.
+	Emit func(w Window) error
+
+	Late int64 // rows dropped as late
+
+	windows   map[windowKey]*Window
+	watermark time.Time
+}
+
+type windowKey struct {
+	table string
+	start int64 // unix nanos
+}
+
+// Run reads events from Src, adding their rows, till Src returns error.
+// On io.EOF, open windows are flushed and nil is returned.
+func (w *Windower) Run() error {
+	for {
+		e, err := w.Src.NextEvent()
+		if err == io.EOF {
+			return w.Flush()
+		}
+		if err != nil {
+			return err
+		}
+		if e.Header.Timestamp == 0 {
+			// artificial events, such as heartbeat
+			continue
+		}
+		t := time.Unix(int64(e.Header.Timestamp), 0)
+		if d, ok := e.Data.(RowsEvent); ok && d.TableMap != nil {
+			n := 0
+			for {
+				_, _, err := w.Src.NextRow()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					return err
+				}
+				n++
+			}
+			table := d.TableMap.SchemaName + "." + d.TableMap.TableName
+			if err := w.Add(t, table, e.Header.EventType, n); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := w.Advance(t); err != nil {
+			return err
+		}
+	}
+}
+
+// Add counts n rows of table changed at time t by RowsEvent of given
+// type, and advances watermark.
+func (w *Windower) Add(t time.Time, table string, typ EventType, n int) error {
+	start := t.Truncate(w.Size)
+	if !w.watermark.IsZero() && !start.Add(w.Size).After(w.watermark) {
+		w.Late += int64(n)
+		return nil
+	}
+	if w.windows == nil {
+		w.windows = make(map[windowKey]*Window)
+	}
+	k := windowKey{table, start.UnixNano()}
+	win, ok := w.windows[k]
+	if !ok {
+		win = &Window{Table: table, Start: start, End: start.Add(w.Size)}
+		w.windows[k] = win
+	}
+	switch {
+	case typ.IsWriteRows():
+		win.Inserts += int64(n)
+	case typ.IsUpdateRows():
+		win.Updates += int64(n)
+	case typ.IsDeleteRows():
+		win.Deletes += int64(n)
+	}
+	return w.Advance(t)
+}
+
+// Advance advances watermark as per event time t, emitting windows
+// which end on or before the watermark. It is useful to close windows
+// when there are no changes.
+func (w *Windower) Advance(t time.Time) error {
+	wm := t.Add(-w.AllowedLateness)
+	if !wm.After(w.watermark) {
+		return nil
+	}
+	w.watermark = wm
+	return w.emit(func(win *Window) bool { return !win.End.After(wm) })
+}
+
+// Flush emits all open windows, such as at end of stream.
+func (w *Windower) Flush() error {
+	return w.emit(func(*Window) bool { return true })
+}
+
+func (w *Windower) emit(done func(win *Window) bool) error {
+	var list []*Window
+	for k, win := range w.windows {
+		if done(win) {
+			list = append(list, win)
+			delete(w.windows, k)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].Start.Equal(list[j].Start) {
+			return list[i].Start.Before(list[j].Start)
+		}
+		return list[i].Table < list[j].Table
+	})
+	for _, win := range list {
+		if err := w.Emit(*win); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package binlog
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWindower(t *testing.T) {
+	var got []Window
+	w := &Windower{
+		Size:            time.Minute,
+		AllowedLateness: 10 * time.Second,
+		Emit: func(win Window) error {
+			got = append(got, win)
+			return nil
+		},
+	}
+	base := time.Unix(1600000020, 0) // 1600000020 is start of minute
+	at := func(sec int) time.Time { return base.Add(time.Duration(sec) * time.Second) }
+	for _, c := range []struct {
+		sec   int
+		table string
+		typ   EventType
+		n     int
+	}{
+		{0, "db.t2", WRITE_ROWS_EVENTv2, 2},
+		{5, "db.t1", UPDATE_ROWS_EVENTv2, 1},
+		{62, "db.t1", WRITE_ROWS_EVENTv2, 1},
+		{55, "db.t1", DELETE_ROWS_EVENTv2, 3}, // late, but within allowed lateness
+		{75, "db.t1", WRITE_ROWS_EVENTv2, 1},  // closes first minute
+		{30, "db.t1", WRITE_ROWS_EVENTv2, 4},  // late
+	} {
+		if err := w.Add(at(c.sec), c.table, c.typ, c.n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []Window{
+		{Table: "db.t1", Start: at(0), End: at(60), Updates: 1, Deletes: 3},
+		{Table: "db.t2", Start: at(0), End: at(60), Inserts: 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	if w.Late != 4 {
+		t.Fatalf("Late: got %d, want 4", w.Late)
+	}
+	got = nil
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	want = []Window{{Table: "db.t1", Start: at(60), End: at(120), Inserts: 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestWindower_Run(t *testing.T) {
+	bl, err := Open(filepath.Join("testdata", "fixtures", "mysql80_full"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bl.Close()
+	if err := bl.Seek(0, "binlog.000001", 4); err != nil {
+		t.Fatal(err)
+	}
+	var got []Window
+	w := &Windower{Src: bl, Size: time.Hour, Emit: func(win Window) error {
+		got = append(got, win)
+		return nil
+	}}
+	if err := w.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Table != "test.all_types" || got[0].Inserts != 2 {
+		t.Fatalf("got %+v", got)
+	}
+}
@@ -4,19 +4,34 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"net"
+	"sync"
 )
 
+// writer encodes a command into packets. Packets are written together
+// by Close, so that multi-packet commands take a single writev, when
+// underlying writer is a network connection.
 type writer struct {
-	wd  io.Writer
-	buf []byte
-	seq *uint8
-	err error
+	wd   io.Writer
+	buf  []byte      // current packet
+	full net.Buffers // complete packets, not yet written
+	seq  *uint8
+	err  error
 }
 
+// writerBufs pools packet buffers, so that commands do not allocate
+// their buffers afresh.
+var writerBufs = sync.Pool{
+	New: func() interface{} { return make([]byte, 0, 4096) },
+}
+
+// maxPooledBuf is capacity of largest packet buffer returned to pool.
+const maxPooledBuf = 64 << 10
+
 func newWriter(w io.Writer, seq *uint8) *writer {
 	return &writer{
 		wd:  w,
-		buf: make([]byte, 4, headerSize+maxPacketSize),
+		buf: writerBufs.Get().([]byte)[:headerSize],
 		seq: seq,
 	}
 }
@@ -25,14 +40,11 @@ func (w *writer) flush() error {
 	if w.err != nil {
 		return w.err
 	}
-	for len(w.buf) >= headerSize+maxPacketSize {
+	if len(w.buf) == headerSize+maxPacketSize {
 		w.buf[0], w.buf[1], w.buf[2], w.buf[3] = 0xff, 0xff, 0xff, *w.seq
 		*w.seq++
-		if _, w.err = w.wd.Write(w.buf[:headerSize+maxPacketSize]); w.err != nil {
-			return w.err
-		}
-		copy(w.buf[4:], w.buf[headerSize+maxPacketSize:])
-		w.buf = w.buf[0 : headerSize+len(w.buf)-(headerSize+maxPacketSize)]
+		w.full = append(w.full, w.buf)
+		w.buf = writerBufs.Get().([]byte)[:headerSize]
 	}
 	return nil
 }
@@ -44,7 +56,15 @@ func (w *writer) Close() error {
 	payload := len(w.buf) - headerSize
 	w.buf[0], w.buf[1], w.buf[2], w.buf[3] = byte(payload), byte(payload>>8), byte(payload>>16), *w.seq
 	*w.seq++
-	_, err := w.wd.Write(w.buf)
+	packets := append(w.full, w.buf)
+	bufs := append(net.Buffers(nil), packets...) // consumed by WriteTo
+	_, err := bufs.WriteTo(w.wd)
+	for _, b := range packets {
+		if cap(b) <= maxPooledBuf {
+			writerBufs.Put(b[:0])
+		}
+	}
+	w.buf, w.full = nil, nil
 	return err
 }
 
@@ -0,0 +1,44 @@
+package binlog
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriter_multiPacket(t *testing.T) {
+	for _, size := range []int{0, 10, maxPacketSize - 1, maxPacketSize, maxPacketSize + 10} {
+		payload := bytes.Repeat([]byte{'x'}, size)
+		var out bytes.Buffer
+		var seq uint8
+		w := newWriter(&out, &seq)
+		if _, err := w.Write(payload); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		// read back packets
+		var got []byte
+		packets := 0
+		b := out.Bytes()
+		for {
+			n := int(b[0]) | int(b[1])<<8 | int(b[2])<<16
+			if int(b[3]) != packets {
+				t.Fatalf("size %d: packet %d has seq %d", size, packets, b[3])
+			}
+			got = append(got, b[headerSize:headerSize+n]...)
+			b = b[headerSize+n:]
+			packets++
+			if n < maxPacketSize {
+				break
+			}
+		}
+		if len(b) != 0 || !bytes.Equal(got, payload) {
+			t.Fatalf("size %d: payload mismatch", size)
+		}
+		if want := size/maxPacketSize + 1; packets != want || int(seq) != want {
+			t.Fatalf("size %d: got %d packets, seq %d, want %d", size, packets, seq, want)
+		}
+	}
+}
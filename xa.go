@@ -0,0 +1,187 @@
+package binlog
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// String returns xid as used in XA statements, such as X'6731',X'62',1.
+func (x XID) String() string {
+	return fmt.Sprintf("X'%s',X'%s',%d", hex.EncodeToString(x.GTRID), hex.EncodeToString(x.BQUAL), x.FormatID)
+}
+
+// parseXID parses xid in XA statement, such as:
+//
+//	'gtrid'
+//	X'6731',X'62',1
+func parseXID(s string) (XID, error) {
+	x := XID{FormatID: 1}
+	s = strings.TrimSpace(s)
+	for i := 0; i < 3 && s != ""; i++ {
+		if i > 0 {
+			if s[0] != ',' {
+				break
+			}
+			s = strings.TrimSpace(s[1:])
+		}
+		if i == 2 {
+			j := 0
+			for j < len(s) && '0' <= s[j] && s[j] <= '9' {
+				j++
+			}
+			n, err := strconv.ParseInt(s[:j], 10, 32)
+			if err != nil {
+				return x, fmt.Errorf("binlog: invalid xid format id %q", s)
+			}
+			x.FormatID, s = int32(n), s[j:]
+			break
+		}
+		b, rest, err := parseXIDPart(s)
+		if err != nil {
+			return x, err
+		}
+		if i == 0 {
+			x.GTRID = b
+		} else {
+			x.BQUAL = b
+		}
+		s = strings.TrimSpace(rest)
+	}
+	if x.GTRID == nil {
+		return x, fmt.Errorf("binlog: xid missing")
+	}
+	return x, nil
+}
+
+// parseXIDPart parses string literal at start of s, which is either
+// quoted string or hexadecimal literal.
+func parseXIDPart(s string) ([]byte, string, error) {
+	switch {
+	case len(s) > 2 && (s[0] == 'X' || s[0] == 'x') && s[1] == '\'':
+		j := strings.IndexByte(s[2:], '\'')
+		if j != -1 {
+			b, err := hex.DecodeString(s[2 : 2+j])
+			if err == nil {
+				return b, s[2+j+1:], nil
+			}
+		}
+	case len(s) > 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X'):
+		j := 2
+		for j < len(s) && strings.IndexByte("0123456789abcdefABCDEF", s[j]) != -1 {
+			j++
+		}
+		b, err := hex.DecodeString(s[2:j])
+		if err == nil {
+			return b, s[j:], nil
+		}
+	case len(s) > 0 && (s[0] == '\'' || s[0] == '"'):
+		var b []byte
+		for j := 1; j < len(s); j++ {
+			switch c := s[j]; {
+			case c == '\\' && j+1 < len(s):
+				j++
+				b = append(b, s[j])
+			case c == s[0] && j+1 < len(s) && s[j+1] == s[0]:
+				j++
+				b = append(b, c)
+			case c == s[0]:
+				if b == nil {
+					b = []byte{}
+				}
+				return b, s[j+1:], nil
+			default:
+				b = append(b, c)
+			}
+		}
+	}
+	return nil, "", fmt.Errorf("binlog: invalid xid %q", s)
+}
+
+// XAState is state change of an XA transaction.
+type XAState uint8
+
+// XAState constants.
+const (
+	XAStart    XAState = iota + 1 // XA START
+	XAEnd                         // XA END
+	XAPrepare                     // XA PREPARE
+	XACommit                      // XA COMMIT
+	XARollback                    // XA ROLLBACK
+)
+
+var xaStateNames = map[XAState]string{
+	XAStart:    "start",
+	XAEnd:      "end",
+	XAPrepare:  "prepare",
+	XACommit:   "commit",
+	XARollback: "rollback",
+}
+
+func (s XAState) String() string {
+	if name, ok := xaStateNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("XAState(%d)", uint8(s))
+}
+
+// XATransactionEvent is a synthetic event, returned along with
+// boundary events, right after QueryEvent with XA statement or
+// XAPrepareEvent. It is never written to binlog.
+//
+// The changes of XA transaction are logged between XA START and
+// XA PREPARE, but are committed only on XA COMMIT, which is logged
+// later as a separate transaction, possibly after changes of other
+// transactions. Consumers must hold the changes of prepared XA
+// transaction, until XACommit or XARollback with same XID.
+type XATransactionEvent struct {
+	State    XAState
+	XID      XID
+	OnePhase bool // XA COMMIT ... ONE PHASE, logged without XA PREPARE
+}
+
+// xaEvent returns XATransactionEvent for event e, if it is
+// XA statement or XAPrepareEvent.
+func xaEvent(e Event) (XATransactionEvent, bool) {
+	switch d := e.Data.(type) {
+	case XAPrepareEvent:
+		if d.OnePhase {
+			return XATransactionEvent{XACommit, d.XID, true}, true
+		}
+		return XATransactionEvent{XAPrepare, d.XID, false}, true
+	case QueryEvent:
+		q := strings.TrimSpace(d.Query)
+		if !hasPrefixFold(q, "XA ") {
+			return XATransactionEvent{}, false
+		}
+		q = strings.TrimSpace(q[3:])
+		for _, state := range []XAState{XAStart, XAEnd, XAPrepare, XACommit, XARollback} {
+			name := xaStateNames[state]
+			if hasPrefixFold(q, name+" ") {
+				xid, err := parseXID(q[len(name):])
+				if err != nil {
+					return XATransactionEvent{}, false
+				}
+				onePhase := state == XACommit && hasSuffixFold(q, " ONE PHASE")
+				return XATransactionEvent{state, xid, onePhase}, true
+			}
+		}
+	}
+	return XATransactionEvent{}, false
+}
+
+// queueXAEvent queues XATransactionEvent for e, if boundary
+// events are enabled.
+func queueXAEvent(r *reader, e Event) {
+	if !r.opts.boundaryEvents {
+		return
+	}
+	if xe, ok := xaEvent(e); ok {
+		r.pending = append(r.pending, synthetic(e, xe))
+	}
+}
+
+func hasSuffixFold(s, suffix string) bool {
+	return len(s) >= len(suffix) && strings.EqualFold(s[len(s)-len(suffix):], suffix)
+}
@@ -0,0 +1,75 @@
+package binlog
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseXID(t *testing.T) {
+	tests := []struct {
+		s    string
+		want XID
+	}{
+		{"'g1'", XID{1, []byte("g1"), nil}},
+		{"X'6731',X'',1", XID{1, []byte("g1"), []byte{}}},
+		{"'g''1', 'b\\'1' , 7", XID{7, []byte("g'1"), []byte("b'1")}},
+		{"0x6731,'b' ONE PHASE", XID{1, []byte("g1"), []byte("b")}},
+	}
+	for _, tc := range tests {
+		got, err := parseXID(tc.s)
+		if err != nil {
+			t.Errorf("parseXID(%q): %v", tc.s, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("parseXID(%q): got %#v, want %#v", tc.s, got, tc.want)
+		}
+	}
+	for _, s := range []string{"", "g1", "'g1", "X'zz'", "'g','b',x"} {
+		if _, err := parseXID(s); err == nil {
+			t.Errorf("parseXID(%q): error expected", s)
+		}
+	}
+	if got := (XID{1, []byte("g1"), []byte("b")}).String(); got != "X'6731',X'62',1" {
+		t.Fatal(got)
+	}
+}
+
+func TestTxTracker_xa(t *testing.T) {
+	xid := XID{1, []byte("g1"), []byte{}}
+	events := []Event{
+		{Header: EventHeader{EventType: QUERY_EVENT}, Data: QueryEvent{Query: "XA START X'6731',X'',1"}},
+		{Header: EventHeader{EventType: WRITE_ROWS_EVENTv2}, Data: RowsEvent{}},
+		{Header: EventHeader{EventType: QUERY_EVENT}, Data: QueryEvent{Query: "XA END X'6731',X'',1"}},
+		{Header: EventHeader{EventType: XA_PREPARE_LOG_EVENT}, Data: XAPrepareEvent{XID: xid}},
+		{Header: EventHeader{EventType: QUERY_EVENT}, Data: QueryEvent{Query: "XA COMMIT X'6731',X'',1"}},
+	}
+	r := &reader{opts: &decodeOptions{boundaryEvents: true}}
+	var got []interface{}
+	for _, e := range events {
+		if err := r.tx.track(r, e); err != nil {
+			t.Fatal(err)
+		}
+		for {
+			pe, ok := r.nextPending()
+			if !ok {
+				break
+			}
+			if end, ok := pe.Data.(EndTransactionEvent); ok {
+				pe.Data = EndTransactionEvent{Rollback: end.Rollback, Prepared: end.Prepared}
+			}
+			got = append(got, pe.Data)
+		}
+	}
+	want := []interface{}{
+		BeginTransactionEvent{},
+		XATransactionEvent{State: XAStart, XID: xid},
+		XATransactionEvent{State: XAEnd, XID: xid},
+		EndTransactionEvent{Prepared: true},
+		XATransactionEvent{State: XAPrepare, XID: xid},
+		XATransactionEvent{State: XACommit, XID: xid},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %#v, want %#v", got, want)
+	}
+}